@@ -0,0 +1,86 @@
+package mari
+
+import "bufio"
+import "fmt"
+import "io"
+
+
+//============================================= Mari Backup
+
+
+// Backup
+//	Streams a consistent copy of the current version of Mari to w, returning the total number of bytes written.
+//	Nodes are laid out as they would be in a compacted file, so the backup can be restored without rebuilding the trie.
+//	The backup is taken in a read transaction, so writes can continue but resizing and compaction wait until it completes.
+func (mariInst *Mari) Backup(w io.Writer) (int64, error) {
+	writer := bufio.NewWriter(w)
+	var written int64
+
+	write := func(data []byte) error {
+		n, writeErr := writer.Write(data)
+		written += int64(n)
+		return writeErr
+	}
+
+	readErr := mariInst.ReadTx(func(tx *MariTx) error {
+		if ! mariInst.opened { return fmt.Errorf("attempting to back up: %w", ErrClosed) }
+
+		currRoot := loadINodeFromPointer(tx.root)
+
+		header := append([]byte(BackupMagic), serializeUint64(currRoot.version)...)
+		writeHeaderErr := write(header)
+		if writeHeaderErr != nil { return writeHeaderErr }
+
+		endOff, backupErr := mariInst.backupRecursive(currRoot, uint64(InitRootOffset), write)
+		if backupErr != nil { return backupErr }
+
+		trailer := append(serializeUint64(currRoot.startOffset), serializeUint64(endOff)...)
+		return write(trailer)
+	})
+
+	if readErr != nil { return written, readErr }
+
+	flushErr := writer.Flush()
+	if flushErr != nil { return written, flushErr }
+
+	return written, nil
+}
+
+// backupRecursive
+//	Recursively writes the subtree rooted at node, children first, starting at offset in the restored file.
+//	As each child is written, the child pointer in the node is updated to the offset of the child in the restored file, so the node can be written directly after its children.
+//	Versions are reset to 0, since only the backed up version is written.
+//	Returns the offset directly after the node's leaf.
+func (mariInst *Mari) backupRecursive(node *MariINode, offset uint64, write func(data []byte) error) (uint64, error) {
+	count, countErr := mariInst.resolveCount(node)
+	if countErr != nil { return 0, countErr }
+
+	nextStartOffset := offset
+
+	for _, child := range node.children {
+		childNode, getChildErr := mariInst.readINodeFromMemMap(child.startOffset)
+		if getChildErr != nil { return 0, getChildErr }
+
+		updatedOffset, backupErr := mariInst.backupRecursive(childNode, nextStartOffset, write)
+		if backupErr != nil { return 0, backupErr }
+
+		child.startOffset = childNode.startOffset
+		nextStartOffset = updatedOffset
+	}
+
+	node.version = 0
+	node.startOffset = nextStartOffset
+	node.count = count
+	node.leaf.version = 0
+
+	sNode, serializeErr := node.serializeINode(false)
+	if serializeErr != nil { return 0, serializeErr }
+
+	sLeaf, sLeafErr := node.leaf.serializeLNode()
+	if sLeafErr != nil { return 0, sLeafErr }
+
+	writeNodeErr := write(append(sNode, sLeaf...))
+	if writeNodeErr != nil { return 0, writeNodeErr }
+
+	return node.leaf.endOffset + 1, nil
+}
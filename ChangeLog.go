@@ -0,0 +1,224 @@
+package mari
+
+import "errors"
+import "fmt"
+import "os"
+
+
+//============================================= Mari Change Log
+
+
+// ReadChangeLog
+//	Read every entry in the change log with a sequence number greater than or equal to fromSequence, in sequence order.
+//	The log is read from disk on every call, so a follower can open the instance read only and poll for new entries.
+func (mariInst *Mari) ReadChangeLog(fromSequence uint64) ([]ChangeEntry, error) {
+	if mariInst.changeLogPath == "" { return nil, errors.New("change log is not enabled, open mari with ChangeLog set") }
+
+	data, readErr := os.ReadFile(mariInst.changeLogPath)
+	if readErr != nil { return nil, readErr }
+
+	var entries []ChangeEntry
+	for currOffset := 0; currOffset < len(data); {
+		entry, entryLength, decodeErr := mariInst.deserializeChangeEntry(data[currOffset:])
+		if decodeErr != nil { return nil, decodeErr }
+		if entry == nil { break }
+
+		if entry.Sequence >= fromSequence { entries = append(entries, *entry) }
+		currOffset += entryLength
+	}
+
+	return entries, nil
+}
+
+// Apply
+//	Replay a change entry read from the change log of a leader in a single write transaction, committed as the version of the entry.
+//	Entries at or below the last applied sequence are skipped, so re-applying an entry is a no-op.
+//	The change log must be enabled on the follower, since applied entries are appended to it under the sequence number of the leader.
+func (mariInst *Mari) Apply(entry ChangeEntry) error {
+	if mariInst.changeLog == nil { return errors.New("apply requires the change log to be enabled on the follower") }
+	if entry.Sequence == 0 { return errors.New("change entry is missing a sequence number") }
+
+	mariInst.changeLogLock.Lock()
+	applied := entry.Sequence <= mariInst.changeLogSeq
+	mariInst.changeLogLock.Unlock()
+
+	if applied { return nil }
+
+	return mariInst.updateTx(func(tx *MariTx) error {
+		tx.sequence = entry.Sequence
+
+		for _, put := range entry.Puts {
+			putErr := tx.put(put.Key, put.Value)
+			if putErr != nil { return putErr }
+		}
+
+		for _, key := range entry.Deletes {
+			delErr := tx.delete(key)
+			if delErr != nil { return delErr }
+		}
+
+		return nil
+	}, entry.Version)
+}
+
+// openChangeLog
+//	Open the change log next to the memory mapped file, determining the last sequence number from the existing entries.
+//	If the last entry was only partially written, the log is truncated back to the end of the last complete entry so new entries are appended after it.
+//	Read only instances only record the path of the log, since it is read from disk on every call to ReadChangeLog.
+func (mariInst *Mari) openChangeLog(fileWithFilePath string) error {
+	mariInst.changeLogPath = fileWithFilePath + ChangeLogFileName
+	if mariInst.readOnly { return nil }
+
+	var openErr error
+	mariInst.changeLog, openErr = os.OpenFile(mariInst.changeLogPath, os.O_RDWR | os.O_CREATE | os.O_APPEND, mariInst.fileMode)
+	if openErr != nil { return openErr }
+
+	data, readErr := os.ReadFile(mariInst.changeLogPath)
+	if readErr != nil { return readErr }
+
+	currOffset := 0
+	for currOffset < len(data) {
+		entry, entryLength, decodeErr := mariInst.deserializeChangeEntry(data[currOffset:])
+		if decodeErr != nil { return decodeErr }
+		if entry == nil { break }
+
+		mariInst.changeLogSeq = entry.Sequence
+		currOffset += entryLength
+	}
+
+	mariInst.changeLogSize = int64(currOffset)
+	if currOffset < len(data) { return mariInst.changeLog.Truncate(int64(currOffset)) }
+	return nil
+}
+
+// closeChangeLog
+//	Flush and close the change log, if one is open.
+func (mariInst *Mari) closeChangeLog() error {
+	if mariInst.changeLog == nil { return nil }
+
+	syncErr := mariInst.changeLog.Sync()
+	if syncErr != nil { return syncErr }
+
+	return mariInst.changeLog.Close()
+}
+
+// appendChangeLog
+//	Append the changes of a committed transaction to the change log under the next sequence number, or under sequence if it is set by Apply.
+//	This is called while the change log lock is held from before the commit, so entries are appended in the order transactions commit.
+//	The entry is appended before the new root is published, so if the append fails, the log is truncated back to the end of the last entry and the transaction is not committed.
+func (mariInst *Mari) appendChangeLog(version, sequence uint64, changes []KeyValuePair) error {
+	if sequence == 0 { sequence = mariInst.changeLogSeq + 1 }
+	entry := ChangeEntry{ Sequence: sequence, Version: version }
+
+	lastChange := make(map[string]int)
+	for idx, change := range changes { lastChange[string(change.Key)] = idx }
+
+	for idx, change := range changes {
+		if lastChange[string(change.Key)] != idx { continue }
+
+		if change.Value == nil {
+			entry.Deletes = append(entry.Deletes, change.Key)
+		} else { entry.Puts = append(entry.Puts, change) }
+	}
+
+	sEntry, serializeErr := mariInst.serializeChangeEntry(&entry)
+	if serializeErr != nil { return fmt.Errorf("failed to serialize the change log entry for version %d: %w", version, serializeErr) }
+
+	_, writeErr := mariInst.changeLog.Write(sEntry)
+	if writeErr != nil {
+		truncateErr := mariInst.changeLog.Truncate(mariInst.changeLogSize)
+		if truncateErr != nil { return fmt.Errorf("failed to append version %d to the change log: %w, and failed to truncate the partial entry: %s", version, writeErr, truncateErr.Error()) }
+
+		return fmt.Errorf("failed to append version %d to the change log: %w", version, writeErr)
+	}
+
+	mariInst.changeLogSeq = entry.Sequence
+	mariInst.changeLogSize += int64(len(sEntry))
+	return nil
+}
+
+// serializeChangeEntry
+//	Serialize a change entry, prefixed by its length. The sequence, version, and number of puts and deletes are followed by each put and then each delete.
+//	Values are encoded with the value codec, if one is set, so the change log never holds plaintext values that the memory map would not.
+func (mariInst *Mari) serializeChangeEntry(entry *ChangeEntry) ([]byte, error) {
+	var sEntry []byte
+
+	sEntry = append(sEntry, serializeUint64(entry.Sequence)...)
+	sEntry = append(sEntry, serializeUint64(entry.Version)...)
+	sEntry = append(sEntry, serializeUint32(uint32(len(entry.Puts)))...)
+	sEntry = append(sEntry, serializeUint32(uint32(len(entry.Deletes)))...)
+
+	for _, put := range entry.Puts {
+		encoded, encodeErr := mariInst.encodeValue(put.Key, put.Value)
+		if encodeErr != nil { return nil, encodeErr }
+
+		sEntry = append(sEntry, serializeUint16(uint16(len(put.Key)))...)
+		sEntry = append(sEntry, serializeUint32(uint32(len(encoded)))...)
+		sEntry = append(sEntry, put.Key...)
+		sEntry = append(sEntry, encoded...)
+	}
+
+	for _, key := range entry.Deletes {
+		sEntry = append(sEntry, serializeUint16(uint16(len(key)))...)
+		sEntry = append(sEntry, key...)
+	}
+
+	return append(serializeUint32(uint32(len(sEntry))), sEntry...), nil
+}
+
+// deserializeChangeEntry
+//	Deserialize the change entry at the start of data, returning the entry and the total length it occupies.
+//	Nil is returned if data does not hold a complete entry, which happens when the last append was interrupted.
+func (mariInst *Mari) deserializeChangeEntry(data []byte) (*ChangeEntry, int, error) {
+	if len(data) < 4 { return nil, 0, nil }
+
+	length, decLenErr := deserializeUint32(data[:4])
+	if decLenErr != nil { return nil, 0, decLenErr }
+
+	entryLength := 4 + int(length)
+	if len(data) < entryLength || length < ChangeEntryHeaderSize { return nil, 0, nil }
+
+	sEntry := data[4:entryLength]
+	entry := &ChangeEntry{}
+
+	entry.Sequence, _ = deserializeUint64(sEntry[0:8])
+	entry.Version, _ = deserializeUint64(sEntry[8:16])
+	totalPuts, _ := deserializeUint32(sEntry[16:20])
+	totalDeletes, _ := deserializeUint32(sEntry[20:ChangeEntryHeaderSize])
+
+	corruptErr := fmt.Errorf("change log entry %d is corrupt", entry.Sequence)
+	currOffset := ChangeEntryHeaderSize
+
+	for range make([]int, totalPuts) {
+		if currOffset + 6 > len(sEntry) { return nil, 0, corruptErr }
+
+		keyLength, _ := deserializeUint16(sEntry[currOffset:currOffset + 2])
+		valueLength, _ := deserializeUint32(sEntry[currOffset + 2:currOffset + 6])
+
+		keyStart := currOffset + 6
+		valueStart := keyStart + int(keyLength)
+		valueEnd := valueStart + int(valueLength)
+		if valueEnd > len(sEntry) { return nil, 0, corruptErr }
+
+		key := append([]byte{}, sEntry[keyStart:valueStart]...)
+		value, decodeErr := mariInst.decodeValue(key, sEntry[valueStart:valueEnd])
+		if decodeErr != nil { return nil, 0, decodeErr }
+
+		entry.Puts = append(entry.Puts, KeyValuePair{ Version: entry.Version, Key: key, Value: value })
+		currOffset = valueEnd
+	}
+
+	for range make([]int, totalDeletes) {
+		if currOffset + 2 > len(sEntry) { return nil, 0, corruptErr }
+
+		keyLength, _ := deserializeUint16(sEntry[currOffset:currOffset + 2])
+
+		keyEnd := currOffset + 2 + int(keyLength)
+		if keyEnd > len(sEntry) { return nil, 0, corruptErr }
+
+		entry.Deletes = append(entry.Deletes, append([]byte{}, sEntry[currOffset + 2:keyEnd]...))
+		currOffset = keyEnd
+	}
+
+	return entry, entryLength, nil
+}
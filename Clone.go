@@ -0,0 +1,160 @@
+package mari
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "runtime"
+import "sync/atomic"
+
+
+//============================================= Mari Clone
+
+
+// Clone
+//	Write an independent copy of the current committed version of Mari to the file at destPath and open it.
+//	This is a compaction that targets a new file instead of swapping in place, so the copy is renumbered to version 0.
+//	The cloned instance is opened with the same options as the source instance, but is always file backed.
+func (mariInst *Mari) Clone(destPath string) (*Mari, error) {
+	for _, path := range []string{ destPath, destPath + VersionIndexFileName } {
+		_, statErr := os.Stat(path)
+		if statErr == nil { return nil, errors.New("clone destination already exists") }
+		if ! os.IsNotExist(statErr) { return nil, statErr }
+	}
+
+	cloneErr := mariInst.cloneToFile(destPath)
+	if cloneErr != nil { return nil, cloneErr }
+
+	nodePoolSize := mariInst.nodePool.minSize
+	appendOnly := mariInst.appendOnly
+	compactRetain := mariInst.compactRetain
+	compactTrigger := mariInst.compactTrigger
+	compactFragmentation := mariInst.compactFragmentation
+	growthFactor := mariInst.growthFactor
+
+	var nodePoolCeiling *int64
+	if mariInst.nodePool.ceiling > 0 {
+		ceiling := mariInst.nodePool.ceiling
+		nodePoolCeiling = &ceiling
+	}
+
+	var nodeCacheSize int
+	if mariInst.nodeCache != nil { nodeCacheSize = mariInst.nodeCache.capacity }
+
+	opts := MariOpts{
+		Filepath: filepath.Dir(destPath),
+		FileName: filepath.Base(destPath),
+		FileMode: mariInst.fileMode,
+		NodePoolSize: &nodePoolSize,
+		NodePoolCeiling: nodePoolCeiling,
+		NodeCacheSize: nodeCacheSize,
+		OverflowThreshold: mariInst.overflowThreshold,
+		AppendOnly: &appendOnly,
+		CompactRetain: &compactRetain,
+		GrowthFactor: &growthFactor,
+		ValueCodec: mariInst.valueCodec,
+		MergeFunc: mariInst.mergeFunc,
+		OnCommit: mariInst.onCommit,
+		OnCompactionProgress: mariInst.onCompactionProgress,
+		OnCompactionComplete: mariInst.onCompactionComplete,
+		Advise: AdvisePattern(atomic.LoadUint32(&mariInst.advise)),
+		LockMemory: mariInst.lockMemory,
+		Durability: mariInst.durability,
+		FlushInterval: mariInst.flushInterval,
+	}
+
+	if compactFragmentation > 0 {
+		opts.CompactFragmentation = &compactFragmentation
+	} else { opts.CompactTrigger = &compactTrigger }
+
+	return Open(opts)
+}
+
+// cloneToFile
+//	Serialize the current root to the file at destPath, followed by the metadata pointing to the cloned root.
+func (mariInst *Mari) cloneToFile(destPath string) error {
+	return mariInst.withCommittedRoot(func(rootOffset uint64) error {
+		currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
+		if readRootErr != nil { return readRootErr }
+
+		return mariInst.writeRootToFile(currRoot, destPath)
+	})
+}
+
+// withCommittedRoot
+//	Call fn with the offset of the current committed root while holding the read lock, so resizing and compaction wait until fn completes.
+//	The memory map is first remapped if Mari is read only, so the root reflects the latest commit of the writer.
+func (mariInst *Mari) withCommittedRoot(fn func(rootOffset uint64) error) error {
+	remapErr := mariInst.remapReadOnly()
+	if remapErr != nil { return remapErr }
+
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return fmt.Errorf("attempting to copy: %w", ErrClosed) }
+
+	_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+	if loadROffErr != nil { return loadROffErr }
+
+	return fn(rootOffset)
+}
+
+// writeRootToFile
+//	Serialize the root and everything beneath it to the file at destPath, followed by the metadata pointing to the written root.
+//	If the write fails, the partially written file is removed.
+func (mariInst *Mari) writeRootToFile(currRoot *MariINode, destPath string) error {
+	flag := os.O_RDWR | os.O_CREATE | os.O_EXCL
+	destFile, openDestErr := os.OpenFile(destPath, flag, mariInst.fileMode)
+	if openDestErr != nil { return openDestErr }
+
+	compact := &MariCompaction{
+		tempFile: destFile,
+		compactedVersion: currRoot.version,
+		baseVersion: currRoot.version,
+		growthFactor: mariInst.growthFactor,
+	}
+
+	compact.tempData.Store(MMap{})
+
+	writeErr := func() error {
+		resizeErr := compact.resizeTempFile(0)
+		if resizeErr != nil { return resizeErr }
+
+		rootPtr := storeINodeAsPointer(currRoot)
+		endOff, serializeErr := mariInst.serializeCurrentVersionToNewFile(compact, rootPtr, 0, uint64(InitRootOffset))
+		if serializeErr != nil { return serializeErr }
+
+		newMeta := &MariMetaData{
+			version: 0,
+			rootOffset: uint64(InitRootOffset),
+			nextStartOffset: endOff,
+		}
+
+		identity, identityErr := newIdentity()
+		if identityErr != nil { return identityErr }
+
+		writeIdErr := compact.writeIdentityToTempMemMap(identity, 0)
+		if writeIdErr != nil { return writeIdErr }
+
+		_, writeMetaErr := compact.writeMetaToTempMemMap(newMeta.serializeMetaData())
+		if writeMetaErr != nil { return writeMetaErr }
+
+		unmapErr := compact.munmapTemp()
+		if unmapErr != nil { return unmapErr }
+
+		pageSize := uint64(DefaultPageSize)
+		return destFile.Truncate(int64(((endOff + pageSize - 1) / pageSize) * pageSize))
+	}()
+
+	if writeErr != nil {
+		if len(compact.tempData.Load().(MMap)) > 0 { compact.munmapTemp() }
+		destFile.Close()
+		os.Remove(destPath)
+
+		return writeErr
+	}
+
+	return destFile.Close()
+}
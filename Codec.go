@@ -0,0 +1,83 @@
+package mari
+
+import "crypto/aes"
+import "crypto/cipher"
+import "crypto/rand"
+import "errors"
+import "io"
+
+
+//============================================= Mari Codec
+
+
+// NewEncryptionCodec
+//	Create a value codec that encrypts values with AES-GCM, where the key must be 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
+//	Only values are encrypted. Keys are stored in plaintext so the trie stays ordered, which means Range, Iterate, and every other ordered read work as they do without a codec.
+//	A random nonce is generated for every write and stored in front of the ciphertext, and the key of the pair is authenticated with the value so a value moved under a different key fails to decrypt.
+func NewEncryptionCodec(key []byte) (*MariEncryptionCodec, error) {
+	block, blockErr := aes.NewCipher(key)
+	if blockErr != nil { return nil, blockErr }
+
+	aead, gcmErr := cipher.NewGCM(block)
+	if gcmErr != nil { return nil, gcmErr }
+
+	return &MariEncryptionCodec{ aead: aead }, nil
+}
+
+// Encode
+//	Seal the value with a new random nonce, returning the nonce followed by the ciphertext and authentication tag.
+func (codec *MariEncryptionCodec) Encode(key, value []byte) ([]byte, error) {
+	nonce := make([]byte, codec.aead.NonceSize(), codec.aead.NonceSize() + len(value) + codec.aead.Overhead())
+
+	_, readErr := io.ReadFull(rand.Reader, nonce)
+	if readErr != nil { return nil, readErr }
+
+	return codec.aead.Seal(nonce, nonce, value, key), nil
+}
+
+// Decode
+//	Split the nonce from the stored value and open the ciphertext.
+//	If the stored value has been modified, or was encrypted with a different key or under a different key of the trie, an error is returned instead of the value.
+func (codec *MariEncryptionCodec) Decode(key, stored []byte) ([]byte, error) {
+	nonceSize := codec.aead.NonceSize()
+	if len(stored) < nonceSize + codec.aead.Overhead() { return nil, errors.New("encrypted value is too short, it may be corrupt or was not written with the encryption codec") }
+
+	value, openErr := codec.aead.Open(nil, stored[:nonceSize], stored[nonceSize:], key)
+	if openErr != nil { return nil, errors.New("encrypted value failed authentication, it was tampered with or the encryption key is wrong") }
+
+	return value, nil
+}
+
+// encodeValue
+//	Apply the value codec, if one is set, to a value before it is written.
+func (mariInst *Mari) encodeValue(key, value []byte) ([]byte, error) {
+	if mariInst.valueCodec == nil { return value, nil }
+	return mariInst.valueCodec.Encode(key, value)
+}
+
+// decodeValue
+//	Reverse the value codec, if one is set, on a stored value.
+func (mariInst *Mari) decodeValue(key, stored []byte) ([]byte, error) {
+	if mariInst.valueCodec == nil { return stored, nil }
+	return mariInst.valueCodec.Decode(key, stored)
+}
+
+// newKeyValuePair
+//	Create the key-value pair for a leaf, decoding the value with the value codec if one is set.
+//	Values stored out of line are read from the overflow first.
+func (mariInst *Mari) newKeyValuePair(leaf *MariLNode) (*KeyValuePair, error) {
+	stored, resolveErr := mariInst.resolveValue(leaf)
+	if resolveErr != nil { return nil, resolveErr }
+
+	value, decodeErr := mariInst.decodeValue(leaf.key, stored)
+	if decodeErr != nil { return nil, decodeErr }
+
+	return &KeyValuePair{ Version: leaf.version, Key: leaf.key, Value: value }, nil
+}
+
+// newKeyPair
+//	Create the key-value pair for a leaf without its value, for scans with KeysOnly set.
+//	The value is never decoded, so the value of the pair is always nil.
+func (mariInst *Mari) newKeyPair(leaf *MariLNode) (*KeyValuePair, error) {
+	return &KeyValuePair{ Version: leaf.version, Key: leaf.key }, nil
+}
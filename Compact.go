@@ -13,16 +13,24 @@ import "unsafe"
 // newCompaction
 //	Instatiate the compaction strategy on compaction signal.
 //	Creates a new temporary memory mapped file where the version to be snapshotted will be written to.
+//	The temporary file is created with the file mode of the instance, so the mode survives the swap.
+//	For in memory instances, no temporary file is created and the new copy is built in anonymous memory.
 func (mariInst *Mari) newCompaction(compactedVersion uint64) (*MariCompaction, error) {
-	tempFileName := mariInst.file.Name() + "temp"
+	compact := &MariCompaction{
+		compactedVersion: compactedVersion,
+		growthFactor: mariInst.growthFactor,
+		onProgress: mariInst.onCompactionProgress,
+		estimatedTotal: atomic.LoadUint64(&mariInst.liveBytes),
+	}
 
-	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
-	tempFile, openTempFileErr := os.OpenFile(tempFileName, flag, 0600)
-	if openTempFileErr != nil { return nil, openTempFileErr }
+	if ! mariInst.inMemory {
+		tempFileName := mariInst.file.Name() + CompactTempFileName
 
-	compact := &MariCompaction{ 
-		tempFile: tempFile,
-		compactedVersion: compactedVersion,
+		flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
+		tempFile, openTempFileErr := os.OpenFile(tempFileName, flag, mariInst.fileMode)
+		if openTempFileErr != nil { return nil, openTempFileErr }
+
+		compact.tempFile = tempFile
 	}
 
 	compact.tempData.Store(MMap{})
@@ -33,9 +41,73 @@ func (mariInst *Mari) newCompaction(compactedVersion uint64) (*MariCompaction, e
 	return compact, nil
 }
 
+// fragmentationTrigger
+//	Create a compaction trigger that compacts once the ratio of dead bytes to serialized bytes exceeds the threshold.
+//	Dead bytes are the serialized bytes minus the maintained live bytes, and nothing is compacted until at least FragmentationMinSize bytes have been serialized.
+//	The default trigger on the maximum version is still applied.
+func (mariInst *Mari) fragmentationTrigger(threshold float64) MariCompactionTrigger {
+	return func(metaData *MariMetaData) bool {
+		if metaData.version - 1 >= MaxCompactVersion { return true }
+
+		serialized := metaData.nextStartOffset - uint64(InitRootOffset)
+		if serialized < FragmentationMinSize { return false }
+
+		live := atomic.LoadUint64(&mariInst.liveBytes)
+		if live >= serialized { return false }
+
+		return float64(serialized - live) / float64(serialized) > threshold
+	}
+}
+
+// initializeLiveBytes
+//	Determine the live bytes of the instance on open.
+//	If the fragmentation trigger is enabled, every node in the current version is visited to sum the serialized size, otherwise all serialized bytes are assumed to be live.
+func (mariInst *Mari) initializeLiveBytes() error {
+	_, endOffset, loadEndOffErr := mariInst.loadMetaEndSerialized()
+	if loadEndOffErr != nil { return loadEndOffErr }
+
+	live := endOffset - uint64(InitRootOffset)
+
+	if mariInst.compactFragmentation > 0 {
+		_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+		if loadROffErr != nil { return loadROffErr }
+
+		currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
+		if readRootErr != nil { return readRootErr }
+
+		var stats MariStats
+		statsErr := mariInst.statsRecursive(currRoot, 0, &stats)
+		if statsErr != nil { return statsErr }
+
+		live = stats.LiveBytes
+	}
+
+	atomic.StoreUint64(&mariInst.liveBytes, live)
+	return nil
+}
+
+// updateLiveBytes
+//	After a path copy is written, the serialized path becomes live and the nodes it replaced become dead.
+//	The live bytes are clamped at 0, since nodes removed from the trie without being copied are not tracked.
+func (mariInst *Mari) updateLiveBytes(written, replaced uint64) {
+	for {
+		live := atomic.LoadUint64(&mariInst.liveBytes)
+
+		updated := live + written
+		if replaced < updated {
+			updated -= replaced
+		} else { updated = 0 }
+
+		if atomic.CompareAndSwapUint64(&mariInst.liveBytes, live, updated) { return }
+	}
+}
+
 // signalCompact
-//	When the maximum version is reached, signal the compaction go routine
+//	When the maximum version is reached, signal the compaction go routine.
+//	If the instance is append only, compaction never occurs so no signal is sent.
 func (mariInst *Mari) signalCompact() {
+	if mariInst.appendOnly || ! mariInst.opened { return }
+
 	select { 
 		case mariInst.signalCompactChan <- true:
 		default:
@@ -43,12 +115,16 @@ func (mariInst *Mari) signalCompact() {
 }
 
 // compactHandler
-//	Run in a separate go routine.
+//	Run in a separate go routine, which returns once the signal channel is closed.
 //	On signal, sets the resizing flag and acquires the write lock.
-//	The current root is loaded and then the elements are recursively written to the new file.
-//	On completion, the original memory mapped file is removed and the new file is swapped in.
+//	The current root, along with any retained or snapshot versions, is loaded and then the elements are recursively written to the new file.
+//	On completion, the new file is swapped in and the original memory mapped file is removed.
 func (mariInst *Mari) compactHandler() {
+	defer mariInst.handlersWG.Done()
+
 	for range mariInst.signalCompactChan {
+		var oldSize, newSize int64
+
 		compactErr := func() error {
 			for ! atomic.CompareAndSwapUint32(&mariInst.isResizing, 0, 1) { runtime.Gosched() }
 			defer atomic.StoreUint32(&mariInst.isResizing, 0)
@@ -64,94 +140,249 @@ func (mariInst *Mari) compactHandler() {
 		
 			compact, newCompactStratErr := mariInst.newCompaction(currRoot.version)
 			if newCompactStratErr != nil { return newCompactStratErr }
+
+			retain := uint64(mariInst.compactRetain)
+			if currRoot.version + 1 > retain { compact.baseVersion = currRoot.version - (retain - 1) }
+
+			oldestPinned, hasSnapshots := mariInst.oldestSnapshotVersion()
+			if hasSnapshots && oldestPinned < compact.baseVersion { compact.baseVersion = oldestPinned }
+			if compact.baseVersion < currRoot.version {
+				compact.offsets = make(map[uint64]uint64)
+				compact.overflowOffsets = make(map[uint64]uint64)
+			}
+		
+			newVersion := compact.remapVersion(currRoot.version)
 		
-			currRootPtr := storeINodeAsPointer(currRoot)
-			endOff, serializeVersionErr := mariInst.serializeCurrentVersionToNewFile(compact, currRootPtr, 0, 0, InitRootOffset)
+			newRootOffsets, endOff, serializeVersionErr := mariInst.serializeRetainedVersionsToNewFile(compact, currRoot, rootOffset)
 			if serializeVersionErr != nil { 
-				os.Remove(compact.tempFile.Name())
+				compact.discardTemp()
 				return serializeVersionErr 
 			}
 		
+			if compact.onProgress != nil { compact.onProgress(endOff - uint64(InitRootOffset), endOff - uint64(InitRootOffset)) }
+
 			newMeta := &MariMetaData{
-				version: 0,
-				rootOffset: uint64(InitRootOffset),
+				version: newVersion,
+				rootOffset: newRootOffsets[len(newRootOffsets) - 1],
 				nextStartOffset: endOff,
 			}
 		
+			identity, epoch, loadIdErr := mariInst.loadMetaIdentity()
+			if loadIdErr != nil {
+				compact.discardTemp()
+				return loadIdErr
+			}
+
+			writeIdErr := compact.writeIdentityToTempMemMap(identity, epoch + 1)
+			if writeIdErr != nil {
+				compact.discardTemp()
+				return writeIdErr
+			}
+
 			serializedMeta := newMeta.serializeMetaData()
 			_, writeErr := compact.writeMetaToTempMemMap(serializedMeta)
 			if writeErr != nil { 
-				os.Remove(compact.tempFile.Name())
+				compact.discardTemp()
 				return writeErr 
 			}
 			
-			swapErr := mariInst.swapTempFileWithMari(compact)
+			oldSize = int64(len(mariInst.data.Load().(MMap)))
+
+			swapErr := mariInst.swapTempFileWithMari(compact, endOff)
 			if swapErr != nil { 
-				os.Remove(compact.tempFile.Name())
+				compact.discardTemp()
 				return swapErr 
 			}
 
+			resetVIdxErr := mariInst.resetVersionIndex()
+			if resetVIdxErr != nil { return resetVIdxErr }
+
+			for version, newRootOffset := range newRootOffsets {
+				if newRootOffset == 0 { continue }
+
+				storeOffsetErr := mariInst.storeStartOffset(uint64(version), newRootOffset)
+				if storeOffsetErr != nil { return storeOffsetErr }
+			}
+
+			removeSwapErr := mariInst.removeSwapFile()
+			if removeSwapErr != nil { return removeSwapErr }
+
+			mariInst.remapSnapshots(compact)
+			atomic.StoreUint64(&mariInst.liveBytes, endOff - uint64(InitRootOffset))
+			atomic.AddUint64(&mariInst.metrics.Compactions, 1)
+			newSize = int64(len(mariInst.data.Load().(MMap)))
+
 			return nil
 		}()
 
-		if compactErr != nil { fmt.Println("error on compaction process:", compactErr) }
+		if compactErr != nil {
+			mariInst.reportError(fmt.Errorf("error on compaction process: %w", compactErr))
+			continue
+		}
+
+		if mariInst.onCompactionComplete != nil { mariInst.onCompactionComplete(oldSize, newSize) }
 	}
 }
 
+// serializeRetainedVersionsToNewFile
+//	Write the root of every retained version to the new file, from the oldest retained version to the current version.
+//	When multiple versions are retained, nodes shared between versions are only written once.
+//	Versions that share the root of the current version are mapped to the new offset of the current root instead of being written again.
+//	Returns the offset of each retained root in the new file indexed by its new version, where versions that could not be resolved have an offset of 0.
+func (mariInst *Mari) serializeRetainedVersionsToNewFile(compact *MariCompaction, currRoot *MariINode, rootOffset uint64) ([]uint64, uint64, error) {
+	currVersion := currRoot.version
+	newRootOffsets := make([]uint64, compact.remapVersion(currVersion) + 1)
+	nextStartOffset := uint64(InitRootOffset)
+	var sharedVersions []uint64
+
+	for version := compact.baseVersion; version <= currVersion; version++ {
+		versionRoot := currRoot
+
+		if version != currVersion {
+			versionRootOffset, loadOffErr := mariInst.loadStartOffset(version)
+			if loadOffErr != nil { return nil, 0, loadOffErr }
+			if versionRootOffset == 0 { continue }
+			if versionRootOffset == rootOffset {
+				sharedVersions = append(sharedVersions, version)
+				continue
+			}
+
+			var readRootErr error
+			versionRoot, readRootErr = mariInst.readINodeFromMemMap(versionRootOffset)
+			if readRootErr != nil { return nil, 0, readRootErr }
+		}
+
+		newRootOffsets[compact.remapVersion(version)] = nextStartOffset
+
+		versionRootPtr := storeINodeAsPointer(versionRoot)
+		endOff, serializeErr := mariInst.serializeCurrentVersionToNewFile(compact, versionRootPtr, 0, nextStartOffset)
+		if serializeErr != nil { return nil, 0, serializeErr }
+
+		nextStartOffset = endOff
+	}
+
+	for _, version := range sharedVersions {
+		newRootOffsets[compact.remapVersion(version)] = newRootOffsets[compact.remapVersion(currVersion)]
+	}
+
+	return newRootOffsets, nextStartOffset, nil
+}
+
 // serializeCurrentVersionToNewFile
-//	Recursively builds the new copy of the current version to the new file.
-//	All previous unused paths are discarded.
+//	Recursively builds the new copy of a version to the new file.
+//	All previous unused paths are discarded, node versions are remapped, and nodes already written for a previously retained version are reused.
 //	At each level, the nodes are directly written to the memory map as to avoid loading the entire structure into memory.
-func (mariInst *Mari) serializeCurrentVersionToNewFile(compact *MariCompaction, node *unsafe.Pointer, level int, version, offset uint64) (uint64, error) {
+func (mariInst *Mari) serializeCurrentVersionToNewFile(compact *MariCompaction, node *unsafe.Pointer, level int, offset uint64) (uint64, error) {
 	currNode := loadINodeFromPointer(node)
+
+	count, countErr := mariInst.resolveCount(currNode)
+	if countErr != nil { return 0, countErr }
+
+	if compact.offsets != nil { compact.offsets[currNode.startOffset] = offset }
 	
-	currNode.version = version
+	currNode.version = compact.remapVersion(currNode.version)
 	currNode.startOffset = offset
-	currNode.leaf.version = version
+	currNode.leaf.version = compact.remapVersion(currNode.leaf.version)
 
 	sNode, serializeErr := currNode.serializeINode(true)
 	if serializeErr != nil { return 0, serializeErr }
 
+	var overflowValue []byte
+	prevOverflowOffset := currNode.leaf.overflowOffset
+	writtenOverflowOffset, isWrittenOverflow := compact.overflowOffsets[prevOverflowOffset]
+
+	switch {
+		case isOverflow(currNode.leaf) && isWrittenOverflow:
+			currNode.leaf.overflowOffset = writtenOverflowOffset
+		case isOverflow(currNode.leaf):
+			value, resolveErr := mariInst.resolveValue(currNode.leaf)
+			if resolveErr != nil { return 0, resolveErr }
+
+			currNode.leaf.value = value
+			currNode.leaf.overflowOffset = 0
+			currNode.leaf.overflowLength = 0
+
+			overflowValue = mariInst.moveToOverflow(currNode.leaf)
+			if compact.overflowOffsets != nil && isOverflow(currNode.leaf) { compact.overflowOffsets[prevOverflowOffset] = currNode.leaf.overflowOffset }
+		default:
+			overflowValue = mariInst.moveToOverflow(currNode.leaf)
+	}
+
 	serializedKeyVal, sLeafErr := currNode.leaf.serializeLNode()
 	if sLeafErr != nil { return 0, sLeafErr }
 
-	nextStartOffset := currNode.leaf.endOffset + 1
+	overflowEndOffset := currNode.leaf.endOffset + uint64(len(overflowValue))
+	nextStartOffset := overflowEndOffset + 1
 
 	if len(currNode.children) > 0 {
 		for _, child := range currNode.children {
+			if compact.offsets != nil {
+				writtenOffset, ok := compact.offsets[child.startOffset]
+				if ok {
+					sNode = append(sNode, serializeUint64(writtenOffset)...)
+					continue
+				}
+			}
+
 			sNode = append(sNode, serializeUint64(nextStartOffset)...)
-	
-			childNode, getChildErr := mariInst.readINodeFromMemMap(child.startOffset)
-			if getChildErr != nil { return 0, getChildErr }
+
+			childNode := child
+			if ! isPathCopy(child) {
+				var getChildErr error
+				childNode, getChildErr = mariInst.readINodeFromMemMap(child.startOffset)
+				if getChildErr != nil { return 0, getChildErr }
+			}
 	
 			childPtr := storeINodeAsPointer(childNode)
-			updatedOffset, serializeErr := mariInst.serializeCurrentVersionToNewFile(compact, childPtr, level + 1, version, nextStartOffset)
+			updatedOffset, serializeErr := mariInst.serializeCurrentVersionToNewFile(compact, childPtr, level + 1, nextStartOffset)
 			if serializeErr != nil { return 0, serializeErr }
 	
 			nextStartOffset = updatedOffset
 		}
 	}
 
-	resizeErr := compact.resizeTempFile(currNode.leaf.endOffset + 1)
+	resizeErr := compact.resizeTempFile(overflowEndOffset + 1)
 	if resizeErr != nil { return 0, resizeErr }
 
+	sNode = append(sNode, serializeUint64(count)...)
 	sNode = append(sNode, serializedKeyVal...)
+	sNode = append(sNode, overflowValue...)
 
 	temp := compact.tempData.Load().(MMap)
-	copy(temp[currNode.startOffset:currNode.leaf.endOffset + 1], sNode)
+	copy(temp[currNode.startOffset:overflowEndOffset + 1], sNode)
 
+	compact.reportProgress(nextStartOffset - uint64(InitRootOffset))
 	return nextStartOffset, nil
 }
 
 // swapTempFileWithMari
 //	Close the current mari memory mapped file and swap the new compacted copy.
-//	Rebuild the version index on compaction
-func (mariInst *Mari) swapTempFileWithMari(compact *MariCompaction) error {
+//	The temporary file is grown by doubling while it is built, so the swapped in file is truncated down to the end of the serialized data, rounded up to a page boundary.
+//	For in memory instances, the current mapping is released and the new copy is remapped to the same rounded size.
+//	The original is left as the swap file until removeSwapFile is called once the version index is reset, so if the process stops before then, recoverCompaction restores the original file from the swap file on the next open.
+func (mariInst *Mari) swapTempFileWithMari(compact *MariCompaction, nextStartOffset uint64) error {
+	pageSize := uint64(DefaultPageSize)
+	compactedSize := int(((nextStartOffset + pageSize - 1) / pageSize) * pageSize)
+
+	if mariInst.inMemory {
+		closeErr := mariInst.closeFile()
+		if closeErr != nil { return closeErr }
+
+		remapped, remapErr := remapAnon(compact.tempData.Load().(MMap), compactedSize)
+		if remapErr != nil { return remapErr }
+
+		compact.tempData.Store(MMap{})
+		mariInst.data.Store(remapped)
+		mariInst.nodeCache.clear()
+		mariInst.lockMmap(remapped)
+		return mariInst.adviseMmap(remapped)
+	}
+
 	currFileName := mariInst.file.Name()
 	tempFileName := compact.tempFile.Name()
-	swapFileName := mariInst.file.Name() + "swap"
+	swapFileName := mariInst.file.Name() + CompactSwapFileName
 
-	closeErr := mariInst.Close()
+	closeErr := mariInst.closeFile()
 	if closeErr != nil { return closeErr }
 
 	flushTempErr := compact.tempFile.Sync()
@@ -163,19 +394,48 @@ func (mariInst *Mari) swapTempFileWithMari(compact *MariCompaction) error {
 	closeTempErr := compact.tempFile.Close()
 	if closeTempErr != nil { return closeTempErr }
 	
-	os.Rename(currFileName, swapFileName)
-	os.Rename(tempFileName, currFileName)
+	swapErr := os.Rename(currFileName, swapFileName)
+	if swapErr != nil { return swapErr }
 
-	os.Remove(swapFileName)
+	renameTempErr := os.Rename(tempFileName, currFileName)
+	if renameTempErr != nil {
+		os.Rename(swapFileName, currFileName)
+		return renameTempErr
+	}
 
-	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	syncDirErr := syncDir(currFileName)
+	if syncDirErr != nil { return syncDirErr }
+
+	flag := os.O_RDWR | os.O_CREATE
 	
 	var openFileErr error
-	mariInst.file, openFileErr = os.OpenFile(currFileName, flag, 0600)
+	mariInst.file, openFileErr = os.OpenFile(currFileName, flag, mariInst.fileMode)
 	if openFileErr != nil { return openFileErr }
 
+	truncateErr := mariInst.file.Truncate(int64(compactedSize))
+	if truncateErr != nil { return truncateErr }
+
 	mmapErr := mariInst.mMap()
 	if mmapErr != nil { return mmapErr }
 
 	return nil
-}
\ No newline at end of file
+}
+
+// removeSwapFile
+//	Complete the swap by removing the original file, once the version index has been reset to the compacted copy and flushed.
+//	Until the removal is flushed to the directory, recoverCompaction sees that the version index matches the compacted copy and finishes the removal on the next open.
+func (mariInst *Mari) removeSwapFile() error {
+	if mariInst.inMemory { return nil }
+
+	mariInst.vIdxLock.RLock()
+	flushVIdxErr := mariInst.vIdx.Load().(MMap).Flush()
+	mariInst.vIdxLock.RUnlock()
+	if flushVIdxErr != nil { return flushVIdxErr }
+
+	currFileName := mariInst.file.Name()
+
+	removeSwapErr := os.Remove(currFileName + CompactSwapFileName)
+	if removeSwapErr != nil { return removeSwapErr }
+
+	return syncDir(currFileName)
+}
@@ -1,6 +1,12 @@
 package mari
 
+import "encoding/binary"
 import "errors"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "runtime"
 
 
 //============================================= Mari Compact Utils
@@ -28,23 +34,121 @@ func (compact *MariCompaction) munmapTemp() error {
 	return nil
 }
 
+// discardTemp
+//	Remove the temporary file when compaction fails.
+//	For in memory compactions, the temporary anonymous mapping is unmapped instead.
+func (compact *MariCompaction) discardTemp() error {
+	if compact.tempFile == nil { return compact.munmapTemp() }
+	return os.Remove(compact.tempFile.Name())
+}
+
+// reportProgress
+//	Call the progress hook once at least CompactProgressInterval bytes have been written to the compacted copy since progress was last reported.
+//	The total is estimated from the live bytes when the compaction began, which does not account for retained versions or values moved in or out of line, so it is raised to the bytes written if they exceed it.
+func (compact *MariCompaction) reportProgress(written uint64) {
+	if compact.onProgress == nil || written < compact.reported + CompactProgressInterval { return }
+	compact.reported = written
+
+	total := compact.estimatedTotal
+	if written > total { total = written }
+
+	compact.onProgress(written, total)
+}
+
+// recoverCompaction
+//	Finish or roll back a compaction that was interrupted while the compacted copy was being swapped in.
+//	The original file is renamed to the swap file before the temporary file takes its name, and the swap file is only removed once the version index has been reset to the compacted copy, so if a swap file exists it is always the intact original.
+//	If the version index already matches the file at the file name, the compacted copy was swapped in and indexed, so the swap is complete and the original is removed. Otherwise the original is restored over anything at the file name, and the version index, which is only reset after the swap, still matches it.
+//	Any temporary file is then incomplete or unused and is removed. Compaction holds the write lock until the swap completes, so no write is lost by discarding the compacted copy.
+func (mariInst *Mari) recoverCompaction(fileWithFilePath string) error {
+	tempFileName := fileWithFilePath + CompactTempFileName
+	swapFileName := fileWithFilePath + CompactSwapFileName
+
+	_, statSwapErr := os.Stat(swapFileName)
+	if statSwapErr == nil {
+		swapped, checkErr := mariInst.isCompactionSwapped(fileWithFilePath)
+		if checkErr != nil { return checkErr }
+
+		if swapped {
+			removeSwapErr := os.Remove(swapFileName)
+			if removeSwapErr != nil { return fmt.Errorf("error removing original of completed compaction of %s: %w", fileWithFilePath, removeSwapErr) }
+		} else {
+			restoreErr := os.Rename(swapFileName, fileWithFilePath)
+			if restoreErr != nil { return fmt.Errorf("error restoring %s from interrupted compaction: %w", fileWithFilePath, restoreErr) }
+		}
+
+		syncDirErr := syncDir(fileWithFilePath)
+		if syncDirErr != nil { return syncDirErr }
+	} else if ! errors.Is(statSwapErr, os.ErrNotExist) { return statSwapErr }
+
+	removeTempErr := os.Remove(tempFileName)
+	if removeTempErr != nil && ! errors.Is(removeTempErr, os.ErrNotExist) { return removeTempErr }
+
+	return nil
+}
+
+// isCompactionSwapped
+//	Determine whether the file at the file name is a compacted copy that the version index was already reset to, by matching the identity and epoch in its header against the header of the version index.
+//	A missing or partially written file at the file name never matches, since compaction syncs the copy before it is renamed into place.
+func (mariInst *Mari) isCompactionSwapped(fileWithFilePath string) (bool, error) {
+	file, openErr := os.Open(fileWithFilePath)
+	if errors.Is(openErr, os.ErrNotExist) { return false, nil }
+	if openErr != nil { return false, openErr }
+	defer file.Close()
+
+	header := make([]byte, InitRootOffset)
+	_, readErr := io.ReadFull(file, header)
+	if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) { return false, nil }
+	if readErr != nil { return false, readErr }
+
+	vIdxIdentity, vIdxEpoch, loadVIdxIdErr := mariInst.loadVersionIndexIdentity()
+	if loadVIdxIdErr != nil { return false, loadVIdxIdErr }
+
+	identity := binary.LittleEndian.Uint64(header[MetaIdentityIdx:MetaEpochIdx])
+	epoch := binary.LittleEndian.Uint64(header[MetaEpochIdx:MetaEpochIdx + OffsetSize])
+
+	return identity != 0 && identity == vIdxIdentity && epoch == vIdxEpoch, nil
+}
+
+// syncDir
+//	Flush the directory containing the file, so renames and removals of files in it survive a crash.
+//	Directories cannot be flushed on Windows, where renames are written through by the file system, so this is a no-op there.
+func syncDir(fileWithFilePath string) error {
+	if runtime.GOOS == "windows" { return nil }
+
+	dir, openErr := os.Open(filepath.Dir(fileWithFilePath))
+	if openErr != nil { return openErr }
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// remapVersion
+//	Determine the version of a node in the compacted file.
+//	Versions are renumbered relative to the oldest retained version, and anything older becomes version 0.
+func (compact *MariCompaction) remapVersion(version uint64) uint64 {
+	if version < compact.baseVersion { return 0 }
+	return version - compact.baseVersion
+}
+
 // resizeTempFile
 //	As the new copy is being built, the file will need to be resized as more elements are appended.
-//	Follow a similar strategy to the resizeFile method for the mari memory map.
+//	Follow the same strategy as resizeMmap, using the growth factor of the instance and repeating the growth until the offset fits.
+//	For in memory compactions, the anonymous mapping is grown and the existing contents are copied over.
 func (compact *MariCompaction) resizeTempFile(offset uint64) error {
 	temp := compact.tempData.Load().(MMap)
 	if offset > 0 && int(offset) < len(temp) { return nil }
 	
-	allocateSize := func() int64 {
-		switch {
-			case len(temp) == 0:
-				return int64(DefaultPageSize) * 16 * 1000 // 64MB
-			case len(temp) >= MaxResize:
-				return int64(len(temp) + MaxResize)
-			default:
-				return int64(len(temp) * 2)
-		}
-	}()
+	allocateSize := growSize(int64(len(temp)), compact.growthFactor)
+	for uint64(allocateSize) <= offset { allocateSize = growSize(allocateSize, compact.growthFactor) }
+
+	if compact.tempFile == nil {
+		remapped, remapErr := remapAnon(temp, int(allocateSize))
+		if remapErr != nil { return remapErr }
+
+		compact.tempData.Store(remapped)
+		return nil
+	}
 
 	if len(temp) > 0 {
 		flushErr := compact.tempFile.Sync()
@@ -63,6 +167,22 @@ func (compact *MariCompaction) resizeTempFile(offset uint64) error {
 	return nil
 }
 
+// writeIdentityToTempMemMap
+//	Copy the identity and epoch of the new file into the temporary memory map, along with the format marker, which are flushed to disk along with the metadata.
+func (compact *MariCompaction) writeIdentityToTempMemMap(identity, epoch uint64) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil { err = errors.New("error writing identity to mmap") }
+	}()
+
+	temp := compact.tempData.Load().(MMap)
+	binary.LittleEndian.PutUint64(temp[MetaIdentityIdx:MetaEpochIdx], identity)
+	binary.LittleEndian.PutUint64(temp[MetaEpochIdx:MetaEpochIdx + OffsetSize], epoch)
+	copy(temp[MetaFormatIdx:MetaFormatIdx + OffsetSize], FileFormatMagic)
+
+	return nil
+}
+
 // writeMetaToTempMemMap
 //	Copy the serialized metadata into the memory map.
 func (compact *MariCompaction) writeMetaToTempMemMap(sMeta []byte) (ok bool, err error) {
@@ -76,6 +196,7 @@ func (compact *MariCompaction) writeMetaToTempMemMap(sMeta []byte) (ok bool, err
 
 	temp := compact.tempData.Load().(MMap)
 	copy(temp[MetaVersionIdx:MetaEndSerializedOffset + OffsetSize], sMeta)
+	if compact.tempFile == nil { return true, nil }
 
 	flushErr := compact.tempFile.Sync()
 	if flushErr != nil { return false, flushErr }
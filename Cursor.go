@@ -0,0 +1,145 @@
+package mari
+
+import "bytes"
+import "errors"
+import "sort"
+
+
+//============================================= Mari Cursor
+
+
+// Cursor
+//	Creates a stateful cursor within the transaction, positioned at the given start key, or the smallest key if nil.
+//	The cursor is only valid for the lifetime of the transaction it was created in.
+func (tx *MariTx) Cursor(startKey []byte) *MariCursor {
+	cursor := &MariCursor{ tx: tx }
+	cursor.reset(startKey)
+
+	return cursor
+}
+
+// Next
+//	Advance the cursor and return the next key-value pair in ascending order.
+//	False is returned once the cursor has been exhausted.
+func (cursor *MariCursor) Next() (*KeyValuePair, bool, error) {
+	if cursor.closed { return nil, false, errors.New("cursor is closed") }
+
+	for len(cursor.stack) > 0 {
+		frame := cursor.stack[len(cursor.stack) - 1]
+
+		if len(frame.buffered) > 0 {
+			leaf := frame.buffered[0]
+			frame.buffered = frame.buffered[1:]
+
+			if cursor.bound != nil && bytes.Compare(leaf.key, cursor.bound) == -1 { continue }
+			kvPair, decodeErr := cursor.tx.store.newKeyValuePair(leaf)
+			if decodeErr != nil { return nil, false, decodeErr }
+
+			return kvPair, true, nil
+		}
+
+		for frame.nextIdx <= MaxIndexForLevel {
+			currIdx := byte(frame.nextIdx)
+			if isBitSet(frame.node.bitmap, currIdx) || frame.carried[currIdx] != nil { break }
+			frame.nextIdx++
+		}
+
+		if frame.nextIdx > MaxIndexForLevel {
+			cursor.pop()
+			continue
+		}
+
+		currIdx := byte(frame.nextIdx)
+		frame.nextIdx++
+
+		if ! isBitSet(frame.node.bitmap, currIdx) {
+			frame.buffered = sortLeavesAscending(frame.carried[currIdx])
+			continue
+		}
+
+		childOffset := frame.node.children[getPosition(frame.node.bitmap, currIdx, frame.level)]
+		childNode, getChildErr := cursor.tx.store.getChildNode(childOffset, frame.node.version)
+		if getChildErr != nil { return nil, false, getChildErr }
+
+		childBounded := frame.bounded && len(cursor.bound) > frame.level && currIdx == getIndexForLevel(cursor.bound, frame.level)
+		cursor.push(childNode, frame.level + 1, frame.carried[currIdx], childBounded, childNode != childOffset)
+	}
+
+	return nil, false, nil
+}
+
+// Seek
+//	Reposition the cursor so the next call to Next returns the smallest key greater than or equal to the given key.
+func (cursor *MariCursor) Seek(key []byte) error {
+	if cursor.closed { return errors.New("cursor is closed") }
+
+	cursor.reset(key)
+	return nil
+}
+
+// Close
+//	Close the cursor, recycling any nodes read by the cursor back to the node pool.
+func (cursor *MariCursor) Close() error {
+	if cursor.closed { return nil }
+
+	for len(cursor.stack) > 0 { cursor.pop() }
+	cursor.closed = true
+
+	return nil
+}
+
+// reset
+//	Recycle the current traversal stack and restart from the root of the transaction, bounded by the given key.
+func (cursor *MariCursor) reset(bound []byte) {
+	for len(cursor.stack) > 0 { cursor.pop() }
+
+	cursor.bound = bound
+	cursor.push(loadINodeFromPointer(cursor.tx.root), 0, nil, bound != nil, false)
+}
+
+// push
+//	Add a frame for the node to the traversal stack.
+//	Leaves whose key ends at the level of the node are smallest in the subtree and are buffered immediately, while the rest are carried to the index of their next byte.
+//	If the node is on the path of the bound, the indexes before the bound are skipped.
+func (cursor *MariCursor) push(node *MariINode, level int, pending []*MariLNode, bounded bool, owned bool) {
+	frame := &MariCursorFrame{ node: node, level: level, bounded: bounded, owned: owned }
+
+	leaves := append([]*MariLNode{}, pending...)
+	if hasKey(node.leaf) { leaves = append(leaves, node.leaf) }
+
+	if len(node.children) == 0 {
+		frame.buffered = sortLeavesAscending(leaves)
+		frame.nextIdx = MaxIndexForLevel + 1
+	} else {
+		frame.carried = make(map[byte][]*MariLNode)
+
+		for _, leaf := range leaves {
+			if len(leaf.key) == level {
+				frame.buffered = append(frame.buffered, leaf)
+			} else { frame.carried[leaf.key[level]] = append(frame.carried[leaf.key[level]], leaf) }
+		}
+
+		if bounded && len(cursor.bound) > level { frame.nextIdx = int(getIndexForLevel(cursor.bound, level)) }
+	}
+
+	cursor.stack = append(cursor.stack, frame)
+}
+
+// pop
+//	Remove the top frame from the traversal stack, and if the node was read by the cursor, recycle it back to the node pool.
+func (cursor *MariCursor) pop() {
+	frame := cursor.stack[len(cursor.stack) - 1]
+	cursor.stack = cursor.stack[:len(cursor.stack) - 1]
+
+	if frame.owned {
+		cursor.tx.store.nodePool.putLNode(frame.node.leaf)
+		cursor.tx.store.nodePool.putINode(frame.node)
+	}
+}
+
+// sortLeavesAscending
+//	Sort a group of leaves in ascending order by key.
+func sortLeavesAscending(leaves []*MariLNode) []*MariLNode {
+	sort.Slice(leaves, func(i, j int) bool { return bytes.Compare(leaves[i].key, leaves[j].key) == -1 })
+	return leaves
+}
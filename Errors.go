@@ -0,0 +1,37 @@
+package mari
+
+import "errors"
+
+
+//============================================= Mari Errors
+
+
+// ErrKeyNotFound is returned by MustGet when the key does not exist
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrKeyTooLong is wrapped by the error returned when a key longer than MaxKeyLength is written
+var ErrKeyTooLong = errors.New("key too long")
+
+// ErrReadOnlyTx is returned when a write is attempted within a read only transaction
+var ErrReadOnlyTx = errors.New("attempting to perform a write in a read only transaction, use tx.UpdateTx")
+
+// ErrResizing is wrapped by the error returned when a write transaction exhausts its retries while the memory map is being resized or compacted
+var ErrResizing = errors.New("memory map is being resized")
+
+// ErrCorruptNode is wrapped by the errors returned when a serialized node or leaf cannot be read or fails verification
+var ErrCorruptNode = errors.New("corrupt node")
+
+// ErrClosed is wrapped by the errors returned when an operation is attempted on a closed instance
+var ErrClosed = errors.New("mari instance is closed")
+
+// ErrVersionIndexMismatch is wrapped by the error returned from Open when the version index belongs to a different file, or a different epoch of the same file, than the memory mapped file
+var ErrVersionIndexMismatch = errors.New("version index does not match mari file")
+
+// ErrStreamDiscarded is wrapped by the error returned from a write transaction when a compaction discarded a value put with PutReader after it was copied from its reader but before the transaction committed, so the value must be put again with a new reader
+var ErrStreamDiscarded = errors.New("streamed value was discarded by a compaction before it was committed")
+
+// ErrUnsupportedFormat is wrapped by the error returned from Open when the file does not begin with the header layout marked by FileFormatMagic, such as a file written by an older version of mari
+var ErrUnsupportedFormat = errors.New("unsupported mari file format")
+
+// ErrNotSynced is wrapped by the error returned from a write transaction with DurabilitySync when the transaction committed, but flushing it to disk failed, so it may not survive a crash
+var ErrNotSynced = errors.New("transaction committed but was not flushed to disk")
@@ -0,0 +1,99 @@
+package mari
+
+import "bufio"
+import "encoding/json"
+import "errors"
+import "io"
+
+
+//============================================= Mari Export
+
+
+// ExportJSON
+//	Streams every key-value pair in the current version of Mari to w as a JSON array, in ascending key order.
+//	Each pair is written as an object containing the base64 encoded key and value, along with the version of the pair.
+//	Pairs are encoded one at a time as the cursor advances, so the export never buffers the full dataset in memory.
+func (mariInst *Mari) ExportJSON(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	_, openErr := writer.WriteString("[")
+	if openErr != nil { return openErr }
+
+	readErr := mariInst.ReadTx(func(tx *MariTx) error {
+		first := true
+
+		return tx.ForEach(nil, func(kvPair *KeyValuePair) (bool, error) {
+			sPair, marshalErr := json.Marshal(&MariJSONPair{ Key: kvPair.Key, Value: kvPair.Value, Version: kvPair.Version })
+			if marshalErr != nil { return false, marshalErr }
+
+			if ! first {
+				_, sepErr := writer.WriteString(",")
+				if sepErr != nil { return false, sepErr }
+			}
+
+			first = false
+
+			_, writeErr := writer.Write(sPair)
+			if writeErr != nil { return false, writeErr }
+
+			return true, nil
+		})
+	})
+
+	if readErr != nil { return readErr }
+
+	_, closeErr := writer.WriteString("]")
+	if closeErr != nil { return closeErr }
+
+	return writer.Flush()
+}
+
+// ImportJSON
+//	Reads a JSON array in the format written by ExportJSON from r and inserts every pair in batches of ImportBatchSize.
+//	The version of each exported pair is ignored. Returns the total number of pairs imported, including those committed before a batch fails.
+func (mariInst *Mari) ImportJSON(r io.Reader) (int, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+
+	openToken, openErr := decoder.Token()
+	if openErr != nil { return 0, openErr }
+	if delim, ok := openToken.(json.Delim); ! ok || delim != '[' { return 0, errors.New("json import must be an array of key-value pairs") }
+
+	var imported int
+	batch := make([]KeyValuePair, 0, ImportBatchSize)
+
+	flushBatch := func() error {
+		if len(batch) == 0 { return nil }
+
+		putErr := mariInst.UpdateTx(func(tx *MariTx) error {
+			return tx.PutBatch(batch)
+		})
+
+		if putErr != nil { return putErr }
+
+		imported += len(batch)
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for decoder.More() {
+		var pair MariJSONPair
+		decodeErr := decoder.Decode(&pair)
+		if decodeErr != nil { return imported, decodeErr }
+
+		batch = append(batch, KeyValuePair{ Key: pair.Key, Value: pair.Value })
+
+		if len(batch) == ImportBatchSize {
+			flushErr := flushBatch()
+			if flushErr != nil { return imported, flushErr }
+		}
+	}
+
+	_, closeErr := decoder.Token()
+	if closeErr != nil { return imported, closeErr }
+
+	flushErr := flushBatch()
+	if flushErr != nil { return imported, flushErr }
+
+	return imported, nil
+}
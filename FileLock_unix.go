@@ -0,0 +1,22 @@
+//go:build unix
+
+package mari
+
+import "errors"
+import "fmt"
+import "os"
+import "golang.org/x/sys/unix"
+
+
+//============================================= File Lock Unix
+
+
+// lockFile
+//	Acquire an exclusive advisory lock on the file without blocking, which is released when the file is closed.
+//	Only read-write instances take the lock, so two processes cannot both assume ownership of the metadata, while read only instances can attach alongside the owner.
+func lockFile(file *os.File) error {
+	lockErr := unix.Flock(int(file.Fd()), unix.LOCK_EX | unix.LOCK_NB)
+	if errors.Is(lockErr, unix.EWOULDBLOCK) { return fmt.Errorf("mari file %s is already opened by another process", file.Name()) }
+
+	return lockErr
+}
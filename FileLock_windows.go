@@ -0,0 +1,25 @@
+//go:build windows
+
+package mari
+
+import "errors"
+import "fmt"
+import "os"
+import "golang.org/x/sys/windows"
+
+
+//============================================= File Lock Windows
+
+
+// lockFile
+//	Acquire an exclusive lock on the file without blocking, which is released when the file is closed.
+//	Only read-write instances take the lock, so two processes cannot both assume ownership of the metadata, while read only instances can attach alongside the owner.
+//	Windows locks are mandatory for the locked range, so a single byte at the largest offset is locked instead of the data, leaving the file readable by read only instances.
+func lockFile(file *os.File) error {
+	overlapped := &windows.Overlapped{ Offset: 0xFFFFFFFF, OffsetHigh: 0x7FFFFFFF }
+
+	lockErr := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK | windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped)
+	if errors.Is(lockErr, windows.ERROR_LOCK_VIOLATION) { return fmt.Errorf("mari file %s is already opened by another process", file.Name()) }
+
+	return lockErr
+}
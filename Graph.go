@@ -0,0 +1,95 @@
+package mari
+
+import "bufio"
+import "fmt"
+import "io"
+import "strings"
+
+
+//============================================= Mari Graph
+
+
+// ExportDOT
+//	Writes the current version of the ordered array mapped trie to w as a GraphViz DOT graph, for debugging.
+//	If maxDepth is not negative, the children of nodes at maxDepth are collapsed into a single node.
+func (mariInst *Mari) ExportDOT(w io.Writer, maxDepth int) error {
+	writer := bufio.NewWriter(w)
+
+	_, openErr := writer.WriteString("digraph mari {\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	if openErr != nil { return openErr }
+
+	readErr := mariInst.ReadTx(func(tx *MariTx) error {
+		nodeId := 0
+		return mariInst.exportDOTRecursive(writer, loadINodeFromPointer(tx.root), 0, maxDepth, &nodeId)
+	})
+
+	if readErr != nil { return readErr }
+
+	_, closeErr := writer.WriteString("}\n")
+	if closeErr != nil { return closeErr }
+
+	return writer.Flush()
+}
+
+// exportDOTRecursive
+//	Write the internal node and its leaf, then recursively write each child and the edge to it, returning the id assigned to the node.
+//	Ids are assigned in the order nodes are visited, since path copies that are not yet serialized do not have a unique offset.
+func (mariInst *Mari) exportDOTRecursive(writer *bufio.Writer, node *MariINode, level, maxDepth int, nodeId *int) error {
+	currId := *nodeId
+	*nodeId++
+
+	label := fmt.Sprintf("level: %d\noffset: %d-%d\nversion: %d\nbitmap: %s", level, node.startOffset, node.endOffset, node.version, formatBitmap(node.bitmap))
+	_, nodeErr := fmt.Fprintf(writer, "\tn%d [label=%q];\n", currId, label)
+	if nodeErr != nil { return nodeErr }
+
+	if hasKey(node.leaf) {
+		valueSize := uint64(len(node.leaf.value))
+		if isOverflow(node.leaf) { valueSize = node.leaf.overflowLength }
+
+		leafLabel := fmt.Sprintf("key: %q\nvalue: %d bytes\nversion: %d", node.leaf.key, valueSize, node.leaf.version)
+		_, leafErr := fmt.Fprintf(writer, "\tl%d [shape=ellipse, label=%q];\n\tn%d -> l%d [style=dashed];\n", currId, leafLabel, currId, currId)
+		if leafErr != nil { return leafErr }
+	}
+
+	if len(node.children) == 0 { return nil }
+
+	if maxDepth >= 0 && level >= maxDepth {
+		_, omittedErr := fmt.Fprintf(writer, "\to%d [shape=plaintext, label=\"%d children omitted\"];\n\tn%d -> o%d [style=dotted];\n", currId, len(node.children), currId, currId)
+		return omittedErr
+	}
+
+	indexes := bitmapIndexes(node.bitmap)
+	for pos, childOffset := range node.children {
+		childNode, getChildErr := mariInst.getChildNode(childOffset, node.version)
+		if getChildErr != nil { return getChildErr }
+
+		childId := *nodeId
+		exportErr := mariInst.exportDOTRecursive(writer, childNode, level + 1, maxDepth, nodeId)
+		if exportErr != nil { return exportErr }
+
+		_, edgeErr := fmt.Fprintf(writer, "\tn%d -> n%d [label=\"0x%02x\"];\n", currId, childId, indexes[pos])
+		if edgeErr != nil { return edgeErr }
+	}
+
+	return nil
+}
+
+// bitmapIndexes
+//	Get the index of every bit set in the 256 bit bitmap in ascending order, which matches the order of the child node array.
+func bitmapIndexes(bitmap [8]uint32) []byte {
+	var indexes []byte
+	for index := 0; index < 256; index++ {
+		if isBitSet(bitmap, byte(index)) { indexes = append(indexes, byte(index)) }
+	}
+
+	return indexes
+}
+
+// formatBitmap
+//	Format the 256 bit bitmap as hex, from the sub bitmap for the highest indexes to the lowest.
+func formatBitmap(bitmap [8]uint32) string {
+	var sb strings.Builder
+	for idx := len(bitmap) - 1; idx >= 0; idx-- { fmt.Fprintf(&sb, "%08x", bitmap[idx]) }
+
+	return sb.String()
+}
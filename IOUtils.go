@@ -1,21 +1,27 @@
 package mari
 
+import "errors"
+import "fmt"
 import "runtime"
 import "sync/atomic"
+import "time"
 import "unsafe"
 
 
+
 //============================================= Mari IO Utils
 
 
 // compareAndSwap
 //	Performs CAS operation.
+//	On failure, the discarded copy is recycled back into the node pool.
+//	The leaf is only recycled if it was newly created for the copy, since copies share the leaf of the original node until it is replaced.
 func (mariInst *Mari) compareAndSwap(node *unsafe.Pointer, currNode, nodeCopy *MariINode) bool {
 	if atomic.CompareAndSwapPointer(node, unsafe.Pointer(currNode), unsafe.Pointer(nodeCopy)) {
 		return true
 	} else {
-		mariInst.nodePool.iNodePool.Put(nodeCopy.leaf)
-		mariInst.nodePool.lNodePool.Put(nodeCopy)
+		if nodeCopy.leaf != currNode.leaf { mariInst.nodePool.putLNode(nodeCopy.leaf) }
+		mariInst.nodePool.putINode(nodeCopy)
 
 		return false
 	}
@@ -23,15 +29,17 @@ func (mariInst *Mari) compareAndSwap(node *unsafe.Pointer, currNode, nodeCopy *M
 
 // determineIfResize
 //	Helper function that signals go routine for resizing if the condition to resize is met.
+//	The offset the write needs to reach is recorded before signalling, so the resize can allocate enough for the write in one shot.
 func (mariInst *Mari) determineIfResize(offset uint64) bool {
 	mMap := mariInst.data.Load().(MMap)
 
 	switch {
 		case offset > 0 && int(offset) < len(mMap):
 			return false
-		case len(mMap) == 0 || ! atomic.CompareAndSwapUint32(&mariInst.isResizing, 0, 1):
+		case len(mMap) == 0 || ! mariInst.opened || ! atomic.CompareAndSwapUint32(&mariInst.isResizing, 0, 1):
 			return true
 		default:
+			atomic.StoreUint64(&mariInst.resizeTo, offset + 1)
 			mariInst.signalResizeChan <- true
 			return true
 	}
@@ -40,7 +48,10 @@ func (mariInst *Mari) determineIfResize(offset uint64) bool {
 // flushRegionToDisk
 //	Flushes a region of the memory map to disk instead of flushing the entire map. 
 //	When a startoffset is provided, if it is not aligned with the start of the last page, the offset needs to be normalized.
+//	In memory instances have nothing to flush.
 func (mariInst *Mari) flushRegionToDisk(startOffset, endOffset uint64) error {
+	if mariInst.inMemory { return nil }
+
 	startOffsetOfPage := startOffset & ^(uint64(DefaultPageSize) - 1)
 
 	mMap := mariInst.data.Load().(MMap)
@@ -54,36 +65,177 @@ func (mariInst *Mari) flushRegionToDisk(startOffset, endOffset uint64) error {
 
 // handleFlush
 //	This is "optimistic" flushing. 
-//	A separate go routine is spawned and signalled to flush changes to the mmap to disk.
+//	A separate go routine is spawned and signalled to flush changes to the mmap to disk, which returns once the signal channel is closed.
+//	If a flush interval is set, the file is also flushed on a timer whenever a write has been committed since the last timed flush, so many writes are coalesced into one fsync.
 func (mariInst *Mari) handleFlush() {
-	for range mariInst.signalFlushChan {
-		func() {
-			for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
-			
-			mariInst.rwResizeLock.RLock()
-			defer mariInst.rwResizeLock.RUnlock()
+	defer mariInst.handlersWG.Done()
 
-			mariInst.file.Sync()
-		}()
+	var tick <-chan time.Time
+	if mariInst.flushInterval > 0 {
+		ticker := time.NewTicker(mariInst.flushInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
 	}
+
+	for {
+		select {
+			case _, ok := <-mariInst.signalFlushChan:
+				if ! ok { return }
+				mariInst.flushFile()
+			case <-tick:
+				if atomic.CompareAndSwapUint32(&mariInst.flushPending, 1, 0) { mariInst.flushFile() }
+		}
+	}
+}
+
+// flushFile
+//	Flush the memory mapped file to disk from the flush go routine, reporting any error on the errors channel.
+//	The read lock is held so the file is not swapped or resized during the flush.
+func (mariInst *Mari) flushFile() {
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+	
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	atomic.AddUint64(&mariInst.metrics.Flushes, 1)
+	flushErr := mariInst.file.Sync()
+	if flushErr != nil { mariInst.reportError(fmt.Errorf("error on flush: %w", flushErr)) }
+}
+
+// growSize
+//	Determine the next size of a memory map when it is resized.
+//	An empty map is allocated 64MB, otherwise the size is multiplied by the growth factor, where each step grows by at most MaxResize and at least one page.
+//	The new size is rounded up to a page boundary.
+func growSize(size int64, growthFactor float64) int64 {
+	if size == 0 { return int64(DefaultPageSize) * 16 * 1000 } // 64MB
+
+	growth := int64(float64(size) * (growthFactor - 1))
+	if growth > MaxResize { growth = MaxResize }
+	if growth < int64(DefaultPageSize) { growth = int64(DefaultPageSize) }
+
+	pageSize := int64(DefaultPageSize)
+	return ((size + growth + pageSize - 1) / pageSize) * pageSize
 }
 
 // handleResize
 //	A separate go routine is spawned to handle resizing the memory map.
-//	When the mmap reaches its size limit, the go routine is signalled.
+//	When the mmap reaches its size limit, the go routine is signalled. The go routine returns once the signal channel is closed.
 func (mariInst *Mari) handleResize() {
-	for range mariInst.signalResizeChan { mariInst.resizeMmap() }
+	defer mariInst.handlersWG.Done()
+
+	for range mariInst.signalResizeChan { 
+		_, resizeErr := mariInst.resizeMmap(atomic.LoadUint64(&mariInst.resizeTo))
+		if resizeErr != nil { mariInst.reportError(fmt.Errorf("error on resize: %w", resizeErr)) }
+	}
+}
+
+// remapReadOnly
+//	A read only instance may be attached to a file that another process is writing to, so the file and version index can grow past the current mappings.
+//	If the end of the serialized data or the latest version is no longer covered, the resizing flag is set, the write lock is acquired, and both are remapped to their current size.
+//	For read-write instances this is a no-op.
+func (mariInst *Mari) remapReadOnly() error {
+	if ! mariInst.readOnly || ! mariInst.isStale() { return nil }
+
+	for ! atomic.CompareAndSwapUint32(&mariInst.isResizing, 0, 1) { runtime.Gosched() }
+	defer atomic.StoreUint32(&mariInst.isResizing, 0)
+
+	mariInst.rwResizeLock.Lock()
+	defer mariInst.rwResizeLock.Unlock()
+
+	if ! mariInst.opened || ! mariInst.isStale() { return nil }
+
+	unmapErr := mariInst.munmap()
+	if unmapErr != nil { return unmapErr }
+
+	mmapErr := mariInst.mMap()
+	if mmapErr != nil { return mmapErr }
+
+	mariInst.vIdxLock.Lock()
+	defer mariInst.vIdxLock.Unlock()
+
+	unmapVIdxErr := mariInst.munmapVersionIndex()
+	if unmapVIdxErr != nil { return unmapVIdxErr }
+
+	return mariInst.mMapVersionIndex()
+}
+
+// isStale
+//	Determine if the end of the serialized data or the latest version lie past the current mappings.
+func (mariInst *Mari) isStale() bool {
+	_, endOffset, loadEndOffErr := mariInst.loadMetaEndSerialized()
+	if loadEndOffErr != nil { return false }
+
+	_, version, loadVErr := mariInst.loadMetaVersion()
+	if loadVErr != nil { return false }
+
+	mMap := mariInst.data.Load().(MMap)
+	vIdx := mariInst.vIdx.Load().(MMap)
+
+	return endOffset > uint64(len(mMap)) || (version + 1) * OffsetSize > uint64(len(vIdx))
+}
+
+// reportError
+//	Surface an error from a background go routine on the errors channel.
+//	If the channel is full, the error is dropped instead of blocking.
+func (mariInst *Mari) reportError(err error) {
+	select {
+		case mariInst.errorsChan <- err:
+		default:
+	}
 }
 
 // mmap
 //	Helper to memory map the mariInst File in to buffer.
 func (mariInst *Mari) mMap() error {
-	mMap, mmapErr := Map(mariInst.file, RDWR, 0)
+	prot := RDWR
+	if mariInst.readOnly { prot = RDONLY }
+
+	mMap, mmapErr := Map(mariInst.file, prot, 0)
 	if mmapErr != nil { return mmapErr }
 
 	mariInst.data.Store(mMap)
+	mariInst.nodeCache.clear()
+	mariInst.lockMmap(mMap)
 
-	return nil
+	return mariInst.adviseMmap(mMap)
+}
+
+// Advise
+//	Hint the access pattern of the memory map to the operating system, replacing the pattern passed in the instance options.
+//	Sequential hints favor readahead for large Range and Iterate scans, while random hints avoid reading in pages that point Gets will not touch.
+//	The pattern is reapplied whenever the memory map is remapped by a resize or compaction. On platforms without madvise, this is a no-op.
+func (mariInst *Mari) Advise(pattern AdvisePattern) error {
+	if pattern > AdviseWillNeed { return errors.New("advise must be one of the advise patterns") }
+	atomic.StoreUint32(&mariInst.advise, uint32(pattern))
+
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return fmt.Errorf("attempting to advise: %w", ErrClosed) }
+
+	return mariInst.adviseMmap(mariInst.data.Load().(MMap))
+}
+
+// adviseMmap
+//	Apply the current access pattern to a new memory map. Without a hint, the defaults of the operating system are left as is.
+func (mariInst *Mari) adviseMmap(mMap MMap) error {
+	pattern := AdvisePattern(atomic.LoadUint32(&mariInst.advise))
+	if pattern == AdviseNormal { return nil }
+
+	return mMap.Advise(pattern)
+}
+
+// lockMmap
+//	Lock a new memory map in RAM if LockMemory is set. Unmapping releases the lock, so it is reapplied whenever the memory map is remapped.
+//	Locking fails without the privilege to lock memory or when the map exceeds the limit on locked memory, in which case the map is left unlocked and the failure is reported instead of returned.
+func (mariInst *Mari) lockMmap(mMap MMap) {
+	if ! mariInst.lockMemory || len(mMap) == 0 { return }
+
+	lockErr := mMap.Lock()
+	if lockErr != nil { mariInst.reportError(fmt.Errorf("unable to lock %d bytes of the memory map in RAM, pages may be swapped out: %w", len(mMap), lockErr)) }
 }
 
 // munmap
@@ -94,13 +246,17 @@ func (mariInst *Mari) munmap() error {
 	if unmapErr != nil { return unmapErr }
 
 	mariInst.data.Store(MMap{})
+	mariInst.nodeCache.clear()
+
 	return nil
 }
 
 // resizeMmap
 //	Dynamically resizes the underlying memory mapped file.
-//	When a file is first created, default size is 64MB and doubles the mem map on each resize until 1GB.
-func (mariInst *Mari) resizeMmap() (bool, error) {
+//	When a file is first created, default size is 64MB and the mem map grows by the growth factor on each resize, which is doubling by default, until each step is capped at 1GB.
+//	If a single write needs more than one step of growth, like a path containing a very large value, the growth is repeated until the mem map is at least minSize, so the write fits after one resize.
+//	For in memory instances, a larger anonymous region is mapped and the existing contents are copied over.
+func (mariInst *Mari) resizeMmap(minSize uint64) (bool, error) {
 	mariInst.rwResizeLock.Lock()
 	
 	defer mariInst.rwResizeLock.Unlock()
@@ -108,16 +264,19 @@ func (mariInst *Mari) resizeMmap() (bool, error) {
 
 	mMap := mariInst.data.Load().(MMap)
 
-	allocateSize := func() int64 {
-		switch {
-			case len(mMap) == 0:
-				return int64(DefaultPageSize) * 16 * 1000 // 64MB
-			case len(mMap) >= MaxResize:
-				return int64(len(mMap) + MaxResize)
-			default:
-				return int64(len(mMap) * 2)
-		}
-	}()
+	allocateSize := growSize(int64(len(mMap)), mariInst.growthFactor)
+	for uint64(allocateSize) < minSize { allocateSize = growSize(allocateSize, mariInst.growthFactor) }
+
+	if mariInst.inMemory {
+		remapped, remapErr := remapAnon(mMap, int(allocateSize))
+		if remapErr != nil { return false, remapErr }
+
+		mariInst.data.Store(remapped)
+		mariInst.nodeCache.clear()
+		mariInst.lockMmap(remapped)
+		atomic.AddUint64(&mariInst.metrics.Resizes, 1)
+		return true, mariInst.adviseMmap(remapped)
+	}
 
 	if len(mMap) > 0 {
 		flushErr := mariInst.file.Sync()
@@ -133,12 +292,41 @@ func (mariInst *Mari) resizeMmap() (bool, error) {
 	mmapErr := mariInst.mMap()
 	if mmapErr != nil { return false, mmapErr }
 
+	atomic.AddUint64(&mariInst.metrics.Resizes, 1)
 	return true, nil
 }
 
+// syncFiles
+//	Flush the memory mapped file, the change log if it is enabled, and the version index to disk.
+//	In memory instances have nothing to flush.
+func (mariInst *Mari) syncFiles() error {
+	if mariInst.inMemory { return nil }
+
+	atomic.AddUint64(&mariInst.metrics.Flushes, 1)
+	flushErr := mariInst.file.Sync()
+	if flushErr != nil { return flushErr }
+
+	if mariInst.changeLog != nil {
+		syncCLogErr := mariInst.changeLog.Sync()
+		if syncCLogErr != nil { return syncCLogErr }
+	}
+
+	return mariInst.versionIndex.Sync()
+}
+
 // signalFlush
 //	Called by all writes to "optimistically" handle flushing changes to the mmap to disk.
+//	In memory instances are never flushed, so no signal is sent.
+//	Only the async durability level flushes in the background, since no sync leaves flushing to the operating system and sync flushes before the commit returns.
+//	If a flush interval is set, the write is only marked as pending and is flushed on the next tick of the flush go routine.
 func (mariInst *Mari) signalFlush() {
+	if ! mariInst.opened || mariInst.inMemory || mariInst.durability != DurabilityAsync { return }
+
+	if mariInst.flushInterval > 0 {
+		atomic.StoreUint32(&mariInst.flushPending, 1)
+		return
+	}
+
 	select {
 		case mariInst.signalFlushChan <- true:
 		default:
@@ -147,7 +335,11 @@ func (mariInst *Mari) signalFlush() {
 
 // exclusiveWriteMmap
 //	Takes a path copy and writes the nodes to the memory map, then updates the metadata.
-func (mariInst *Mari) exclusiveWriteMmap(path *MariINode) (bool, error) {
+//	The commit only succeeds if the current version is still prevVersion, the version of the root the path was copied from.
+//	Once committed, the new root is recorded in the version index and the changes are delivered to the change log and watchers.
+func (mariInst *Mari) exclusiveWriteMmap(path *MariINode, prevVersion uint64, tx *MariTx, streams []*MariPendingStream) (bool, error) {
+	changes := tx.changes
+
 	if atomic.LoadUint32(&mariInst.isResizing) == 1 { return false, nil }
 
 	versionPtr, version, loadVErr := mariInst.loadMetaVersion()
@@ -160,6 +352,7 @@ func (mariInst *Mari) exclusiveWriteMmap(path *MariINode) (bool, error) {
 	if loadSOffErr != nil { return false, nil }
 
 	newVersion := path.version
+	replacedSize := replacedSizeOfPath(path)
 	newOffsetInMMap := endOffset
 	
 	serializedPath, serializeErr := mariInst.serializePathToMemMap(path, newOffsetInMMap)
@@ -174,26 +367,67 @@ func (mariInst *Mari) exclusiveWriteMmap(path *MariINode) (bool, error) {
 	isResize := mariInst.determineIfResize(updatedMeta.nextStartOffset)
 	if isResize { return false, nil }
 
-	if ! mariInst.appendOnly && mariInst.compactTrigger(updatedMeta) {
+	if ! mariInst.appendOnly && len(streams) == 0 && mariInst.compactTrigger(updatedMeta) {
 		mariInst.signalCompact()
 		return false, nil
 	}
 	
 	if atomic.LoadUint32(&mariInst.isResizing) == 0 {
-		if version == updatedMeta.version - 1 && atomic.CompareAndSwapUint64(versionPtr, version, updatedMeta.version) {
-			mariInst.storeMetaPointer(endOffsetPtr, updatedMeta.nextStartOffset)
-			
+		if mariInst.changeLog != nil {
+			mariInst.changeLogLock.Lock()
+			defer mariInst.changeLogLock.Unlock()
+		}
+
+		if version == prevVersion && atomic.CompareAndSwapUint64(endOffsetPtr, endOffset, updatedMeta.nextStartOffset) {
+			if ! atomic.CompareAndSwapUint64(versionPtr, version, updatedMeta.version) {
+				atomic.CompareAndSwapUint64(endOffsetPtr, updatedMeta.nextStartOffset, endOffset)
+				return false, nil
+			}
+
 			_, writeNodesToMmapErr := mariInst.writeNodesToMemMap(serializedPath, newOffsetInMMap)
 			if writeNodesToMmapErr != nil {
-				mariInst.storeMetaPointer(endOffsetPtr, endOffset)
+				atomic.CompareAndSwapUint64(endOffsetPtr, updatedMeta.nextStartOffset, endOffset)
 				mariInst.storeMetaPointer(versionPtr, version)
 				mariInst.storeMetaPointer(rootOffsetPtr, prevRootOffset)
 
 				return false, writeNodesToMmapErr
 			}
+
+			storeOffsetErr := mariInst.storeStartOffset(updatedMeta.version, updatedMeta.rootOffset)
+			if storeOffsetErr != nil {
+				atomic.CompareAndSwapUint64(endOffsetPtr, updatedMeta.nextStartOffset, endOffset)
+				mariInst.storeMetaPointer(versionPtr, version)
+				mariInst.storeMetaPointer(rootOffsetPtr, prevRootOffset)
+
+				return false, storeOffsetErr
+			}
 			
+			mariInst.recordStreamChanges(tx, streams)
+			for idx := range changes { changes[idx].Version = updatedMeta.version }
+
+			if mariInst.changeLog != nil {
+				appendErr := mariInst.appendChangeLog(updatedMeta.version, tx.sequence, changes)
+				if appendErr != nil {
+					mariInst.storeStartOffset(updatedMeta.version, 0)
+					atomic.CompareAndSwapUint64(endOffsetPtr, updatedMeta.nextStartOffset, endOffset)
+					mariInst.storeMetaPointer(versionPtr, version)
+					mariInst.storeMetaPointer(rootOffsetPtr, prevRootOffset)
+
+					return false, appendErr
+				}
+			}
+
 			mariInst.storeMetaPointer(rootOffsetPtr, updatedMeta.rootOffset)
+			written := updatedMeta.nextStartOffset - endOffset + streamsLength(streams)
+			mariInst.updateLiveBytes(written, replacedSize)
+			atomic.AddUint64(&mariInst.metrics.BytesWritten, written)
 			mariInst.signalFlush()
+			mariInst.notifyWatchers(changes)
+
+			if mariInst.durability == DurabilitySync {
+				syncErr := mariInst.syncFiles()
+				if syncErr != nil { return true, fmt.Errorf("%w: version %d: %w", ErrNotSynced, updatedMeta.version, syncErr) }
+			}
 
 			return true, nil
 		}
@@ -1,6 +1,7 @@
 package mari
 
 import "bytes"
+import "sort"
 import "unsafe"
 
 
@@ -14,15 +15,26 @@ func (mariInst *Mari) iterateRecursive(
 	node *unsafe.Pointer, minVersion uint64, 
 	startKey []byte, totalResults, level int, 
 	acc []*KeyValuePair, transform MariOpTransform,
+	keysOnly bool, scanCtx *MariScanContext,
 ) ([]*KeyValuePair, error) {
-	genKeyValPair := func(node *MariINode) *KeyValuePair {
-		kvPair := &KeyValuePair {
-			Version: node.leaf.version,
-			Key: node.leaf.key,
-			Value: node.leaf.value,
-		}
+	checkErr := scanCtx.check()
+	if checkErr != nil { return nil, checkErr }
+
+	newPair := mariInst.newKeyValuePair
+	getChild := mariInst.viewChildNode
+	if keysOnly {
+		newPair = mariInst.newKeyPair
+		getChild = mariInst.viewChildNodeKey
+	}
 
-		return kvPair
+	appendTransformed := func(node *MariINode) error {
+		kvPair, decodeErr := newPair(node.leaf)
+		if decodeErr != nil { return decodeErr }
+
+		transformed := transform(kvPair)
+		if transformed != nil { acc = append(acc, transformed) }
+
+		return nil
 	}
 
 	currNode := loadINodeFromPointer(node)
@@ -34,22 +46,37 @@ func (mariInst *Mari) iterateRecursive(
 			case totalResults == len(acc):
 				return acc, nil
 			case len(startKey) == level:
-				if currNode.leaf.version >= minVersion { acc = append(acc, transform(genKeyValPair(currNode))) }
+				if currNode.leaf.version >= minVersion {
+					appendErr := appendTransformed(currNode)
+					if appendErr != nil { return nil, appendErr }
+				}
+
 				startKeyPos = 0
 			case startKey != nil && len(startKey) > level:
 				if bytes.Compare(currNode.leaf.key, startKey) == 1 || bytes.Equal(currNode.leaf.key, startKey) {
-					if currNode.leaf.version >= minVersion { acc = append(acc, transform(genKeyValPair(currNode))) }
+					if currNode.leaf.version >= minVersion {
+						appendErr := appendTransformed(currNode)
+						if appendErr != nil { return nil, appendErr }
+					}
 				}
 
 				startKeyIndex := getIndexForLevel(startKey, level)
 				startKeyPos = getPosition(currNode.bitmap, startKeyIndex, level)
 			default:
-				if currNode.leaf.version >= minVersion && len(currNode.leaf.key) > 0 { 
-					acc = append(acc, transform(genKeyValPair(currNode)))
+				if currNode.leaf.version >= minVersion && hasKey(currNode.leaf) { 
+					appendErr := appendTransformed(currNode)
+					if appendErr != nil { return nil, appendErr }
 				} 
 
 				startKeyPos = 0
 		}
+	} else if len(startKey) == 0 {
+		if totalResults > len(acc) && currNode.leaf.version >= minVersion && hasKey(currNode.leaf) {
+			appendErr := appendTransformed(currNode)
+			if appendErr != nil { return nil, appendErr }
+		}
+
+		startKeyPos = 0
 	} else {
 		startKeyIdx := getIndexForLevel(startKey, level)
 		startKeyPos = getPosition(currNode.bitmap, startKeyIdx, level)
@@ -61,7 +88,7 @@ func (mariInst *Mari) iterateRecursive(
 		for totalResults > len(acc) && currPos < len(currNode.children) {
 			childOffset := currNode.children[currPos]
 
-			childNode, getChildErr := mariInst.getChildNode(childOffset, currNode.version)
+			childNode, getChildErr := getChild(childOffset, currNode.version)
 			if getChildErr != nil { return nil, getChildErr}
 			childPtr := storeINodeAsPointer(childNode)
 
@@ -69,10 +96,10 @@ func (mariInst *Mari) iterateRecursive(
 
 			switch {
 				case currPos == startKeyPos && startKey != nil:
-					acc, iterErr = mariInst.iterateRecursive(childPtr, minVersion, startKey, totalResults, level + 1, acc, transform)
+					acc, iterErr = mariInst.iterateRecursive(childPtr, minVersion, startKey, totalResults, level + 1, acc, transform, keysOnly, scanCtx)
 					if iterErr != nil { return nil, iterErr }
 				default:
-					acc, iterErr = mariInst.iterateRecursive(childPtr, minVersion, nil, totalResults, level + 1, acc, transform)
+					acc, iterErr = mariInst.iterateRecursive(childPtr, minVersion, nil, totalResults, level + 1, acc, transform, keysOnly, scanCtx)
 					if iterErr != nil { return nil, iterErr }
 			}
 
@@ -81,4 +108,225 @@ func (mariInst *Mari) iterateRecursive(
 	}
 
 	return acc, nil
-}
\ No newline at end of file
+}
+
+// iterateReverseRecursive
+//	The descending counterpart to iterateRecursive, creating a cursor that begins at the specified start key and moves towards smaller keys.
+//	Leaves are carried down into the child at the index of their next byte as pending leaves, so results are strictly descending.
+func (mariInst *Mari) iterateReverseRecursive(
+	node *unsafe.Pointer, minVersion uint64, 
+	startKey []byte, totalResults, level int, 
+	acc []*KeyValuePair, transform MariOpTransform,
+	keysOnly bool, pending []*MariLNode,
+) ([]*KeyValuePair, error) {
+	currNode := loadINodeFromPointer(node)
+
+	newPair := mariInst.newKeyValuePair
+	getChild := mariInst.viewChildNode
+	if keysOnly {
+		newPair = mariInst.newKeyPair
+		getChild = mariInst.viewChildNodeKey
+	}
+
+	emit := func(leaf *MariLNode) error {
+		if totalResults == len(acc) || leaf.version < minVersion { return nil }
+		if startKey != nil && bytes.Compare(leaf.key, startKey) == 1 { return nil }
+
+		kvPair, decodeErr := newPair(leaf)
+		if decodeErr != nil { return decodeErr }
+
+		transformed := transform(kvPair)
+		if transformed != nil { acc = append(acc, transformed) }
+
+		return nil
+	}
+
+	emitDescending := func(leaves []*MariLNode) error {
+		sort.Slice(leaves, func(i, j int) bool { return bytes.Compare(leaves[i].key, leaves[j].key) == 1 })
+		for _, leaf := range leaves {
+			emitErr := emit(leaf)
+			if emitErr != nil { return emitErr }
+		}
+
+		return nil
+	}
+
+	leaves := pending
+	if hasKey(currNode.leaf) { leaves = append(leaves, currNode.leaf) }
+
+	if len(currNode.children) == 0 {
+		emitErr := emitDescending(leaves)
+		if emitErr != nil { return nil, emitErr }
+
+		return acc, nil
+	}
+
+	var prefixLeaves []*MariLNode
+	carried := make(map[byte][]*MariLNode)
+
+	for _, leaf := range leaves {
+		if len(leaf.key) == level {
+			prefixLeaves = append(prefixLeaves, leaf)
+		} else { carried[leaf.key[level]] = append(carried[leaf.key[level]], leaf) }
+	}
+
+	maxIdx := MaxIndexForLevel
+	if startKey != nil {
+		switch {
+			case len(startKey) > level:
+				maxIdx = int(getIndexForLevel(startKey, level))
+			default:
+				maxIdx = -1
+		}
+	}
+
+	for idx := maxIdx; totalResults > len(acc) && idx >= 0; idx-- {
+		currIdx := byte(idx)
+
+		if ! isBitSet(currNode.bitmap, currIdx) {
+			if carried[currIdx] != nil {
+				emitErr := emitDescending(carried[currIdx])
+				if emitErr != nil { return nil, emitErr }
+			}
+
+			continue
+		}
+
+		childNode, getChildErr := getChild(currNode.children[getPosition(currNode.bitmap, currIdx, level)], currNode.version)
+		if getChildErr != nil { return nil, getChildErr }
+		childPtr := storeINodeAsPointer(childNode)
+
+		var childStartKey []byte
+		if startKey != nil && idx == maxIdx { childStartKey = startKey }
+
+		var iterErr error
+		acc, iterErr = mariInst.iterateReverseRecursive(childPtr, minVersion, childStartKey, totalResults, level + 1, acc, transform, keysOnly, carried[currIdx])
+		if iterErr != nil { return nil, iterErr }
+	}
+
+	emitErr := emitDescending(prefixLeaves)
+	if emitErr != nil { return nil, emitErr }
+
+	return acc, nil
+}
+
+// minRecursive
+//	Descend the leftmost child of each node to find the smallest key in the subtree.
+//	A leaf whose key ends at the level of the node is a prefix of every key beneath it, so it is the smallest key in the subtree.
+//	Deletes can leave empty subtrees behind, so if a child holds no keys the next child is tried, and a longer leaf on the node is compared against the result.
+func (mariInst *Mari) minRecursive(node *unsafe.Pointer, level int) (*MariLNode, error) {
+	currNode := loadINodeFromPointer(node)
+
+	var leaf *MariLNode
+	if hasKey(currNode.leaf) { leaf = currNode.leaf }
+
+	if leaf != nil && len(leaf.key) == level { return leaf, nil }
+
+	var childMin *MariLNode
+	for idx := 0; childMin == nil && idx < len(currNode.children); idx++ {
+		childNode, getChildErr := mariInst.viewChildNode(currNode.children[idx], currNode.version)
+		if getChildErr != nil { return nil, getChildErr }
+
+		var minErr error
+		childMin, minErr = mariInst.minRecursive(storeINodeAsPointer(childNode), level + 1)
+		if minErr != nil { return nil, minErr }
+	}
+
+	if leaf != nil && (childMin == nil || bytes.Compare(leaf.key, childMin.key) == -1) { return leaf, nil }
+	return childMin, nil
+}
+
+// maxRecursive
+//	Descend the rightmost child of each node to find the largest key in the subtree.
+//	If a child holds no keys the previous child is tried, and a leaf on the node is compared against the result.
+func (mariInst *Mari) maxRecursive(node *unsafe.Pointer, level int) (*MariLNode, error) {
+	currNode := loadINodeFromPointer(node)
+
+	var leaf *MariLNode
+	if hasKey(currNode.leaf) { leaf = currNode.leaf }
+
+	var childMax *MariLNode
+	for idx := len(currNode.children) - 1; childMax == nil && idx >= 0; idx-- {
+		childNode, getChildErr := mariInst.viewChildNode(currNode.children[idx], currNode.version)
+		if getChildErr != nil { return nil, getChildErr }
+
+		var maxErr error
+		childMax, maxErr = mariInst.maxRecursive(storeINodeAsPointer(childNode), level + 1)
+		if maxErr != nil { return nil, maxErr }
+	}
+
+	if leaf != nil && (childMax == nil || bytes.Compare(leaf.key, childMax.key) == 1) { return leaf, nil }
+	return childMax, nil
+}
+
+// walkRecursive
+//	Traverses the trie from the given node depth first, invoking visit on every leaf that contains a key, in no particular order.
+//	If visit returns false, the traversal stops and false is propagated back up to the root.
+func (mariInst *Mari) walkRecursive(node *unsafe.Pointer, visit func(leaf *MariLNode) (bool, error)) (bool, error) {
+	currNode := loadINodeFromPointer(node)
+
+	if hasKey(currNode.leaf) {
+		cont, visitErr := visit(currNode.leaf)
+		if visitErr != nil { return false, visitErr }
+		if ! cont { return false, nil }
+	}
+
+	for _, childOffset := range currNode.children {
+		childNode, getChildErr := mariInst.viewChildNode(childOffset, currNode.version)
+		if getChildErr != nil { return false, getChildErr }
+
+		childPtr := storeINodeAsPointer(childNode)
+		cont, walkErr := mariInst.walkRecursive(childPtr, visit)
+		if walkErr != nil { return false, walkErr }
+		if ! cont { return false, nil }
+	}
+
+	return true, nil
+}
+
+// depthRecursive
+//	Walks every node in the subtree and returns the maximum level reached, where the node passed in is at the given level.
+//	Child nodes are read with only the key of their leaf, so value bytes are never touched.
+func (mariInst *Mari) depthRecursive(node *unsafe.Pointer, level int) (int, error) {
+	currNode := loadINodeFromPointer(node)
+	maxLevel := level
+
+	for _, childOffset := range currNode.children {
+		childNode, getChildErr := mariInst.viewChildNodeKey(childOffset, currNode.version)
+		if getChildErr != nil { return 0, getChildErr }
+
+		childPtr := storeINodeAsPointer(childNode)
+		childLevel, depthErr := mariInst.depthRecursive(childPtr, level + 1)
+		if depthErr != nil { return 0, depthErr }
+
+		if childLevel > maxLevel { maxLevel = childLevel }
+	}
+
+	return maxLevel, nil
+}
+
+// prefixRecursive
+//	Descends the path of the prefix byte by byte until the subtree containing every key with the prefix is reached, then walks the subtree.
+//	Leaves on the path above the subtree can hold keys longer than their level, so they are also visited if they begin with the prefix.
+//	If the bit for the next byte of the prefix is not set at a level, no further keys share the prefix.
+func (mariInst *Mari) prefixRecursive(node *unsafe.Pointer, prefix []byte, level int, visit func(leaf *MariLNode) (bool, error)) (bool, error) {
+	if len(prefix) == level { return mariInst.walkRecursive(node, visit) }
+
+	currNode := loadINodeFromPointer(node)
+
+	if hasKey(currNode.leaf) && bytes.HasPrefix(currNode.leaf.key, prefix) {
+		cont, visitErr := visit(currNode.leaf)
+		if visitErr != nil { return false, visitErr }
+		if ! cont { return false, nil }
+	}
+
+	index := getIndexForLevel(prefix, level)
+	if ! isBitSet(currNode.bitmap, index) { return true, nil }
+
+	pos := getPosition(currNode.bitmap, index, level)
+	childNode, getChildErr := mariInst.viewChildNode(currNode.children[pos], currNode.version)
+	if getChildErr != nil { return false, getChildErr }
+
+	childPtr := storeINodeAsPointer(childNode)
+	return mariInst.prefixRecursive(childPtr, prefix, level + 1, visit)
+}
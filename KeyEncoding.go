@@ -0,0 +1,40 @@
+package mari
+
+import "encoding/binary"
+import "errors"
+
+
+//============================================= Mari Key Encoding
+
+
+// EncodeUint64
+//	Encode an unsigned integer as an 8 byte big endian key, so the byte order of encoded keys matches the numeric order.
+//	Range, Iterate, and every other ordered read then return numeric keys in ascending numeric order.
+func EncodeUint64(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
+
+// DecodeUint64
+//	Decode a key created with EncodeUint64 back into the unsigned integer.
+func DecodeUint64(data []byte) (uint64, error) {
+	if len(data) != 8 { return uint64(0), errors.New("invalid data length for encoded uint64 key") }
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// EncodeInt64
+//	Encode a signed integer as an 8 byte big endian key with the sign bit flipped.
+//	Flipping the sign bit moves negative values below positive values, so the byte order of encoded keys matches the numeric order.
+func EncodeInt64(v int64) []byte {
+	return EncodeUint64(uint64(v) ^ (1 << 63))
+}
+
+// DecodeInt64
+//	Decode a key created with EncodeInt64 back into the signed integer.
+func DecodeInt64(data []byte) (int64, error) {
+	decoded, decodeErr := DecodeUint64(data)
+	if decodeErr != nil { return int64(0), decodeErr }
+
+	return int64(decoded ^ (1 << 63)), nil
+}
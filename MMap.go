@@ -2,7 +2,6 @@ package mari
 
 import "errors"
 import "os"
-import "golang.org/x/sys/unix"
 
 
 //============================================= MMap
@@ -10,20 +9,31 @@ import "golang.org/x/sys/unix"
 
 // Map 
 //	Memory maps an entire file.
+//	The system calls that create, flush, and unmap a mapping are implemented per platform, in MMap_unix.go and MMap_windows.go.
 func Map(file *os.File, prot, flags int) (MMap, error) {
 	return mapRegion(file, -1, prot, flags, 0)
 }
 
-// Flush
-//	Writes the byte slice from the mmap to disk.
-func (mapped MMap) Flush() error {
-	return unix.Msync(mapped, unix.MS_SYNC)
+// MapAnon
+//	Memory maps an anonymous region of the given length, which is not backed by any file.
+func MapAnon(length int) (MMap, error) {
+	return mapRegion(nil, length, RDWR, ANON, 0)
 }
 
-// Unmap 
-//	Unmaps the byte slice from the memory mapped file.
-func (mapped MMap) Unmap() error {
-	return unix.Munmap(mapped)
+// remapAnon
+//	Grow or shrink an anonymous mapping by mapping a new region of the given length, copying the existing contents, and unmapping the original region.
+func remapAnon(mapped MMap, length int) (MMap, error) {
+	remapped, mapErr := MapAnon(length)
+	if mapErr != nil { return nil, mapErr }
+
+	if len(mapped) > 0 {
+		copy(remapped, mapped)
+
+		unmapErr := mapped.Unmap()
+		if unmapErr != nil { return nil, unmapErr }
+	}
+
+	return remapped, nil
 }
 
 // mapRegion 
@@ -51,26 +61,3 @@ func mapRegion(file *os.File, length int, prot, flags int, offset int64) (MMap,
 
 	return mmapHelper(length, uintptr(prot), uintptr(flags), fileDescriptor, offset)
 }
-
-// mmapHelper 
-//	Utility function for mmap.
-func mmapHelper(length int, inprot, inflags, fileDescriptor uintptr, offset int64) ([]byte, error) {
-	flags := unix.MAP_SHARED
-	prot := unix.PROT_READ
-	
-	switch {
-		case inprot & COPY != 0:
-			prot |= unix.PROT_WRITE
-			flags = unix.MAP_PRIVATE
-		case inprot & RDWR != 0:
-			prot |= unix.PROT_WRITE
-	}
-	
-	if inprot & EXEC != 0 { prot |= unix.PROT_EXEC }
-	if inflags & ANON != 0 { flags |= unix.MAP_ANON }
-
-	bytes, mmapErr := unix.Mmap(int(fileDescriptor), offset, length, prot, flags)
-	if mmapErr != nil { return nil, mmapErr }
-	
-	return bytes, nil
-}
\ No newline at end of file
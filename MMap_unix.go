@@ -0,0 +1,69 @@
+//go:build unix
+
+package mari
+
+import "golang.org/x/sys/unix"
+
+
+//============================================= MMap Unix
+
+
+// Flush
+//	Writes the byte slice from the mmap to disk.
+func (mapped MMap) Flush() error {
+	return unix.Msync(mapped, unix.MS_SYNC)
+}
+
+// Unmap 
+//	Unmaps the byte slice from the memory mapped file.
+func (mapped MMap) Unmap() error {
+	return unix.Munmap(mapped)
+}
+
+// Advise
+//	Hint the access pattern of the mapped region to the kernel with madvise.
+func (mapped MMap) Advise(pattern AdvisePattern) error {
+	if len(mapped) == 0 { return nil }
+
+	advice := unix.MADV_NORMAL
+	switch pattern {
+		case AdviseSequential:
+			advice = unix.MADV_SEQUENTIAL
+		case AdviseRandom:
+			advice = unix.MADV_RANDOM
+		case AdviseWillNeed:
+			advice = unix.MADV_WILLNEED
+	}
+
+	return unix.Madvise(mapped, advice)
+}
+
+// Lock
+//	Lock the mapped region in RAM with mlock, so its pages are not swapped out until it is unmapped.
+func (mapped MMap) Lock() error {
+	if len(mapped) == 0 { return nil }
+	return unix.Mlock(mapped)
+}
+
+// mmapHelper 
+//	Utility function for mmap.
+func mmapHelper(length int, inprot, inflags, fileDescriptor uintptr, offset int64) ([]byte, error) {
+	flags := unix.MAP_SHARED
+	prot := unix.PROT_READ
+	
+	switch {
+		case inprot & COPY != 0:
+			prot |= unix.PROT_WRITE
+			flags = unix.MAP_PRIVATE
+		case inprot & RDWR != 0:
+			prot |= unix.PROT_WRITE
+	}
+	
+	if inprot & EXEC != 0 { prot |= unix.PROT_EXEC }
+	if inflags & ANON != 0 { flags |= unix.MAP_ANON }
+
+	bytes, mmapErr := unix.Mmap(int(fileDescriptor), offset, length, prot, flags)
+	if mmapErr != nil { return nil, mmapErr }
+	
+	return bytes, nil
+}
\ No newline at end of file
@@ -0,0 +1,97 @@
+//go:build windows
+
+package mari
+
+import "errors"
+import "os"
+import "sync"
+import "unsafe"
+import "golang.org/x/sys/windows"
+
+
+//============================================= MMap Windows
+
+
+// mappingHandles: the file mapping handle backing each view, keyed by the address of the view, since the handle must be closed once the view is unmapped
+var mappingHandles = make(map[uintptr]windows.Handle)
+// mappingLock: a mutex for registering and releasing the file mapping handles
+var mappingLock sync.Mutex
+
+
+// Flush
+//	Writes the byte slice from the mmap to disk.
+//	The view is flushed to the file, which the callers follow with a sync of the file where durability is required.
+func (mapped MMap) Flush() error {
+	if len(mapped) == 0 { return nil }
+	return windows.FlushViewOfFile(uintptr(unsafe.Pointer(&mapped[0])), uintptr(len(mapped)))
+}
+
+// Unmap
+//	Unmaps the byte slice from the memory mapped file, then closes the file mapping handle that backed the view.
+func (mapped MMap) Unmap() error {
+	if len(mapped) == 0 { return nil }
+
+	addr := uintptr(unsafe.Pointer(&mapped[0]))
+	unmapErr := windows.UnmapViewOfFile(addr)
+	if unmapErr != nil { return os.NewSyscallError("UnmapViewOfFile", unmapErr) }
+
+	mappingLock.Lock()
+	handle, ok := mappingHandles[addr]
+	delete(mappingHandles, addr)
+	mappingLock.Unlock()
+
+	if ! ok { return errors.New("unmapped a view with no file mapping handle") }
+	return os.NewSyscallError("CloseHandle", windows.CloseHandle(handle))
+}
+
+// Advise
+//	Windows has no equivalent of madvise for mapped views, so access pattern hints are a no-op.
+func (mapped MMap) Advise(pattern AdvisePattern) error {
+	return nil
+}
+
+// Lock
+//	Lock the view in the working set of the process with VirtualLock, so its pages are not paged out until it is unmapped.
+func (mapped MMap) Lock() error {
+	if len(mapped) == 0 { return nil }
+	return os.NewSyscallError("VirtualLock", windows.VirtualLock(uintptr(unsafe.Pointer(&mapped[0])), uintptr(len(mapped))))
+}
+
+// mmapHelper
+//	Utility function for mmap.
+//	A file mapping object is created for the file, or backed by the paging file for anonymous mappings, and a view of the requested region is mapped from it.
+//	The protection flags match the unix implementation, where copy on write views never write back to the file.
+func mmapHelper(length int, inprot, inflags, fileDescriptor uintptr, offset int64) ([]byte, error) {
+	protect := uint32(windows.PAGE_READONLY)
+	access := uint32(windows.FILE_MAP_READ)
+
+	switch {
+		case inprot & COPY != 0:
+			protect = windows.PAGE_WRITECOPY
+			access = windows.FILE_MAP_COPY
+		case inprot & RDWR != 0:
+			protect = windows.PAGE_READWRITE
+			access = windows.FILE_MAP_WRITE
+	}
+
+	if inprot & EXEC != 0 {
+		protect <<= 4
+		access |= windows.FILE_MAP_EXECUTE
+	}
+
+	maxSize := offset + int64(length)
+	handle, createErr := windows.CreateFileMapping(windows.Handle(fileDescriptor), nil, protect, uint32(maxSize >> 32), uint32(maxSize & 0xFFFFFFFF), nil)
+	if createErr != nil { return nil, os.NewSyscallError("CreateFileMapping", createErr) }
+
+	addr, mapErr := windows.MapViewOfFile(handle, access, uint32(offset >> 32), uint32(offset & 0xFFFFFFFF), uintptr(length))
+	if mapErr != nil {
+		windows.CloseHandle(handle)
+		return nil, os.NewSyscallError("MapViewOfFile", mapErr)
+	}
+
+	mappingLock.Lock()
+	mappingHandles[addr] = handle
+	mappingLock.Unlock()
+
+	return unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), length), nil
+}
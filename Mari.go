@@ -1,7 +1,10 @@
 package mari
 
+import "errors"
+import "fmt"
 import "os"
 import "path/filepath"
+import "runtime"
 import "sync/atomic"
 
 
@@ -9,10 +12,20 @@ import "sync/atomic"
 
 
 // Open initializes Mari
-//	This will create the memory mapped file or read it in if it already exists.
-//	Then, the meta data is initialized and written to the first 0-23 bytes in the memory map.
+//	This will create the memory mapped file or read it in if it already exists, or map anonymous memory if InMemory is set.
+//	Then, the meta data is initialized and written to the first 0-39 bytes in the memory map.
 //	An initial root MariINode will also be written to the memory map as well.
 func Open(opts MariOpts) (*Mari, error) {
+	if ! opts.InMemory {
+		if opts.FileName == "" { opts.FileName = DefaultFileName }
+
+		if opts.Filepath != "" {
+			stat, statErr := os.Stat(opts.Filepath)
+			if statErr != nil { return nil, statErr }
+			if ! stat.IsDir() { return nil, fmt.Errorf("filepath %s is not a directory, pass the name of the file as the file name", opts.Filepath) }
+		}
+	}
+
 	fileWithFilePath := filepath.Join(opts.Filepath, opts.FileName)
 
 	mariInst := &Mari{
@@ -21,17 +34,70 @@ func Open(opts MariOpts) (*Mari, error) {
 		signalCompactChan: make(chan bool),
 		signalFlushChan: make(chan bool),
 		signalResizeChan: make(chan bool),
+		errorsChan: make(chan error, ErrorsBufferSize),
+		snapshots: make(map[uint64]*MariSnapshotRef),
+		watchers: make(map[*MariWatcher]bool),
 	}
 
-	if opts.NodePoolSize != nil {
-		nodePoolSize := *opts.NodePoolSize
-		mariInst.nodePool = newMariNodePool(nodePoolSize)
-	} else { mariInst.nodePool = newMariNodePool(DefaultNodePoolSize) }
+	nodePoolSize := DefaultNodePoolSize
+	if opts.NodePoolSize != nil { nodePoolSize = *opts.NodePoolSize }
+
+	var nodePoolCeiling int64
+	if opts.NodePoolCeiling != nil {
+		if *opts.NodePoolCeiling < nodePoolSize { return nil, errors.New("node pool ceiling must be at least the node pool size") }
+		nodePoolCeiling = *opts.NodePoolCeiling
+	}
+
+	mariInst.nodePool = newMariNodePool(nodePoolSize, nodePoolCeiling)
+	if ! opts.ReadOnly { mariInst.nodePool.initializePools() } // read only instances never copy nodes, so nothing is pre-allocated
+
+	if opts.NodeCacheSize < 0 { return nil, errors.New("node cache size must be at least 0") }
+	if opts.NodeCacheSize > 0 { mariInst.nodeCache = newNodeCache(opts.NodeCacheSize) }
+
+	if opts.FileMode &^ os.ModePerm != 0 { return nil, errors.New("file mode must only contain permission bits") }
+	if opts.FileMode != 0 {
+		mariInst.fileMode = opts.FileMode
+	} else { mariInst.fileMode = DefaultFileMode }
+
+	if opts.OverflowThreshold < 0 { return nil, errors.New("overflow threshold must be at least 0") }
+	mariInst.overflowThreshold = opts.OverflowThreshold
 
 	if opts.AppendOnly != nil {
 		mariInst.appendOnly = *opts.AppendOnly
 	} else { mariInst.appendOnly = false }
 
+	if opts.CompactRetain != nil {
+		if *opts.CompactRetain < 1 { return nil, errors.New("compact retain must be at least 1") }
+		mariInst.compactRetain = *opts.CompactRetain
+	} else { mariInst.compactRetain = 1 }
+
+	if opts.GrowthFactor != nil {
+		if *opts.GrowthFactor <= 1 { return nil, errors.New("growth factor must be greater than 1") }
+		mariInst.growthFactor = *opts.GrowthFactor
+	} else { mariInst.growthFactor = DefaultGrowthFactor }
+
+	if opts.Durability > DurabilitySync { return nil, errors.New("durability must be one of the durability levels") }
+	mariInst.durability = opts.Durability
+
+	if opts.FlushInterval < 0 { return nil, errors.New("flush interval must be at least 0") }
+	if opts.FlushInterval > 0 && mariInst.durability != DurabilityAsync { return nil, errors.New("flush interval requires async durability") }
+	mariInst.flushInterval = opts.FlushInterval
+
+	if opts.Advise > AdviseWillNeed { return nil, errors.New("advise must be one of the advise patterns") }
+	mariInst.advise = uint32(opts.Advise)
+	mariInst.lockMemory = opts.LockMemory
+
+	mariInst.valueCodec = opts.ValueCodec
+	mariInst.mergeFunc = opts.MergeFunc
+	mariInst.onCommit = opts.OnCommit
+	mariInst.onCompactionProgress = opts.OnCompactionProgress
+	mariInst.onCompactionComplete = opts.OnCompactionComplete
+
+	if opts.MaxTxRetries != nil {
+		if *opts.MaxTxRetries < 0 { return nil, errors.New("max tx retries must be at least 0") }
+		mariInst.maxTxRetries = *opts.MaxTxRetries
+	} else { mariInst.maxTxRetries = -1 }
+
 	if opts.CompactTrigger != nil {	
 		mariInst.compactTrigger = *opts.CompactTrigger
 	} else { 
@@ -40,20 +106,80 @@ func Open(opts MariOpts) (*Mari, error) {
 		} 
 	}
 
-	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
-	
-	var openFileErr error
-	mariInst.file, openFileErr = os.OpenFile(fileWithFilePath, flag, 0600)
-	if openFileErr != nil { return nil, openFileErr	}
+	if opts.CompactFragmentation != nil {
+		if opts.CompactTrigger != nil { return nil, errors.New("compact fragmentation cannot be combined with a custom compact trigger") }
+		if *opts.CompactFragmentation <= 0 || *opts.CompactFragmentation >= 1 { return nil, errors.New("compact fragmentation must be between 0 and 1") }
+
+		mariInst.compactFragmentation = *opts.CompactFragmentation
+		mariInst.compactTrigger = mariInst.fragmentationTrigger(mariInst.compactFragmentation)
+	}
+
+	mariInst.inMemory = opts.InMemory
+	mariInst.readOnly = opts.ReadOnly
+	if mariInst.inMemory && mariInst.readOnly { return nil, errors.New("an in memory instance cannot be opened read only") }
+	if opts.RebuildVersionIndex && mariInst.readOnly { return nil, errors.New("the version index cannot be rebuilt on a read only instance") }
+	if opts.RecoverCorruptRoot && mariInst.readOnly { return nil, errors.New("a corrupt root cannot be recovered on a read only instance") }
+	if opts.ChangeLog && mariInst.inMemory { return nil, errors.New("the change log requires a file backed instance") }
+
+	isNewVIdx, openVIdxErr := mariInst.openVersionIndex(fileWithFilePath)
+	if openVIdxErr != nil { return nil, openVIdxErr }
+
+	if ! mariInst.inMemory {
+		if ! mariInst.readOnly {
+			recoverErr := mariInst.recoverCompaction(fileWithFilePath)
+			if recoverErr != nil {
+				mariInst.closeOnOpenErr()
+				return nil, recoverErr
+			}
+		}
+
+		flag := os.O_RDWR | os.O_CREATE
+		if mariInst.readOnly { flag = os.O_RDONLY }
+		
+		var openFileErr error
+		mariInst.file, openFileErr = os.OpenFile(fileWithFilePath, flag, mariInst.fileMode)
+		if openFileErr != nil {
+			mariInst.closeOnOpenErr()
+			return nil, openFileErr
+		}
+	}
 
 	mariInst.filepath = opts.Filepath
 	
 	atomic.StoreUint32(&mariInst.isResizing, 0)
 	mariInst.data.Store(MMap{})
 
-	initFileErr := mariInst.initializeFile()
-	if initFileErr != nil { return nil, initFileErr	}
+	if opts.ChangeLog {
+		openCLogErr := mariInst.openChangeLog(fileWithFilePath)
+		if openCLogErr != nil {
+			mariInst.closeOnOpenErr()
+			return nil, openCLogErr
+		}
+	}
 
+	initFileErr := mariInst.initializeFile(isNewVIdx || opts.RebuildVersionIndex)
+	if initFileErr != nil {
+		mariInst.closeOnOpenErr()
+		return nil, initFileErr
+	}
+
+	if mariInst.readOnly { return mariInst, nil }
+
+	if opts.RecoverCorruptRoot {
+		recoverErr := mariInst.recoverCorruptRoot()
+		if recoverErr != nil {
+			mariInst.closeOnOpenErr()
+			return nil, recoverErr
+		}
+	}
+
+	initLiveErr := mariInst.initializeLiveBytes()
+	if initLiveErr != nil {
+		mariInst.closeOnOpenErr()
+		return nil, initLiveErr
+	}
+
+	mariInst.handlersWG.Add(3)
 	go mariInst.compactHandler()
 	go mariInst.handleFlush()
 	go mariInst.handleResize()
@@ -62,10 +188,55 @@ func Open(opts MariOpts) (*Mari, error) {
 }
 
 // Close
-//	Close Mari, unmapping the file from memory and closing the file.
+//	Close Mari, stopping the background go routines, unmapping the file from memory and closing the file.
+//	Transactions started after Close return an error wrapping ErrClosed, and calling Close again is a no-op.
 func (mariInst *Mari) Close() error {
-	if ! mariInst.opened { return nil }
+	mariInst.rwResizeLock.Lock()
+	
+	if ! mariInst.opened { 
+		mariInst.rwResizeLock.Unlock()
+		return nil 
+	}
+	
 	mariInst.opened = false
+	
+	close(mariInst.signalCompactChan)
+	close(mariInst.signalFlushChan)
+	close(mariInst.signalResizeChan)
+	
+	mariInst.rwResizeLock.Unlock()
+	mariInst.handlersWG.Wait()
+	close(mariInst.errorsChan)
+	mariInst.closeWatchers()
+
+	closeErr := mariInst.closeFile()
+	if closeErr != nil { return closeErr }
+
+	closeCLogErr := mariInst.closeChangeLog()
+	if closeCLogErr != nil { return closeCLogErr }
+
+	return mariInst.closeVersionIndex()
+}
+
+// Errors
+//	Returns a channel surfacing errors from the background compaction, flush, and resize go routines.
+//	The channel is buffered and errors are dropped when it is full, so background go routines never block on it.
+//	The channel is closed once Mari is closed.
+func (mariInst *Mari) Errors() <-chan error {
+	return mariInst.errorsChan
+}
+
+// closeFile
+//	Flush and unmap the memory mapped file and close it, without stopping the background go routines.
+//	For in memory instances, the anonymous mapping is only unmapped.
+func (mariInst *Mari) closeFile() error {
+	if mariInst.inMemory { return mariInst.munmap() }
+	if mariInst.readOnly {
+		unmapErr := mariInst.munmap()
+		if unmapErr != nil { return unmapErr }
+
+		return mariInst.file.Close()
+	}
 
 	flushErr := mariInst.file.Sync()
 	if flushErr != nil { return flushErr }
@@ -81,9 +252,62 @@ func (mariInst *Mari) Close() error {
 	return nil
 }
 
+// closeOnOpenErr
+//	Release the memory map, the file, the change log, and the version index when Open fails part way through, so the lock on the instance is not left held by an instance that is never returned.
+//	The memory map and change log may not have been opened yet, so each is only released if it was. Errors are ignored, since the error that failed Open is returned instead.
+func (mariInst *Mari) closeOnOpenErr() {
+	mMap, _ := mariInst.data.Load().(MMap)
+	if len(mMap) > 0 { mariInst.munmap() }
+
+	if mariInst.file != nil { mariInst.file.Close() }
+	if mariInst.changeLog != nil { mariInst.changeLog.Close() }
+
+	mariInst.closeVersionIndex()
+}
+
+// Count
+//	Determine the total number of keys in the latest version of Mari.
+//	This is performed within a read only transaction so the count is consistent for the version at the time of the call.
+func (mariInst *Mari) Count() (int, error) {
+	var totalCount int
+
+	countErr := mariInst.ReadTx(func(tx *MariTx) error {
+		var countTxErr error
+		totalCount, countTxErr = tx.Count()
+		return countTxErr
+	})
+
+	if countErr != nil { return 0, countErr }
+	return totalCount, nil
+}
+
+// CurrentVersion
+//	Determine the latest committed version of Mari.
+//	The version in the metadata is incremented before the new path is written, so the version is read from the root that the metadata currently points to.
+//	This ensures the version of an in-flight UpdateTx is never returned.
+func (mariInst *Mari) CurrentVersion() (uint64, error) {
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return 0, fmt.Errorf("attempting to load the current version: %w", ErrClosed) }
+
+	_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+	if loadROffErr != nil { return 0, loadROffErr }
+
+	currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
+	if readRootErr != nil { return 0, readRootErr }
+
+	return currRoot.version, nil
+}
+
 // FileSize
 //	Determine the memory mapped file size.
+//	For in memory instances, the size of the anonymous mapping is returned.
 func (mariInst *Mari) FileSize() (int, error) {
+	if mariInst.inMemory { return len(mariInst.data.Load().(MMap)), nil }
+
 	stat, statErr := mariInst.file.Stat()
 	if statErr != nil { return 0, statErr }
 
@@ -92,28 +316,103 @@ func (mariInst *Mari) FileSize() (int, error) {
 }
 
 // Remove
-//	Close Mari and remove the source file.
+//	Close Mari and remove the source file, the version index, and the change log if it is enabled.
+//	For in memory instances there are no files to remove, so this is the same as Close.
+//	Read only instances never modify the file, so an error is returned and the instance is left open.
 func (mariInst *Mari) Remove() error {
+	if mariInst.readOnly { return errors.New("attempting to remove a read only mari instance") }
+
 	closeErr := mariInst.Close()
 	if closeErr != nil { return closeErr }
+	if mariInst.inMemory { return nil }
 
 	removeErr := os.Remove(mariInst.file.Name())
 	if removeErr != nil { return removeErr }
 
+	removeVIdxErr := os.Remove(mariInst.versionIndex.Name())
+	if removeVIdxErr != nil { return removeVIdxErr }
+
+	if mariInst.changeLog != nil {
+		removeCLogErr := os.Remove(mariInst.changeLogPath)
+		if removeCLogErr != nil { return removeCLogErr }
+	}
+
 	return nil
 }
 
+// Sync
+//	Synchronously flush the memory mapped file to disk.
+//	Writes are otherwise flushed optimistically in the background, so this guarantees all committed transactions are durable when it returns.
+//	The version index is flushed as well. The read lock is held so the file is not swapped or resized during the flush.
+func (mariInst *Mari) Sync() error {
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return fmt.Errorf("attempting to sync: %w", ErrClosed) }
+	if mariInst.readOnly { return nil }
+
+	return mariInst.syncFiles()
+}
+
+// Truncate
+//	Remove every key by resetting Mari to an empty root at version 0, while keeping the file open.
+//	An error is returned if there are open snapshots or the change log is enabled, since neither can follow a truncate.
+func (mariInst *Mari) Truncate() error {
+	if mariInst.readOnly { return errors.New("attempting to truncate a read only mari instance") }
+	if mariInst.changeLog != nil { return errors.New("truncate is not recorded in the change log, so it can not be used when ChangeLog is enabled") }
+
+	for ! atomic.CompareAndSwapUint32(&mariInst.isResizing, 0, 1) { runtime.Gosched() }
+	defer atomic.StoreUint32(&mariInst.isResizing, 0)
+
+	mariInst.rwResizeLock.Lock()
+	defer mariInst.rwResizeLock.Unlock()
+
+	if ! mariInst.opened { return fmt.Errorf("attempting to truncate: %w", ErrClosed) }
+	
+	_, hasSnapshots := mariInst.oldestSnapshotVersion()
+	if hasSnapshots { return errors.New("attempting to truncate with open snapshots, close them first") }
+
+	mariInst.nodeCache.clear()
+
+	identity, epoch, loadIdErr := mariInst.loadMetaIdentity()
+	if loadIdErr != nil { return loadIdErr }
+
+	storeIdErr := mariInst.storeMetaIdentity(identity, epoch + 1)
+	if storeIdErr != nil { return storeIdErr }
+
+	endOffset, initRootErr := mariInst.initRoot()
+	if initRootErr != nil { return initRootErr }
+
+	initMetaErr := mariInst.initMeta(endOffset)
+	if initMetaErr != nil { return initMetaErr }
+
+	resetVIdxErr := mariInst.resetVersionIndex()
+	if resetVIdxErr != nil { return resetVIdxErr }
+
+	storeOffsetErr := mariInst.storeStartOffset(0, uint64(InitRootOffset))
+	if storeOffsetErr != nil { return storeOffsetErr }
+
+	atomic.StoreUint64(&mariInst.liveBytes, endOffset - uint64(InitRootOffset))
+	if mariInst.inMemory { return nil }
+
+	return mariInst.file.Sync()
+}
+
 // initializeFile
 //	Initialize the memory mapped file to persist the hamt.
 //	If file size is 0, initiliaze the file size to 64MB and set the initial metadata and root values into the map.
-//	Otherwise, just map the already initialized file into the memory map.
-func (mariInst *Mari) initializeFile() error {
+//	Otherwise, just map the already initialized file into the memory map, and check the version index against it.
+func (mariInst *Mari) initializeFile(rebuildVersionIndex bool) error {
 	fSize, fSizeErr := mariInst.FileSize()
 	if fSizeErr != nil { return fSizeErr }
 
 	switch {
+		case fSize == 0 && mariInst.readOnly:
+			return errors.New("attempting to open an empty file read only")
 		case fSize == 0:
-			_, resizeErr := mariInst.resizeMmap()
+			_, resizeErr := mariInst.resizeMmap(0)
 			if resizeErr != nil { return resizeErr }
 
 			endOffset, initRootErr := mariInst.initRoot()
@@ -121,9 +420,43 @@ func (mariInst *Mari) initializeFile() error {
 
 			initMetaErr := mariInst.initMeta(endOffset)
 			if initMetaErr != nil { return initMetaErr }
+
+			identity, identityErr := newIdentity()
+			if identityErr != nil { return identityErr }
+
+			storeIdErr := mariInst.storeMetaIdentity(identity, 0)
+			if storeIdErr != nil { return storeIdErr }
+
+			resetVIdxErr := mariInst.resetVersionIndex()
+			if resetVIdxErr != nil { return resetVIdxErr }
+
+			storeOffsetErr := mariInst.storeStartOffset(0, uint64(InitRootOffset))
+			if storeOffsetErr != nil { return storeOffsetErr }
 		default:
 			mmapErr := mariInst.mMap()
 			if mmapErr != nil { return mmapErr }
+
+			checkFormatErr := mariInst.checkFileFormat()
+			if checkFormatErr != nil { return checkFormatErr }
+
+			_, version, loadVErr := mariInst.loadMetaVersion()
+			if loadVErr != nil { return loadVErr }
+
+			_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+			if loadROffErr != nil { return loadROffErr }
+
+			if rebuildVersionIndex {
+				resetVIdxErr := mariInst.resetVersionIndex()
+				if resetVIdxErr != nil { return resetVIdxErr }
+			} else {
+				checkVIdxErr := mariInst.checkVersionIndex(version, rootOffset)
+				if checkVIdxErr != nil { return checkVIdxErr }
+			}
+
+			if mariInst.readOnly { return nil }
+
+			storeOffsetErr := mariInst.storeStartOffset(version, rootOffset)
+			if storeOffsetErr != nil { return storeOffsetErr }
 	}
 
 	return nil
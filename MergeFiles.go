@@ -0,0 +1,103 @@
+package mari
+
+import "errors"
+import "os"
+import "path/filepath"
+
+
+//============================================= Mari Merge Files
+
+
+// Merge
+//	Combine the keys of every source into a new instance opened with opts, for joining shards or compacted snapshots.
+//	Like Open, each location is the Filepath directory and FileName, and the sources are opened read only. The destination must not already exist.
+//	Sources are read in the order they are passed, so when a key exists in several sources, the resolver chooses the value to keep, or the value from the last source wins if it is nil.
+func Merge(opts MariOpts, resolve MariConflictResolver, sources ...MariOpts) (*Mari, error) {
+	if len(sources) == 0 { return nil, errors.New("merge requires at least one source file") }
+	if opts.ReadOnly { return nil, errors.New("merge destination cannot be opened read only") }
+
+	if ! opts.InMemory {
+		fileName := opts.FileName
+		if fileName == "" { fileName = DefaultFileName }
+
+		dest := filepath.Join(opts.Filepath, fileName)
+		for _, path := range []string{ dest, dest + VersionIndexFileName } {
+			_, statErr := os.Stat(path)
+			if statErr == nil { return nil, errors.New("merge destination already exists") }
+			if ! os.IsNotExist(statErr) { return nil, statErr }
+		}
+	}
+
+	destInst, openErr := Open(opts)
+	if openErr != nil { return nil, openErr }
+
+	for _, source := range sources {
+		mergeErr := destInst.mergeFromFile(source, resolve)
+		if mergeErr != nil {
+			destInst.Remove()
+			return nil, mergeErr
+		}
+	}
+
+	return destInst, nil
+}
+
+// mergeFromFile
+//	Open the source file read only and write every key-value pair in it to Mari, resolving keys that already exist with the resolver if one is set.
+func (mariInst *Mari) mergeFromFile(source MariOpts, resolve MariConflictResolver) error {
+	source.ReadOnly = true
+
+	sourceInst, openErr := Open(source)
+	if openErr != nil { return openErr }
+	defer sourceInst.Close()
+
+	batch := make([]KeyValuePair, 0, ImportBatchSize)
+
+	flushBatch := func() error {
+		if len(batch) == 0 { return nil }
+
+		putErr := mariInst.UpdateTx(func(tx *MariTx) error {
+			if resolve == nil { return tx.PutBatch(batch) }
+
+			for _, kvPair := range batch {
+				existing, getErr := tx.Get(kvPair.Key, nil)
+				if getErr != nil { return getErr }
+
+				value := kvPair.Value
+				if existing != nil { value = resolve(kvPair.Key, existing.Value, kvPair.Value) }
+
+				if value == nil {
+					delErr := tx.Delete(kvPair.Key)
+					if delErr != nil { return delErr }
+
+					continue
+				}
+
+				putErr := tx.Put(kvPair.Key, value)
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { return putErr }
+
+		batch = batch[:0]
+		return nil
+	}
+
+	readErr := sourceInst.ReadTx(func(tx *MariTx) error {
+		return tx.ForEach(nil, func(kvPair *KeyValuePair) (bool, error) {
+			batch = append(batch, KeyValuePair{ Key: kvPair.Key, Value: kvPair.Value })
+			if len(batch) < ImportBatchSize { return true, nil }
+
+			flushErr := flushBatch()
+			if flushErr != nil { return false, flushErr }
+
+			return true, nil
+		})
+	})
+
+	if readErr != nil { return readErr }
+	return flushBatch()
+}
@@ -1,6 +1,9 @@
 package mari
 
+import "crypto/rand"
+import "encoding/binary"
 import "errors"
+import "fmt"
 import "sync/atomic"
 import "unsafe"
 
@@ -10,7 +13,7 @@ import "unsafe"
 
 // initMeta
 //	Initialize and serialize the metadata in a new Mari.
-//	Version starts at 0 and increments, and root offset starts at 24.
+//	Version starts at 0 and increments, and root offset starts at 48, directly after the identity, epoch, and format marker of the file.
 func (mariInst *Mari) initMeta(nextStart uint64) error {
 	newMeta := &MariMetaData{
 		version: 0,
@@ -114,4 +117,66 @@ func (mariInst *Mari) writeMetaToMemMap(sMeta []byte) (ok bool, err error) {
 	if flushErr != nil { return false, flushErr }
 
 	return true, nil
-}
\ No newline at end of file
+}
+
+// newIdentity
+//	Generate a random identity for a new file, which is copied to its version index so the two files can be matched on open.
+func newIdentity() (uint64, error) {
+	identity := make([]byte, OffsetSize)
+	_, randErr := rand.Read(identity)
+	if randErr != nil { return 0, randErr }
+
+	return binary.LittleEndian.Uint64(identity), nil
+}
+
+// loadMetaIdentity
+//	Get the identity and epoch of the file from the memory map.
+func (mariInst *Mari) loadMetaIdentity() (identity uint64, epoch uint64, err error) {
+	defer func() {
+		r := recover()
+		if r != nil { 
+			identity = 0
+			epoch = 0
+			err = errors.New("error getting identity from mmap")
+		}
+	}()
+
+	mMap := mariInst.data.Load().(MMap)
+	identity = binary.LittleEndian.Uint64(mMap[MetaIdentityIdx:MetaEpochIdx])
+	epoch = binary.LittleEndian.Uint64(mMap[MetaEpochIdx:MetaEpochIdx + OffsetSize])
+
+	return identity, epoch, nil
+}
+
+// storeMetaIdentity
+//	Write the identity and epoch of the file into the memory map, along with the format marker, and flush them to disk.
+//	They are only written when the file is created and when the version index is about to be reset, while the write lock is held.
+func (mariInst *Mari) storeMetaIdentity(identity, epoch uint64) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil { err = errors.New("error storing identity in mmap") }
+	}()
+
+	mMap := mariInst.data.Load().(MMap)
+	binary.LittleEndian.PutUint64(mMap[MetaIdentityIdx:MetaEpochIdx], identity)
+	binary.LittleEndian.PutUint64(mMap[MetaEpochIdx:MetaEpochIdx + OffsetSize], epoch)
+	copy(mMap[MetaFormatIdx:MetaFormatIdx + OffsetSize], FileFormatMagic)
+
+	return mariInst.flushRegionToDisk(MetaIdentityIdx, MetaFormatIdx + OffsetSize)
+}
+
+// checkFileFormat
+//	Verify that the file begins with the header layout marked by FileFormatMagic, before any other field of the header is trusted.
+//	Files written before the marker was introduced have a shorter header, so their root node sits where the marker is expected and the check fails instead of misreading the nodes as metadata.
+func (mariInst *Mari) checkFileFormat() (err error) {
+	defer func() {
+		r := recover()
+		if r != nil { err = fmt.Errorf("file is too short to hold the mari header: %w", ErrUnsupportedFormat) }
+	}()
+
+	mMap := mariInst.data.Load().(MMap)
+	format := mMap[MetaFormatIdx:MetaFormatIdx + OffsetSize]
+	if string(format) != FileFormatMagic { return fmt.Errorf("file header is marked %q instead of %q, it may have been written by an older version of mari: %w", format, FileFormatMagic, ErrUnsupportedFormat) }
+
+	return nil
+}
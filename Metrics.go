@@ -0,0 +1,34 @@
+package mari
+
+import "sync/atomic"
+
+
+//============================================= Mari Metrics
+
+
+// Metrics
+//	Get the cumulative operation counters of Mari since the instance was opened, including puts, gets, deletes, write transaction retries, resizes, compactions, flushes, bytes written, and node cache hits and misses.
+//	Unlike Stats, no nodes are visited, so Metrics is cheap enough to be scraped on an interval and exported to any metrics system.
+//	Each counter is loaded atomically, but the counters are not loaded together, so they may be from slightly different points in time.
+func (mariInst *Mari) Metrics() MariMetrics {
+	return MariMetrics{
+		Puts: atomic.LoadUint64(&mariInst.metrics.Puts),
+		Gets: atomic.LoadUint64(&mariInst.metrics.Gets),
+		Deletes: atomic.LoadUint64(&mariInst.metrics.Deletes),
+		CASRetries: mariInst.TxRetries(),
+		Resizes: atomic.LoadUint64(&mariInst.metrics.Resizes),
+		Compactions: atomic.LoadUint64(&mariInst.metrics.Compactions),
+		Flushes: atomic.LoadUint64(&mariInst.metrics.Flushes),
+		BytesWritten: atomic.LoadUint64(&mariInst.metrics.BytesWritten),
+		NodeCacheHits: atomic.LoadUint64(&mariInst.metrics.NodeCacheHits),
+		NodeCacheMisses: atomic.LoadUint64(&mariInst.metrics.NodeCacheMisses),
+	}
+}
+
+// NodePoolStats
+//	Get the utilization of the node pool, for tuning NodePoolSize.
+//	Misses are gets where the pool was empty, so a high ratio of misses to gets indicates the pool is starved.
+func (mariInst *Mari) NodePoolStats() (size, maxSize int64, gets, puts, misses uint64) {
+	np := mariInst.nodePool
+	return atomic.LoadInt64(&np.size), atomic.LoadInt64(&np.maxSize), atomic.LoadUint64(&np.gets), atomic.LoadUint64(&np.puts), atomic.LoadUint64(&np.misses)
+}
@@ -1,6 +1,7 @@
 package mari
 
 import "errors"
+import "fmt"
 import "sync/atomic"
 import "unsafe"
 
@@ -13,22 +14,41 @@ import "unsafe"
 //	This is used for path copying, so on operations that modify the trie, a copy is created instead of modifying the existing node.
 //	The data structure is essentially immutable. 
 //	If an operation succeeds, the copy replaces the existing node, otherwise the copy is discarded.
+//	The copy records the serialized size of the node it replaces, which is carried over when a path copy is copied again.
 func (mariInst *Mari) copyINode(node *MariINode) *MariINode {
 	nodeCopy := mariInst.nodePool.getINode()
 	
 	nodeCopy.version = node.version
 	nodeCopy.bitmap = node.bitmap
 	nodeCopy.leaf = node.leaf
+	nodeCopy.count = node.count
+	nodeCopy.hasCount = node.hasCount
 	nodeCopy.children = make([]*MariINode, len(node.children))
 
+	if isPathCopy(node) {
+		nodeCopy.replacedSize = node.replacedSize
+	} else { nodeCopy.replacedSize = node.serializedSize() }
+
 	copy(nodeCopy.children, node.children)
 	
 	return nodeCopy
 }
 
+// clonePathCopy
+//	Recursively copy every node of a path copy, sharing the serialized nodes it points to.
+//	Path copies are modified in place by later writes in the same transaction, while serialized nodes never are, so only the path copies need to be copied to preserve the current state.
+func (mariInst *Mari) clonePathCopy(node *MariINode) *MariINode {
+	if ! isPathCopy(node) { return node }
+
+	nodeCopy := mariInst.copyINode(node)
+	for idx, child := range nodeCopy.children { nodeCopy.children[idx] = mariInst.clonePathCopy(child) }
+
+	return nodeCopy
+}
+
 // determineEndOffsetINode
 //	Determine the end offset of a serialized MariINode.
-//	This will be the start offset through the children index, plus (number of children * 8 bytes).
+//	This will be the start offset through the children index, plus (number of children * 8 bytes), plus the 8 byte subtree count.
 func (node *MariINode) determineEndOffsetINode() uint64 {
 	nodeEndOffset := node.startOffset
 
@@ -42,8 +62,8 @@ func (node *MariINode) determineEndOffsetINode() uint64 {
 	}()
 
 	if encodedChildrenLength != 0 {
-		nodeEndOffset += uint64(NodeChildrenIdx + encodedChildrenLength)
-	} else { nodeEndOffset += NodeChildrenIdx }
+		nodeEndOffset += uint64(NodeChildrenIdx + encodedChildrenLength + NodeCountSize)
+	} else { nodeEndOffset += NodeChildrenIdx + NodeCountSize }
 
 	return nodeEndOffset - 1
 }
@@ -54,7 +74,10 @@ func (node *MariINode) determineEndOffsetINode() uint64 {
 func (node *MariLNode) determineEndOffsetLNode() uint64 {
 	nodeEndOffset := node.startOffset
 	if node.key != nil {
-		nodeEndOffset += uint64(NodeKeyIdx + int(node.keyLength) + len(node.value))
+		valueSize := len(node.value)
+		if isOverflow(node) { valueSize = NodeOverflowRefSize }
+
+		nodeEndOffset += uint64(NodeKeyIdx + int(node.keyLength) + valueSize)
 	} else { nodeEndOffset += uint64(NodeKeyIdx) }
 	
 	return nodeEndOffset - 1
@@ -78,6 +101,39 @@ func (mariInst *Mari) getChildNode(childOffset *MariINode, version uint64) (*Mar
 	return childNode, nil
 }
 
+// getChildNodeKey
+//	Get the child node of an internal node, only reading the key of the child's leaf from the memory map.
+//	Children on the current path copy are already in memory and are returned as is.
+func (mariInst *Mari) getChildNodeKey(childOffset *MariINode, version uint64) (*MariINode, error) {
+	if childOffset.version == version && childOffset.startOffset == 0 { return childOffset, nil }
+	return mariInst.readINodeKeyFromMemMap(childOffset.startOffset)
+}
+
+// viewChildNode
+//	Get the child node of an internal node for a read only traversal.
+//	If the node cache is enabled, the cached node is returned without a copy, so the caller must not modify or recycle it.
+func (mariInst *Mari) viewChildNode(childOffset *MariINode, version uint64) (*MariINode, error) {
+	if childOffset.version == version && childOffset.startOffset == 0 { return childOffset, nil }
+	return mariInst.readINodeViewFromMemMap(childOffset.startOffset, false)
+}
+
+// viewChildNodeKey
+//	Get the child node of an internal node for a read only traversal that only needs the key of the child's leaf.
+//	A cached node still holds the value of its leaf, which key only traversals never read.
+func (mariInst *Mari) viewChildNodeKey(childOffset *MariINode, version uint64) (*MariINode, error) {
+	if childOffset.version == version && childOffset.startOffset == 0 { return childOffset, nil }
+	return mariInst.readINodeViewFromMemMap(childOffset.startOffset, true)
+}
+
+// getChildCount
+//	Get the subtree count of a child of an internal node, only reading the key of the child's leaf from the memory map.
+func (mariInst *Mari) getChildCount(childOffset *MariINode, version uint64) (uint64, error) {
+	childNode, getChildErr := mariInst.viewChildNodeKey(childOffset, version)
+	if getChildErr != nil { return 0, getChildErr }
+
+	return mariInst.resolveCount(childNode)
+}
+
 // getSerializedNodeSize
 //	Get the length of the node based on the length of its serialized representation.
 func getSerializedNodeSize(data []byte) uint64 {
@@ -96,6 +152,74 @@ func (mariInst *Mari) initRoot() (uint64, error) {
 	return endOffset, nil
 }
 
+// isPathCopy
+//	Determine whether a node is part of an in memory path copy that has not been serialized yet.
+//	Nodes read from the memory map always have a start offset past the metadata, while copied and newly created nodes have a start offset of 0 until they are written.
+func isPathCopy(node *MariINode) bool {
+	return node.startOffset == 0
+}
+
+// hasKey
+//	Determine whether a leaf holds a key.
+//	Empty leaves have a nil key, while the empty key is a non-nil key of length 0, which can only be held by the leaf of the root.
+func hasKey(leaf *MariLNode) bool {
+	return leaf.key != nil
+}
+
+// isOverflow
+//	Determine whether the value of a leaf is stored out of line, in which case the leaf only holds the offset and length of the value.
+func isOverflow(leaf *MariLNode) bool {
+	return leaf.overflowLength > 0
+}
+
+// isPendingOverflow
+//	Determine whether a leaf references a value put with PutReader that is not written yet.
+//	The offset of the value is assigned when the transaction commits, and no value is ever written at offset 0 since the metadata is stored there.
+func isPendingOverflow(leaf *MariLNode) bool {
+	return isOverflow(leaf) && leaf.overflowOffset == 0
+}
+
+// moveToOverflow
+//	Before a leaf is serialized, move a value longer than the overflow threshold out of line, returning the value to write directly after the serialized leaf.
+//	The leaf keeps a reference to where the value will be written, so later path copies serialize only the reference and the value is never rewritten.
+//	Leaves that already reference an overflow value, or with values within the threshold, are serialized as is and nothing is returned.
+func (mariInst *Mari) moveToOverflow(leaf *MariLNode) []byte {
+	if mariInst.overflowThreshold == 0 || isOverflow(leaf) || len(leaf.value) <= mariInst.overflowThreshold { return nil }
+
+	value := leaf.value
+	leaf.overflowOffset = leaf.startOffset + uint64(NodeKeyIdx + int(leaf.keyLength) + NodeOverflowRefSize)
+	leaf.overflowLength = uint64(len(value))
+	leaf.value = nil
+
+	return value
+}
+
+// resolveValue
+//	Get the value of a leaf, reading it from the memory map if it is stored out of line.
+//	Values are only resolved when they are read, so operations that never touch the value, like path copies and key only scans, never read the overflow.
+func (mariInst *Mari) resolveValue(leaf *MariLNode) (value []byte, err error) {
+	if ! isOverflow(leaf) { return leaf.value, nil }
+	if isPendingOverflow(leaf) { return nil, errors.New("value put with PutReader cannot be read before the transaction commits") }
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			value = nil
+			err = fmt.Errorf("error reading overflow value from mem map: %w", ErrCorruptNode)
+		}
+	}()
+
+	mMap := mariInst.data.Load().(MMap)
+	return mMap[leaf.overflowOffset:leaf.overflowOffset + leaf.overflowLength], nil
+}
+
+// leafCount
+//	A leaf contributes to the subtree count of its node only if it holds a key.
+func leafCount(leaf *MariLNode) int64 {
+	if hasKey(leaf) { return 1 }
+	return 0
+}
+
 // loadNodeFromPointer
 //	Load Mari node from an unsafe pointer.
 func loadINodeFromPointer(ptr *unsafe.Pointer) *MariINode {
@@ -126,18 +250,64 @@ func (mariInst *Mari) newLeafNode(key, value []byte, version uint64) *MariLNode
 
 // readINodeFromMemMap
 //	Reads an internal node in Mari from the serialized memory map.
-func (mariInst *Mari) readINodeFromMemMap(startOffset uint64) (node *MariINode, err error) {
+func (mariInst *Mari) readINodeFromMemMap(startOffset uint64) (*MariINode, error) {
+	return mariInst.readINodeWithLeafFromMemMap(startOffset, false)
+}
+
+// readINodeKeyFromMemMap
+//	Reads an internal node in Mari from the serialized memory map, only reading the key of the associated leaf.
+//	The value of the leaf is left nil, which avoids touching the value bytes for operations that only need keys.
+func (mariInst *Mari) readINodeKeyFromMemMap(startOffset uint64) (*MariINode, error) {
+	return mariInst.readINodeWithLeafFromMemMap(startOffset, true)
+}
+
+// readINodeViewFromMemMap
+//	Reads an internal node for a read only traversal, returning the cached node itself on a hit instead of a copy.
+func (mariInst *Mari) readINodeViewFromMemMap(startOffset uint64, keyOnly bool) (*MariINode, error) {
+	return mariInst.readINodeFromMemMapWith(startOffset, keyOnly, true)
+}
+
+// readINodeWithLeafFromMemMap
+//	Reads an internal node and its leaf from the serialized memory map.
+//	If keyOnly is true, the value of the leaf is not read.
+//	If the node cache is enabled, a copy of the node is returned from the cache when the version at the offset matches, and nodes read with their value are added to the cache.
+func (mariInst *Mari) readINodeWithLeafFromMemMap(startOffset uint64, keyOnly bool) (*MariINode, error) {
+	return mariInst.readINodeFromMemMapWith(startOffset, keyOnly, false)
+}
+
+// readINodeFromMemMapWith
+//	Reads an internal node and its leaf from the serialized memory map, or from the node cache.
+//	If view is true, a cache hit returns the cached node itself, otherwise a copy that the caller is free to modify.
+func (mariInst *Mari) readINodeFromMemMapWith(startOffset uint64, keyOnly bool, view bool) (node *MariINode, err error) {
 	defer func() {
 		r := recover()
 		if r != nil {
 			node = nil
-			err = errors.New("error reading node from mem map")
+			err = fmt.Errorf("error reading node from mem map: %w", ErrCorruptNode)
 		}
 	}()
 	
 	endOffsetIdx := startOffset + NodeEndOffsetIdx
 	
 	mMap := mariInst.data.Load().(MMap)
+
+	if mariInst.nodeCache != nil {
+		version, decVersionErr := deserializeUint64(mMap[startOffset + NodeVersionIdx:startOffset + NodeVersionIdx + OffsetSize])
+		if decVersionErr != nil { return nil, decVersionErr }
+
+		var cached *MariINode
+		if view {
+			cached = mariInst.nodeCache.view(startOffset, version)
+		} else { cached = mariInst.nodeCache.get(startOffset, version, keyOnly) }
+
+		if cached != nil {
+			atomic.AddUint64(&mariInst.metrics.NodeCacheHits, 1)
+			return cached, nil
+		}
+
+		atomic.AddUint64(&mariInst.metrics.NodeCacheMisses, 1)
+	}
+
 	sEndOffset := mMap[endOffsetIdx:endOffsetIdx + OffsetSize]
 
 	endOffset, decEndOffErr := deserializeUint64(sEndOffset)
@@ -147,21 +317,24 @@ func (mariInst *Mari) readINodeFromMemMap(startOffset uint64) (node *MariINode,
 	node, decNodeErr := deserializeINode(sNode)
 	if decNodeErr != nil { return nil, decNodeErr }
 
-	leaf, readLeafErr := mariInst.readLNodeFromMemMap(node.leaf.startOffset)
+	leaf, readLeafErr := mariInst.readLNodeFromMemMap(node.leaf.startOffset, keyOnly)
 	if readLeafErr != nil { return nil, readLeafErr }
 
 	node.leaf = leaf
+	if mariInst.nodeCache != nil && ! keyOnly { mariInst.nodeCache.put(startOffset, node) }
+
 	return node, nil
 }
 
 // readLNodeFromMemMap
 //	Reads a leaf node in Mari from the serialized memory map.
-func (mariInst *Mari) readLNodeFromMemMap(startOffset uint64) (node *MariLNode, err error) {
+//	If keyOnly is true, the serialized node is only read up to the end of the key and the value is left nil.
+func (mariInst *Mari) readLNodeFromMemMap(startOffset uint64, keyOnly bool) (node *MariLNode, err error) {
 	defer func() {
 		r := recover()
 		if r != nil {
 			node = nil
-			err = errors.New("error reading node from mem map")
+			err = fmt.Errorf("error reading node from mem map: %w", ErrCorruptNode)
 		}
 	}()
 	
@@ -172,13 +345,76 @@ func (mariInst *Mari) readLNodeFromMemMap(startOffset uint64) (node *MariLNode,
 	endOffset, decEndOffErr := deserializeUint64(sEndOffset)
 	if decEndOffErr != nil { return nil, decEndOffErr }
 
+	if keyOnly {
+		keyLengthIdx := startOffset + NodeKeyLength
+		keyLength, decKeyLenErr := deserializeUint16(mMap[keyLengthIdx:keyLengthIdx + 2])
+		if decKeyLenErr != nil { return nil, decKeyLenErr }
+		if keyLength == EmptyKeyLength { keyLength = 0 }
+
+		endOffset = startOffset + NodeKeyIdx + uint64(keyLength) - 1
+	}
+
 	sNode := mMap[startOffset:endOffset + 1]
 	node, decNodeErr := deserializeLNode(sNode)
 	if decNodeErr != nil { return nil, decNodeErr }
 
+	if keyOnly { node.value = nil }
 	return node, nil
 }
 
+// resolveCount
+//	Get the total number of leaves with a key in the subtree rooted at the node.
+//	Nodes serialized before subtree counts were stored do not have a count, so it is computed by walking the subtree and cached on the node.
+func (mariInst *Mari) resolveCount(node *MariINode) (uint64, error) {
+	if node.hasCount { return node.count, nil }
+
+	var count uint64
+	if hasKey(node.leaf) { count++ }
+
+	for _, child := range node.children {
+		childCount, getCountErr := mariInst.getChildCount(child, node.version)
+		if getCountErr != nil { return 0, getCountErr }
+
+		count += childCount
+	}
+
+	node.count = count
+	node.hasCount = true
+
+	return count, nil
+}
+
+// replacedSizeOfPath
+//	Sum the serialized size of every node in the memory map replaced by the path copy, following the same children that are serialized by serializePathToMemMap.
+//	Nodes on the path that were read from the memory map but never copied, like the root of a transaction that did not modify anything, are serialized again so their own size is replaced.
+//	A leaf carried over to the path copy still references its overflow value, so the overflow value is not replaced.
+func replacedSizeOfPath(root *MariINode) uint64 {
+	var total uint64
+	stack := []*MariINode{ root }
+
+	for len(stack) > 0 {
+		node := stack[len(stack) - 1]
+		stack = stack[:len(stack) - 1]
+
+		replaced := node.replacedSize
+		if ! isPathCopy(node) { replaced = node.serializedSize() }
+		if isOverflow(node.leaf) && ! isPendingOverflow(node.leaf) && replaced >= node.leaf.overflowLength { replaced -= node.leaf.overflowLength }
+		total += replaced
+
+		for _, child := range node.children {
+			if child.version == node.version { stack = append(stack, child) }
+		}
+	}
+
+	return total
+}
+
+// serializedSize
+//	The size of a node read from the memory map, including its leaf and the overflow value of its leaf.
+func (node *MariINode) serializedSize() uint64 {
+	return (node.endOffset - node.startOffset + 1) + (node.leaf.endOffset - node.leaf.startOffset + 1) + node.leaf.overflowLength
+}
+
 // storeNodeAsPointer
 //	Store a MariINode as an unsafe pointer.
 func storeINodeAsPointer(node *MariINode) *unsafe.Pointer {
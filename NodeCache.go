@@ -0,0 +1,112 @@
+package mari
+
+import "container/list"
+
+
+//============================================= Mari Node Cache
+
+
+// newNodeCache
+//	Create a node cache holding up to capacity nodes.
+func newNodeCache(capacity int) *MariNodeCache {
+	return &MariNodeCache{
+		capacity: capacity,
+		entries: make(map[uint64]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get
+//	Get a copy of the node cached at the offset, moving it to the front of the cache.
+//	Nodes in the memory map are never rewritten in place, so the cached node is valid as long as the version serialized at the offset still matches, which only changes if the offset is reused after a truncate.
+//	If keyOnly is true, the value of the leaf in the copy is left nil like readINodeKeyFromMemMap.
+func (cache *MariNodeCache) get(offset uint64, version uint64, keyOnly bool) *MariINode {
+	cached := cache.lookup(offset, version)
+	if cached == nil { return nil }
+
+	nodeCopy := copyCachedNode(cached)
+	if keyOnly { nodeCopy.leaf.value = nil }
+
+	return nodeCopy
+}
+
+// view
+//	Get the node cached at the offset without copying it, for read paths that never modify or recycle the nodes they visit.
+//	Nodes without a serialized subtree count are still copied, since their count is resolved lazily on the node itself.
+func (cache *MariNodeCache) view(offset uint64, version uint64) *MariINode {
+	cached := cache.lookup(offset, version)
+	if cached == nil || cached.hasCount { return cached }
+
+	return copyCachedNode(cached)
+}
+
+// lookup
+//	Find the node cached at the offset, moving it to the front of the cache, or evicting it if the version at the offset no longer matches.
+func (cache *MariNodeCache) lookup(offset uint64, version uint64) *MariINode {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	elem, ok := cache.entries[offset]
+	if ! ok { return nil }
+
+	entry := elem.Value.(*MariNodeCacheEntry)
+	if entry.node.version != version {
+		cache.order.Remove(elem)
+		delete(cache.entries, offset)
+
+		return nil
+	}
+
+	cache.order.MoveToFront(elem)
+	return entry.node
+}
+
+// put
+//	Cache a copy of a node read from the offset, evicting the least recently used node if the cache is full.
+func (cache *MariNodeCache) put(offset uint64, node *MariINode) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	elem, ok := cache.entries[offset]
+	if ok {
+		elem.Value.(*MariNodeCacheEntry).node = copyCachedNode(node)
+		cache.order.MoveToFront(elem)
+
+		return
+	}
+
+	if cache.order.Len() >= cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*MariNodeCacheEntry).offset)
+	}
+
+	cache.entries[offset] = cache.order.PushFront(&MariNodeCacheEntry{ offset: offset, node: copyCachedNode(node) })
+}
+
+// clear
+//	Remove every node from the cache.
+//	Keys and values of cached leaves point into the memory map, so the cache is cleared whenever the memory map is unmapped, remapped, or rewritten in place.
+func (cache *MariNodeCache) clear() {
+	if cache == nil { return }
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.entries = make(map[uint64]*list.Element)
+	cache.order.Init()
+}
+
+// copyCachedNode
+//	Copy a node, its leaf, and the offsets of its children, so the copy can be modified or recycled into the node pool by the caller without affecting the cache.
+//	The key and value of the leaf still point into the memory map, which is never modified for a serialized node.
+func copyCachedNode(node *MariINode) *MariINode {
+	nodeCopy := *node
+	leafCopy := *node.leaf
+
+	nodeCopy.leaf = &leafCopy
+	nodeCopy.children = make([]*MariINode, len(node.children))
+	for idx, child := range node.children { nodeCopy.children[idx] = &MariINode{ startOffset: child.startOffset } }
+
+	return &nodeCopy
+}
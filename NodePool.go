@@ -10,34 +10,40 @@ import "sync/atomic"
 // NewMariNodePool
 //	Creates a new node pool for recycling nodes instead of letting garbage collection handle them.
 //	Should help performance when there are a large number of go routines attempting to allocate/deallocate nodes.
-func newMariNodePool(maxSize int64) *MariNodePool {
+//	If the ceiling is greater than 0, the max size of the pool is tuned at runtime between the initial max size and the ceiling.
+//	The pool starts empty, and initializePools pre-allocates it.
+func newMariNodePool(maxSize, ceiling int64) *MariNodePool {
 	size := int64(0)
-	np := &MariNodePool{ maxSize: maxSize, size: size }
+	np := &MariNodePool{ maxSize: maxSize, minSize: maxSize, ceiling: ceiling, size: size }
 
 	iNodePool := &sync.Pool { 
 		New: func() interface {} { 
+			atomic.AddUint64(&np.misses, 1)
+			atomic.AddUint64(&np.windowMisses, 1)
 			return np.resetINode(&MariINode{})
 		},
 	}
 
 	lNodePool := &sync.Pool {
 		New: func() interface {} { 
+			atomic.AddUint64(&np.misses, 1)
+			atomic.AddUint64(&np.windowMisses, 1)
 			return np.resetLNode(&MariLNode{})
 		},
 	}
 
 	np.iNodePool = iNodePool
 	np.lNodePool = lNodePool
-	np.initializePools()
 
 	return np
 }
 
 // getINode
 //	Attempt to get a pre-allocated internal node from the node pool and decrement the total allocated nodes.
-//	If the pool is empty, a new node is allocated
+//	If the pool is empty, a new node is allocated and counted as a miss.
 func (np *MariNodePool) getINode() *MariINode {
 	node := np.iNodePool.Get().(*MariINode)
+	np.recordGet()
 	if atomic.LoadInt64(&np.size) > 0 { atomic.AddInt64(&np.size, -1) }
 
 	return node
@@ -45,16 +51,50 @@ func (np *MariNodePool) getINode() *MariINode {
 
 // getLNode
 //	Attempt to get a pre-allocated leaf node from the node pool and decrement the total allocated nodes.
-//	If the pool is empty, a new node is allocated
+//	If the pool is empty, a new node is allocated and counted as a miss.
 func (np *MariNodePool) getLNode() *MariLNode {
 	node := np.lNodePool.Get().(*MariLNode)
+	np.recordGet()
 	if atomic.LoadInt64(&np.size) > 0 { atomic.AddInt64(&np.size, -1) }
 
 	return node
 }
 
+// recordGet
+//	Count a get from the node pool, and if the pool auto tunes, adjust its max size at the end of every window of NodePoolTuneWindow gets.
+func (np *MariNodePool) recordGet() {
+	atomic.AddUint64(&np.gets, 1)
+	if np.ceiling == 0 { return }
+
+	if atomic.AddUint64(&np.windowGets, 1) % NodePoolTuneWindow == 0 { np.tune() }
+}
+
+// tune
+//	Adjust the max size of an auto tuning node pool based on the last window of gets.
+//	If the miss rate is above NodePoolGrowMissRate, the pool is starved, so the max size is doubled, up to the ceiling.
+//	If there were no misses and more than half of the max size is sitting idle in the pool, the max size is halved, down to the initial max size.
+//	Once the max size shrinks, recycled nodes are dropped until the pool is back under it, and the garbage collector reclaims the excess.
+func (np *MariNodePool) tune() {
+	misses := atomic.SwapUint64(&np.windowMisses, 0)
+	maxSize := atomic.LoadInt64(&np.maxSize)
+
+	switch {
+		case float64(misses) / float64(NodePoolTuneWindow) > NodePoolGrowMissRate:
+			grown := maxSize * 2
+			if grown < 2 { grown = 2 }
+			if grown > np.ceiling { grown = np.ceiling }
+
+			atomic.CompareAndSwapInt64(&np.maxSize, maxSize, grown)
+		case misses == 0 && atomic.LoadInt64(&np.size) > maxSize / 2:
+			shrunk := maxSize / 2
+			if shrunk < np.minSize { shrunk = np.minSize }
+
+			atomic.CompareAndSwapInt64(&np.maxSize, maxSize, shrunk)
+	}
+}
+
 // initializePool
-//	When Mari is opened, initialize the pool with the max size of nodes.
+//	When Mari is opened for writes, initialize the pool with the max size of nodes.
 func (np *MariNodePool) initializePools() {
 	for range make([]int, np.maxSize / 2) {
 		np.iNodePool.Put(np.resetINode(&MariINode{}))
@@ -71,9 +111,10 @@ func (np *MariNodePool) initializePools() {
 //	Attempt to put an internal node back into the pool once a path has been copied + serialized.
 //	If the pool is at max capacity, drop the node and let the garbage collector take care of it.
 func (np *MariNodePool) putINode(node *MariINode) {
-	if atomic.LoadInt64(&np.size) < np.maxSize { 
+	if atomic.LoadInt64(&np.size) < atomic.LoadInt64(&np.maxSize) { 
 		np.iNodePool.Put(np.resetINode(node))
 		atomic.AddInt64(&np.size, 1)
+		atomic.AddUint64(&np.puts, 1)
 	}
 }
 
@@ -81,9 +122,10 @@ func (np *MariNodePool) putINode(node *MariINode) {
 //	Attempt to put a leaf node back into the pool once a path has been copied + serialized.
 //	If the pool is at max capacity, drop the node and let the garbage collector take care of it.
 func (np *MariNodePool) putLNode(node *MariLNode) {
-	if atomic.LoadInt64(&np.size) < np.maxSize { 
+	if atomic.LoadInt64(&np.size) < atomic.LoadInt64(&np.maxSize) { 
 		np.lNodePool.Put(np.resetLNode(node))
 		atomic.AddInt64(&np.size, 1)
+		atomic.AddUint64(&np.puts, 1)
 	}
 }
 
@@ -94,6 +136,9 @@ func (np *MariNodePool) resetINode(node *MariINode) *MariINode{
 	node.startOffset = 0
 	node.endOffset = 0
 	node.bitmap = [8]uint32{0, 0, 0, 0, 0, 0, 0, 0}
+	node.count = 0
+	node.hasCount = true
+	node.replacedSize = 0
 	
 	node.leaf = &MariLNode{ 
 		version: 0, 
@@ -102,6 +147,8 @@ func (np *MariNodePool) resetINode(node *MariINode) *MariINode{
 		keyLength: 0, 
 		key: nil, 
 		value: nil, 
+		overflowOffset: 0,
+		overflowLength: 0,
 	}
 
 	node.children = make([]*MariINode, 0)
@@ -118,6 +165,8 @@ func (np *MariNodePool) resetLNode(node *MariLNode) *MariLNode{
 	node.keyLength = 0
 	node.key = nil
 	node.value = nil
+	node.overflowOffset = 0
+	node.overflowLength = 0
 
 	return node
 }
\ No newline at end of file
@@ -1,185 +1,328 @@
 package mari
 
 import "bytes"
+import "sync/atomic"
 import "unsafe"
 
 
 //============================================= Mari Operations
 
 
-// putRecursive
-//	Attempts to traverse through the trie, locating the node at a given level to modify for the key-value pair.
-//	It first hashes the key, determines the sparse index in the bitmap to modify, and creates a copy of the current node to be modified.
-//	If the bit in the bitmap of the node is not set, a new leaf node is created, the bitmap of the copy is modified to reflect the position of the new leaf node, and the child node array is extended to include the new leaf node.
-//	Then, an atomic compare and swap operation is performed where the operation attempts to replace the current node with the modified copy.
-//	If the operation succeeds the response is returned by moving back up the tree. If it fails, the copy is discarded and the operation returns to the root to be reattempted.
-//	If the current bit is set in the bitmap, the operation checks if the node at the location in the child node array is a leaf node or an internal node.
-//	If it is a leaf node and the key is the same as the incoming key, the copy is modified with the new value and we attempt to compare and swap the current child leaf node with the new copy.
-//	If the leaf node does not contain the same key, the operation creates a new internal node, and inserts the new leaf node for the incoming key and value as well as the existing child node into the new internal node.
-//	Attempts to compare and swap the current leaf node with the new internal node containing the existing child node and the new leaf node for the incoming key and value.
-//	If the node is an internal node, the operation traverses down the tree to the internal node and the above steps are repeated until the key-value pair is inserted.
-func (mariInst *Mari) putRecursive(node *unsafe.Pointer, key, value []byte, level int) (bool, error) {
-	var putErr error
+// putIterative
+//	Traverses the trie in a loop over the levels of the key, copying each node on the path, so long keys do not grow the stack by one frame per byte.
+//	Each level is recorded as a path frame, including levels a displaced leaf is moved down to by putAtLevel.
+//	The frames are then unwound from the bottom up, adjusting subtree counts and swapping each copy in, and a failed compare and swap reattempts from the root.
+func (mariInst *Mari) putIterative(node *unsafe.Pointer, leaf *MariLNode, level int) (bool, error) {
+	var frames []*MariPathFrame
+	var swapped bool
 
-	currNode := loadINodeFromPointer(node)
-	nodeCopy := mariInst.copyINode(currNode)
-	nodeCopy.leaf.version = nodeCopy.version
+	currPtr := node
+
+	for {
+		key := leaf.key
+
+		currNode := loadINodeFromPointer(currPtr)
+		currCount, countErr := mariInst.resolveCount(currNode)
+		if countErr != nil { return false, countErr }
+
+		nodeCopy := currNode
+		if ! isPathCopy(currNode) { nodeCopy = mariInst.copyINode(currNode) }
+
+		holdsKey := hasKey(nodeCopy.leaf) && bytes.Equal(nodeCopy.leaf.key, key)
+		if len(key) != level && ! holdsKey && isBitSet(nodeCopy.bitmap, getIndexForLevel(key, level)) {
+			pos := getPosition(nodeCopy.bitmap, getIndexForLevel(key, level), level)
+
+			frame, descendErr := mariInst.descendFrame(currPtr, currNode, nodeCopy, currCount, pos)
+			if descendErr != nil { return false, descendErr }
+
+			frames = append(frames, frame)
+			currPtr = frame.childPtr
+			level++
+			continue
+		}
+
+		frame, displaced, putErr := mariInst.putAtLevel(currPtr, currNode, nodeCopy, currCount, leaf, level)
+		if putErr != nil { return false, putErr }
+
+		if displaced == nil {
+			swapped = mariInst.compareAndSwap(currPtr, currNode, nodeCopy)
+			break
+		}
+
+		frames = append(frames, frame)
+		currPtr = frame.childPtr
+		leaf = displaced
+		level++
+	}
+
+	for idx := len(frames) - 1; idx >= 0; idx-- {
+		frame := frames[idx]
+
+		updatedChild := loadINodeFromPointer(frame.childPtr)
+		frame.nodeCopy.children[frame.pos] = updatedChild
+		frame.nodeCopy.count = uint64(int64(frame.currCount) + int64(updatedChild.count) - int64(frame.childCount))
+
+		swapped = mariInst.compareAndSwap(frame.node, frame.currNode, frame.nodeCopy)
+	}
+
+	return swapped, nil
+}
+
+// descendFrame
+//	Build the path frame for descending from the copy of a node into the child at pos, where the child takes the version of the copy.
+func (mariInst *Mari) descendFrame(node *unsafe.Pointer, currNode, nodeCopy *MariINode, currCount uint64, pos int) (*MariPathFrame, error) {
+	childNode, getChildErr := mariInst.getChildNode(nodeCopy.children[pos], nodeCopy.version)
+	if getChildErr != nil { return nil, getChildErr }
+
+	childCount, countErr := mariInst.resolveCount(childNode)
+	if countErr != nil { return nil, countErr }
+
+	childNode.version = nodeCopy.version
+
+	return &MariPathFrame{
+		node: node,
+		currNode: currNode,
+		nodeCopy: nodeCopy,
+		currCount: currCount,
+		pos: pos,
+		childPtr: storeINodeAsPointer(childNode),
+		childCount: childCount,
+	}, nil
+}
+
+// putAtLevel
+//	Place the leaf in the copy of the node at the given level, where the bit for the next byte of the key is not set or the key ends at the level.
+//	The shorter of the new key and the current leaf takes the leaf of the node, and the longer one moves to the child for its next byte.
+//	A leaf displaced into an existing child is returned with the frame for that child, so putIterative can continue down with it.
+func (mariInst *Mari) putAtLevel(node *unsafe.Pointer, currNode, nodeCopy *MariINode, currCount uint64, leaf *MariLNode, level int) (*MariPathFrame, *MariLNode, error) {
+	var childDelta int64
+	var frame *MariPathFrame
+	var displaced *MariLNode
 
-	putNewINode := func(node *MariINode, currIdx byte, uKey, uVal []byte) (*MariINode, error) {
-		node.bitmap = setBit(node.bitmap, currIdx)
-		pos := getPosition(node.bitmap, currIdx, level)
+	key := leaf.key
 
-		newINode := mariInst.newInternalNode(node.version)
-		iNodePtr := storeINodeAsPointer(newINode)
-		_, putINodeErr := mariInst.putRecursive(iNodePtr, uKey, uVal, level + 1)
-		if putINodeErr != nil { return nil, putINodeErr }
+	currLeafCount := leafCount(currNode.leaf)
 
-		updatedINode:= loadINodeFromPointer(iNodePtr)
-		node.children = extendTable(node.children, node.bitmap, pos, updatedINode)
+	putNewINode := func(currIdx byte, uLeaf *MariLNode) {
+		nodeCopy.bitmap = setBit(nodeCopy.bitmap, currIdx)
+		pos := getPosition(nodeCopy.bitmap, currIdx, level)
 
-		return node, nil
+		newINode := mariInst.newInternalNode(nodeCopy.version)
+		newINode.leaf = uLeaf
+		newINode.count = uint64(leafCount(uLeaf))
+
+		nodeCopy.children = extendTable(nodeCopy.children, nodeCopy.bitmap, pos, newINode)
+		childDelta += int64(newINode.count)
 	}
 
+	relocateLeaf := func(uLeaf *MariLNode) error {
+		newIdx := getIndexForLevel(uLeaf.key, level)
+		if ! isBitSet(nodeCopy.bitmap, newIdx) {
+			putNewINode(newIdx, uLeaf)
+			return nil
+		}
+
+		var descendErr error
+		frame, descendErr = mariInst.descendFrame(node, currNode, nodeCopy, 0, getPosition(nodeCopy.bitmap, newIdx, level))
+		if descendErr != nil { return descendErr }
+
+		displaced = uLeaf
+		return nil
+	}
+
+	var putErr error
+
 	if len(key) == level {
 		switch {
-			case bytes.Equal(nodeCopy.leaf.key, key):
-				if ! bytes.Equal(nodeCopy.leaf.value, value) { nodeCopy.leaf = mariInst.newLeafNode(key, value, nodeCopy.version) }
+			case hasKey(nodeCopy.leaf) && bytes.Equal(nodeCopy.leaf.key, key):
+				if isOverflow(nodeCopy.leaf) || isOverflow(leaf) || ! bytes.Equal(nodeCopy.leaf.value, leaf.value) || nodeCopy.leaf.version != leaf.version { nodeCopy.leaf = leaf }
 			default:
 				currentLeaf := nodeCopy.leaf
-				nodeCopy.leaf = mariInst.newLeafNode(key, value, nodeCopy.version)
-
-				if len(currentLeaf.key) > len(key) {
-					idx := getIndexForLevel(currentLeaf.key, level)
+				nodeCopy.leaf = leaf
 
-					if ! isBitSet(nodeCopy.bitmap, idx) { 
-						nodeCopy, putErr = putNewINode(nodeCopy, idx, currentLeaf.key, currentLeaf.value)
-						if putErr != nil { return false, putErr }
-					}
-				}
+				if len(currentLeaf.key) > len(key) { putErr = relocateLeaf(currentLeaf) }
 		}
 	} else {
 		index := getIndexForLevel(key, level)
-		
-		switch {
-			case ! isBitSet(nodeCopy.bitmap, index):
-				if level > 0 {
-					popCount := populationCount(nodeCopy.bitmap)
-					currentLeaf := nodeCopy.leaf
 
+		if level > 0 {
+			popCount := populationCount(nodeCopy.bitmap)
+			currentLeaf := nodeCopy.leaf
+
+			switch {
+				case bytes.Equal(currentLeaf.key, key):
+					if isOverflow(currentLeaf) || isOverflow(leaf) || ! bytes.Equal(currentLeaf.value, leaf.value) || currentLeaf.version != leaf.version { nodeCopy.leaf = leaf }
+				case ! hasKey(currentLeaf) && popCount == 0:
+					nodeCopy.leaf = leaf
+				case ! hasKey(currentLeaf) && popCount > 0:
+					putNewINode(index, leaf)
+				default:
 					switch {
-						case bytes.Equal(currentLeaf.key, key):
-							if ! bytes.Equal(currentLeaf.value, value) { nodeCopy.leaf = mariInst.newLeafNode(key, value, nodeCopy.version) }
-						case len(currentLeaf.key) == 0 && popCount == 0:
-							nodeCopy.leaf = mariInst.newLeafNode(key, value, nodeCopy.version)
-						case len(currentLeaf.key) == 0 && popCount > 0:
-							nodeCopy, putErr = putNewINode(nodeCopy, index, key, value)
-							if putErr != nil { return false, putErr }
+						case len(key) > len(currentLeaf.key) && len(currentLeaf.key) > 0:
+							putNewINode(index, leaf)
+						case len(currentLeaf.key) > len(key):
+							nodeCopy.leaf = leaf
+							putErr = relocateLeaf(currentLeaf)
 						default:
-							switch {
-								case len(key) > len(currentLeaf.key) && len(currentLeaf.key) > 0:
-									nodeCopy, putErr = putNewINode(nodeCopy, index, key, value)
-									if putErr != nil { return false, putErr }
-								case len(currentLeaf.key) > len(key):
-									nodeCopy.leaf = mariInst.newLeafNode(key, value, nodeCopy.version)
-									newIdx := getIndexForLevel(currentLeaf.key, level)
-									
-									if ! isBitSet(nodeCopy.bitmap, newIdx) {
-										nodeCopy, putErr = putNewINode(nodeCopy, newIdx, currentLeaf.key, currentLeaf.value)
-										if putErr != nil { return false, putErr }
-									}
-								default:
-									nodeCopy.leaf = mariInst.newLeafNode(nil, nil, nodeCopy.version)
-
-									nodeCopy, putErr = putNewINode(nodeCopy, index, key, value)
-									if putErr != nil { return false, putErr }
-		
-									newIdx := getIndexForLevel(currentLeaf.key, level)
-
-									if ! isBitSet(nodeCopy.bitmap, newIdx) {
-										nodeCopy, putErr = putNewINode(nodeCopy, newIdx, currentLeaf.key, currentLeaf.value)
-										if putErr != nil { return false, putErr }
-									} else {
-										newPos := getPosition(nodeCopy.bitmap, newIdx, level)
-										
-										childOffset := nodeCopy.children[newPos]
-										childNode, getChildErr := mariInst.getChildNode(childOffset, nodeCopy.version)
-										if getChildErr != nil { return false, getChildErr }
-							
-										childNode.version = nodeCopy.version
-										childPtr := storeINodeAsPointer(childNode)
-										_, putErr = mariInst.putRecursive(childPtr, currentLeaf.key, currentLeaf.value, level + 1)
-										if putErr != nil { return false, putErr }
-
-										updatedCNode := loadINodeFromPointer(childPtr)
-										nodeCopy.children[newPos] = updatedCNode
-									}
-							}
+							nodeCopy.leaf = mariInst.newLeafNode(nil, nil, nodeCopy.version)
+							putNewINode(index, leaf)
+							putErr = relocateLeaf(currentLeaf)
 					}
-				} else {
-					nodeCopy, putErr = putNewINode(nodeCopy, index, key, value)
-					if putErr != nil { return false, putErr }
-				}
-			default:
-				pos := getPosition(nodeCopy.bitmap, index, level)
+			}
+		} else { putNewINode(index, leaf) }
+	}
 
-				childOffset := nodeCopy.children[pos]
-				childNode, getChildErr := mariInst.getChildNode(childOffset, nodeCopy.version)
-				if getChildErr != nil { return false, getChildErr }
-	
-				childNode.version = nodeCopy.version
-				childPtr := storeINodeAsPointer(childNode)
-	
-				_, putErr = mariInst.putRecursive(childPtr, key, value, level + 1)
-				if putErr != nil { return false, putErr }
-	
-				nodeCopy.children[pos] = loadINodeFromPointer(childPtr)
+	if putErr != nil { return nil, nil, putErr }
+
+	nodeCopy.count = uint64(int64(currCount) + leafCount(nodeCopy.leaf) - currLeafCount + childDelta)
+	if frame != nil { frame.currCount = nodeCopy.count }
+
+	return frame, displaced, nil
+}
+
+// getIterative
+//	Attempts to retrieve a value for a given key, looping over the levels of the key instead of recursing so long keys do not grow the stack.
+//	If the bit for the byte of the key at a level is not set in the bitmap, return nil since the key has not been inserted yet into the trie.
+//	If the transform returns nil for the key value pair, the key is treated as not found.
+func (mariInst *Mari) getIterative(node *unsafe.Pointer, key []byte, level int, transform MariOpTransform) (*KeyValuePair, error) {
+	atomic.AddUint64(&mariInst.metrics.Gets, 1)
+
+	leaf, findErr := mariInst.findLeaf(node, key, level)
+	if findErr != nil || leaf == nil { return nil, findErr }
+
+	kvPair, decodeErr := mariInst.newKeyValuePair(leaf)
+	if decodeErr != nil { return nil, decodeErr }
+
+	return transform(kvPair), nil
+}
+
+// findLeaf
+//	Traverse the trie to the leaf holding the key, returning nil if the key does not exist.
+//	The value of the leaf is not resolved, so callers can read an out of line value however they need.
+func (mariInst *Mari) findLeaf(node *unsafe.Pointer, key []byte, level int) (*MariLNode, error) {
+	currNode := loadINodeFromPointer(node)
+
+	for ; ; level++ {
+		if hasKey(currNode.leaf) && bytes.Equal(key, currNode.leaf.key) { return currNode.leaf, nil }
+		if len(key) == level { return nil, nil }
+
+		index := getIndexForLevel(key, level)
+		if ! isBitSet(currNode.bitmap, index) { return nil, nil }
+
+		pos := getPosition(currNode.bitmap, index, level)
+		childNode, getChildErr := mariInst.viewChildNode(currNode.children[pos], currNode.version)
+		if getChildErr != nil { return nil, getChildErr }
+
+		currNode = childNode
+	}
+}
+
+// getManyRecursive
+//	Retrieve the values for a sorted set of keys in a single traversal, where indexes maps each key back to its position in the results.
+//	At each node, keys matching the leaf are resolved, and the remaining keys are grouped by their byte at the current level so each child is only read once for every key that passes through it.
+//	Since the keys are sorted, keys sharing a prefix are always adjacent and end up in the same group.
+//	Keys that end at the current level or whose bit is not set in the bitmap do not exist, and are left as nil in the results.
+func (mariInst *Mari) getManyRecursive(node *unsafe.Pointer, keys [][]byte, indexes []int, level int, transform MariOpTransform, results []*KeyValuePair) error {
+	currNode := loadINodeFromPointer(node)
+
+	for start := 0; start < len(keys); {
+		key := keys[start]
+
+		if hasKey(currNode.leaf) && bytes.Equal(key, currNode.leaf.key) {
+			kvPair, decodeErr := mariInst.newKeyValuePair(currNode.leaf)
+			if decodeErr != nil { return decodeErr }
+
+			results[indexes[start]] = transform(kvPair)
+			start++
+			continue
+		}
+
+		if len(key) == level {
+			start++
+			continue
+		}
+
+		index := getIndexForLevel(key, level)
+
+		end := start + 1
+		for end < len(keys) && len(keys[end]) > level && getIndexForLevel(keys[end], level) == index && ! bytes.Equal(keys[end], currNode.leaf.key) { end++ }
+
+		if isBitSet(currNode.bitmap, index) {
+			pos := getPosition(currNode.bitmap, index, level)
+			childNode, getChildErr := mariInst.viewChildNode(currNode.children[pos], currNode.version)
+			if getChildErr != nil { return getChildErr }
+
+			childPtr := storeINodeAsPointer(childNode)
+			getErr := mariInst.getManyRecursive(childPtr, keys[start:end], indexes[start:end], level + 1, transform, results)
+			if getErr != nil { return getErr }
 		}
+
+		start = end
 	}
 
-	return mariInst.compareAndSwap(node, currNode, nodeCopy), nil
+	return nil
+}
+
+// hasRecursive
+//	Attempts to recursively determine whether a key exists within the ordered array mapped trie.
+//	The traversal is the same as getIterative, but child nodes are read with only the key of their leaf so value bytes are never touched.
+//	If the bit for the key is not set at a level, the key does not exist.
+func (mariInst *Mari) hasRecursive(node *unsafe.Pointer, key []byte, level int) (bool, error) {
+	currNode := loadINodeFromPointer(node)
+
+	if hasKey(currNode.leaf) && bytes.Equal(key, currNode.leaf.key) { return true, nil }
+	if len(key) == level { return false, nil }
+
+	index := getIndexForLevel(key, level)
+	if ! isBitSet(currNode.bitmap, index) { return false, nil }
+
+	pos := getPosition(currNode.bitmap, index, level)
+	childNode, getChildErr := mariInst.viewChildNodeKey(currNode.children[pos], currNode.version)
+	if getChildErr != nil { return false, getChildErr }
+
+	childPtr := storeINodeAsPointer(childNode)
+	return mariInst.hasRecursive(childPtr, key, level + 1)
 }
 
-// getRecursive
-//	Attempts to recursively retrieve a value for a given key within the ordered array mapped trie.
-//	For each node traversed to at each level the operation travels to, the sparse index is calculated for the hashed key.
-//	If the bit is not set in the bitmap, return nil since the key has not been inserted yet into the trie.
-//	Otherwise, determine the position in the child node array for the sparse index.
-//	If the child node is a leaf node and the key to be searched for is the same as the key of the child node, the value has been found.
-//	Since the trie utilizes path copying, any threads modifying the trie are modifying copies so it the get operation returns the value at the point in time of the get operation.
-//	If the node is node a leaf node, but instead an internal node, recurse down the path to the next level to the child node in the position of the child node array and repeat the above.
-func (mariInst *Mari) getRecursive(node *unsafe.Pointer, key []byte, level int, transform MariOpTransform) (*KeyValuePair, error) {
+// hasManyRecursive
+//	Determine whether each of a sorted set of keys exists in a single traversal, where indexes maps each key back to its position in the results.
+//	The traversal is the same as getManyRecursive, but child nodes are read with only the key of their leaf so value bytes are never touched.
+func (mariInst *Mari) hasManyRecursive(node *unsafe.Pointer, keys [][]byte, indexes []int, level int, results []bool) error {
 	currNode := loadINodeFromPointer(node)
-	
-	getKeyVal := func() *KeyValuePair {
-		return &KeyValuePair{
-			Version: currNode.leaf.version,
-			Key: currNode.leaf.key,
-			Value: currNode.leaf.value,
+
+	for start := 0; start < len(keys); {
+		key := keys[start]
+
+		if hasKey(currNode.leaf) && bytes.Equal(key, currNode.leaf.key) {
+			results[indexes[start]] = true
+			start++
+			continue
+		}
+
+		if len(key) == level {
+			start++
+			continue
 		}
-	}
 
-	if len(key) == level {
-		if bytes.Equal(key, currNode.leaf.key) { return transform(getKeyVal()), nil }
-		return nil, nil
-	} else {
-		if bytes.Equal(key, currNode.leaf.key) { return transform(getKeyVal()), nil }
-		
 		index := getIndexForLevel(key, level)
-		
-		switch {
-			case ! isBitSet(currNode.bitmap, index):
-				return nil, nil
-			default:
-				pos := getPosition(currNode.bitmap, index, level)
-				childOffset := currNode.children[pos]
 
-				childNode, getChildErr := mariInst.getChildNode(childOffset, currNode.version)
-				if getChildErr != nil { return nil, getChildErr }
+		end := start + 1
+		for end < len(keys) && len(keys[end]) > level && getIndexForLevel(keys[end], level) == index && ! bytes.Equal(keys[end], currNode.leaf.key) { end++ }
 
-				childPtr := storeINodeAsPointer(childNode)
-				return mariInst.getRecursive(childPtr, key, level + 1, transform)
+		if isBitSet(currNode.bitmap, index) {
+			pos := getPosition(currNode.bitmap, index, level)
+			childNode, getChildErr := mariInst.viewChildNodeKey(currNode.children[pos], currNode.version)
+			if getChildErr != nil { return getChildErr }
+
+			childPtr := storeINodeAsPointer(childNode)
+			hasErr := mariInst.hasManyRecursive(childPtr, keys[start:end], indexes[start:end], level + 1, results)
+			if hasErr != nil { return hasErr }
 		}
+
+		start = end
 	}
+
+	return nil
 }
 
 // deleteRecursive
@@ -190,20 +333,26 @@ func (mariInst *Mari) getRecursive(node *unsafe.Pointer, key []byte, level int,
 //	If the child node is a leaf node and the key of the child node is equal to the key of the key to delete, the copy is modified to update the bitmap and shrink the table and remove the given node.
 //	A compare and swap operation is performed, and if successful traverse back up the trie and complete, otherwise the operation is returned to the root to retry.
 //	If the child node is an internal node, the operation recurses down the trie to the next level.
-//	On return, if the internal node is empty, the copy modified so the bitmap is updated and table is shrunk.
+//	On return, if the internal node is empty, the copy modified so the bitmap is updated and table is shrunk, and the size it replaced is carried up to the copy since it is never serialized.
+//	The subtree count of each node on the path is adjusted by the change in the count of the child that was recursed into.
 //	A compare and swap operation is performed on the current node with the new copy.
 func (mariInst *Mari) deleteRecursive(node *unsafe.Pointer, key []byte, level int) (bool, error) {
 	currNode := loadINodeFromPointer(node)
+	currCount, countErr := mariInst.resolveCount(currNode)
+	if countErr != nil { return false, countErr }
+
 	nodeCopy := mariInst.copyINode(currNode)
 
 	deleteKeyVal := func() bool {
 		nodeCopy.leaf = mariInst.newLeafNode(nil, nil, nodeCopy.version)
+		nodeCopy.count = currCount - 1
+		
 		return mariInst.compareAndSwap(node, currNode, nodeCopy)
 	}
 
 	if len(key) == level {
 		switch {
-			case bytes.Equal(nodeCopy.leaf.key, key):
+			case hasKey(nodeCopy.leaf) && bytes.Equal(nodeCopy.leaf.key, key):
 				return deleteKeyVal(), nil
 			default:
 				return true, nil
@@ -212,7 +361,7 @@ func (mariInst *Mari) deleteRecursive(node *unsafe.Pointer, key []byte, level in
 		index := getIndexForLevel(key, level)
 
 		switch {
-			case bytes.Equal(nodeCopy.leaf.key, key):
+			case hasKey(nodeCopy.leaf) && bytes.Equal(nodeCopy.leaf.key, key):
 				return deleteKeyVal(), nil
 			case ! isBitSet(nodeCopy.bitmap, index):
 				return true, nil
@@ -223,6 +372,9 @@ func (mariInst *Mari) deleteRecursive(node *unsafe.Pointer, key []byte, level in
 				childNode, getChildErr := mariInst.getChildNode(childOffset, nodeCopy.version)
 				if getChildErr != nil { return false, getChildErr }
 		
+				childCount, countErr := mariInst.resolveCount(childNode)
+				if countErr != nil { return false, countErr }
+		
 				childNode.version = nodeCopy.version
 				childPtr := storeINodeAsPointer(childNode)
 
@@ -231,11 +383,13 @@ func (mariInst *Mari) deleteRecursive(node *unsafe.Pointer, key []byte, level in
 
 				updatedChildNode := loadINodeFromPointer(childPtr)
 				nodeCopy.children[pos] = updatedChildNode
+				nodeCopy.count = currCount - childCount + updatedChildNode.count
 
-				if updatedChildNode.leaf.version == nodeCopy.version {
+				if updatedChildNode.leaf.version == nodeCopy.version && ! hasKey(updatedChildNode.leaf) {
 					childNodePopCount := populationCount(updatedChildNode.bitmap)
 					
 					if childNodePopCount == 0 {
+						nodeCopy.replacedSize += updatedChildNode.replacedSize
 						nodeCopy.bitmap = setBit(nodeCopy.bitmap, index)
 						nodeCopy.children = shrinkTable(nodeCopy.children, nodeCopy.bitmap, pos)
 					}
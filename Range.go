@@ -8,105 +8,237 @@ import "unsafe"
 
 
 // rangeRecursive
-//	Limit the indexes to check in the range at level 0, and then recursively traverse the paths between the start and end index.
+//	Limit the indexes to check at each level to those between the start and end key, and then recursively traverse the paths between the start and end index.
 //	On the start key path, continue to use the start index to check the level to see which index forward should be recursively checked.
-//	The opposite is done for the end key path.
+//	The opposite is done for the end key path. Leaves holding keys longer than the level of their node are carried down as pending leaves, so results are passed to visit in exact order.
+//	If visit returns false, the traversal stops and false is propagated back up to the root.
 func (mariInst *Mari) rangeRecursive(
 	node *unsafe.Pointer, minVersion uint64, 
 	startKey, endKey []byte, level int, 
-	transform MariOpTransform,
-) ([]*KeyValuePair, error) {
-	genKeyValPair := func(node *MariINode) *KeyValuePair {
-		kvPair := &KeyValuePair {
-			Version: node.leaf.version,
-			Key: node.leaf.key,
-			Value: node.leaf.value,
+	bounds MariRangeBounds, pending []*MariLNode, 
+	visit func(leaf *MariLNode) bool,
+) (bool, error) {
+	checkErr := bounds.scanCtx.check()
+	if checkErr != nil { return false, checkErr }
+
+	currNode := loadINodeFromPointer(node)
+
+	emitLeaves := func(leaves []*MariLNode) bool {
+		sortLeavesAscending(leaves)
+
+		for idx := range leaves {
+			leaf := leaves[idx]
+			if bounds.reverse { leaf = leaves[len(leaves) - 1 - idx] }
+			if leaf.version < minVersion || ! bounds.contains(leaf.key, startKey, endKey) { continue }
+			if ! visit(leaf) { return false }
 		}
 
-		return kvPair
+		return true
 	}
 
-	currNode := loadINodeFromPointer(node)
+	leaves := append([]*MariLNode{}, pending...)
+	if hasKey(currNode.leaf) { leaves = append(leaves, currNode.leaf) }
 
-	var sortedKvPairs []*KeyValuePair
-	var startKeyPos, endKeyPos int
+	if len(currNode.children) == 0 { return emitLeaves(leaves), nil }
 
-	if level > 0 {
-		switch {
-			case startKey != nil && len(startKey) > level:
-				if currNode.leaf.version >= minVersion && bytes.Compare(currNode.leaf.key, startKey) == 1 {
-					sortedKvPairs = append(sortedKvPairs, transform(genKeyValPair(currNode)))
-				} else { return sortedKvPairs, nil }
-
-				startKeyIndex := getIndexForLevel(startKey, level)
-				startKeyPos = getPosition(currNode.bitmap, startKeyIndex, level)
-				endKeyPos = len(currNode.children)
-			case endKey != nil && len(endKey) > level:
-				if currNode.leaf.version >= minVersion && bytes.Compare(currNode.leaf.key, endKey) == -1 {
-					sortedKvPairs = append(sortedKvPairs, transform(genKeyValPair(currNode)))
-				} else { return sortedKvPairs, nil }
-
-				startKeyPos = 0
-				endKeyIndex := getIndexForLevel(endKey, level)
-				endKeyPos = getPosition(currNode.bitmap, endKeyIndex, level)
-			default:
-				if currNode.leaf.version >= minVersion && len(currNode.leaf.key) > 0 { 
-					sortedKvPairs = append(sortedKvPairs, transform(genKeyValPair(currNode))) 
-				}
+	var prefixLeaves []*MariLNode
+	carried := make(map[byte][]*MariLNode)
 
-				startKeyPos = 0
-				endKeyPos = len(currNode.children)
-		}
-	} else {
+	for _, leaf := range leaves {
+		if len(leaf.key) == level {
+			prefixLeaves = append(prefixLeaves, leaf)
+		} else { carried[leaf.key[level]] = append(carried[leaf.key[level]], leaf) }
+	}
+
+	startKeyIdx := 0
+	if startKey != nil && len(startKey) > level { startKeyIdx = int(getIndexForLevel(startKey, level)) }
+
+	endKeyIdx := MaxIndexForLevel
+	if endKey != nil {
 		switch {
-			case startKey == nil && endKey == nil:
-				startKeyPos = 0
-				endKeyPos = len(currNode.children)
+			case len(endKey) > level:
+				endKeyIdx = int(getIndexForLevel(endKey, level))
 			default:
-				startKeyIndex := getIndexForLevel(startKey, 0)
-				startKeyPos = getPosition(currNode.bitmap, startKeyIndex, 0)
+				endKeyIdx = -1
+		}
+	}
+
+	if ! bounds.reverse && ! emitLeaves(prefixLeaves) { return false, nil }
+
+	for currIdx := 0; currIdx <= endKeyIdx - startKeyIdx; currIdx++ {
+		idx := startKeyIdx + currIdx
+		if bounds.reverse { idx = endKeyIdx - currIdx }
 
-				endKeyIndex := getIndexForLevel(endKey, 0)
-				endKeyPos = getPosition(currNode.bitmap, endKeyIndex, 0)
+		index := byte(idx)
+
+		if ! isBitSet(currNode.bitmap, index) {
+			if carried[index] != nil && ! emitLeaves(carried[index]) { return false, nil }
+			continue
 		}
+
+		getChild := mariInst.viewChildNode
+		if bounds.keysOnly { getChild = mariInst.viewChildNodeKey }
+
+		childNode, getChildErr := getChild(currNode.children[getPosition(currNode.bitmap, index, level)], currNode.version)
+		if getChildErr != nil { return false, getChildErr }
+		childPtr := storeINodeAsPointer(childNode)
+
+		var childStartKey, childEndKey []byte
+		if startKey != nil && len(startKey) > level && idx == startKeyIdx { childStartKey = startKey }
+		if endKey != nil && idx == endKeyIdx { childEndKey = endKey }
+
+		cont, rangeErr := mariInst.rangeRecursive(childPtr, minVersion, childStartKey, childEndKey, level + 1, bounds, carried[index], visit)
+		if rangeErr != nil { return false, rangeErr }
+		if ! cont { return false, nil }
 	}
 
-	if len(currNode.children) > 0 {
-		var kvPairs []*KeyValuePair
-		var rangeErr error
+	if bounds.reverse { return emitLeaves(prefixLeaves), nil }
 
-		switch {
-			case startKeyPos == endKeyPos:
-				childNode, getChildErr := mariInst.getChildNode(currNode.children[startKeyPos], currNode.version)
-				if getChildErr != nil { return nil, getChildErr}
-				childPtr := storeINodeAsPointer(childNode)
+	return true, nil
+}
 
-				kvPairs, rangeErr = mariInst.rangeRecursive(childPtr, minVersion, startKey, endKey, level + 1, transform)
-				if rangeErr != nil { return nil, rangeErr }
+// prefetchRange
+//	Advise the operating system with MADV_WILLNEED that the byte range spanning the children of the root between the start and end key will be needed, so a cold scan reads it in ahead of the traversal.
+//	Nodes are appended after the nodes they are copied from, so the span begins at the lowest offset among those children and ends at the end of the serialized data.
+//	If the next child past the end key was written after every child in the range, the span ends where it begins instead. After compaction, nodes are laid out depth first, so the span is exactly the subtrees in the range.
+//	Descendants that were not copied since the oldest child in the range was written may lie before the span, so prefetching is only a hint and the traversal is unaffected either way.
+func (mariInst *Mari) prefetchRange(node *unsafe.Pointer, startKey, endKey []byte) {
+	currNode := loadINodeFromPointer(node)
+	if len(currNode.children) == 0 { return }
 
-				if len(kvPairs) > 0 { sortedKvPairs = append(sortedKvPairs, kvPairs...) }
-			default:
-				for idx, childOffset := range currNode.children[startKeyPos:endKeyPos] {		
-					childNode, getChildErr := mariInst.getChildNode(childOffset, currNode.version)
-					if getChildErr != nil { return nil, getChildErr}
-					childPtr := storeINodeAsPointer(childNode)
-		
-					switch {
-						case idx == 0 && startKey != nil:
-							kvPairs, rangeErr = mariInst.rangeRecursive(childPtr, minVersion, startKey, nil, level + 1, transform)
-							if rangeErr != nil { return nil, rangeErr }
-						case idx == endKeyPos && endKey != nil:
-							kvPairs, rangeErr = mariInst.rangeRecursive(childPtr, minVersion, nil, endKey, level + 1, transform)
-							if rangeErr != nil { return nil, rangeErr }
-						default:
-							kvPairs, rangeErr = mariInst.rangeRecursive(childPtr, minVersion, nil, nil, level + 1, transform)
-							if rangeErr != nil { return nil, rangeErr }
-					}
-		
-					if len(kvPairs) > 0 { sortedKvPairs = append(sortedKvPairs, kvPairs...) }
-				}
+	startKeyIdx := 0
+	if len(startKey) > 0 { startKeyIdx = int(getIndexForLevel(startKey, 0)) }
+
+	endKeyIdx := MaxIndexForLevel
+	if endKey != nil {
+		if len(endKey) == 0 { return }
+		endKeyIdx = int(getIndexForLevel(endKey, 0))
+	}
+
+	if endKeyIdx < startKeyIdx { return }
+
+	startPos := getPosition(currNode.bitmap, byte(startKeyIdx), 0)
+	endPos := getPosition(currNode.bitmap, byte(endKeyIdx), 0)
+	if isBitSet(currNode.bitmap, byte(endKeyIdx)) { endPos++ }
+	if startPos >= endPos { return }
+
+	lowOffset, highOffset := currNode.children[startPos].startOffset, currNode.children[startPos].startOffset
+	for _, child := range currNode.children[startPos:endPos] {
+		if child.startOffset < lowOffset { lowOffset = child.startOffset }
+		if child.startOffset > highOffset { highOffset = child.startOffset }
+	}
+
+	_, endOffset, loadErr := mariInst.loadMetaEndSerialized()
+	if loadErr != nil { return }
+
+	if endPos < len(currNode.children) {
+		nextOffset := currNode.children[endPos].startOffset
+		if nextOffset > highOffset && nextOffset < endOffset { endOffset = nextOffset }
+	}
+
+	mMap := mariInst.data.Load().(MMap)
+	if endOffset > uint64(len(mMap)) { endOffset = uint64(len(mMap)) }
+
+	lowOffset -= lowOffset % uint64(DefaultPageSize)
+	if lowOffset >= endOffset { return }
+
+	mMap[lowOffset:endOffset].Advise(AdviseWillNeed)
+}
+
+// partitionRange
+//	Split the indexes of the root between the start and end key into at most the given number of contiguous sub ranges for a parallel range scan.
+//	The populated indexes are grouped using the stored count of each child, so each sub range holds roughly the same number of keys.
+//	The first sub range keeps the start key and the last keeps the end key, along with their inclusivity.
+//	Every other boundary is the single byte key of the first index of the next sub range, which is inclusive as a start key and exclusive as an end key, so no key is scanned twice.
+func (mariInst *Mari) partitionRange(root *MariINode, startKey, endKey []byte, bounds MariRangeBounds, workers int) ([]MariRangePartition, error) {
+	startKeyIdx := 0
+	if len(startKey) > 0 { startKeyIdx = int(startKey[0]) }
+
+	endKeyIdx := MaxIndexForLevel
+	if endKey != nil { endKeyIdx = int(endKey[0]) }
+
+	var indexes []int
+	var counts []uint64
+	var total uint64
+
+	for idx := startKeyIdx; idx <= endKeyIdx; idx++ {
+		index := byte(idx)
+		if ! isBitSet(root.bitmap, index) { continue }
+
+		childCount, getCountErr := mariInst.getChildCount(root.children[getPosition(root.bitmap, index, 0)], root.version)
+		if getCountErr != nil { return nil, getCountErr }
+
+		indexes = append(indexes, idx)
+		counts = append(counts, childCount)
+		total += childCount
+	}
+
+	boundaries := []int{ startKeyIdx }
+	var accumulated uint64
+
+	for pos, idx := range indexes {
+		if len(boundaries) == workers { break }
+		if pos > 0 && accumulated >= total * uint64(len(boundaries)) / uint64(workers) { boundaries = append(boundaries, idx) }
+
+		accumulated += counts[pos]
+	}
+
+	partitions := make([]MariRangePartition, len(boundaries))
+	for pos, idx := range boundaries {
+		partition := MariRangePartition{ startKey: startKey, endKey: endKey, bounds: bounds }
+
+		if pos > 0 {
+			partition.startKey = []byte{ byte(idx) }
+			partition.bounds.startInclusive = true
+		}
+
+		if pos < len(boundaries) - 1 {
+			partition.endKey = []byte{ byte(boundaries[pos + 1]) }
+			partition.bounds.endInclusive = false
+		}
+
+		partitions[pos] = partition
+	}
+
+	return partitions, nil
+}
+
+// emitTransformed
+//	Build a leaf visitor for rangeRecursive that transforms each leaf into a key-value pair before passing it to emit.
+//	If the transform returns nil for a key value pair, it is skipped.
+//	If keys only is set, the pair is built without the value, so the value is never decoded.
+//	If the value of a leaf fails to decode, the error is stored in decodeErr and the traversal is stopped.
+func (mariInst *Mari) emitTransformed(transform MariOpTransform, keysOnly bool, emit func(kvPair *KeyValuePair) bool, decodeErr *error) func(leaf *MariLNode) bool {
+	newPair := mariInst.newKeyValuePair
+	if keysOnly { newPair = mariInst.newKeyPair }
+
+	return func(leaf *MariLNode) bool {
+		decoded, newErr := newPair(leaf)
+		if newErr != nil {
+			*decodeErr = newErr
+			return false
 		}
+
+		kvPair := transform(decoded)
+		if kvPair == nil { return true }
+
+		return emit(kvPair)
+	}
+}
+
+// contains
+//	Determine whether a key falls within the start and end key, honoring whether each bound is inclusive or exclusive.
+//	A nil start or end key is treated as unbounded.
+func (bounds MariRangeBounds) contains(key, startKey, endKey []byte) bool {
+	if startKey != nil {
+		startCmp := bytes.Compare(key, startKey)
+		if startCmp == -1 || (startCmp == 0 && ! bounds.startInclusive) { return false }
+	}
+
+	if endKey != nil {
+		endCmp := bytes.Compare(key, endKey)
+		if endCmp == 1 || (endCmp == 0 && ! bounds.endInclusive) { return false }
 	}
 
-	return sortedKvPairs, nil
-}
\ No newline at end of file
+	return true
+}
@@ -0,0 +1,96 @@
+package mari
+
+import "bytes"
+import "unsafe"
+
+
+//============================================= Mari Rank
+
+
+// rankRecursive
+//	Determine the total number of keys less than the given key by descending only the path of the key.
+//	At each level, every child at an index before the next byte of the key only contains smaller keys, so the subtree counts of those children are summed without visiting them.
+//	The leaf of each node on the path is compared directly, since it may hold a key longer than the level of the node.
+func (mariInst *Mari) rankRecursive(node *unsafe.Pointer, key []byte, level int) (uint64, error) {
+	currNode := loadINodeFromPointer(node)
+
+	var rank uint64
+	if hasKey(currNode.leaf) && bytes.Compare(currNode.leaf.key, key) == -1 { rank++ }
+	if len(key) == level { return rank, nil }
+
+	index := getIndexForLevel(key, level)
+	pos := getPosition(currNode.bitmap, index, level)
+
+	for _, childOffset := range currNode.children[:pos] {
+		childCount, getCountErr := mariInst.getChildCount(childOffset, currNode.version)
+		if getCountErr != nil { return 0, getCountErr }
+
+		rank += childCount
+	}
+
+	if ! isBitSet(currNode.bitmap, index) { return rank, nil }
+
+	childNode, getChildErr := mariInst.viewChildNodeKey(currNode.children[pos], currNode.version)
+	if getChildErr != nil { return 0, getChildErr }
+
+	childPtr := storeINodeAsPointer(childNode)
+	childRank, rankErr := mariInst.rankRecursive(childPtr, key, level + 1)
+	if rankErr != nil { return 0, rankErr }
+
+	return rank + childRank, nil
+}
+
+// selectRecursive
+//	Find the leaf holding the nth smallest key, where n is zero indexed, by skipping over whole subtrees using their counts.
+//	Like rangeRecursive, leaves with keys longer than the level of their node are carried down to the index of their next byte as pending leaves, so they are ordered with the keys of that child.
+//	Only the subtree containing the nth key is descended, so the operation is proportional to the depth of the trie.
+func (mariInst *Mari) selectRecursive(node *unsafe.Pointer, n uint64, level int, pending []*MariLNode) (*MariLNode, error) {
+	currNode := loadINodeFromPointer(node)
+
+	leaves := append([]*MariLNode{}, pending...)
+	if hasKey(currNode.leaf) { leaves = append(leaves, currNode.leaf) }
+
+	var prefixLeaves []*MariLNode
+	carried := make(map[byte][]*MariLNode)
+
+	for _, leaf := range leaves {
+		if len(leaf.key) == level || len(currNode.children) == 0 {
+			prefixLeaves = append(prefixLeaves, leaf)
+		} else { carried[leaf.key[level]] = append(carried[leaf.key[level]], leaf) }
+	}
+
+	sortLeavesAscending(prefixLeaves)
+	if n < uint64(len(prefixLeaves)) { return prefixLeaves[n], nil }
+	n -= uint64(len(prefixLeaves))
+
+	for idx := 0; idx <= MaxIndexForLevel && len(currNode.children) > 0; idx++ {
+		index := byte(idx)
+		carriedCount := uint64(len(carried[index]))
+
+		if ! isBitSet(currNode.bitmap, index) {
+			if n < carriedCount {
+				sortLeavesAscending(carried[index])
+				return carried[index][n], nil
+			}
+
+			n -= carriedCount
+			continue
+		}
+
+		childOffset := currNode.children[getPosition(currNode.bitmap, index, level)]
+		childCount, getCountErr := mariInst.getChildCount(childOffset, currNode.version)
+		if getCountErr != nil { return nil, getCountErr }
+
+		if n < carriedCount + childCount {
+			childNode, getChildErr := mariInst.viewChildNode(childOffset, currNode.version)
+			if getChildErr != nil { return nil, getChildErr }
+
+			childPtr := storeINodeAsPointer(childNode)
+			return mariInst.selectRecursive(childPtr, n, level + 1, carried[index])
+		}
+
+		n -= carriedCount + childCount
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,292 @@
+package mari
+
+import "bufio"
+import "bytes"
+import "compress/flate"
+import "errors"
+import "io"
+import "os"
+import "sort"
+
+
+//============================================= Mari Segment
+
+
+// WriteSegment
+//	Writes the current version of Mari to an immutable segment file of independently compressed blocks of roughly blockSize bytes.
+//	A sparse index of the first key of each block is appended after the blocks, followed by a footer pointing to the index.
+//	Values are decoded with the value codec, if one is set, since a segment is opened without one.
+func (mariInst *Mari) WriteSegment(path string, blockSize int) error {
+	if blockSize <= 0 { return errors.New("block size must be greater than 0") }
+
+	segmentFile, createErr := os.OpenFile(path, os.O_RDWR | os.O_CREATE | os.O_TRUNC, mariInst.fileMode)
+	if createErr != nil { return createErr }
+	defer segmentFile.Close()
+
+	writer := bufio.NewWriter(segmentFile)
+
+	var index []*MariSegmentBlock
+	var block []byte
+	var firstKey []byte
+	var offset uint64
+
+	flushBlock := func() error {
+		if len(block) == 0 { return nil }
+
+		compressed, compressErr := compressSegmentBlock(block)
+		if compressErr != nil { return compressErr }
+
+		_, writeErr := writer.Write(compressed)
+		if writeErr != nil { return writeErr }
+
+		index = append(index, &MariSegmentBlock{ firstKey: firstKey, offset: offset, length: uint64(len(compressed)) })
+		offset += uint64(len(compressed))
+
+		block = nil
+		firstKey = nil
+
+		return nil
+	}
+
+	readErr := mariInst.ReadTx(func(tx *MariTx) error {
+		return tx.ForEach(nil, func(kvPair *KeyValuePair) (bool, error) {
+			if firstKey == nil { firstKey = append([]byte{}, kvPair.Key...) }
+			block = append(block, serializeSegmentEntry(&MariLNode{ version: kvPair.Version, key: kvPair.Key, value: kvPair.Value })...)
+
+			if len(block) >= blockSize {
+				flushErr := flushBlock()
+				if flushErr != nil { return false, flushErr }
+			}
+
+			return true, nil
+		})
+	})
+
+	if readErr != nil { return readErr }
+
+	flushErr := flushBlock()
+	if flushErr != nil { return flushErr }
+
+	indexOffset := offset
+	for _, entry := range index {
+		var sEntry []byte
+		sEntry = append(sEntry, serializeUint64(entry.offset)...)
+		sEntry = append(sEntry, serializeUint64(entry.length)...)
+		sEntry = append(sEntry, serializeUint16(uint16(len(entry.firstKey)))...)
+		sEntry = append(sEntry, entry.firstKey...)
+
+		_, writeErr := writer.Write(sEntry)
+		if writeErr != nil { return writeErr }
+	}
+
+	footer := append(serializeUint64(indexOffset), serializeUint64(uint64(len(index)))...)
+	_, writeFooterErr := writer.Write(footer)
+	if writeFooterErr != nil { return writeFooterErr }
+
+	bufferFlushErr := writer.Flush()
+	if bufferFlushErr != nil { return bufferFlushErr }
+
+	return segmentFile.Sync()
+}
+
+// OpenSegment
+//	Opens a segment file written by WriteSegment as a read only handle.
+//	Only the sparse index is loaded into memory, blocks are read and decompressed on demand.
+func OpenSegment(path string) (*MariSegment, error) {
+	segmentFile, openErr := os.Open(path)
+	if openErr != nil { return nil, openErr }
+
+	stat, statErr := segmentFile.Stat()
+	if statErr != nil {
+		segmentFile.Close()
+		return nil, statErr
+	}
+
+	index, readIndexErr := readSegmentIndex(segmentFile, stat.Size())
+	if readIndexErr != nil {
+		segmentFile.Close()
+		return nil, readIndexErr
+	}
+
+	return &MariSegment{ file: segmentFile, index: index, size: uint64(stat.Size()) }, nil
+}
+
+// Close
+//	Close the underlying segment file.
+func (segment *MariSegment) Close() error {
+	return segment.file.Close()
+}
+
+// Get
+//	Attempts to retrieve the value for a key within the segment.
+//	The sparse index is binary searched for the only block that can contain the key, which is then decompressed and scanned.
+//	Nil is returned if the key does not exist.
+func (segment *MariSegment) Get(key []byte) (*KeyValuePair, error) {
+	blockIdx := segment.findBlock(key)
+	if blockIdx < 0 { return nil, nil }
+
+	kvPairs, readBlockErr := segment.readBlock(blockIdx)
+	if readBlockErr != nil { return nil, readBlockErr }
+
+	pos := sort.Search(len(kvPairs), func(i int) bool { return bytes.Compare(kvPairs[i].Key, key) >= 0 })
+	if pos < len(kvPairs) && bytes.Equal(kvPairs[pos].Key, key) { return kvPairs[pos], nil }
+
+	return nil, nil
+}
+
+// Range
+//	Returns all key-value pairs in the segment between the start key and end key, inclusive, in ascending order.
+//	The scan begins at the block that can contain the start key and continues until a key larger than the end key is found.
+func (segment *MariSegment) Range(startKey, endKey []byte) ([]*KeyValuePair, error) {
+	if bytes.Compare(startKey, endKey) == 1 { return nil, errors.New("start key is larger than end key") }
+
+	blockIdx := segment.findBlock(startKey)
+	if blockIdx < 0 { blockIdx = 0 }
+
+	var sortedKvPairs []*KeyValuePair
+
+	for ; blockIdx < len(segment.index); blockIdx++ {
+		kvPairs, readBlockErr := segment.readBlock(blockIdx)
+		if readBlockErr != nil { return nil, readBlockErr }
+
+		for _, kvPair := range kvPairs {
+			if bytes.Compare(kvPair.Key, endKey) == 1 { return sortedKvPairs, nil }
+			if bytes.Compare(kvPair.Key, startKey) >= 0 { sortedKvPairs = append(sortedKvPairs, kvPair) }
+		}
+	}
+
+	return sortedKvPairs, nil
+}
+
+// findBlock
+//	Determine the index of the last block whose first key is less than or equal to the key.
+//	Returns -1 if the key is smaller than every key in the segment.
+func (segment *MariSegment) findBlock(key []byte) int {
+	pos := sort.Search(len(segment.index), func(i int) bool { return bytes.Compare(segment.index[i].firstKey, key) == 1 })
+	return pos - 1
+}
+
+// readBlock
+//	Read and decompress a single block from the segment file, deserializing its entries.
+func (segment *MariSegment) readBlock(blockIdx int) ([]*KeyValuePair, error) {
+	entry := segment.index[blockIdx]
+	if entry.length > segment.size || entry.offset > segment.size - entry.length { return nil, errors.New("segment block is out of bounds") }
+
+	compressed := make([]byte, entry.length)
+	_, readErr := segment.file.ReadAt(compressed, int64(entry.offset))
+	if readErr != nil { return nil, readErr }
+
+	block, decompressErr := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if decompressErr != nil { return nil, decompressErr }
+
+	var kvPairs []*KeyValuePair
+
+	currOffset := 0
+	for currOffset < len(block) {
+		if currOffset + SegmentEntryHeaderSize > len(block) { return nil, errors.New("segment block is corrupt") }
+
+		version, decVersionErr := deserializeUint64(block[currOffset:currOffset + 8])
+		if decVersionErr != nil { return nil, decVersionErr }
+
+		keyLength, decKeyLenErr := deserializeUint16(block[currOffset + 8:currOffset + 10])
+		if decKeyLenErr != nil { return nil, decKeyLenErr }
+
+		valueLength, decValLenErr := deserializeUint32(block[currOffset + 10:currOffset + SegmentEntryHeaderSize])
+		if decValLenErr != nil { return nil, decValLenErr }
+
+		keyStart := currOffset + SegmentEntryHeaderSize
+		valueStart := keyStart + int(keyLength)
+		valueEnd := valueStart + int(valueLength)
+		if valueEnd > len(block) { return nil, errors.New("segment block is corrupt") }
+
+		kvPairs = append(kvPairs, &KeyValuePair{
+			Version: version,
+			Key: block[keyStart:valueStart],
+			Value: block[valueStart:valueEnd],
+		})
+
+		currOffset = valueEnd
+	}
+
+	return kvPairs, nil
+}
+
+// readSegmentIndex
+//	Read the footer of the segment to locate the sparse index, then deserialize each index entry.
+func readSegmentIndex(segmentFile *os.File, size int64) ([]*MariSegmentBlock, error) {
+	if size < SegmentFooterSize { return nil, errors.New("segment file is too small to contain a footer") }
+
+	footer := make([]byte, SegmentFooterSize)
+	_, readFooterErr := segmentFile.ReadAt(footer, size - SegmentFooterSize)
+	if readFooterErr != nil { return nil, readFooterErr }
+
+	indexOffset, decIndexOffErr := deserializeUint64(footer[:OffsetSize])
+	if decIndexOffErr != nil { return nil, decIndexOffErr }
+
+	totalBlocks, decTotalErr := deserializeUint64(footer[OffsetSize:])
+	if decTotalErr != nil { return nil, decTotalErr }
+
+	indexEnd := uint64(size - SegmentFooterSize)
+	if indexOffset > indexEnd { return nil, errors.New("segment index offset is out of bounds") }
+
+	sIndex := make([]byte, indexEnd - indexOffset)
+	_, readIndexErr := segmentFile.ReadAt(sIndex, int64(indexOffset))
+	if readIndexErr != nil { return nil, readIndexErr }
+	if totalBlocks > uint64(len(sIndex) / ((2 * OffsetSize) + 2)) { return nil, errors.New("segment block count is larger than the index") }
+
+	index := make([]*MariSegmentBlock, 0, totalBlocks)
+
+	currOffset := 0
+	for range make([]int, totalBlocks) {
+		if currOffset + (2 * OffsetSize) + 2 > len(sIndex) { return nil, errors.New("segment index is corrupt") }
+
+		offset, decOffErr := deserializeUint64(sIndex[currOffset:currOffset + OffsetSize])
+		if decOffErr != nil { return nil, decOffErr }
+
+		length, decLenErr := deserializeUint64(sIndex[currOffset + OffsetSize:currOffset + (2 * OffsetSize)])
+		if decLenErr != nil { return nil, decLenErr }
+
+		keyLength, decKeyLenErr := deserializeUint16(sIndex[currOffset + (2 * OffsetSize):currOffset + (2 * OffsetSize) + 2])
+		if decKeyLenErr != nil { return nil, decKeyLenErr }
+
+		keyStart := currOffset + (2 * OffsetSize) + 2
+		keyEnd := keyStart + int(keyLength)
+		if keyEnd > len(sIndex) { return nil, errors.New("segment index is corrupt") }
+
+		index = append(index, &MariSegmentBlock{ firstKey: sIndex[keyStart:keyEnd], offset: offset, length: length })
+		currOffset = keyEnd
+	}
+
+	return index, nil
+}
+
+// compressSegmentBlock
+//	Compress a serialized block using flate.
+func compressSegmentBlock(block []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	compressor, newWriterErr := flate.NewWriter(&buf, flate.DefaultCompression)
+	if newWriterErr != nil { return nil, newWriterErr }
+
+	_, writeErr := compressor.Write(block)
+	if writeErr != nil { return nil, writeErr }
+
+	closeErr := compressor.Close()
+	if closeErr != nil { return nil, closeErr }
+
+	return buf.Bytes(), nil
+}
+
+// serializeSegmentEntry
+//	Serialize a leaf as a segment entry. The version, key length, and value length are followed by the key and value.
+func serializeSegmentEntry(leaf *MariLNode) []byte {
+	var sEntry []byte
+
+	sEntry = append(sEntry, serializeUint64(leaf.version)...)
+	sEntry = append(sEntry, serializeUint16(uint16(len(leaf.key)))...)
+	sEntry = append(sEntry, serializeUint32(uint32(len(leaf.value)))...)
+	sEntry = append(sEntry, leaf.key...)
+	sEntry = append(sEntry, leaf.value...)
+
+	return sEntry
+}
@@ -1,7 +1,7 @@
 package mari
 
 import "encoding/binary"
-import "errors"
+import "fmt"
 
 
 //============================================= Mari Serialization
@@ -9,6 +9,7 @@ import "errors"
 
 // serializeMetaData
 //	Serialize the metadata at the first 0-23 bytes of the memory map. version is 8 bytes and Root Offset is 8 bytes.
+//	The identity and epoch that follow are written separately, since they only change when the version index is reset.
 func (meta *MariMetaData) serializeMetaData() []byte {
 	versionBytes := make([]byte, OffsetSize)
 	binary.LittleEndian.PutUint64(versionBytes, meta.version)
@@ -25,6 +26,7 @@ func (meta *MariMetaData) serializeMetaData() []byte {
 
 // deserializeINode
 //	Deserialize the byte representation of an internal in the memory mapped file.
+//	Nodes serialized before subtree counts were stored end directly after the child pointers, so the count is only read if the node is long enough to contain it.
 func deserializeINode(snode []byte) (*MariINode, error) {
 	version, decVersionErr := deserializeUint64(snode[NodeVersionIdx:NodeStartOffsetIdx])
 	if decVersionErr != nil { return nil, decVersionErr }
@@ -63,6 +65,15 @@ func deserializeINode(snode []byte) (*MariINode, error) {
 		currOffset += NodeChildPtrSize
 	}
 
+	var count uint64
+	hasCount := len(snode) >= currOffset + NodeCountSize
+	
+	if hasCount {
+		var decCountErr error
+		count, decCountErr = deserializeUint64(snode[currOffset:currOffset + NodeCountSize])
+		if decCountErr != nil { return nil, decCountErr }
+	}
+
 	return &MariINode{
 		version: version,
 		startOffset: startOffset,
@@ -70,15 +81,21 @@ func deserializeINode(snode []byte) (*MariINode, error) {
 		bitmap: bitmaps,
 		leaf: &MariLNode{ startOffset: leafOffset },
 		children: children,
+		count: count,
+		hasCount: hasCount,
 	}, nil
 }
 
 // deserializeLNode
 //	Deserialize the byte representation of a leaf node in the memory mapped file.
+//	If the overflow flag is set on the version, the value bytes hold the offset and length of the value instead of the value itself, and the value is left nil until it is resolved.
 func deserializeLNode(snode []byte) (*MariLNode, error) {
 	version, decVersionErr := deserializeUint64(snode[NodeVersionIdx:NodeStartOffsetIdx])
 	if decVersionErr != nil { return nil, decVersionErr }
 
+	isOverflowValue := version & LeafOverflowFlag != 0
+	version &^= LeafOverflowFlag
+
 	startOffset, decStartOffErr := deserializeUint64(snode[NodeStartOffsetIdx:NodeEndOffsetIdx])
 	if decStartOffErr != nil { return nil, decStartOffErr	}
 
@@ -88,8 +105,31 @@ func deserializeLNode(snode []byte) (*MariLNode, error) {
 	keyLength, decKeyLenErr := deserializeUint16(snode[NodeKeyLength:NodeKeyIdx])
 	if decKeyLenErr != nil { return nil, decKeyLenErr }
 
-	key := snode[NodeKeyIdx:NodeKeyIdx + keyLength]
-	value := snode[NodeKeyIdx + keyLength:]
+	var key []byte
+	switch keyLength {
+		case 0:
+		case EmptyKeyLength:
+			keyLength = 0
+			key = []byte{}
+		default:
+			key = snode[NodeKeyIdx:NodeKeyIdx + int(keyLength)]
+	}
+
+	value := snode[NodeKeyIdx + int(keyLength):]
+
+	var overflowOffset, overflowLength uint64
+	if isOverflowValue {
+		if len(value) >= NodeOverflowRefSize {
+			var decOverflowErr error
+			overflowOffset, decOverflowErr = deserializeUint64(value[:OffsetSize])
+			if decOverflowErr != nil { return nil, decOverflowErr }
+
+			overflowLength, decOverflowErr = deserializeUint64(value[OffsetSize:NodeOverflowRefSize])
+			if decOverflowErr != nil { return nil, decOverflowErr }
+		}
+
+		value = nil
+	}
 
 	return &MariLNode{
 		version: version,
@@ -98,68 +138,86 @@ func deserializeLNode(snode []byte) (*MariLNode, error) {
 		keyLength: keyLength,
 		key: key,
 		value: value,
+		overflowOffset: overflowOffset,
+		overflowLength: overflowLength,
 	}, nil
 }
 
 // serializePathToMemMap
-//	Serializes a path copy by starting at the root, getting the latest available offset in the memory map, and recursively serializing.
+//	Serializes a path copy by starting at the root, getting the latest available offset in the memory map, and serializing each node on the path in order.
+//	The nodes are placed depth first, so each node is followed by the nodes on the path below it.
 func (mariInst *Mari) serializePathToMemMap(root *MariINode, nextOffsetInMMap uint64) ([]byte, error) {
-	serializedPath, serializeErr := mariInst.serializeRecursive(root, 0, nextOffsetInMMap)
-	if serializeErr != nil { return nil, serializeErr }
+	var serializedPath []byte
+	stack := []MariSerializeFrame{{ node: root, slot: -1 }}
+
+	for len(stack) > 0 {
+		frame := stack[len(stack) - 1]
+		stack = stack[:len(stack) - 1]
+
+		offset := nextOffsetInMMap + uint64(len(serializedPath))
+		if frame.slot >= 0 { copy(serializedPath[frame.slot:], serializeUint64(offset)) }
+
+		var serializeErr error
+		serializedPath, stack, serializeErr = mariInst.serializePathNode(frame.node, offset, serializedPath, stack)
+		if serializeErr != nil { return nil, serializeErr }
+	}
 
 	return serializedPath, nil
 }
 
-// serializeRecursive
-//	Traverses the path copy down to the end of the path.
-//	If the node is a leaf, serialize it and return. If the node is a internal node, serialize each of the children recursively if
-//	the version matches the version of the root. If it is an older version, just serialize the existing offset in the memory map.
-func (mariInst *Mari) serializeRecursive(node *MariINode, level int, offset uint64) ([]byte, error) {
+// serializePathNode
+//	Serialize a single node on the path copy at the given offset and append it to the serialized path.
+//	If a child has an older version, just serialize the existing offset in the memory map. Otherwise the child is on the path,
+//	so a slot is left for its offset and the child is pushed onto the stack, to be placed once the nodes before it are serialized.
+func (mariInst *Mari) serializePathNode(node *MariINode, offset uint64, serializedPath []byte, stack []MariSerializeFrame) ([]byte, []MariSerializeFrame, error) {
 	node.startOffset = offset
-	
+
 	sNode, serializeErr := node.serializeINode(true)
-	if serializeErr != nil { return nil, serializeErr }
+	if serializeErr != nil { return nil, nil, serializeErr }
 
-	serializedKeyVal, sLeafErr := node.leaf.serializeLNode()
-	if sLeafErr != nil { return nil, sLeafErr }
+	overflowValue := mariInst.moveToOverflow(node.leaf)
 
-	var childrenOnPaths []byte
-	nextStartOffset := node.leaf.endOffset + 1
+	serializedKeyVal, sLeafErr := node.leaf.serializeLNode()
+	if sLeafErr != nil { return nil, nil, sLeafErr }
 
-	for _, child := range node.children {
+	slots := make([]int, len(node.children))
+	for idx, child := range node.children {
+		slots[idx] = len(serializedPath) + len(sNode)
 		if child.version != node.version {
 			sNode = append(sNode, serializeUint64(child.startOffset)...)
-		} else {
-			sNode = append(sNode, serializeUint64(nextStartOffset)...)
-			childrenOnPath, serializeErr := mariInst.serializeRecursive(child, level + 1, nextStartOffset)
-			if serializeErr != nil { return nil, serializeErr }
-
-			nextStartOffset += getSerializedNodeSize(childrenOnPath)
-			childrenOnPaths = append(childrenOnPaths, childrenOnPath...)
-		}
+		} else { sNode = append(sNode, serializeUint64(0)...) }
 	}
 
+	sNode = append(sNode, serializeUint64(node.count)...)
 	sNode = append(sNode, serializedKeyVal...)
+	sNode = append(sNode, overflowValue...)
+	serializedPath = append(serializedPath, sNode...)
 
-	if len(childrenOnPaths) > 0 { sNode = append(sNode, childrenOnPaths...) }
+	for idx := len(node.children) - 1; idx >= 0; idx-- {
+		child := node.children[idx]
+		if child.version == node.version { stack = append(stack, MariSerializeFrame{ node: child, slot: slots[idx] }) }
+	}
 
 	mariInst.nodePool.putLNode(node.leaf)
 	mariInst.nodePool.putINode(node)
-	
-	return sNode, nil
+
+	return serializedPath, stack, nil
 }
 
 // serializeLNode
 //	Serialize a leaf node in the mariInst. Append the key and value together since both are already byte slices.
+//	If the value is stored out of line, the overflow flag is set on the version and the offset and length of the value are appended in place of the value.
 func (node *MariLNode) serializeLNode() ([]byte, error) {
 	var sLNode []byte
 
 	node.endOffset = node.determineEndOffsetLNode()
 
 	sVersion := serializeUint64(node.version)
+	if isOverflow(node) { sVersion = serializeUint64(node.version | LeafOverflowFlag) }
 	sStartOffset := serializeUint64(node.startOffset)
 	sEndOffset := serializeUint64(node.endOffset)
 	sKeyLength := serializeUint16(node.keyLength)
+	if hasKey(node) && node.keyLength == 0 { sKeyLength = serializeUint16(EmptyKeyLength) }
 
 	sLNode = append(sLNode, sVersion...)
 	sLNode = append(sLNode, sStartOffset...)
@@ -167,13 +225,18 @@ func (node *MariLNode) serializeLNode() ([]byte, error) {
 	sLNode = append(sLNode, sKeyLength...)
 	
 	sLNode = append(sLNode, node.key...)
-	sLNode = append(sLNode, node.value...)
+
+	if isOverflow(node) {
+		sLNode = append(sLNode, serializeUint64(node.overflowOffset)...)
+		sLNode = append(sLNode, serializeUint64(node.overflowLength)...)
+	} else { sLNode = append(sLNode, node.value...) }
 
 	return sLNode, nil
 }
 
 // serializeINode
 //	Serialize an internal node in the mariInst. This involves scanning the children nodes and serializing the offset in the memory map for each one.
+//	The subtree count follows the child pointers. If serializing a path, the caller appends both the child pointers and the count.
 func (node *MariINode) serializeINode(serializePath bool) ([]byte, error) {
 	var sINode []byte
 
@@ -202,6 +265,8 @@ func (node *MariINode) serializeINode(serializePath bool) ([]byte, error) {
 			snode := serializeUint64(cnode.startOffset)
 			sINode = append(sINode, snode...)
 		}
+
+		sINode = append(sINode, serializeUint64(node.count)...)
 	}
 
 	return sINode, nil
@@ -218,7 +283,7 @@ func serializeUint64(in uint64) []byte {
 }
 
 func deserializeUint64(data []byte) (uint64, error) {
-	if len(data) != 8 { return uint64(0), errors.New("invalid data length for byte slice to uint64") }
+	if len(data) != 8 { return uint64(0), fmt.Errorf("invalid data length for byte slice to uint64: %w", ErrCorruptNode) }
 	return binary.LittleEndian.Uint64(data), nil
 }
 
@@ -229,7 +294,7 @@ func serializeUint32(in uint32) []byte {
 }
 
 func deserializeUint32(data []byte) (uint32, error) {
-	if len(data) != 4 { return uint32(0), errors.New("invalid data length for byte slice to uint32") }
+	if len(data) != 4 { return uint32(0), fmt.Errorf("invalid data length for byte slice to uint32: %w", ErrCorruptNode) }
 	return binary.LittleEndian.Uint32(data), nil
 }
 
@@ -240,6 +305,6 @@ func serializeUint16(in uint16) []byte {
 }
 
 func deserializeUint16(data []byte) (uint16, error) {
-	if len(data) != 2 { return uint16(0), errors.New("invalid data length for byte slice to uint16") }
+	if len(data) != 2 { return uint16(0), fmt.Errorf("invalid data length for byte slice to uint16: %w", ErrCorruptNode) }
 	return binary.LittleEndian.Uint16(data), nil
 }
\ No newline at end of file
@@ -0,0 +1,118 @@
+package mari
+
+import "errors"
+import "fmt"
+import "runtime"
+import "sync/atomic"
+
+
+//============================================= Mari Snapshot
+
+
+// Snapshot
+//	Pin the current version of Mari and return a handle for reading it.
+//	Each snapshot increments the reference count for its version, and compaction will retain every version from the oldest pinned version onwards.
+//	The snapshot must be closed once it is no longer needed so the versions can be reclaimed.
+func (mariInst *Mari) Snapshot() (*MariSnapshot, error) {
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return nil, fmt.Errorf("attempting to snapshot: %w", ErrClosed) }
+
+	_, currVersion, loadVErr := mariInst.loadMetaVersion()
+	if loadVErr != nil { return nil, loadVErr }
+
+	mariInst.snapshotLock.Lock()
+	defer mariInst.snapshotLock.Unlock()
+
+	ref, ok := mariInst.snapshots[currVersion]
+	if ! ok {
+		ref = &MariSnapshotRef{ version: currVersion }
+		mariInst.snapshots[currVersion] = ref
+	}
+
+	ref.refs++
+
+	return &MariSnapshot{ store: mariInst, ref: ref }, nil
+}
+
+// Version
+//	The version pinned by the snapshot.
+//	Since compaction renumbers retained versions, the pinned version may change after a compaction.
+func (snapshot *MariSnapshot) Version() uint64 {
+	snapshot.store.snapshotLock.Lock()
+	defer snapshot.store.snapshotLock.Unlock()
+
+	return snapshot.ref.version
+}
+
+// ViewTx
+//	Handles read related operations against the pinned version.
+//	The version is resolved while holding the read lock, so the root is always located in the current memory map, even if compaction has occured since the snapshot was taken.
+func (snapshot *MariSnapshot) ViewTx(txOps func(tx *MariTx) error) error {
+	if atomic.LoadUint32(&snapshot.closed) == 1 { return errors.New("snapshot is closed") }
+
+	mariInst := snapshot.store
+	remapErr := mariInst.remapReadOnly()
+	if remapErr != nil { return remapErr }
+
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	return mariInst.viewVersion(snapshot.Version(), txOps)
+}
+
+// Close
+//	Release the snapshot, decrementing the reference count for the pinned version.
+//	Once no snapshots reference the version, it can be reclaimed on the next compaction.
+func (snapshot *MariSnapshot) Close() error {
+	if ! atomic.CompareAndSwapUint32(&snapshot.closed, 0, 1) { return nil }
+
+	mariInst := snapshot.store
+	mariInst.snapshotLock.Lock()
+	defer mariInst.snapshotLock.Unlock()
+
+	snapshot.ref.refs--
+	if snapshot.ref.refs == 0 { delete(mariInst.snapshots, snapshot.ref.version) }
+
+	return nil
+}
+
+// oldestSnapshotVersion
+//	Determine the oldest version pinned by an open snapshot.
+//	Returns false if there are no open snapshots.
+func (mariInst *Mari) oldestSnapshotVersion() (uint64, bool) {
+	mariInst.snapshotLock.Lock()
+	defer mariInst.snapshotLock.Unlock()
+
+	var oldest uint64
+	var found bool
+
+	for version := range mariInst.snapshots {
+		if ! found || version < oldest {
+			oldest = version
+			found = true
+		}
+	}
+
+	return oldest, found
+}
+
+// remapSnapshots
+//	After compaction renumbers the retained versions, update the version pinned by every open snapshot.
+func (mariInst *Mari) remapSnapshots(compact *MariCompaction) {
+	mariInst.snapshotLock.Lock()
+	defer mariInst.snapshotLock.Unlock()
+
+	remapped := make(map[uint64]*MariSnapshotRef)
+	for _, ref := range mariInst.snapshots {
+		ref.version = compact.remapVersion(ref.version)
+		remapped[ref.version] = ref
+	}
+
+	mariInst.snapshots = remapped
+}
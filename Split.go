@@ -0,0 +1,91 @@
+package mari
+
+import "bytes"
+import "errors"
+import "os"
+
+
+//============================================= Mari Split
+
+
+// Split
+//	Write every key less than at to a new file at leftPath, and every other key to a new file at rightPath.
+//	Both files are written from the same committed version, renumbered to version 0 like Clone. If either file fails, both are removed.
+//	Values are copied as stored, so the new files must be opened with the same value codec as the source instance.
+func (mariInst *Mari) Split(at []byte, leftPath, rightPath string) error {
+	if leftPath == rightPath { return errors.New("split destinations must be different files") }
+
+	for _, path := range []string{ leftPath, leftPath + VersionIndexFileName, rightPath, rightPath + VersionIndexFileName } {
+		_, statErr := os.Stat(path)
+		if statErr == nil { return errors.New("split destination already exists") }
+		if ! os.IsNotExist(statErr) { return statErr }
+	}
+
+	return mariInst.withCommittedRoot(func(rootOffset uint64) error {
+		for _, side := range []struct{ path string; keepLess bool }{ { leftPath, true }, { rightPath, false } } {
+			currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
+			if readRootErr != nil { return readRootErr }
+
+			splitRoot, splitErr := mariInst.splitRecursive(currRoot, at, 0, side.keepLess)
+			if splitErr == nil { splitErr = mariInst.writeRootToFile(splitRoot, side.path) }
+
+			if splitErr != nil {
+				os.Remove(leftPath)
+				return splitErr
+			}
+		}
+
+		return nil
+	})
+}
+
+// splitRecursive
+//	Build a copy of the node containing only the keys on one side of at, where keepLess keeps the keys less than at.
+//	Only the child on the path of at is recursed into, since every other child falls entirely on one side and is kept or dropped whole.
+func (mariInst *Mari) splitRecursive(node *MariINode, at []byte, level int, keepLess bool) (*MariINode, error) {
+	nodeCopy := mariInst.copyINode(node)
+
+	var count uint64
+	if hasKey(node.leaf) && (bytes.Compare(node.leaf.key, at) == -1) == keepLess {
+		count++
+	} else { nodeCopy.leaf = mariInst.newLeafNode(nil, nil, nodeCopy.version) }
+
+	var bitmap [8]uint32
+	var children []*MariINode
+
+	for pos, index := range bitmapIndexes(node.bitmap) {
+		child := node.children[pos]
+
+		if len(at) > level && index == at[level] {
+			childNode, getChildErr := mariInst.getChildNode(child, node.version)
+			if getChildErr != nil { return nil, getChildErr }
+
+			childCopy, splitErr := mariInst.splitRecursive(childNode, at, level + 1, keepLess)
+			if splitErr != nil { return nil, splitErr }
+			if ! hasKey(childCopy.leaf) && len(childCopy.children) == 0 { continue }
+
+			bitmap = setBit(bitmap, index)
+			children = append(children, childCopy)
+			count += childCopy.count
+
+			continue
+		}
+
+		isLess := len(at) > level && index < at[level]
+		if isLess != keepLess { continue }
+
+		childCount, getCountErr := mariInst.getChildCount(child, node.version)
+		if getCountErr != nil { return nil, getCountErr }
+
+		bitmap = setBit(bitmap, index)
+		children = append(children, child)
+		count += childCount
+	}
+
+	nodeCopy.bitmap = bitmap
+	nodeCopy.children = children
+	nodeCopy.count = count
+	nodeCopy.hasCount = true
+
+	return nodeCopy, nil
+}
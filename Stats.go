@@ -0,0 +1,67 @@
+package mari
+
+import "fmt"
+import "runtime"
+import "sync/atomic"
+
+
+//============================================= Mari Stats
+
+
+// Stats
+//	Collect a snapshot of the current state of Mari, including the current version, the size of the memory mapped file and the version index, the number of keys, the depth of the trie, and the bytes of dead space.
+//	Dead space is the size of the memory mapped file minus the serialized size of every node and leaf reachable from the current root, so it includes the metadata and any space preallocated past the end of the serialized data.
+//	Every node in the current version is visited, so this can be expensive on large instances.
+func (mariInst *Mari) Stats() (MariStats, error) {
+	remapErr := mariInst.remapReadOnly()
+	if remapErr != nil { return MariStats{}, remapErr }
+
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return MariStats{}, fmt.Errorf("attempting to collect stats on: %w", ErrClosed) }
+
+	_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+	if loadROffErr != nil { return MariStats{}, loadROffErr }
+
+	currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
+	if readRootErr != nil { return MariStats{}, readRootErr }
+
+	fileSize, sizeErr := mariInst.FileSize()
+	if sizeErr != nil { return MariStats{}, sizeErr }
+
+	stats := MariStats{
+		Version: currRoot.version,
+		FileSize: uint64(fileSize),
+		VersionIndexSize: uint64(len(mariInst.vIdx.Load().(MMap))),
+		TxRetries: mariInst.TxRetries(),
+	}
+
+	statsErr := mariInst.statsRecursive(currRoot, 0, &stats)
+	if statsErr != nil { return MariStats{}, statsErr }
+
+	if stats.LiveBytes < stats.FileSize { stats.DeadBytes = stats.FileSize - stats.LiveBytes }
+
+	return stats, nil
+}
+
+// statsRecursive
+//	Accumulate the number of keys, the maximum depth, and the serialized size of the node, its leaf, and the overflow value of its leaf for every node in the subtree.
+func (mariInst *Mari) statsRecursive(node *MariINode, level int, stats *MariStats) error {
+	if level > stats.Depth { stats.Depth = level }
+
+	stats.KeyCount += uint64(leafCount(node.leaf))
+	stats.LiveBytes += node.serializedSize()
+
+	for _, childOffset := range node.children {
+		childNode, readChildErr := mariInst.readINodeFromMemMap(childOffset.startOffset)
+		if readChildErr != nil { return readChildErr }
+
+		statsErr := mariInst.statsRecursive(childNode, level + 1, stats)
+		if statsErr != nil { return statsErr }
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package mari
+
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "io"
+import "os"
+import "sync/atomic"
+
+
+//============================================= Mari Stream
+
+
+// pendingStreams
+//	Collect the values put with PutReader that are still referenced by the path copy, following the same children that are serialized by serializePathToMemMap.
+//	Values put with PutReader that were overwritten, deleted, or rolled back in the transaction are no longer referenced, so their readers are never read.
+func (tx *MariTx) pendingStreams(root *MariINode, streams []*MariPendingStream) []*MariPendingStream {
+	stack := []*MariINode{ root }
+
+	for len(stack) > 0 {
+		node := stack[len(stack) - 1]
+		stack = stack[:len(stack) - 1]
+
+		if isPendingOverflow(node.leaf) {
+			stream, ok := tx.streams[node.leaf]
+			if ok { streams = append(streams, stream) }
+		}
+
+		for idx := len(node.children) - 1; idx >= 0; idx-- {
+			if node.children[idx].version == node.version { stack = append(stack, node.children[idx]) }
+		}
+	}
+
+	return streams
+}
+
+// copyStreams
+//	Copy the values put with PutReader into space reserved past the end of the serialized data, so the commit only publishes their offsets.
+//	The resize read lock stays held during the copy, since the path copy still shares keys and values with the memory map, so resizes and compactions wait on a slow reader.
+//	Values copied by an earlier attempt of the transaction are reused. If the space can not be reserved yet, false is returned and the transaction is retried.
+func (mariInst *Mari) copyStreams(streams, copied []*MariPendingStream) ([]*MariPendingStream, bool, error) {
+	_, epoch, loadIdErr := mariInst.loadMetaIdentity()
+	if loadIdErr != nil { return copied, false, loadIdErr }
+
+	var pending []*MariPendingStream
+	reused := make([]bool, len(copied))
+	for _, stream := range streams {
+		prevStream := matchCopiedStream(stream, copied, reused)
+		if prevStream == nil {
+			pending = append(pending, stream)
+			continue
+		}
+
+		if prevStream.epoch != epoch { return copied, false, fmt.Errorf("streamed value for key %q: %w", stream.key, ErrStreamDiscarded) }
+
+		stream.offset = prevStream.offset
+		stream.leaf.overflowOffset = prevStream.offset
+		stream.epoch = epoch
+	}
+
+	if len(pending) == 0 { return copied, true, nil }
+
+	endOffsetPtr, endOffset, loadSOffErr := mariInst.loadMetaEndSerialized()
+	if loadSOffErr != nil { return copied, false, loadSOffErr }
+
+	reservedEnd := endOffset + streamsLength(pending)
+	if mariInst.determineIfResize(reservedEnd) { return copied, false, nil }
+	if ! atomic.CompareAndSwapUint64(endOffsetPtr, endOffset, reservedEnd) { return copied, false, nil }
+
+	assignStreamOffsets(pending, endOffset)
+	for _, stream := range pending { stream.epoch = epoch }
+	copied = append(copied, pending...)
+
+	return copied, true, mariInst.writeStreamsToMemMap(pending)
+}
+
+// matchCopiedStream
+//	Find the first value copied by an earlier attempt of the transaction that was put with the same key and length, and is not already reused by this attempt.
+func matchCopiedStream(stream *MariPendingStream, copied []*MariPendingStream, reused []bool) *MariPendingStream {
+	for idx, prevStream := range copied {
+		if reused[idx] || prevStream.length != stream.length || ! bytes.Equal(prevStream.key, stream.key) { continue }
+
+		reused[idx] = true
+		return prevStream
+	}
+
+	return nil
+}
+
+// assignStreamOffsets
+//	Place each pending value back to back from the offset, returning the offset directly after the last value.
+//	The offset is recorded on the stream as well as the leaf, since leaves are returned to the node pool once the path copy is serialized.
+func assignStreamOffsets(streams []*MariPendingStream, offset uint64) uint64 {
+	for _, stream := range streams {
+		stream.offset = offset
+		stream.leaf.overflowOffset = offset
+		offset += stream.length
+	}
+
+	return offset
+}
+
+// streamsLength
+//	The total length of the values.
+func streamsLength(streams []*MariPendingStream) uint64 {
+	var length uint64
+	for _, stream := range streams { length += stream.length }
+
+	return length
+}
+
+// writeStreamsToMemMap
+//	Copy each pending value from its reader directly into its place in the memory map, so the value is never buffered in memory.
+//	If a reader ends before the size it was put with, the write fails.
+func (mariInst *Mari) writeStreamsToMemMap(streams []*MariPendingStream) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil { err = fmt.Errorf("error writing streamed value to mmap: %v", r) }
+	}()
+
+	mMap := mariInst.data.Load().(MMap)
+
+	for _, stream := range streams {
+		_, readErr := io.ReadFull(stream.reader, mMap[stream.offset:stream.offset + stream.length])
+		if errors.Is(readErr, io.EOF) { readErr = io.ErrUnexpectedEOF }
+		if readErr != nil { return fmt.Errorf("error reading streamed value of length %d: %w", stream.length, readErr) }
+	}
+
+	return nil
+}
+
+// recordStreamChanges
+//	Copy each value written from a reader into the change recorded for its put, so watchers, the commit hook, and the change log see the committed value.
+//	Values are only copied when something consumes the changes, so streaming a value does not otherwise allocate it in memory.
+func (mariInst *Mari) recordStreamChanges(tx *MariTx, streams []*MariPendingStream) {
+	if len(streams) == 0 { return }
+
+	mariInst.watchLock.Lock()
+	hasWatchers := len(mariInst.watchers) > 0
+	mariInst.watchLock.Unlock()
+
+	if ! hasWatchers && mariInst.onCommit == nil && mariInst.changeLog == nil { return }
+
+	mMap := mariInst.data.Load().(MMap)
+	for _, stream := range streams {
+		tx.changes[stream.change].Value = append([]byte{}, mMap[stream.offset:stream.offset + stream.length]...)
+	}
+}
+
+// newValueReader
+//	Open a reader over a value stored out of line, with its own handle on the file so reads go through the file instead of the memory map.
+//	This is called within a transaction, so the file at the path of the instance is the file the value was read from.
+func (mariInst *Mari) newValueReader(leaf *MariLNode) (*MariValueReader, error) {
+	_, epoch, loadIdErr := mariInst.loadMetaIdentity()
+	if loadIdErr != nil { return nil, loadIdErr }
+
+	file, openErr := os.Open(mariInst.file.Name())
+	if openErr != nil { return nil, openErr }
+
+	return &MariValueReader{ file: file, epoch: epoch, offset: leaf.overflowOffset, length: leaf.overflowLength }, nil
+}
+
+// Read
+//	Read the next part of the value from the file, returning io.EOF once the whole value has been read.
+//	A compaction writes a new file, so the old file stays readable through the handle, but a truncate reuses the space of the value, so the read fails if the epoch of the file has moved on.
+func (reader *MariValueReader) Read(p []byte) (int, error) {
+	if reader.closed { return 0, errors.New("attempting to read from a closed value reader") }
+	if reader.read == reader.length { return 0, io.EOF }
+
+	remaining := reader.length - reader.read
+	if uint64(len(p)) > remaining { p = p[:remaining] }
+
+	n, readErr := reader.file.ReadAt(p, int64(reader.offset + reader.read))
+	if readErr != nil { return 0, fmt.Errorf("error reading overflow value from file: %w: %w", ErrCorruptNode, readErr) }
+
+	epoch := make([]byte, OffsetSize)
+	_, readEpochErr := reader.file.ReadAt(epoch, MetaEpochIdx)
+	if readEpochErr != nil { return 0, readEpochErr }
+	if binary.LittleEndian.Uint64(epoch) != reader.epoch { return 0, errors.New("value was discarded by a truncate while it was being read") }
+
+	reader.read += uint64(n)
+	return n, nil
+}
+
+// Close
+//	Close the handle on the file held by the reader. Closing a reader more than once has no effect.
+func (reader *MariValueReader) Close() error {
+	if reader.closed { return nil }
+
+	reader.closed = true
+	return reader.file.Close()
+}
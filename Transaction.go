@@ -1,9 +1,16 @@
 package mari
 
 import "bytes"
+import "context"
+import "encoding/binary"
 import "errors"
+import "fmt"
+import "io"
 import "runtime"
+import "sort"
+import "sync"
 import "sync/atomic"
+import "time"
 import "unsafe"
 
 
@@ -19,19 +26,35 @@ func newTx(mariInst *Mari, rootPtr *unsafe.Pointer, isWrite bool) *MariTx {
 		store: mariInst,
 		root: rootPtr,
 		isWrite: isWrite,
+		done: make(chan struct{}),
 	}
 }
 
+// finish
+//	Stop any range chan traversals still running for the transaction and wait for them to exit, so nothing reads the memory map once the read lock is released.
+func (tx *MariTx) finish() {
+	close(tx.done)
+	tx.producers.Wait()
+}
+
 // ReadTx
-//	Handles all read related operations.
+//	Handles all read related operations, and is the canonical read only transaction.
 //	It gets the latest version of the ordered array mapped trie and starts from that offset in the mem-map.
+//	The root offset is loaded once and pinned for the whole transaction, so every Get, Range, and Iterate within txOps reads the same consistent snapshot, even while writes commit new versions.
+//	The read lock is held until txOps returns, so the pinned version cannot be moved by a resize or reclaimed by compaction mid transaction.
 //	Get is concurrent since it will perform the operation on an existing path, so new paths can be written at the same time with new versions.
+//	If the instance has been closed, an error wrapping ErrClosed is returned without running the transaction.
 func (mariInst *Mari) ReadTx(txOps func(tx *MariTx) error) error {
+	remapErr := mariInst.remapReadOnly()
+	if remapErr != nil { return remapErr }
+
 	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
 	
 	mariInst.rwResizeLock.RLock()
 	defer mariInst.rwResizeLock.RUnlock()
 
+	if ! mariInst.opened { return fmt.Errorf("attempting to read: %w", ErrClosed) }
+
 	_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
 	if loadROffErr != nil { return loadROffErr }
 
@@ -42,6 +65,57 @@ func (mariInst *Mari) ReadTx(txOps func(tx *MariTx) error) error {
 
 	transaction := newTx(mariInst, rootPtr, false)
 	viewErr := txOps(transaction)
+	transaction.finish()
+	if viewErr != nil { return viewErr }
+
+	return nil
+}
+
+// ViewTx
+//	Equivalent to ReadTx, pinning the current version for the whole transaction.
+//	It shares its name with ViewTx on a snapshot and ViewTxAtVersion, which pin an older version with the same guarantees, so read only transactions can be written the same way against any of them.
+func (mariInst *Mari) ViewTx(txOps func(tx *MariTx) error) error {
+	return mariInst.ReadTx(txOps)
+}
+
+// ViewTxAtVersion
+//	Handles read related operations against a historical version of the ordered array mapped trie.
+//	The root offset for the version is loaded from the version index and a read only transaction is pinned to that root.
+//	If the version has not been written yet, or predates the last compaction, an error is returned.
+func (mariInst *Mari) ViewTxAtVersion(version uint64, txOps func(tx *MariTx) error) error {
+	remapErr := mariInst.remapReadOnly()
+	if remapErr != nil { return remapErr }
+
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+	
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	return mariInst.viewVersion(version, txOps)
+}
+
+// viewVersion
+//	Resolve the root of a version from the version index and run a read only transaction against it.
+//	The caller must hold the read lock so the version cannot be reclaimed by compaction mid transaction.
+func (mariInst *Mari) viewVersion(version uint64, txOps func(tx *MariTx) error) error {
+	if ! mariInst.opened { return fmt.Errorf("attempting to read: %w", ErrClosed) }
+
+	_, currVersion, loadVErr := mariInst.loadMetaVersion()
+	if loadVErr != nil { return loadVErr }
+	if version > currVersion { return errors.New("version has not been written yet") }
+
+	rootOffset, loadOffErr := mariInst.loadStartOffset(version)
+	if loadOffErr != nil { return loadOffErr }
+	if rootOffset == 0 { return errors.New("version is not resolvable, it may predate the last compaction") }
+
+	versionRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
+	if readRootErr != nil { return readRootErr }
+
+	rootPtr := storeINodeAsPointer(versionRoot)
+
+	transaction := newTx(mariInst, rootPtr, false)
+	viewErr := txOps(transaction)
+	transaction.finish()
 	if viewErr != nil { return viewErr }
 
 	return nil
@@ -52,18 +126,51 @@ func (mariInst *Mari) ReadTx(txOps func(tx *MariTx) error) error {
 //	If the operation fails, the copied and modified path is discarded and the operation retries back at the root until completed.
 //	The operation begins at the latest known version of root, reads from the metadata in the memory map.
 //	The version of the copy is incremented and if the metadata is the same after the path copying has occured, the path is serialized and appended to the memory-map.
+//	Read only instances return an error without running the transaction, as do closed instances, with an error wrapping ErrClosed.
 //	The metadata is also being updated to reflect the new version and the new root offset.
+//	Between retries, the transaction backs off by yielding the processor and then sleeping for exponentially longer periods, and if MaxTxRetries is set, an error is returned once the retries are exhausted.
+//	Every retry, including those waiting on a resize or compaction, is counted towards the limit and towards the total returned by TxRetries.
+//	If an OnCommit hook is set, it is called once the transaction commits, after the resize lock is released, so the hook is free to start its own transactions.
+//	With DurabilitySync, if the transaction commits but flushing it to disk fails, the hook is still called and the returned error wraps ErrNotSynced, so the caller can tell a commit that may not survive a crash from a transaction that failed.
 func (mariInst *Mari) UpdateTx(txOps func(tx *MariTx) error) error {
-	for {
+	return mariInst.updateTx(txOps, 0)
+}
+
+// updateTx
+//	The write transaction loop behind UpdateTx, which commits the transaction as the version after the current version.
+//	If targetVersion is greater than that version, the transaction is committed as targetVersion instead, leaving a gap in the version index for the skipped versions.
+func (mariInst *Mari) updateTx(txOps func(tx *MariTx) error, targetVersion uint64) error {
+	if mariInst.readOnly { return errors.New("attempting to perform a write on a read only mari instance") }
+
+	var copied []*MariPendingStream
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			atomic.AddUint64(&mariInst.txRetries, 1)
+			if mariInst.maxTxRetries >= 0 && attempt > mariInst.maxTxRetries { return mariInst.retriesExceededError() }
+
+			txBackoff(attempt)
+		}
+
 		for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
 		mariInst.rwResizeLock.RLock()
 
+		if ! mariInst.opened {
+			mariInst.rwResizeLock.RUnlock()
+			return fmt.Errorf("attempting to update: %w", ErrClosed)
+		}
+
 		versionPtr, version, loadVErr := mariInst.loadMetaVersion()
-		if loadVErr != nil { return loadVErr }
+		if loadVErr != nil {
+			mariInst.rwResizeLock.RUnlock()
+			return loadVErr
+		}
 
 		if version == atomic.LoadUint64(versionPtr) {
 			_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
-			if loadROffErr != nil { return loadROffErr }
+			if loadROffErr != nil {
+				mariInst.rwResizeLock.RUnlock()
+				return loadROffErr
+			}
 	
 			currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
 			if readRootErr != nil {
@@ -71,53 +178,473 @@ func (mariInst *Mari) UpdateTx(txOps func(tx *MariTx) error) error {
 				return readRootErr
 			}
 	
+			rootVersion := currRoot.version
 			currRoot.version = currRoot.version + 1
+			if targetVersion > currRoot.version { currRoot.version = targetVersion }
+			commitVersion := currRoot.version
 			rootPtr := storeINodeAsPointer(currRoot)
 			
 			transaction := newTx(mariInst, rootPtr, true)
 			updateErr := txOps(transaction)
-			if updateErr != nil { return updateErr }
+			transaction.finish()
+			if updateErr != nil {
+				mariInst.rwResizeLock.RUnlock()
+				return updateErr
+			}
 
 			updatedRootCopy := loadINodeFromPointer(rootPtr)
-			ok, writeErr := mariInst.exclusiveWriteMmap(updatedRootCopy)
-			if writeErr != nil {
+			streams := transaction.pendingStreams(updatedRootCopy, nil)
+			if len(streams) > 0 {
+				var reserved bool
+				var copyErr error
+
+				copied, reserved, copyErr = mariInst.copyStreams(streams, copied)
+				if copyErr != nil || ! reserved {
+					mariInst.rwResizeLock.RUnlock()
+					if copyErr != nil { return copyErr }
+
+					continue
+				}
+			}
+
+			ok, writeErr := mariInst.exclusiveWriteMmap(updatedRootCopy, rootVersion, transaction, streams)
+			if ! ok && writeErr != nil {
 				mariInst.rwResizeLock.RUnlock()
 				return writeErr
 			}
 
 			if ok {
 				mariInst.rwResizeLock.RUnlock() 
-				return nil
+				if mariInst.onCommit != nil { mariInst.onCommit(commitVersion, transaction.changes) }
+
+				return writeErr
 			}
 		}
 
 		mariInst.rwResizeLock.RUnlock()
-		runtime.Gosched()
 	}
 }
 
+// retriesExceededError
+//	Build the error returned once a write transaction exhausts MaxTxRetries.
+//	If the memory map is being resized or compacted, the error wraps ErrResizing, since the retries were spent waiting on the resize rather than on contention with other writers.
+func (mariInst *Mari) retriesExceededError() error {
+	if atomic.LoadUint32(&mariInst.isResizing) == 1 {
+		return fmt.Errorf("write transaction exceeded the maximum of %d retries: %w", mariInst.maxTxRetries, ErrResizing)
+	}
+
+	return fmt.Errorf("write transaction exceeded the maximum of %d retries", mariInst.maxTxRetries)
+}
+
+// TxRetries
+//	The total number of write transaction attempts that were discarded and retried since the instance was opened.
+//	A steadily climbing count indicates contention between writers, which can be bounded with MaxTxRetries.
+func (mariInst *Mari) TxRetries() uint64 {
+	return atomic.LoadUint64(&mariInst.txRetries)
+}
+
 // Put 
 //	Inserts or updates key-value pair into the ordered array mapped trie.
 //	The operation begins at the root of the trie and traverses through the tree until the correct location is found, copying the entire path.
 func (tx *MariTx) Put(key, value []byte) error {
-	if ! tx.isWrite { return errors.New("attempting to perform a write in a read only transaction, use tx.UpdateTx") }
+	if ! tx.isWrite { return ErrReadOnlyTx }
 
-	_, putErr := tx.store.putRecursive(tx.root, key, value, 0)
+	putErr := tx.put(key, value)
 	if putErr != nil { return putErr }
 	
 	return nil
 }
 
+// PutReader
+//	Inserts or updates a key with a value of the given size read from the reader, without buffering the value in memory.
+//	The value is always stored out of line. Once the operations of the transaction return, the value is copied from the reader into space reserved at the end of the file, and the commit only publishes its offset, so the reader must remain readable until UpdateTx returns.
+//	The reader is read only once. If the transaction retries, the copy is reused, unless a compaction has discarded it, in which case the transaction fails with an error wrapping ErrStreamDiscarded.
+//	If the reader ends before size bytes are read, the transaction fails and nothing is committed. The value cannot be read back within the same transaction, and streamed values cannot be used with a value codec.
+func (tx *MariTx) PutReader(key []byte, r io.Reader, size int64) error {
+	if ! tx.isWrite { return ErrReadOnlyTx }
+	if size < 0 { return fmt.Errorf("invalid size for streamed value: %d", size) }
+	if size == 0 { return tx.put(key, []byte{}) }
+	if tx.store.valueCodec != nil { return errors.New("streamed values cannot be put when a value codec is set") }
+
+	if key == nil { key = []byte{} }
+	if len(key) > MaxKeyLength { return fmt.Errorf("key of length %d exceeds the maximum key length of %d: %w", len(key), MaxKeyLength, ErrKeyTooLong) }
+
+	leaf := tx.store.newLeafNode(key, nil, loadINodeFromPointer(tx.root).version)
+	leaf.overflowLength = uint64(size)
+
+	atomic.AddUint64(&tx.store.metrics.Puts, 1)
+	_, putErr := tx.store.putIterative(tx.root, leaf, 0)
+	if putErr != nil { return putErr }
+
+	tx.recordChange(key, []byte{})
+
+	if tx.streams == nil { tx.streams = make(map[*MariLNode]*MariPendingStream) }
+	tx.streams[leaf] = &MariPendingStream{ key: key, leaf: leaf, reader: r, length: uint64(size), change: len(tx.changes) - 1 }
+
+	return nil
+}
+
+// PutBatch
+//	Inserts or updates many key-value pairs in a single call.
+//	The pairs are sorted by key before being inserted, so consecutive keys that share a prefix modify the same nodes of the path copy in place instead of copying the path from the root again.
+//	If a key appears more than once, the last pair in the input wins, and all pairs are applied atomically with the enclosing UpdateTx.
+func (tx *MariTx) PutBatch(pairs []KeyValuePair) error {
+	if ! tx.isWrite { return ErrReadOnlyTx }
+
+	sorted := make([]*KeyValuePair, len(pairs))
+	for idx := range pairs { sorted[idx] = &pairs[idx] }
+
+	sort.SliceStable(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].Key, sorted[j].Key) == -1 })
+
+	for _, kvPair := range sorted {
+		putErr := tx.put(kvPair.Key, kvPair.Value)
+		if putErr != nil { return putErr }
+	}
+
+	return nil
+}
+
+// PutReturning
+//	Inserts or updates a key-value pair, returning the previous key-value pair that was overwritten, or nil if the key did not exist.
+//	The previous pair, including its version, is read against the same root of the write transaction before the put, so no separate Get is needed.
+func (tx *MariTx) PutReturning(key, value []byte) (*KeyValuePair, error) {
+	if ! tx.isWrite { return nil, ErrReadOnlyTx }
+
+	prevKvPair, getErr := tx.store.getIterative(tx.root, key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+	if getErr != nil { return nil, getErr }
+
+	putErr := tx.put(key, value)
+	if putErr != nil { return nil, putErr }
+
+	return prevKvPair, nil
+}
+
+// PutIfAbsent
+//	Inserts the key-value pair only if the key does not already exist, returning true if the pair was written.
+//	The existence check and the insert are performed against the same root of the write transaction, so the check-and-set is atomic within the UpdateTx.
+func (tx *MariTx) PutIfAbsent(key, value []byte) (bool, error) {
+	if ! tx.isWrite { return false, ErrReadOnlyTx }
+
+	kvPair, getErr := tx.store.getIterative(tx.root, key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+	if getErr != nil { return false, getErr }
+	if kvPair != nil { return false, nil }
+
+	putErr := tx.put(key, value)
+	if putErr != nil { return false, putErr }
+
+	return true, nil
+}
+
+// CompareAndSwapValue
+//	Replaces the value for the key only if the current value is equal to the expected value, returning true if the swap occured.
+//	The current value is read against the same root of the write transaction that is written to, so the comparison and replacement are atomic within the UpdateTx.
+//	If the key does not exist, no swap occurs. Use PutIfAbsent to create keys.
+func (tx *MariTx) CompareAndSwapValue(key, expected, newValue []byte) (bool, error) {
+	if ! tx.isWrite { return false, ErrReadOnlyTx }
+
+	kvPair, getErr := tx.store.getIterative(tx.root, key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+	if getErr != nil { return false, getErr }
+	if kvPair == nil || ! bytes.Equal(kvPair.Value, expected) { return false, nil }
+
+	putErr := tx.put(key, newValue)
+	if putErr != nil { return false, putErr }
+
+	return true, nil
+}
+
+// BatchIf
+//	Applies the puts and then the deletes only if the current value of every check matches, where a nil value matches a missing key.
+//	The version of a check is ignored. Returns whether or not the batch was applied.
+func (tx *MariTx) BatchIf(checks []KeyValuePair, puts []KeyValuePair, deletes [][]byte) (bool, error) {
+	if ! tx.isWrite { return false, ErrReadOnlyTx }
+
+	for _, check := range checks {
+		kvPair, getErr := tx.store.getIterative(tx.root, check.Key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+		if getErr != nil { return false, getErr }
+
+		switch {
+			case check.Value == nil && kvPair != nil:
+				return false, nil
+			case check.Value != nil && (kvPair == nil || ! bytes.Equal(kvPair.Value, check.Value)):
+				return false, nil
+		}
+	}
+
+	for _, pair := range puts {
+		putErr := tx.put(pair.Key, pair.Value)
+		if putErr != nil { return false, putErr }
+	}
+
+	for _, key := range deletes {
+		delErr := tx.delete(key)
+		if delErr != nil { return false, delErr }
+	}
+
+	return true, nil
+}
+
+// Update
+//	Performs an atomic read-modify-write on the value for a key, passing the current value, or nil, to fn and storing the result.
+//	If fn returns an error, nothing is written and the error is returned.
+func (tx *MariTx) Update(key []byte, fn func(old []byte) ([]byte, error)) error {
+	if ! tx.isWrite { return ErrReadOnlyTx }
+
+	kvPair, getErr := tx.store.getIterative(tx.root, key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+	if getErr != nil { return getErr }
+
+	var old []byte
+	if kvPair != nil { old = kvPair.Value }
+
+	newValue, fnErr := fn(old)
+	if fnErr != nil { return fnErr }
+
+	putErr := tx.put(key, newValue)
+	if putErr != nil { return putErr }
+
+	return nil
+}
+
+// Merge
+//	Combines the operand with the current value for the key using the MergeFunc of the instance, and stores the result.
+//	The current value, or nil if the key does not exist, is read against the same root of the write transaction, so the merge is applied within the same path copy as the rest of the transaction.
+//	Merges within one transaction are applied in the order they are called, and each merge sees the result of the previous one.
+func (tx *MariTx) Merge(key, operand []byte) error {
+	if ! tx.isWrite { return ErrReadOnlyTx }
+	if tx.store.mergeFunc == nil { return errors.New("merge requires a MergeFunc in the instance options") }
+
+	kvPair, getErr := tx.store.getIterative(tx.root, key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+	if getErr != nil { return getErr }
+
+	var existing []byte
+	if kvPair != nil { existing = kvPair.Value }
+
+	putErr := tx.put(key, tx.store.mergeFunc(existing, operand))
+	if putErr != nil { return putErr }
+
+	return nil
+}
+
+// Increment
+//	Treats the value for the key as a little endian int64, adds delta, stores the result, and returns the new total.
+//	A key that does not exist starts at zero, and a value that is not 8 bytes returns an error without writing.
+//	The read and the write are performed against the same root of the write transaction, so if the commit conflicts, the whole transaction is retried against the new root and no increment is lost.
+func (tx *MariTx) Increment(key []byte, delta int64) (int64, error) {
+	if ! tx.isWrite { return 0, ErrReadOnlyTx }
+
+	kvPair, getErr := tx.store.getIterative(tx.root, key, 0, func(kvPair *KeyValuePair) *KeyValuePair { return kvPair })
+	if getErr != nil { return 0, getErr }
+
+	var total int64
+	if kvPair != nil {
+		if len(kvPair.Value) != 8 { return 0, errors.New("value to increment is not an 8 byte int64") }
+		total = int64(binary.LittleEndian.Uint64(kvPair.Value))
+	}
+
+	total += delta
+
+	sTotal := make([]byte, 8)
+	binary.LittleEndian.PutUint64(sTotal, uint64(total))
+
+	putErr := tx.put(key, sTotal)
+	if putErr != nil { return 0, putErr }
+
+	return total, nil
+}
+
+// PutWithVersion
+//	Inserts or updates a key-value pair, tagging it with the supplied version instead of the version the transaction commits as.
+//	This lets imports and replication preserve the original version of a pair, which is returned by reads and honored by the minimum version of Range and Iterate.
+//	The supplied version must not exceed the version the transaction commits as, since a pair can not be newer than the store that holds it.
+func (tx *MariTx) PutWithVersion(key, value []byte, version uint64) error {
+	if ! tx.isWrite { return ErrReadOnlyTx }
+
+	txVersion := loadINodeFromPointer(tx.root).version
+	if version > txVersion { return fmt.Errorf("supplied version %d exceeds the version of the transaction %d", version, txVersion) }
+
+	return tx.putWithVersion(key, value, version)
+}
+
+// Savepoint
+//	Records the current state of a write transaction, returning the id of the savepoint to pass to RollbackTo.
+//	The path copy of the transaction is copied, so the cost of a savepoint is proportional to the number of nodes modified so far in the transaction.
+func (tx *MariTx) Savepoint() int {
+	root := tx.store.clonePathCopy(loadINodeFromPointer(tx.root))
+	tx.savepoints = append(tx.savepoints, MariSavepoint{ root: root, changes: len(tx.changes) })
+
+	return len(tx.savepoints) - 1
+}
+
+// RollbackTo
+//	Undoes every put and delete made in the transaction since the savepoint was taken, without aborting the rest of the transaction.
+//	Savepoints taken after the savepoint are discarded, while the savepoint itself remains, so the transaction can be rolled back to it again.
+func (tx *MariTx) RollbackTo(savepoint int) error {
+	if ! tx.isWrite { return ErrReadOnlyTx }
+	if savepoint < 0 || savepoint >= len(tx.savepoints) { return fmt.Errorf("savepoint %d does not exist in the transaction", savepoint) }
+
+	saved := tx.savepoints[savepoint]
+	atomic.StorePointer(tx.root, unsafe.Pointer(tx.store.clonePathCopy(saved.root)))
+
+	tx.changes = tx.changes[:saved.changes]
+	tx.savepoints = tx.savepoints[:savepoint + 1]
+
+	return nil
+}
+
+// put
+//	Insert the key-value pair tagged with the version the transaction commits as.
+func (tx *MariTx) put(key, value []byte) error {
+	return tx.putWithVersion(key, value, loadINodeFromPointer(tx.root).version)
+}
+
+// putWithVersion
+//	Encode the value with the value codec of the store, if one is set, and insert the key-value pair tagged with the version against the root of the transaction.
+//	A nil key is stored as the empty key, since a nil key marks a leaf without a key.
+//	Keys longer than MaxKeyLength are rejected before anything is written, since the key length would not fit in the serialized leaf.
+func (tx *MariTx) putWithVersion(key, value []byte, version uint64) error {
+	if key == nil { key = []byte{} }
+	if len(key) > MaxKeyLength { return fmt.Errorf("key of length %d exceeds the maximum key length of %d: %w", len(key), MaxKeyLength, ErrKeyTooLong) }
+
+	encoded, encodeErr := tx.store.encodeValue(key, value)
+	if encodeErr != nil { return encodeErr }
+
+	atomic.AddUint64(&tx.store.metrics.Puts, 1)
+	_, putErr := tx.store.putIterative(tx.root, tx.store.newLeafNode(key, encoded, version), 0)
+	if putErr != nil { return putErr }
+
+	if value == nil { value = []byte{} }
+	tx.recordChange(key, value)
+
+	return nil
+}
+
+// delete
+//	Remove the key against the root of the transaction and record the delete as a change.
+func (tx *MariTx) delete(key []byte) error {
+	atomic.AddUint64(&tx.store.metrics.Deletes, 1)
+	_, delErr := tx.store.deleteRecursive(tx.root, key, 0)
+	if delErr != nil { return delErr }
+
+	tx.recordChange(key, nil)
+	return nil
+}
+
+// recordChange
+//	Record a write made by the transaction, in order, so it can be published once the transaction commits.
+//	A delete is recorded with a nil value. The key and value are copied since keys collected from the trie point into the memory map, which may be remapped after the commit.
+func (tx *MariTx) recordChange(key, value []byte) {
+	change := KeyValuePair{ Key: append([]byte{}, key...) }
+	if value != nil { change.Value = append([]byte{}, value...) }
+
+	tx.changes = append(tx.changes, change)
+}
+
 // Get
 //	Attempts to retrieve the value for a key within the ordered array mapped trie.
 //	The operation begins at the root of the trie and traverses down the path to the key.
+//	If the key does not exist, or the transform drops the result, both the key-value pair and the error are nil, so the result must be checked before it is used.
+//	GetOr and MustGet can be used instead when a miss should not be represented by nil.
 func (tx *MariTx) Get(key []byte, transform *MariOpTransform) (*KeyValuePair, error) {
 	var newTransform MariOpTransform
 	if transform != nil {
 		newTransform = *transform
 	} else { newTransform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
 
-	return tx.store.getRecursive(tx.root, key, 0, newTransform)
+	return tx.store.getIterative(tx.root, key, 0, newTransform)
+}
+
+// GetOr
+//	Get the value for a key, returning the default value if the key does not exist.
+func (tx *MariTx) GetOr(key, def []byte) ([]byte, error) {
+	kvPair, getErr := tx.Get(key, nil)
+	if getErr != nil { return nil, getErr }
+	if kvPair == nil { return def, nil }
+
+	return kvPair.Value, nil
+}
+
+// MustGet
+//	Get the key-value pair for a key, returning ErrKeyNotFound if the key does not exist.
+//	Callers can distinguish a miss from a failed read with errors.Is, without checking the result for nil.
+func (tx *MariTx) MustGet(key []byte) (*KeyValuePair, error) {
+	kvPair, getErr := tx.Get(key, nil)
+	if getErr != nil { return nil, getErr }
+	if kvPair == nil { return nil, ErrKeyNotFound }
+
+	return kvPair, nil
+}
+
+// GetReader
+//	Get a reader over the value for a key, returning ErrKeyNotFound if the key does not exist.
+//	Values stored out of line are read through a separate handle on the file, so the value is never buffered in memory and the reader remains valid after the transaction ends.
+//	The reader holds no lock, but it holds the handle open until it is closed, so it must always be closed. Inline values, values decoded by a value codec, and values of in memory instances are read from memory instead.
+func (tx *MariTx) GetReader(key []byte) (io.ReadCloser, error) {
+	atomic.AddUint64(&tx.store.metrics.Gets, 1)
+
+	leaf, findErr := tx.store.findLeaf(tx.root, key, 0)
+	if findErr != nil { return nil, findErr }
+	if leaf == nil { return nil, ErrKeyNotFound }
+
+	if isOverflow(leaf) && ! isPendingOverflow(leaf) && tx.store.valueCodec == nil && ! tx.store.inMemory {
+		return tx.store.newValueReader(leaf)
+	}
+
+	kvPair, decodeErr := tx.store.newKeyValuePair(leaf)
+	if decodeErr != nil { return nil, decodeErr }
+
+	return io.NopCloser(bytes.NewReader(kvPair.Value)), nil
+}
+
+// GetMany
+//	Retrieves the values for many keys at once, returning one result per key in the same order as the input, where keys that do not exist are nil.
+//	The keys are sorted and the trie is traversed once, so the path for keys sharing a prefix is only read a single time.
+//	This is more efficient than individual calls to Get for large sets of keys within a transaction.
+func (tx *MariTx) GetMany(keys [][]byte, transform *MariOpTransform) ([]*KeyValuePair, error) {
+	var newTransform MariOpTransform
+	if transform != nil {
+		newTransform = *transform
+	} else { newTransform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
+
+	indexes := make([]int, len(keys))
+	for idx := range indexes { indexes[idx] = idx }
+
+	sort.Slice(indexes, func(i, j int) bool { return bytes.Compare(keys[indexes[i]], keys[indexes[j]]) == -1 })
+
+	sortedKeys := make([][]byte, len(keys))
+	for idx, keyIdx := range indexes { sortedKeys[idx] = keys[keyIdx] }
+
+	atomic.AddUint64(&tx.store.metrics.Gets, uint64(len(keys)))
+
+	results := make([]*KeyValuePair, len(keys))
+	getErr := tx.store.getManyRecursive(tx.root, sortedKeys, indexes, 0, newTransform, results)
+	if getErr != nil { return nil, getErr }
+
+	return results, nil
+}
+
+// Has
+//	Determines whether a key exists within the ordered array mapped trie, without reading the value associated with the key.
+//	False is returned if the key does not exist.
+func (tx *MariTx) Has(key []byte) (bool, error) {
+	return tx.store.hasRecursive(tx.root, key, 0)
+}
+
+// HasMany
+//	Determines whether each of many keys exists, returning one flag per key in the same order as the input.
+//	Like GetMany, the keys are sorted and the trie is traversed once, and like Has, value bytes are never read, so large sets of keys can be checked before deciding which values to load.
+func (tx *MariTx) HasMany(keys [][]byte) ([]bool, error) {
+	indexes := make([]int, len(keys))
+	for idx := range indexes { indexes[idx] = idx }
+
+	sort.Slice(indexes, func(i, j int) bool { return bytes.Compare(keys[indexes[i]], keys[indexes[j]]) == -1 })
+
+	sortedKeys := make([][]byte, len(keys))
+	for idx, keyIdx := range indexes { sortedKeys[idx] = keys[keyIdx] }
+
+	results := make([]bool, len(keys))
+	hasErr := tx.store.hasManyRecursive(tx.root, sortedKeys, indexes, 0, results)
+	if hasErr != nil { return nil, hasErr }
+
+	return results, nil
 }
 
 // Delete 
@@ -125,14 +652,164 @@ func (tx *MariTx) Get(key []byte, transform *MariOpTransform) (*KeyValuePair, er
 //	It starts at the root of the trie and recurses down the path to the key to be deleted.
 //	The operation creates an entire, in-memory copy of the path down to the key.
 func (tx *MariTx) Delete(key []byte) error {
-	if ! tx.isWrite { return errors.New("attempting to perform a write in a read only transaction, use tx.UpdateTx") }
+	if ! tx.isWrite { return ErrReadOnlyTx }
 
-	_, delErr := tx.store.deleteRecursive(tx.root, key, 0)
+	delErr := tx.delete(key)
 	if delErr != nil { return delErr }
 	
 	return nil
 }
 
+// DeleteExisting
+//	Attempts to delete a key-value pair within the ordered array mapped trie, returning true only if the key existed and was removed.
+//	The bool returned by deleteRecursive reflects the swap of the path copy rather than whether the key existed, so existence is checked against the same root of the write transaction first.
+func (tx *MariTx) DeleteExisting(key []byte) (bool, error) {
+	if ! tx.isWrite { return false, ErrReadOnlyTx }
+
+	exists, hasErr := tx.store.hasRecursive(tx.root, key, 0)
+	if hasErr != nil { return false, hasErr }
+	if ! exists { return false, nil }
+
+	delErr := tx.delete(key)
+	if delErr != nil { return false, delErr }
+
+	return true, nil
+}
+
+// DeleteRange
+//	Deletes every key-value pair between the start and end key, inclusive, returning the total number of keys removed.
+//	The keys are first collected with the same traversal as Range, and only then deleted, so shrinking nodes during deletion cannot affect the traversal.
+//	Since all deletes are applied to the root of the write transaction, the range is removed atomically when the transaction commits.
+func (tx *MariTx) DeleteRange(startKey, endKey []byte) (int, error) {
+	if ! tx.isWrite { return 0, ErrReadOnlyTx }
+	if bytes.Compare(startKey, endKey) == 1 { return 0, errors.New("start key is larger than end key") }
+
+	var keys [][]byte
+	bounds := newRangeBounds(nil)
+	bounds.keysOnly = true
+
+	_, rangeErr := tx.store.rangeRecursive(tx.root, 0, startKey, endKey, 0, bounds, nil, func(leaf *MariLNode) bool {
+		keys = append(keys, leaf.key)
+		return true
+	})
+
+	if rangeErr != nil { return 0, rangeErr }
+
+	for _, key := range keys {
+		delErr := tx.delete(key)
+		if delErr != nil { return 0, delErr }
+	}
+
+	return len(keys), nil
+}
+
+// DeletePrefix
+//	Deletes every key-value pair whose key begins with the prefix, returning the total number of keys removed.
+//	The subtree for the prefix is located by descending the prefix byte by byte, and the keys beneath it are collected before any are deleted.
+//	This is useful for namespaced keys, for example removing every key under "user:123:" in a single atomic write transaction.
+func (tx *MariTx) DeletePrefix(prefix []byte) (int, error) {
+	if ! tx.isWrite { return 0, ErrReadOnlyTx }
+
+	var keys [][]byte
+	_, prefixErr := tx.store.prefixRecursive(tx.root, prefix, 0, func(leaf *MariLNode) (bool, error) {
+		keys = append(keys, leaf.key)
+		return true, nil
+	})
+
+	if prefixErr != nil { return 0, prefixErr }
+
+	for _, key := range keys {
+		delErr := tx.delete(key)
+		if delErr != nil { return 0, delErr }
+	}
+
+	return len(keys), nil
+}
+
+// Count
+//	Returns the total number of keys in the version of the trie pinned by the transaction.
+//	The trie is walked from the root and each leaf with a non-empty key is counted, without building any key-value pairs.
+func (tx *MariTx) Count() (int, error) {
+	var totalCount int
+
+	_, walkErr := tx.store.walkRecursive(tx.root, func(leaf *MariLNode) (bool, error) {
+		totalCount++
+		return true, nil
+	})
+
+	if walkErr != nil { return 0, walkErr }
+	return totalCount, nil
+}
+
+// Depth
+//	Returns the maximum level reached by any node in the version of the trie pinned by the transaction, where the root is at level 0.
+//	Since each level is indexed by a byte of the key, a deep trie indicates keys with long shared prefixes, which lengthens the path of every read.
+//	If the depth is high, hashing keys before inserting them keeps the trie shallow at the cost of ordered iteration.
+func (tx *MariTx) Depth() (int, error) {
+	return tx.store.depthRecursive(tx.root, 0)
+}
+
+// CountRange
+//	Returns the total number of keys between the start and end key, inclusive.
+//	The traversal is the same as Range, but child nodes are read with only the key of their leaf and no key-value pairs are built, so large ranges can be counted without reading values.
+func (tx *MariTx) CountRange(startKey, endKey []byte) (int, error) {
+	if bytes.Compare(startKey, endKey) == 1 { return 0, errors.New("start key is larger than end key") }
+
+	var totalCount int
+	bounds := newRangeBounds(nil)
+	bounds.keysOnly = true
+
+	_, rangeErr := tx.store.rangeRecursive(tx.root, 0, startKey, endKey, 0, bounds, nil, func(leaf *MariLNode) bool {
+		totalCount++
+		return true
+	})
+
+	if rangeErr != nil { return 0, rangeErr }
+	return totalCount, nil
+}
+
+// RangeKeys
+//	Returns every key between the start and end key, inclusive, in ascending order.
+//	The traversal is the same as Range, but child nodes are read with only the key of their leaf, so value bytes are never read from the memory map.
+func (tx *MariTx) RangeKeys(startKey, endKey []byte) ([][]byte, error) {
+	if bytes.Compare(startKey, endKey) == 1 { return nil, errors.New("start key is larger than end key") }
+
+	var keys [][]byte
+	bounds := newRangeBounds(nil)
+	bounds.keysOnly = true
+
+	_, rangeErr := tx.store.rangeRecursive(tx.root, 0, startKey, endKey, 0, bounds, nil, func(leaf *MariLNode) bool {
+		keys = append(keys, leaf.key)
+		return true
+	})
+
+	if rangeErr != nil { return nil, rangeErr }
+	return keys, nil
+}
+
+// RangeSizeEstimate
+//	Returns the total number of keys between the start and end key, inclusive, along with an estimate of the serialized bytes they occupy.
+//	The number of keys is exact, since it is the rank of the first key past the end key less the rank of the start key, so only the paths of the two keys are descended.
+//	Sizes are not stored per subtree, so the bytes are estimated as the share of keys in the range applied to the live bytes of the memory map, which assumes keys and values are of similar size across the trie.
+func (tx *MariTx) RangeSizeEstimate(startKey, endKey []byte) (int, int64, error) {
+	if bytes.Compare(startKey, endKey) == 1 { return 0, 0, errors.New("start key is larger than end key") }
+
+	startRank, rankErr := tx.store.rankRecursive(tx.root, startKey, 0)
+	if rankErr != nil { return 0, 0, rankErr }
+
+	endRank, rankErr := tx.store.rankRecursive(tx.root, append(append([]byte{}, endKey...), 0x00), 0)
+	if rankErr != nil { return 0, 0, rankErr }
+
+	totalCount, countErr := tx.store.resolveCount(loadINodeFromPointer(tx.root))
+	if countErr != nil { return 0, 0, countErr }
+
+	totalKeys := endRank - startRank
+	if totalKeys == 0 { return 0, 0, nil }
+
+	liveBytes := atomic.LoadUint64(&tx.store.liveBytes)
+	return int(totalKeys), int64(float64(liveBytes) * float64(totalKeys) / float64(totalCount)), nil
+}
+
 // Iterate
 //	Creates an ordered iterator starting at the given start key up to the range specified by total results.
 //	Since the array mapped trie is sorted, the iterate function starts at the startKey and recursively builds the result set up the specified end.
@@ -140,6 +817,41 @@ func (tx *MariTx) Delete(key []byte) error {
 //	If nil is passed for the minimum version, the earliest version in the structure will be used.
 // 	If nil is passed for the transformer, then the kv pair will be returned as is.
 func (tx *MariTx) Iterate(startKey []byte, totalResults int, opts *MariRangeOpts) ([]*KeyValuePair, error) {
+	return tx.IterateCtx(context.Background(), startKey, totalResults, opts)
+}
+
+// IterateCtx
+//	Iterate with a context, which is checked every ScanContextCheckInterval nodes so long iterations can be cancelled.
+//	If the context is cancelled, the iteration stops and the error from the context is returned.
+func (tx *MariTx) IterateCtx(ctx context.Context, startKey []byte, totalResults int, opts *MariRangeOpts) ([]*KeyValuePair, error) {
+	ctxErr := ctx.Err()
+	if ctxErr != nil { return nil, ctxErr }
+
+	var minV uint64 
+	var transform MariOpTransform
+	
+	if opts != nil && opts.MinVersion != nil {
+		minV = *opts.MinVersion
+	} else { minV = 0 }
+
+	if opts != nil && opts.Transform != nil {
+		transform = *opts.Transform
+	} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
+
+	tx.prefetch(startKey, nil, opts)
+
+	accumulator := []*KeyValuePair{}
+	keysOnly := opts != nil && opts.KeysOnly
+	kvPairs, iterErr := tx.store.iterateRecursive(tx.root, minV, startKey, totalResults, 0, accumulator, transform, keysOnly, newScanContext(ctx))
+	if iterErr != nil { return nil, iterErr }
+
+	return kvPairs, nil
+}
+
+// IterateReverse
+//	Creates a descending iterator starting at the given start key, moving towards smaller keys up to the range specified by total results.
+//	The start key is inclusive, and if nil is passed, the iterator begins at the largest key in the structure.
+func (tx *MariTx) IterateReverse(startKey []byte, totalResults int, opts *MariRangeOpts) ([]*KeyValuePair, error) {
 	var minV uint64 
 	var transform MariOpTransform
 	
@@ -151,13 +863,117 @@ func (tx *MariTx) Iterate(startKey []byte, totalResults int, opts *MariRangeOpts
 		transform = *opts.Transform
 	} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
 
+	tx.prefetch(nil, startKey, opts)
+
 	accumulator := []*KeyValuePair{}
-	kvPairs, iterErr := tx.store.iterateRecursive(tx.root, minV, startKey, totalResults, 0, accumulator, transform)
+	keysOnly := opts != nil && opts.KeysOnly
+	kvPairs, iterErr := tx.store.iterateReverseRecursive(tx.root, minV, startKey, totalResults, 0, accumulator, transform, keysOnly, nil)
 	if iterErr != nil { return nil, iterErr }
 
 	return kvPairs, nil
 }
 
+// ForEach
+//	Stream every key-value pair from the start key onwards in ascending order to the callback, without accumulating a result set.
+//	The traversal stops cleanly when the callback returns false or an error, and the error is returned.
+//	If the start key is nil, the scan begins at the smallest key in the structure.
+func (tx *MariTx) ForEach(startKey []byte, fn func(kvPair *KeyValuePair) (bool, error)) error {
+	cursor := tx.Cursor(startKey)
+	defer cursor.Close()
+
+	for {
+		kvPair, ok, nextErr := cursor.Next()
+		if nextErr != nil { return nextErr }
+		if ! ok { return nil }
+
+		cont, fnErr := fn(kvPair)
+		if fnErr != nil { return fnErr }
+		if ! cont { return nil }
+	}
+}
+
+// MinKey
+//	Find the key-value pair with the smallest key by descending the leftmost child at each level, without building a result set.
+//	Nil is returned if the instance is empty.
+func (tx *MariTx) MinKey() (*KeyValuePair, error) {
+	leaf, minErr := tx.store.minRecursive(tx.root, 0)
+	if minErr != nil { return nil, minErr }
+	if leaf == nil { return nil, nil }
+
+	return tx.store.newKeyValuePair(leaf)
+}
+
+// MaxKey
+//	Find the key-value pair with the largest key by descending the rightmost child at each level, without building a result set.
+//	Nil is returned if the instance is empty.
+func (tx *MariTx) MaxKey() (*KeyValuePair, error) {
+	leaf, maxErr := tx.store.maxRecursive(tx.root, 0)
+	if maxErr != nil { return nil, maxErr }
+	if leaf == nil { return nil, nil }
+
+	return tx.store.newKeyValuePair(leaf)
+}
+
+// First
+//	Returns the key-value pairs with the n smallest keys, in ascending order.
+//	The traversal is an unbounded Range that stops as soon as n pairs are found, so only the leftmost subtrees holding those keys are visited.
+func (tx *MariTx) First(n int) ([]*KeyValuePair, error) {
+	return tx.firstN(n, false)
+}
+
+// Last
+//	Returns the key-value pairs with the n largest keys, in descending order.
+//	The traversal is an unbounded reverse Range that stops as soon as n pairs are found, so only the rightmost subtrees holding those keys are visited.
+func (tx *MariTx) Last(n int) ([]*KeyValuePair, error) {
+	return tx.firstN(n, true)
+}
+
+// firstN
+//	Collect up to n key-value pairs from one end of the trie, from the smallest key if reverse is false and from the largest key otherwise.
+func (tx *MariTx) firstN(n int, reverse bool) ([]*KeyValuePair, error) {
+	var kvPairs []*KeyValuePair
+	if n <= 0 { return kvPairs, nil }
+
+	emit := func(kvPair *KeyValuePair) bool {
+		kvPairs = append(kvPairs, kvPair)
+		return len(kvPairs) < n
+	}
+
+	bounds := newRangeBounds(nil)
+	bounds.reverse = reverse
+	transform := func(kvPair *KeyValuePair) *KeyValuePair { return kvPair }
+
+	var decodeErr error
+	_, rangeErr := tx.store.rangeRecursive(tx.root, 0, nil, nil, 0, bounds, nil, tx.store.emitTransformed(transform, false, emit, &decodeErr))
+	if rangeErr != nil { return nil, rangeErr }
+	if decodeErr != nil { return nil, decodeErr }
+
+	return kvPairs, nil
+}
+
+// Rank
+//	Returns the total number of keys that are strictly less than the given key, whether or not the key itself exists.
+//	Each node stores the count of keys in its subtree, so only the path of the key is descended.
+func (tx *MariTx) Rank(key []byte) (int, error) {
+	rank, rankErr := tx.store.rankRecursive(tx.root, key, 0)
+	if rankErr != nil { return 0, rankErr }
+
+	return int(rank), nil
+}
+
+// Select
+//	Returns the key-value pair with the nth smallest key, where n is zero indexed, so Select(Rank(key)) returns the key if it exists.
+//	Nil is returned if n is negative or not less than the total number of keys.
+func (tx *MariTx) Select(n int) (*KeyValuePair, error) {
+	if n < 0 { return nil, nil }
+
+	leaf, selectErr := tx.store.selectRecursive(tx.root, uint64(n), 0, nil)
+	if selectErr != nil { return nil, selectErr }
+	if leaf == nil { return nil, nil }
+
+	return tx.store.newKeyValuePair(leaf)
+}
+
 // Range
 //	Since the array mapped trie is sorted by nature, the range operation begins at the root of the trie.
 //	It checks the root bitmap and determines which indexes to check in the range.
@@ -165,7 +981,19 @@ func (tx *MariTx) Iterate(startKey []byte, totalResults int, opts *MariRangeOpts
 //	A minimum version can be provided which will limit results to the min version forward.
 //	If nil is passed for the minimum version, the earliest version in the structure will be used.
 // 	If nil is passed for the transformer, then the kv pair will be returned as is.
+//	If reverse is set in the options, the results are returned in descending order, but the start key must still be less than or equal to the end key.
+//	The start and end key are both inclusive by default, which can be changed with the StartInclusive and EndInclusive options.
 func (tx *MariTx) Range(startKey, endKey []byte, opts *MariRangeOpts) ([]*KeyValuePair, error) {
+	return tx.RangeCtx(context.Background(), startKey, endKey, opts)
+}
+
+// RangeCtx
+//	Range with a context, which is checked every ScanContextCheckInterval nodes so long scans can be cancelled.
+//	If the context is cancelled, the scan stops and the error from the context is returned.
+func (tx *MariTx) RangeCtx(ctx context.Context, startKey, endKey []byte, opts *MariRangeOpts) ([]*KeyValuePair, error) {
+	ctxErr := ctx.Err()
+	if ctxErr != nil { return nil, ctxErr }
+
 	if bytes.Compare(startKey, endKey) == 1 { return nil, errors.New("start key is larger than end key") }
 
 	var minV uint64 
@@ -179,8 +1007,326 @@ func (tx *MariTx) Range(startKey, endKey []byte, opts *MariRangeOpts) ([]*KeyVal
 		transform = *opts.Transform
 	} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
 
-	kvPairs, rangeErr := tx.store.rangeRecursive(tx.root, minV, startKey, endKey, 0, transform)
+	var kvPairs []*KeyValuePair
+	emit := func(kvPair *KeyValuePair) bool {
+		kvPairs = append(kvPairs, kvPair)
+		return true
+	}
+
+	bounds := newRangeBounds(opts)
+	bounds.scanCtx = newScanContext(ctx)
+	tx.prefetch(startKey, endKey, opts)
+
+	var decodeErr error
+	_, rangeErr := tx.store.rangeRecursive(tx.root, minV, startKey, endKey, 0, bounds, nil, tx.store.emitTransformed(transform, bounds.keysOnly, emit, &decodeErr))
 	if rangeErr != nil { return nil, rangeErr }
+	if decodeErr != nil { return nil, decodeErr }
 
 	return kvPairs, nil
-}
\ No newline at end of file
+}
+
+// RangeParallel
+//	Range split across workers, for very wide ranges.
+//	The root is partitioned into contiguous sub ranges of roughly the same number of keys, and each is scanned by its own go routine.
+//	If a transform is provided, it may be called concurrently from multiple workers.
+func (tx *MariTx) RangeParallel(startKey, endKey []byte, workers int, opts *MariRangeOpts) ([]*KeyValuePair, error) {
+	if workers <= 0 { return nil, errors.New("workers must be greater than 0") }
+	if bytes.Compare(startKey, endKey) == 1 { return nil, errors.New("start key is larger than end key") }
+	if workers == 1 || (endKey != nil && len(endKey) == 0) { return tx.Range(startKey, endKey, opts) }
+
+	var minV uint64 
+	var transform MariOpTransform
+
+	if opts != nil && opts.MinVersion != nil {
+		minV = *opts.MinVersion
+	} else { minV = 0 }
+
+	if opts != nil && opts.Transform != nil {
+		transform = *opts.Transform
+	} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
+
+	bounds := newRangeBounds(opts)
+	tx.prefetch(startKey, endKey, opts)
+
+	partitions, partitionErr := tx.store.partitionRange(loadINodeFromPointer(tx.root), startKey, endKey, bounds, workers)
+	if partitionErr != nil { return nil, partitionErr }
+
+	results := make([][]*KeyValuePair, len(partitions))
+	errs := make([]error, len(partitions))
+
+	var workersWG sync.WaitGroup
+
+	for idx := range partitions {
+		workersWG.Add(1)
+
+		go func(idx int) {
+			defer workersWG.Done()
+
+			partition := partitions[idx]
+			emit := func(kvPair *KeyValuePair) bool {
+				results[idx] = append(results[idx], kvPair)
+				return true
+			}
+
+			var decodeErr error
+			_, rangeErr := tx.store.rangeRecursive(tx.root, minV, partition.startKey, partition.endKey, 0, partition.bounds, nil, tx.store.emitTransformed(transform, bounds.keysOnly, emit, &decodeErr))
+			if rangeErr != nil {
+				errs[idx] = rangeErr
+				return
+			}
+
+			errs[idx] = decodeErr
+		}(idx)
+	}
+
+	workersWG.Wait()
+
+	var total int
+	for idx := range partitions {
+		if errs[idx] != nil { return nil, errs[idx] }
+		total += len(results[idx])
+	}
+
+	kvPairs := make([]*KeyValuePair, 0, total)
+	for idx := range partitions {
+		partIdx := idx
+		if bounds.reverse { partIdx = len(partitions) - 1 - idx }
+
+		kvPairs = append(kvPairs, results[partIdx]...)
+	}
+
+	return kvPairs, nil
+}
+
+// IteratePrefix
+//	Returns up to total results key-value pairs whose key begins with the prefix, in ascending order.
+//	The Reverse, StartInclusive, and EndInclusive options do not apply, but the minimum version and transform are honored like Range.
+func (tx *MariTx) IteratePrefix(prefix []byte, totalResults int, opts *MariRangeOpts) ([]*KeyValuePair, error) {
+	var minV uint64 
+	var transform MariOpTransform
+
+	if opts != nil && opts.MinVersion != nil {
+		minV = *opts.MinVersion
+	} else { minV = 0 }
+
+	if opts != nil && opts.Transform != nil {
+		transform = *opts.Transform
+	} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
+
+	startKey := append([]byte{}, prefix...)
+	endKey := prefixEndKey(prefix)
+	bounds := MariRangeBounds{ startInclusive: true, endInclusive: false }
+	if opts != nil { bounds.keysOnly = opts.KeysOnly }
+
+	var kvPairs []*KeyValuePair
+	emit := func(kvPair *KeyValuePair) bool {
+		kvPairs = append(kvPairs, kvPair)
+		return len(kvPairs) < totalResults
+	}
+
+	if totalResults <= 0 { return kvPairs, nil }
+
+	tx.prefetch(startKey, endKey, opts)
+
+	var decodeErr error
+	_, rangeErr := tx.store.rangeRecursive(tx.root, minV, startKey, endKey, 0, bounds, nil, tx.store.emitTransformed(transform, bounds.keysOnly, emit, &decodeErr))
+	if rangeErr != nil { return nil, rangeErr }
+	if decodeErr != nil { return nil, decodeErr }
+
+	return kvPairs, nil
+}
+
+// Page
+//	Returns up to limit key-value pairs with keys after afterKey, along with the key to pass as afterKey for the next page.
+//	If nil is passed for the after key, the first page begins at the smallest key. The next key is nil once there are no more results.
+//	If Reverse is set, pages are returned in descending order, and StartInclusive and EndInclusive do not apply.
+func (tx *MariTx) Page(afterKey []byte, limit int, opts *MariRangeOpts) ([]*KeyValuePair, []byte, error) {
+	if limit <= 0 { return nil, nil, errors.New("page limit must be greater than 0") }
+
+	var minV uint64 
+	var transform MariOpTransform
+
+	if opts != nil && opts.MinVersion != nil {
+		minV = *opts.MinVersion
+	} else { minV = 0 }
+
+	if opts != nil && opts.Transform != nil {
+		transform = *opts.Transform
+	} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
+
+	bounds := newRangeBounds(opts)
+	bounds.startInclusive = false
+	bounds.endInclusive = false
+
+	var startKey, endKey []byte
+	if bounds.reverse {
+		endKey = afterKey
+	} else { startKey = afterKey }
+
+	tx.prefetch(startKey, endKey, opts)
+
+	newPair := tx.store.newKeyValuePair
+	if bounds.keysOnly { newPair = tx.store.newKeyPair }
+
+	var kvPairs []*KeyValuePair
+	var nextAfter, lastKey []byte
+	var decodeErr error
+
+	_, rangeErr := tx.store.rangeRecursive(tx.root, minV, startKey, endKey, 0, bounds, nil, func(leaf *MariLNode) bool {
+		kvPair, newErr := newPair(leaf)
+		if newErr != nil {
+			decodeErr = newErr
+			return false
+		}
+
+		transformed := transform(kvPair)
+		if transformed == nil { return true }
+
+		if len(kvPairs) == limit {
+			nextAfter = lastKey
+			return false
+		}
+
+		kvPairs = append(kvPairs, transformed)
+		lastKey = append([]byte{}, leaf.key...)
+
+		return true
+	})
+
+	if rangeErr != nil { return nil, nil, rangeErr }
+	if decodeErr != nil { return nil, nil, decodeErr }
+
+	return kvPairs, nextAfter, nil
+}
+
+// RangeChan
+//	Range, emitting each key-value pair on a channel as it is found, followed by any error on the error channel.
+//	The traversal is stopped once the transaction returns, so the channel should be drained before then.
+func (tx *MariTx) RangeChan(startKey, endKey []byte, opts *MariRangeOpts) (<-chan *KeyValuePair, <-chan error) {
+	return tx.RangeChanCtx(context.Background(), startKey, endKey, opts)
+}
+
+// RangeChanCtx
+//	RangeChan with a context, so a consumer that stops reading early can cancel the traversal.
+//	If the context is cancelled, the traversal stops and the error from the context is sent on the error channel.
+func (tx *MariTx) RangeChanCtx(ctx context.Context, startKey, endKey []byte, opts *MariRangeOpts) (<-chan *KeyValuePair, <-chan error) {
+	kvPairsChan := make(chan *KeyValuePair)
+	errChan := make(chan error, 1)
+
+	tx.producers.Add(1)
+	go func() {
+		defer tx.producers.Done()
+		defer close(errChan)
+		defer close(kvPairsChan)
+
+		if bytes.Compare(startKey, endKey) == 1 { 
+			errChan <- errors.New("start key is larger than end key")
+			return
+		}
+
+		var minV uint64 
+		var transform MariOpTransform
+
+		if opts != nil && opts.MinVersion != nil {
+			minV = *opts.MinVersion
+		} else { minV = 0 }
+
+		if opts != nil && opts.Transform != nil {
+			transform = *opts.Transform
+		} else { transform = func(kvPair *KeyValuePair) *KeyValuePair { return kvPair } }
+
+		var stopErr error
+		emit := func(kvPair *KeyValuePair) bool {
+			select {
+				case kvPairsChan <- kvPair:
+					return true
+				case <-ctx.Done():
+					stopErr = ctx.Err()
+				case <-tx.done:
+					stopErr = errors.New("transaction returned before the range chan was drained")
+			}
+
+			return false
+		}
+
+		bounds := newRangeBounds(opts)
+		bounds.scanCtx = newScanContext(ctx)
+		tx.prefetch(startKey, endKey, opts)
+
+		var decodeErr error
+		_, rangeErr := tx.store.rangeRecursive(tx.root, minV, startKey, endKey, 0, bounds, nil, tx.store.emitTransformed(transform, bounds.keysOnly, emit, &decodeErr))
+		if rangeErr != nil {
+			errChan <- rangeErr
+			return
+		}
+
+		if stopErr != nil {
+			errChan <- stopErr
+			return
+		}
+
+		if decodeErr != nil { errChan <- decodeErr }
+	}()
+
+	return kvPairsChan, errChan
+}
+
+// newRangeBounds
+//	Resolve the bound options for a range, where the start and end key are inclusive unless specified otherwise.
+func newRangeBounds(opts *MariRangeOpts) MariRangeBounds {
+	bounds := MariRangeBounds{ startInclusive: true, endInclusive: true }
+	if opts == nil { return bounds }
+
+	if opts.StartInclusive != nil { bounds.startInclusive = *opts.StartInclusive }
+	if opts.EndInclusive != nil { bounds.endInclusive = *opts.EndInclusive }
+	bounds.reverse = opts.Reverse
+	bounds.keysOnly = opts.KeysOnly
+
+	return bounds
+}
+
+// prefetch
+//	If Prefetch is set in the options, hint that the subtrees of the root between the start and end key will be needed before a scan begins.
+//	A nil start or end key leaves that side of the range unbounded.
+func (tx *MariTx) prefetch(startKey, endKey []byte, opts *MariRangeOpts) {
+	if opts == nil || ! opts.Prefetch { return }
+	tx.store.prefetchRange(tx.root, startKey, endKey)
+}
+
+// newScanContext
+//	Create the scan context for a cancellable scan.
+//	If the context can never be cancelled, nil is returned so the scan skips the periodic checks.
+func newScanContext(ctx context.Context) *MariScanContext {
+	if ctx.Done() == nil { return nil }
+	return &MariScanContext{ ctx: ctx }
+}
+
+// check
+//	Count a visited node, and every ScanContextCheckInterval nodes return the error from the context if it has been cancelled.
+//	A nil scan context is never cancelled.
+func (scanCtx *MariScanContext) check() error {
+	if scanCtx == nil { return nil }
+
+	scanCtx.visited++
+	if scanCtx.visited % ScanContextCheckInterval != 0 { return nil }
+
+	return scanCtx.ctx.Err()
+}
+
+// txBackoff
+//	Wait before retrying a write transaction.
+//	The first TxBackoffSpins retries only yield the processor, after which the transaction sleeps for exponentially longer periods, up to TxBackoffMax.
+func txBackoff(attempt int) {
+	if attempt <= TxBackoffSpins {
+		runtime.Gosched()
+		return
+	}
+
+	shift := attempt - TxBackoffSpins
+	if shift > 10 { shift = 10 }
+
+	sleep := time.Microsecond << shift
+	if sleep > TxBackoffMax { sleep = TxBackoffMax }
+
+	time.Sleep(sleep)
+}
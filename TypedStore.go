@@ -0,0 +1,157 @@
+package mari
+
+import "encoding/json"
+
+
+//============================================= Mari Typed Store
+
+
+// NewTypedStore
+//	Wrap a Mari instance with a key and value codec.
+//	Every operation encodes its arguments with the codecs and delegates to the matching MariTx operation, so the typed store shares the instance and its transactions with untyped callers.
+func NewTypedStore[K, V any](store *Mari, keyCodec KeyCodec[K], valueCodec ValueCodec[V]) *TypedStore[K, V] {
+	return &TypedStore[K, V]{ store: store, keyCodec: keyCodec, valueCodec: valueCodec }
+}
+
+// Put
+//	Encode the key and value and insert them in a single write transaction.
+func (typed *TypedStore[K, V]) Put(key K, value V) error {
+	sKey, encKeyErr := typed.keyCodec.Encode(key)
+	if encKeyErr != nil { return encKeyErr }
+
+	sValue, encValErr := typed.valueCodec.Encode(value)
+	if encValErr != nil { return encValErr }
+
+	return typed.store.UpdateTx(func(tx *MariTx) error {
+		return tx.Put(sKey, sValue)
+	})
+}
+
+// Get
+//	Retrieve and decode the value for a key. False is returned, along with the zero value, if the key does not exist.
+func (typed *TypedStore[K, V]) Get(key K) (V, bool, error) {
+	var value V
+
+	sKey, encKeyErr := typed.keyCodec.Encode(key)
+	if encKeyErr != nil { return value, false, encKeyErr }
+
+	var kvPair *KeyValuePair
+	readErr := typed.store.ReadTx(func(tx *MariTx) error {
+		var getErr error
+		kvPair, getErr = tx.Get(sKey, nil)
+		return getErr
+	})
+
+	if readErr != nil { return value, false, readErr }
+	if kvPair == nil { return value, false, nil }
+
+	value, decValErr := typed.valueCodec.Decode(kvPair.Value)
+	if decValErr != nil { return value, false, decValErr }
+
+	return value, true, nil
+}
+
+// Delete
+//	Remove a key in a single write transaction.
+func (typed *TypedStore[K, V]) Delete(key K) error {
+	sKey, encKeyErr := typed.keyCodec.Encode(key)
+	if encKeyErr != nil { return encKeyErr }
+
+	return typed.store.UpdateTx(func(tx *MariTx) error {
+		return tx.Delete(sKey)
+	})
+}
+
+// Range
+//	Return every entry with a key between the start and end key, inclusive, in the order of the encoded keys.
+func (typed *TypedStore[K, V]) Range(startKey, endKey K) ([]Entry[K, V], error) {
+	sStartKey, encStartErr := typed.keyCodec.Encode(startKey)
+	if encStartErr != nil { return nil, encStartErr }
+
+	sEndKey, encEndErr := typed.keyCodec.Encode(endKey)
+	if encEndErr != nil { return nil, encEndErr }
+
+	var kvPairs []*KeyValuePair
+	readErr := typed.store.ReadTx(func(tx *MariTx) error {
+		var rangeErr error
+		kvPairs, rangeErr = tx.Range(sStartKey, sEndKey, nil)
+		return rangeErr
+	})
+
+	if readErr != nil { return nil, readErr }
+	return typed.decodeEntries(kvPairs)
+}
+
+// Iterate
+//	Return up to total results entries beginning at the start key, in the order of the encoded keys.
+func (typed *TypedStore[K, V]) Iterate(startKey K, totalResults int) ([]Entry[K, V], error) {
+	sStartKey, encStartErr := typed.keyCodec.Encode(startKey)
+	if encStartErr != nil { return nil, encStartErr }
+
+	var kvPairs []*KeyValuePair
+	readErr := typed.store.ReadTx(func(tx *MariTx) error {
+		var iterErr error
+		kvPairs, iterErr = tx.Iterate(sStartKey, totalResults, nil)
+		return iterErr
+	})
+
+	if readErr != nil { return nil, readErr }
+	return typed.decodeEntries(kvPairs)
+}
+
+// decodeEntries
+//	Decode the key and value of each key-value pair into an entry.
+func (typed *TypedStore[K, V]) decodeEntries(kvPairs []*KeyValuePair) ([]Entry[K, V], error) {
+	entries := make([]Entry[K, V], 0, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		key, decKeyErr := typed.keyCodec.Decode(kvPair.Key)
+		if decKeyErr != nil { return nil, decKeyErr }
+
+		value, decValErr := typed.valueCodec.Decode(kvPair.Value)
+		if decValErr != nil { return nil, decValErr }
+
+		entries = append(entries, Entry[K, V]{ Version: kvPair.Version, Key: key, Value: value })
+	}
+
+	return entries, nil
+}
+
+// Uint64KeyCodec
+//	A key codec for unsigned integers using EncodeUint64, so keys are ordered numerically.
+func Uint64KeyCodec() KeyCodec[uint64] {
+	return KeyCodec[uint64]{
+		Encode: func(key uint64) ([]byte, error) { return EncodeUint64(key), nil },
+		Decode: DecodeUint64,
+	}
+}
+
+// Int64KeyCodec
+//	A key codec for signed integers using EncodeInt64, so keys are ordered numerically.
+func Int64KeyCodec() KeyCodec[int64] {
+	return KeyCodec[int64]{
+		Encode: func(key int64) ([]byte, error) { return EncodeInt64(key), nil },
+		Decode: DecodeInt64,
+	}
+}
+
+// StringKeyCodec
+//	A key codec storing strings as their bytes, so keys are ordered lexicographically.
+func StringKeyCodec() KeyCodec[string] {
+	return KeyCodec[string]{
+		Encode: func(key string) ([]byte, error) { return []byte(key), nil },
+		Decode: func(data []byte) (string, error) { return string(data), nil },
+	}
+}
+
+// JSONValueCodec
+//	A value codec that marshals values to and from JSON.
+func JSONValueCodec[V any]() ValueCodec[V] {
+	return ValueCodec[V]{
+		Encode: func(value V) ([]byte, error) { return json.Marshal(value) },
+		Decode: func(data []byte) (V, error) {
+			var value V
+			unmarshalErr := json.Unmarshal(data, &value)
+			return value, unmarshalErr
+		},
+	}
+}
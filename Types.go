@@ -1,8 +1,13 @@
 package mari
 
+import "container/list"
+import "context"
+import "crypto/cipher"
+import "io"
 import "os"
 import "sync"
 import "sync/atomic"
+import "time"
 import "unsafe"
 
 
@@ -11,16 +16,60 @@ type MMap []byte
 
 // MariOpts initialize the Mari
 type MariOpts struct {
-	// Filepath: the path to the memory mapped file
+	// Filepath: the path to the directory that holds the memory mapped file, which must already exist
 	Filepath string
-	// FileName: the name of the file for the mari instance
+	// FileName: the name of the file for the mari instance within Filepath, which defaults to DefaultFileName
 	FileName string
+	// FileMode: optionally set the permissions that the memory mapped file, version index, change log, and segments are created with, such as 0640 for data files shared with a monitoring sidecar. By default files are only readable by the owner (0600)
+	FileMode os.FileMode
 	// NodePoolSize: the total number of pre-allocated nodes to create in the node pool
 	NodePoolSize *int64
+	// NodePoolCeiling: optionally let the max size of the node pool grow up to this ceiling while the miss rate stays high, and shrink back towards NodePoolSize while most pooled nodes sit idle. By default the node pool has a fixed size
+	NodePoolCeiling *int64
+	// NodeCacheSize: optionally cache up to this many recently read internal nodes, so hot paths are not deserialized from the memory map on every read. By default no nodes are cached
+	NodeCacheSize int
+	// OverflowThreshold: optionally store values longer than this many bytes out of line, keeping only the offset and length of the value in the leaf so path copies and scans do not carry large values. By default values are always stored inline
+	OverflowThreshold int
 	// CompactionTrigger: the custom compaction trigger function
 	CompactTrigger *MariCompactionTrigger
 	// AppendOnly: optionally pass true to stop the compaction process from occuring
 	AppendOnly *bool
+	// CompactRetain: the number of most recent versions to retain on compaction. By default only the latest version is retained
+	CompactRetain *int
+	// CompactFragmentation: optionally compact once the ratio of dead bytes to serialized bytes exceeds this threshold, between 0 and 1. Cannot be combined with CompactTrigger
+	CompactFragmentation *float64
+	// OnCompactionProgress: optionally called periodically while a compaction writes the compacted copy, with the bytes written so far and an estimate of the total, so long compactions can report progress
+	OnCompactionProgress MariCompactionProgressHook
+	// OnCompactionComplete: optionally called after each successful compaction with the size of the memory map before and after the compaction
+	OnCompactionComplete MariCompactionCompleteHook
+	// GrowthFactor: the factor the memory map is multiplied by on each resize, which must be greater than 1. By default the memory map doubles
+	GrowthFactor *float64
+	// MaxTxRetries: the maximum number of times a write transaction is retried before an error is returned. By default write transactions retry until they succeed
+	MaxTxRetries *int
+	// ValueCodec: optionally encode values before they are written and decode them when they are read, such as the codec returned by NewEncryptionCodec. Keys are never encoded
+	ValueCodec MariValueCodec
+	// MergeFunc: the function tx.Merge uses to combine the existing value for a key with an operand
+	MergeFunc MariMergeFunc
+	// OnCommit: optionally called after each successful write transaction with the committed version and the puts and deletes made in it
+	OnCommit MariCommitHook
+	// ChangeLog: optionally pass true to append every committed write transaction to a change log file next to the memory mapped file, which can be read with ReadChangeLog
+	ChangeLog bool
+	// Advise: optionally hint the access pattern of the memory map to the operating system, such as AdviseSequential for scan heavy workloads or AdviseRandom for point reads. By default no hint is given
+	Advise AdvisePattern
+	// LockMemory: optionally pass true to lock the memory map in RAM with mlock so the operating system does not page it out, for low latency services that cannot tolerate page faults. If the process lacks the privilege or the limit on locked memory is too low, the instance still opens and the failure is reported on the errors channel
+	LockMemory bool
+	// Durability: optionally choose when committed writes are flushed to disk, where DurabilityNoSync leaves flushing to the operating system and DurabilitySync flushes before each write transaction returns. By default writes are flushed asynchronously in the background
+	Durability DurabilityLevel
+	// FlushInterval: optionally flush on a timer instead of after every write, coalescing the writes within each interval into one fsync at the cost of a bounded window of durability. By default every write signals a flush
+	FlushInterval time.Duration
+	// InMemory: optionally pass true to map anonymous memory instead of a file, where the Filepath and FileName are ignored and nothing is persisted
+	InMemory bool
+	// ReadOnly: optionally pass true to map an existing file read only, where writes return an error and no background go routines are started
+	ReadOnly bool
+	// RebuildVersionIndex: optionally pass true to discard the version index on open and rebuild it from the memory mapped file, such as after restoring only one of the two files from a backup. Only the current version is resolvable afterwards
+	RebuildVersionIndex bool
+	// RecoverCorruptRoot: optionally pass true to fall back to the most recent version with a readable root when the root of the current version is corrupt on open, discarding every version after it. The number of discarded versions is reported on the errors channel
+	RecoverCorruptRoot bool
 }
 
 // MariMetaData contains information related to where the root is located in the mem map and the version.
@@ -47,6 +96,12 @@ type MariINode struct {
 	leaf *MariLNode
 	// Children: an array of child nodes, which are MariINodes. Location in the array is determined by the sparse index
 	children []*MariINode
+	// Count: the total number of leaves with a key in the subtree rooted at the node, including its own leaf
+	count uint64
+	// HasCount: whether or not the count is known, which is false for nodes serialized before counts were stored
+	hasCount bool
+	// ReplacedSize: for path copies, the serialized size of the node and leaf in the memory map that the copy replaces, which becomes dead space once the copy is written
+	replacedSize uint64
 }
 
 // MariNode represents a singular node within the hash array mapped trie data structure.
@@ -63,6 +118,10 @@ type MariLNode struct {
 	key []byte
 	// Value: The value associated with a key, in byte array representation. Values are only stored within leaf nodes
 	value []byte
+	// OverflowOffset: the offset of a value stored out of line in the memory map
+	overflowOffset uint64
+	// OverflowLength: the length of a value stored out of line, where 0 means the value is stored inline
+	overflowLength uint64
 }
 
 // KeyValuePair
@@ -81,40 +140,186 @@ type Mari struct {
 	filepath string
 	// file: the Mari file
 	file *os.File
+	// fileMode: the permissions files are created with, which also applies to the compacted copy swapped in on compaction
+	fileMode os.FileMode
 	// opened: flag indicating if the file has been opened
 	opened bool
 	// data: the memory mapped file as a byte slice
 	data atomic.Value
+	// versionIndex: the version index file, mapping each version to the offset of its root
+	versionIndex *os.File
+	// vIdx: the memory mapped version index as a byte slice
+	vIdx atomic.Value
+	// vIdxLock: A Read-Write mutex for locking the version index on resize operations
+	vIdxLock sync.RWMutex
 	// isResizing: atomic flag to determine if the mem map is being resized or not
 	isResizing uint32
+	// resizeTo: the minimum size of the mem map requested by the write that signalled the resize
+	resizeTo uint64
 	// signalResize: send a signal to the resize go routine with the offset for resizing
 	signalResizeChan chan bool
 	// signalFlush: send a signal to flush to disk on writes to avoid contention
 	signalFlushChan chan bool
 	// signalCompactChan: send a signal to compact the database
 	signalCompactChan chan bool
+	// handlersWG: wait group for the background flush, resize, and compaction go routines
+	handlersWG sync.WaitGroup
+	// errorsChan: buffered channel surfacing errors from the background go routines
+	errorsChan chan error
 	// ReadResizeLock: A Read-Write mutex for locking reads on resize operations
 	rwResizeLock sync.RWMutex
 	// NodePool: the sync.Pool for recycling nodes so nodes are not constantly allocated/deallocated
 	nodePool *MariNodePool
+	// nodeCache: the least recently used cache of deserialized internal nodes, or nil if node caching is disabled
+	nodeCache *MariNodeCache
 	// compactAtVersion: the max version the root can be before being compacted
 	compactTrigger MariCompactionTrigger
+	// onCompactionProgress: the hook called periodically while a compaction writes the compacted copy, or nil if there is none
+	onCompactionProgress MariCompactionProgressHook
+	// onCompactionComplete: the hook called after each successful compaction, or nil if there is none
+	onCompactionComplete MariCompactionCompleteHook
 	// appendOnly: a flag to determine whether or not to perform the compaction process. By default will be false
 	appendOnly bool
+	// compactRetain: the number of most recent versions to retain on compaction
+	compactRetain int
+	// compactFragmentation: the ratio of dead bytes to serialized bytes that triggers compaction, where 0 disables the fragmentation trigger
+	compactFragmentation float64
+	// liveBytes: the serialized size of every node and leaf reachable from the current root, maintained on each write and reset on compaction
+	liveBytes uint64
+	// growthFactor: the factor the memory map is multiplied by on each resize
+	growthFactor float64
+	// durability: when committed writes are flushed to disk
+	durability DurabilityLevel
+	// overflowThreshold: the length above which values are stored out of line, where 0 stores every value inline
+	overflowThreshold int
+	// flushInterval: the interval between timed flushes, or 0 if every write signals a flush
+	flushInterval time.Duration
+	// flushPending: set to 1 when a write has been committed since the last timed flush
+	flushPending uint32
+	// advise: the access pattern hinted to the operating system, which is reapplied whenever the memory map is remapped
+	advise uint32
+	// lockMemory: whether the memory map is locked in RAM, which is reapplied whenever the memory map is remapped
+	lockMemory bool
+	// valueCodec: the codec applied to values on write and read, or nil if values are stored as is
+	valueCodec MariValueCodec
+	// mergeFunc: the function combining an existing value with a merge operand, or nil if merges are not supported
+	mergeFunc MariMergeFunc
+	// onCommit: the hook called after each successful write transaction, or nil if there is none
+	onCommit MariCommitHook
+	// changeLog: the append only change log file, or nil if the change log is not enabled or the instance is read only
+	changeLog *os.File
+	// changeLogPath: the path of the change log file, or empty if the change log is not enabled
+	changeLogPath string
+	// changeLogSeq: the sequence number of the last entry appended to the change log
+	changeLogSeq uint64
+	// changeLogSize: the length of the change log up to the end of the last entry, which a failed append is truncated back to
+	changeLogSize int64
+	// changeLogLock: held from before a commit until its change log entry is appended, so entries are appended in commit order
+	changeLogLock sync.Mutex
+	// maxTxRetries: the maximum number of retries for a write transaction, where a negative value retries until success
+	maxTxRetries int
+	// txRetries: the total number of write transaction attempts that were discarded and retried
+	txRetries uint64
+	// metrics: the cumulative operation counters returned by Metrics, which are only accessed atomically
+	metrics MariMetrics
+	// inMemory: a flag to determine whether the memory map and version index are anonymous mappings with no backing files
+	inMemory bool
+	// readOnly: a flag to determine whether the file and version index are mapped read only and all writes are rejected
+	readOnly bool
+	// snapshots: the reference counted snapshots, keyed by the version they pin
+	snapshots map[uint64]*MariSnapshotRef
+	// snapshotLock: a mutex for registering and releasing snapshots
+	snapshotLock sync.Mutex
+	// watchers: the registered watchers that committed changes are delivered to
+	watchers map[*MariWatcher]bool
+	// watchLock: a mutex for registering, notifying, and cancelling watchers
+	watchLock sync.Mutex
+}
+
+// ChangeEntry is a committed write transaction recorded in the change log
+type ChangeEntry struct {
+	// Sequence: the position of the entry in the change log, which increases by one for every committed transaction, even across compactions
+	Sequence uint64
+	// Version: the version the transaction was committed as
+	Version uint64
+	// Puts: the key-value pairs written by the transaction, where only the last write of each key is kept
+	Puts []KeyValuePair
+	// Deletes: the keys deleted by the transaction, where the delete was the last write of the key
+	Deletes [][]byte
+}
+
+// MariWatcher is a subscription to the committed changes of every key beginning with a prefix
+type MariWatcher struct {
+	// prefix: only changes to keys beginning with the prefix are delivered
+	prefix []byte
+	// events: the buffered channel changes are delivered on, which is closed on cancel, on overflow, or when Mari is closed
+	events chan KeyValuePair
+}
+
+// MariSnapshot is a handle pinning a version of Mari, which compaction will not reclaim until the handle is closed
+type MariSnapshot struct {
+	// store: the Mari instance the snapshot was taken from
+	store *Mari
+	// ref: the shared reference count for the pinned version
+	ref *MariSnapshotRef
+	// closed: atomic flag indicating if the snapshot has been released
+	closed uint32
+}
+
+// MariSnapshotRef is the reference count shared by all snapshots pinning the same version
+type MariSnapshotRef struct {
+	// version: the pinned version, which is remapped when compaction renumbers versions
+	version uint64
+	// refs: the number of open snapshots pinning the version
+	refs int
 }
 
 // MariNodePool contains pre-allocated MariINodes/MariLNodes to improve performance so go garbage collection doesn't handle allocating/deallocating nodes on every op
 type MariNodePool struct {
-	// maxSize: the max size for the node pool
+	// maxSize: the max size for the node pool, which is adjusted at runtime if the pool auto tunes
 	maxSize int64
+	// minSize: the initial max size for the node pool, which an auto tuning pool never shrinks below
+	minSize int64
+	// ceiling: the largest max size an auto tuning pool can grow to, or 0 if the pool has a fixed size
+	ceiling int64
 	// size: the current number of allocated nodes in the node pool
 	size int64
+	// gets: the total number of internal and leaf nodes taken from the node pool
+	gets uint64
+	// puts: the total number of internal and leaf nodes recycled back into the node pool
+	puts uint64
+	// misses: the total number of gets where the node pool was empty, so a new node was allocated
+	misses uint64
+	// windowGets: the total number of gets counted towards auto tuning windows
+	windowGets uint64
+	// windowMisses: the number of misses in the current auto tuning window
+	windowMisses uint64
 	// iNodePool: the node pool that contains pre-allocated internal nodes
 	iNodePool *sync.Pool
 	// lNodePool: the node pool that contains pre-allocated leaf nodes
 	lNodePool *sync.Pool
 }
 
+// MariNodeCache is a bounded least recently used cache of deserialized internal nodes, keyed by the offset of the node in the memory map
+type MariNodeCache struct {
+	// lock: a mutex guarding the entries and their order, since every hit moves the entry to the front
+	lock sync.Mutex
+	// capacity: the maximum number of nodes held in the cache
+	capacity int
+	// entries: the element in the order list for each cached offset
+	entries map[uint64]*list.Element
+	// order: the cached nodes from most to least recently used
+	order *list.List
+}
+
+// MariNodeCacheEntry is a single cached node, along with the offset it was read from
+type MariNodeCacheEntry struct {
+	// offset: the start offset of the node in the memory map
+	offset uint64
+	// node: the deserialized node, including the value of its leaf, which is never handed out directly
+	node *MariINode
+}
+
 // MariTx represents a transaction on the store
 type MariTx struct {
 	// store: the mari instance to perform the transaction on
@@ -123,30 +328,341 @@ type MariTx struct {
 	root *unsafe.Pointer
 	// isWrite: determines whether the transaction is read only or read-write
 	isWrite bool
+	// changes: the puts and deletes made by a write transaction, in order, where deletes have a nil value
+	changes []KeyValuePair
+	// sequence: the change log sequence number the transaction is recorded under when applied from a leader, or 0 for the next sequence number
+	sequence uint64
+	// savepoints: the savepoints recorded by the transaction, indexed by the id returned from Savepoint
+	savepoints []MariSavepoint
+	// streams: the values put with PutReader, keyed by their leaf, which are copied from their readers into the file before the transaction commits
+	streams map[*MariLNode]*MariPendingStream
+	// done: closed once the transaction function returns, which stops any range chan traversals still running
+	done chan struct{}
+	// producers: the range chan traversals running for the transaction
+	producers sync.WaitGroup
+}
+
+// MariPendingStream is a value put with PutReader that has not been committed yet
+type MariPendingStream struct {
+	// key: the key the value is put with, which matches the value to its copy when the transaction retries
+	key []byte
+	// leaf: the leaf the value is referenced by
+	leaf *MariLNode
+	// reader: the reader the value is copied from
+	reader io.Reader
+	// offset: the offset in the memory map the value is written to, which is assigned when space is reserved for it
+	offset uint64
+	// epoch: the epoch of the file the value was copied into, since a compaction discards values that are not committed yet
+	epoch uint64
+	// length: the length of the value
+	length uint64
+	// change: the index of the put in the changes of the transaction
+	change int
+}
+
+// MariValueReader reads a value stored out of line through its own handle on the file, so it is unaffected by resizes and remains valid after the transaction ends
+type MariValueReader struct {
+	// file: the handle on the file the value is read from, which is closed with the reader
+	file *os.File
+	// epoch: the epoch of the file when the reader was opened, since a truncate reuses the space the value occupied
+	epoch uint64
+	// offset: the offset of the value in the file
+	offset uint64
+	// length: the length of the value
+	length uint64
+	// read: the number of bytes of the value read so far
+	read uint64
+	// closed: whether the reader has been closed
+	closed bool
+}
+
+// MariSavepoint is the state of a write transaction recorded by Savepoint, which RollbackTo restores
+type MariSavepoint struct {
+	// root: a copy of the root of the transaction, where every node of the path copy is copied so later writes do not modify it
+	root *MariINode
+	// changes: the number of changes recorded by the transaction when the savepoint was taken
+	changes int
 }
 
 // MariaCompactionStrategy is the function signature for custom compaction trigger
 type MariCompactionTrigger = func(metaData *MariMetaData) bool
 
+// MariCompactionProgressHook is called while a compaction writes the compacted copy with the bytes written so far and the estimated total, which is raised to the bytes written if it was an underestimate
+type MariCompactionProgressHook = func(written, total uint64)
+
+// MariCompactionCompleteHook is called after a compaction is swapped in with the size of the memory map before and after the compaction
+type MariCompactionCompleteHook = func(oldSize, newSize int64)
+
 // MariCompaction represents the compaction strategy for removing unused versions
 type MariCompaction struct {
-	// tempFile: the temporary file for compacting the db
+	// tempFile: the temporary file for compacting the db, which is nil for in memory instances
 	tempFile *os.File
 	// tempData: the temporary memory mapped file as byte slice
 	tempData atomic.Value
 	// compactedVersion: the version to compact at
 	compactedVersion uint64
+	// baseVersion: the oldest version retained by the compaction, which becomes version 0 in the compacted file
+	baseVersion uint64
+	// offsets: maps the offset of a node in the original file to its offset in the compacted file when multiple versions are retained
+	offsets map[uint64]uint64
+	// overflowOffsets: maps the offset of an overflow value in the original file to its offset in the compacted file when multiple versions are retained
+	overflowOffsets map[uint64]uint64
+	// growthFactor: the factor the temporary memory map is multiplied by on each resize
+	growthFactor float64
+	// onProgress: the hook called as the compacted copy is written, or nil if there is none
+	onProgress MariCompactionProgressHook
+	// estimatedTotal: the estimated total bytes of the compacted copy, taken from the live bytes when the compaction begins
+	estimatedTotal uint64
+	// reported: the bytes written when progress was last reported
+	reported uint64
+}
+
+// MariJSONPair is the JSON representation of a key-value pair used by ExportJSON, where the key and value are base64 encoded
+type MariJSONPair struct {
+	// Key: the key of the pair
+	Key []byte `json:"key"`
+	// Value: the value of the pair
+	Value []byte `json:"value"`
+	// Version: the version the pair was last written at
+	Version uint64 `json:"version"`
+}
+
+// MariStats is a point in time snapshot of the state of a Mari instance, returned by Stats
+type MariStats struct {
+	// Version: the current committed version
+	Version uint64
+	// FileSize: the size in bytes of the memory mapped file
+	FileSize uint64
+	// VersionIndexSize: the size in bytes of the version index
+	VersionIndexSize uint64
+	// KeyCount: the number of keys in the current version
+	KeyCount uint64
+	// Depth: the maximum level reached by any node in the current version, where the root is at level 0
+	Depth int
+	// LiveBytes: the total serialized size of every node and leaf reachable from the current root
+	LiveBytes uint64
+	// DeadBytes: the bytes of the memory mapped file that are not reachable from the current root, which can be reclaimed by compaction
+	DeadBytes uint64
+	// TxRetries: the total number of write transaction retries since the instance was opened
+	TxRetries uint64
 }
 
-// MariOpTransform is the function signature for transform functions, which modify results
+// MariMetrics contains the cumulative operation counters of a Mari instance since it was opened, returned by Metrics
+type MariMetrics struct {
+	// Puts: the total number of key-value pairs put, including puts in transactions that were retried or discarded
+	Puts uint64
+	// Gets: the total number of keys looked up, including lookups made by other operations
+	Gets uint64
+	// Deletes: the total number of keys deleted, including deletes in transactions that were retried or discarded
+	Deletes uint64
+	// CASRetries: the total number of write transaction retries, after losing the compare and swap on commit or waiting on a resize or compaction
+	CASRetries uint64
+	// Resizes: the total number of times the memory map was grown, including the initial allocation of a new file
+	Resizes uint64
+	// Compactions: the total number of completed compactions
+	Compactions uint64
+	// Flushes: the total number of times the memory mapped file was flushed to disk, either in the background or by Sync
+	Flushes uint64
+	// BytesWritten: the total number of serialized bytes written to the memory map by committed transactions
+	BytesWritten uint64
+	// NodeCacheHits: the total number of internal nodes read from the node cache instead of the memory map
+	NodeCacheHits uint64
+	// NodeCacheMisses: the total number of internal nodes read from the memory map while the node cache is enabled
+	NodeCacheMisses uint64
+}
+
+// AdvisePattern is the access pattern of the memory map hinted to the operating system with madvise
+type AdvisePattern uint32
+
+// DurabilityLevel determines when committed write transactions are flushed to disk
+type DurabilityLevel uint32
+
+// MariOpTransform is the function signature for transform functions, which modify results. Returning nil drops the result
 type MariOpTransform = func(kvPair *KeyValuePair) *KeyValuePair
 
+// MariMergeFunc combines the existing value for a key, which is nil if the key does not exist, with a merge operand into the new value
+type MariMergeFunc = func(existing, operand []byte) []byte
+
+// MariConflictResolver chooses the value to keep when a key from a source file passed to Merge already exists in the destination, returning nil to remove the key
+type MariConflictResolver = func(key, existing, incoming []byte) []byte
+
+// MariCommitHook is called after a write transaction commits with the committed version and the changes made in it, in order, where deletes have a nil value
+type MariCommitHook = func(version uint64, changed []KeyValuePair)
+
+// MariValueCodec transforms values as they are written to and read from Mari. The key is passed so a codec can bind the value to the key it is stored under
+type MariValueCodec interface {
+	// Encode: transform a value before it is written to the memory map
+	Encode(key, value []byte) ([]byte, error)
+	// Decode: reverse Encode on a value read from the memory map, returning an error if the stored value is invalid
+	Decode(key, stored []byte) ([]byte, error)
+}
+
+// MariEncryptionCodec is a value codec that encrypts values with AES-GCM, storing the random nonce in front of the ciphertext
+type MariEncryptionCodec struct {
+	// aead: the AES-GCM cipher used to seal and open values
+	aead cipher.AEAD
+}
+
+// KeyCodec converts typed keys to and from the bytes stored in Mari. The encoding should preserve the order of the keys so ranges over typed keys are ordered
+type KeyCodec[K any] struct {
+	// Encode: convert a key to bytes
+	Encode func(key K) ([]byte, error)
+	// Decode: convert bytes back to a key
+	Decode func(data []byte) (K, error)
+}
+
+// ValueCodec converts typed values to and from the bytes stored in Mari
+type ValueCodec[V any] struct {
+	// Encode: convert a value to bytes
+	Encode func(value V) ([]byte, error)
+	// Decode: convert bytes back to a value
+	Decode func(data []byte) (V, error)
+}
+
+// Entry is a typed key-value pair returned from a TypedStore
+type Entry[K, V any] struct {
+	// Version: the version the pair was written in
+	Version uint64
+	// Key: the decoded key
+	Key K
+	// Value: the decoded value
+	Value V
+}
+
+// TypedStore wraps Mari with codecs so keys and values are passed as typed values instead of bytes
+type TypedStore[K, V any] struct {
+	// store: the Mari instance the typed operations are performed on
+	store *Mari
+	// keyCodec: the codec for keys
+	keyCodec KeyCodec[K]
+	// valueCodec: the codec for values
+	valueCodec ValueCodec[V]
+}
+
+// MariCursor is a stateful, ascending cursor over a transaction, which lazily advances through the ordered array mapped trie
+type MariCursor struct {
+	// tx: the transaction the cursor was created in
+	tx *MariTx
+	// stack: the traversal stack, from the root to the node currently being visited
+	stack []*MariCursorFrame
+	// bound: the smallest key the cursor can return, set by the start key or the last seek
+	bound []byte
+	// closed: flag indicating if the cursor has been closed
+	closed bool
+}
+
+// MariCursorFrame is a single level of the traversal stack of a cursor
+type MariCursorFrame struct {
+	// node: the node being visited at this level
+	node *MariINode
+	// level: the level of the node in the trie
+	level int
+	// nextIdx: the next sparse index in the bitmap of the node to visit
+	nextIdx int
+	// buffered: leaves ready to be returned, in ascending order
+	buffered []*MariLNode
+	// carried: leaves with keys longer than the level of the node, grouped by the index of their next byte
+	carried map[byte][]*MariLNode
+	// bounded: whether or not the node is on the path of the bound
+	bounded bool
+	// owned: whether or not the node was read from the memory map by the cursor, and can be recycled
+	owned bool
+}
+
+// MariPathFrame is a single level of the path descended by a put, which is unwound once the key-value pair is placed
+type MariPathFrame struct {
+	// node: the pointer to the node at this level, which is swapped with the copy
+	node *unsafe.Pointer
+	// currNode: the node at this level before the put
+	currNode *MariINode
+	// nodeCopy: the path copy of the node at this level
+	nodeCopy *MariINode
+	// currCount: the subtree count of the node before the put
+	currCount uint64
+	// pos: the position in the child node array of the child that was descended into
+	pos int
+	// childPtr: the pointer to the child that was descended into
+	childPtr *unsafe.Pointer
+	// childCount: the subtree count of the child before the put
+	childCount uint64
+}
+
+// MariSerializeFrame is a node on the path copy waiting to be serialized
+type MariSerializeFrame struct {
+	// node: the node on the path copy
+	node *MariINode
+	// slot: the position in the serialized path of the offset of the node in its parent, or -1 for the root
+	slot int
+}
+
 // MariRangeOpts contains options for iteration and range functions
 type MariRangeOpts struct {
 	// MinVersion: the min version to return when performing the scan
 	MinVersion *uint64
 	// Transform: the transform function
 	Transform *MariOpTransform
+	// Reverse: return the results of a range in descending order. The start key must still be less than or equal to the end key
+	Reverse bool
+	// StartInclusive: whether or not the start key of a range is included in the results. By default the start key is inclusive
+	StartInclusive *bool
+	// EndInclusive: whether or not the end key of a range is included in the results. By default the end key is inclusive
+	EndInclusive *bool
+	// KeysOnly: skip reading and decoding values, so each key-value pair in the results has a nil value
+	KeysOnly bool
+	// Prefetch: hint to the operating system that the bytes spanning the subtrees within the range will be needed before the traversal begins, so cold scans over data that is not in the page cache read ahead instead of faulting in each node
+	Prefetch bool
+}
+
+// MariRangeBounds contains the resolved bound options for a range traversal
+type MariRangeBounds struct {
+	// startInclusive: whether or not the start key is included in the results
+	startInclusive bool
+	// endInclusive: whether or not the end key is included in the results
+	endInclusive bool
+	// reverse: whether or not the results are emitted in descending order
+	reverse bool
+	// keysOnly: whether or not child nodes are read without the value of their leaf
+	keysOnly bool
+	// scanCtx: the cancellation context of the scan, which is nil if the scan cannot be cancelled
+	scanCtx *MariScanContext
+}
+
+// MariRangePartition is one of the disjoint sub ranges of a parallel range scan, which is scanned by a single worker
+type MariRangePartition struct {
+	// startKey: the start key of the sub range, which is nil if the sub range is unbounded below
+	startKey []byte
+	// endKey: the end key of the sub range, which is nil if the sub range is unbounded above
+	endKey []byte
+	// bounds: the resolved bounds of the sub range
+	bounds MariRangeBounds
+}
+
+// MariScanContext tracks the cancellation context of a long running scan, which is checked periodically as nodes are visited
+type MariScanContext struct {
+	// ctx: the context of the scan
+	ctx context.Context
+	// visited: the total number of nodes visited by the scan
+	visited int
+}
+
+// MariSegment is a read only handle to an immutable, compressed, block based segment written from a version of Mari
+type MariSegment struct {
+	// file: the segment file
+	file *os.File
+	// index: the sparse index of the segment, containing the first key of each block
+	index []*MariSegmentBlock
+	// size: the size of the segment file, which every block must fit within
+	size uint64
+}
+
+// MariSegmentBlock is a single entry in the sparse index of a segment
+type MariSegmentBlock struct {
+	// firstKey: the smallest key contained in the block
+	firstKey []byte
+	// offset: the offset of the compressed block from the start of the segment file
+	offset uint64
+	// length: the length of the compressed block in bytes
+	length uint64
 }
 
 // DefaultPageSize is the default page size set by the underlying OS. Usually will be 4KiB
@@ -154,8 +670,61 @@ var DefaultPageSize = os.Getpagesize()
 
 // DefaultNodePoolSize is the max number of nodes in the node pool, and the pre-allocated node pool size
 const DefaultNodePoolSize = int64(1000000)
+
+// NodePoolTuneWindow is the number of gets from an auto tuning node pool between each adjustment of its max size
+const NodePoolTuneWindow = 10000
+
+// NodePoolGrowMissRate is the ratio of misses to gets within a window above which an auto tuning node pool doubles its max size
+const NodePoolGrowMissRate = 0.1
 //	MaxCompactVersion is the maximum default version to increment to before the compaction process
 const MaxCompactVersion = uint64(1000000)
+// DefaultFileName is the name of the file for the mari instance when no FileName is passed
+const DefaultFileName = "mari"
+// VersionIndexFileName is the suffix appended to the file name of the instance for the version index file
+const VersionIndexFileName = ".vidx"
+// InitVersionIndexSize is the initial size in bytes of the version index, which holds one 8 byte offset per version
+var InitVersionIndexSize = DefaultPageSize * 16
+// CompactTempFileName is the suffix appended to the file name of the instance for the file a compacted copy is built in
+const CompactTempFileName = "temp"
+// CompactProgressInterval is the number of bytes written to the compacted copy between each call to the compaction progress hook
+const CompactProgressInterval = 4 * 1024 * 1024
+// CompactSwapFileName is the suffix appended to the file name of the instance for the original file while a compacted copy is swapped in
+const CompactSwapFileName = "swap"
+// ChangeLogFileName is the suffix appended to the file name of the instance for the change log file
+const ChangeLogFileName = ".clog"
+// ChangeEntryHeaderSize is the size of the sequence, version, and the number of puts and deletes at the start of each change log entry
+const ChangeEntryHeaderSize = 24
+// WatchBufferSize is the number of changes buffered for each watcher before it overflows
+const WatchBufferSize = 1024
+
+// DefaultFileMode is the default permissions files are created with
+const DefaultFileMode = os.FileMode(0600)
+// DefaultGrowthFactor is the default factor the memory map is multiplied by on each resize
+const DefaultGrowthFactor = 2.0
+// FragmentationMinSize is the minimum number of serialized bytes before the fragmentation trigger will compact, so small instances are not compacted repeatedly
+const FragmentationMinSize = uint64(1 << 22)
+// TxBackoffSpins is the number of retries of a write transaction that only yield the processor before the backoff begins sleeping
+const TxBackoffSpins = 4
+// TxBackoffMax is the longest sleep between retries of a write transaction
+const TxBackoffMax = time.Millisecond
+// ErrorsBufferSize is the number of background errors buffered before new errors are dropped
+const ErrorsBufferSize = 100
+// MaxIndexForLevel is the largest sparse index within the 256 bit bitmap of a node
+const MaxIndexForLevel = 255
+// EmptyKeyLength is the key length serialized for a leaf holding the empty key, so it is not mistaken for a leaf without a key
+const EmptyKeyLength = 0xFFFF
+// MaxKeyLength is the longest key that can be stored, since the key length of a leaf is serialized as a uint16 and EmptyKeyLength is reserved
+const MaxKeyLength = EmptyKeyLength - 1
+// LeafOverflowFlag is set on the serialized version of a leaf whose value is stored out of line
+const LeafOverflowFlag = uint64(1) << 63
+// ScanContextCheckInterval is the number of nodes visited by a cancellable scan between checks of the context
+const ScanContextCheckInterval = 1000
+// ImportBatchSize is the number of pairs written per transaction by ImportJSON
+const ImportBatchSize = 10000
+// BackupMagic identifies the start of a backup stream written by Backup
+const BackupMagic = "maribkup"
+// FileFormatMagic identifies the layout of the memory mapped file, and is written to its header directly after the epoch
+const FileFormatMagic = "marifmt1"
 
 const (
 	// Index of Mari Version in serialized metadata
@@ -164,6 +733,12 @@ const (
 	MetaRootOffsetIdx = 8
 	// Index of Node Version in serialized node
 	MetaEndSerializedOffset = 16
+	// Index of the identity of the file in serialized metadata, which is shared with its version index
+	MetaIdentityIdx = 24
+	// Index of the epoch of the file in serialized metadata, which increments each time the version index is reset
+	MetaEpochIdx = 32
+	// Index of the format marker of the file in serialized metadata
+	MetaFormatIdx = 40
 	// The current node version index in serialized node
 	NodeVersionIdx = 0
 	// Index of StartOffset in serialized node
@@ -180,16 +755,54 @@ const (
 	NodeKeyLength = 24
 	// Index of Key in serialized leaf node node
 	NodeKeyIdx = 26
+	// Size of the reference to an overflow value, which is serialized in place of the value (offset, length)
+	NodeOverflowRefSize = 16
 	// OffsetSize for uint64 in serialized node
 	OffsetSize = 8
 	// Bitmap size in bytes since bitmap sis uint32
 	BitmapSize = 4
 	// Size of child pointers, where the pointers are uint64 offsets in the memory map
 	NodeChildPtrSize = 8
+	// Size of the subtree count, which is serialized directly after the child pointers
+	NodeCountSize = 8
 	// Offset for the first version of root on Mari initialization
-	InitRootOffset = 24
+	InitRootOffset = 48
+	// Index of the identity in the version index header
+	VersionIndexIdentityIdx = 0
+	// Index of the epoch in the version index header
+	VersionIndexEpochIdx = 8
+	// Size of the version index header, which holds the identity and epoch of the file it indexes, before the offset of each version
+	VersionIndexHeaderSize = 16
 	// 1 GB MaxResize
 	MaxResize = 1000000000
+	// Size of the segment footer, which contains the index offset and the total number of blocks
+	SegmentFooterSize = 16
+	// Size of the fixed length header of an entry in a segment block (version, key length, value length)
+	SegmentEntryHeaderSize = 14
+	// Size of the backup header, which contains the backup magic and the version that was backed up
+	BackupHeaderSize = 16
+	// Size of the backup trailer, which contains the root offset and the end of the serialized data
+	BackupTrailerSize = 16
+)
+
+const (
+	// AdviseNormal: no hint, leaving readahead to the defaults of the operating system
+	AdviseNormal AdvisePattern = iota
+	// AdviseSequential: pages are accessed in order, so readahead is aggressive, which suits large Range and Iterate scans
+	AdviseSequential
+	// AdviseRandom: pages are accessed in no particular order, so readahead is disabled, which suits point Gets
+	AdviseRandom
+	// AdviseWillNeed: the mapping will be accessed soon, so pages are read in ahead of the access
+	AdviseWillNeed
+)
+
+const (
+	// DurabilityAsync: each commit signals the flush go routine, which flushes to disk in the background, so a crash can lose the writes not yet flushed
+	DurabilityAsync DurabilityLevel = iota
+	// DurabilityNoSync: writes are never explicitly flushed while the instance is open, leaving dirty pages to be written back by the operating system
+	DurabilityNoSync
+	// DurabilitySync: each commit is flushed to disk before the write transaction returns, so every successful write transaction is durable. If the flush fails after the commit, the error returned wraps ErrNotSynced
+	DurabilitySync
 )
 
 const (
@@ -217,14 +830,19 @@ const (
 		0 Version - 8 bytes
 		8 RootOffset - 8 bytes
 		16 EndMmapOffset - 8 bytes
+		24 Identity - 8 bytes
+		32 Epoch - 8 bytes
+		40 FormatMagic - 8 bytes, the layout of the file
+		48 Root of the first version
 
-	[0-7, 8-15, 16-23, 24-27, 28, 29-92, 93+]
+	[0-7, 8-15, 16-23, 24-25, 26+]
 	Node (Leaf):
-		0 Version - 8 bytes
+		0 Version - 8 bytes, the high bit is set when the value is stored out of line
 		8 StartOffset - 8 bytes
 		16 EndOffset - 8 bytes
 		24 KeyLength - 2 bytes, size of the key
 		26 Key - variable length
+		26 + KeyLength Value - variable length, or the offset and length of the value when it is stored out of line - 16 bytes
 
 
 	Node (Internal):
@@ -235,4 +853,5 @@ const (
 		56 LeafOffset - 8 bytes
 		64 Children -->
 			every child will then be 8 bytes, up to 256 * 8 = 2048 bytes
+		64 + 8 * Children Count - 8 bytes, the number of keys in the subtree
 */
\ No newline at end of file
@@ -1,7 +1,9 @@
 package mari
 
 import "fmt"
+import "io"
 import "math/bits"
+import "os"
 
 
 
@@ -9,19 +11,26 @@ import "math/bits"
 
 
 // Print children
-//	Debugging function for printing nodes in the ordered array mapped trie.
+//	Debugging function for printing nodes in the ordered array mapped trie to stdout.
 func (mariInst *Mari) PrintChildren() error {
+	return mariInst.DumpTree(os.Stdout)
+}
+
+// DumpTree
+//	Debugging function for writing the nodes in the ordered array mapped trie to a writer, so the output can be captured in tests and tools.
+//	Each node below the root is written on its own line with its level, index in the child array of its parent, key, value, and version, followed by the total count of elements.
+func (mariInst *Mari) DumpTree(w io.Writer) error {
 	_, rootOffset, readRootOffErr := mariInst.loadMetaRootOffset()
 	if readRootOffErr != nil { return readRootOffErr }
 
 	currRoot, readRootErr := mariInst.readINodeFromMemMap(rootOffset)
 	if readRootErr != nil { return readRootErr }
 	
-	totalCount, readChildrenErr := mariInst.printChildrenRecursive(currRoot, 0, 0)
+	totalCount, readChildrenErr := mariInst.dumpTreeRecursive(w, currRoot, 0, 0)
 	if readChildrenErr != nil { return readChildrenErr }
 
-	fmt.Println("total count of elements:", totalCount)
-	return nil
+	_, writeErr := fmt.Fprintln(w, "total count of elements:", totalCount)
+	return writeErr
 }
 
 // calculateHammingWeight
@@ -61,7 +70,7 @@ func getPosition(bitMap [8]uint32, index byte, level int) int {
 	indexInSubBitmap := index & 0x1F
 	precedingSubBitmapsCount := 0
 	
-	if subBitmapIndex - 1 > 0 {
+	if subBitmapIndex > 0 {
 		switch subBitmapIndex - 1 {
 			case 6:
 				precedingSubBitmapsCount += calculateHammingWeight(bitMap[6])
@@ -121,6 +130,19 @@ func populationCount(bitmap [8]uint32) int {
 	return popCount
 }
 
+// prefixEndKey
+//	Determine the smallest key that is larger than every key beginning with the prefix, by stripping trailing 0xFF bytes and incrementing the last remaining byte.
+//	If the prefix is empty or only contains 0xFF bytes, no such key exists and nil is returned, meaning the range is unbounded above.
+func prefixEndKey(prefix []byte) []byte {
+	endKey := append([]byte{}, prefix...)
+
+	for len(endKey) > 0 && endKey[len(endKey) - 1] == 0xFF { endKey = endKey[:len(endKey) - 1] }
+	if len(endKey) == 0 { return nil }
+
+	endKey[len(endKey) - 1]++
+	return endKey
+}
+
 // setBit
 //	Performs a logical xor operation on the current bitmap and the a 32 bit value where the value is all 0s except for at the position of the incoming index.
 //	Essentially flips the bit if incoming is 1 and bitmap is 0 at that position, or 0 to 1. 
@@ -146,9 +168,9 @@ func shrinkTable(orig []*MariINode, bitmap [8]uint32, pos int) []*MariINode {
 	return newTable
 }
 
-// printChildrenRecursive
-//	Recursively print nodes in the mariInst as we traverse down levels.
-func (mariInst *Mari) printChildrenRecursive(node *MariINode, totalCount, level int) (int, error) {
+// dumpTreeRecursive
+//	Recursively write nodes in the mariInst to the writer as we traverse down levels.
+func (mariInst *Mari) dumpTreeRecursive(w io.Writer, node *MariINode, totalCount, level int) (int, error) {
 	if node == nil { return 0, nil }
 
 	for idx := range node.children {
@@ -157,10 +179,15 @@ func (mariInst *Mari) printChildrenRecursive(node *MariINode, totalCount, level
 		if desErr != nil { return 0, desErr }
 
 		if child != nil {
-			if len(child.leaf.key) > 0 { totalCount += 1 }
+			if hasKey(child.leaf) { totalCount += 1 }
+
+			value, resolveErr := mariInst.resolveValue(child.leaf)
+			if resolveErr != nil { return 0, resolveErr }
+
+			_, writeErr := fmt.Fprintf(w, "Level: %d, Index: %d, Key: %s, Value: %s, Version:%d\n", level + 1, idx, child.leaf.key, value, child.leaf.version)
+			if writeErr != nil { return 0, writeErr }
 
-			fmt.Printf("Level: %d, Index: %d, Key: %s, Value: %s, Version:%d\n", level + 1, idx, child.leaf.key, child.leaf.value, child.leaf.version)
-			newtotalCount, printErr := mariInst.printChildrenRecursive(child, totalCount, level + 1)
+			newtotalCount, printErr := mariInst.dumpTreeRecursive(w, child, totalCount, level + 1)
 			if printErr != nil { return 0, printErr }
 
 			totalCount = newtotalCount
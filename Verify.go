@@ -0,0 +1,153 @@
+package mari
+
+import "bytes"
+import "fmt"
+import "math"
+import "runtime"
+import "sync/atomic"
+
+
+//============================================= Mari Verify
+
+
+// VerifyIntegrity
+//	Walk every node in the current version of Mari, deserializing each node and leaf and checking that the serialized structure is consistent.
+//	The first inconsistency found is returned, along with the offset of the offending node, and nil is returned if the structure is intact.
+//	This is a diagnostic tool for suspected corruption of the memory mapped file, so every node is read even though it can be expensive on large instances.
+func (mariInst *Mari) VerifyIntegrity() error {
+	remapErr := mariInst.remapReadOnly()
+	if remapErr != nil { return remapErr }
+
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return fmt.Errorf("attempting to verify: %w", ErrClosed) }
+
+	_, version, loadVErr := mariInst.loadMetaVersion()
+	if loadVErr != nil { return loadVErr }
+
+	_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+	if loadROffErr != nil { return loadROffErr }
+
+	_, endOffset, loadEndOffErr := mariInst.loadMetaEndSerialized()
+	if loadEndOffErr != nil { return loadEndOffErr }
+
+	mMap := mariInst.data.Load().(MMap)
+	if endOffset > uint64(len(mMap)) { return fmt.Errorf("end of serialized data %d is beyond the memory map of size %d: %w", endOffset, len(mMap), ErrCorruptNode) }
+
+	meta := &MariMetaData{ version: version, rootOffset: rootOffset, nextStartOffset: endOffset }
+
+	_, verifyErr := mariInst.verifyRecursive(rootOffset, nil, meta)
+	return verifyErr
+}
+
+// recoverCorruptRoot
+//	If the root of the current version cannot be read, walk the version index backward to the most recent version whose root can be, and adopt it as the current version.
+//	The metadata is rewritten to point to the recovered root, while the end of the serialized data is kept so new writes are appended after the corrupt data instead of over it.
+//	The discarded versions are cleared from the version index, and how many were discarded is reported on the errors channel, wrapping ErrCorruptNode.
+//	If no previous version has a readable root, an error is returned.
+func (mariInst *Mari) recoverCorruptRoot() error {
+	_, version, loadVErr := mariInst.loadMetaVersion()
+	if loadVErr != nil { return loadVErr }
+
+	_, rootOffset, loadROffErr := mariInst.loadMetaRootOffset()
+	if loadROffErr != nil { return loadROffErr }
+
+	_, endOffset, loadEndOffErr := mariInst.loadMetaEndSerialized()
+	if loadEndOffErr != nil { return loadEndOffErr }
+
+	if mariInst.isReadableRoot(version, rootOffset, endOffset) { return nil }
+
+	for recovered := version; recovered > 0; {
+		recovered--
+
+		offset, loadOffErr := mariInst.loadStartOffset(recovered)
+		if loadOffErr != nil { return loadOffErr }
+		if offset == 0 || ! mariInst.isReadableRoot(recovered, offset, endOffset) { continue }
+
+		recoveredMeta := &MariMetaData{ version: recovered, rootOffset: offset, nextStartOffset: endOffset }
+		_, writeMetaErr := mariInst.writeMetaToMemMap(recoveredMeta.serializeMetaData())
+		if writeMetaErr != nil { return writeMetaErr }
+
+		for discarded := recovered + 1; discarded <= version; discarded++ {
+			storeOffsetErr := mariInst.storeStartOffset(discarded, 0)
+			if storeOffsetErr != nil { return storeOffsetErr }
+		}
+
+		mariInst.reportError(fmt.Errorf("root of version %d is corrupt, recovered version %d and discarded %d versions: %w", version, recovered, version - recovered, ErrCorruptNode))
+		return nil
+	}
+
+	return fmt.Errorf("root of version %d is corrupt and no previous version has a readable root: %w", version, ErrCorruptNode)
+}
+
+// isReadableRoot
+//	Determine whether the root of a version deserializes cleanly at its offset, with a matching start offset and version, and ends within the serialized data.
+func (mariInst *Mari) isReadableRoot(version, offset, endOffset uint64) bool {
+	if offset < uint64(InitRootOffset) || offset >= endOffset { return false }
+
+	root, readErr := mariInst.readINodeFromMemMap(offset)
+	if readErr != nil { return false }
+
+	return root.startOffset == offset && root.version == version && root.leaf.endOffset < endOffset
+}
+
+// verifyRecursive
+//	Verify the node at offset and every node in its subtree, where path is the sequence of indexes taken from the root to reach the node.
+//	For each node, the serialized offsets must be within the serialized data, an overflow value must not overlap its leaf, the size of the node must match the population count of its bitmap, the leaf must directly follow the node, and the key of the leaf must begin with the path to the node.
+//	If the node contains a subtree count, it must match the number of keys found in the subtree.
+//	Returns the number of keys in the subtree.
+func (mariInst *Mari) verifyRecursive(offset uint64, path []byte, meta *MariMetaData) (uint64, error) {
+	if len(path) > math.MaxUint16 { return 0, fmt.Errorf("node at offset %d is deeper than the maximum key length: %w", offset, ErrCorruptNode) }
+
+	if offset < uint64(InitRootOffset) || offset + NodeChildrenIdx > meta.nextStartOffset {
+		return 0, fmt.Errorf("node offset %d is outside of the serialized data: %w", offset, ErrCorruptNode)
+	}
+
+	node, readErr := mariInst.readINodeFromMemMap(offset)
+	if readErr != nil { return 0, fmt.Errorf("unable to deserialize node at offset %d: %w", offset, readErr) }
+
+	if node.startOffset != offset { return 0, fmt.Errorf("node at offset %d has mismatched start offset %d: %w", offset, node.startOffset, ErrCorruptNode) }
+	if node.version > meta.version { return 0, fmt.Errorf("node at offset %d has version %d, which is newer than the current version %d: %w", offset, node.version, meta.version, ErrCorruptNode) }
+	if node.endOffset >= meta.nextStartOffset { return 0, fmt.Errorf("node at offset %d ends at %d, outside of the serialized data: %w", offset, node.endOffset, ErrCorruptNode) }
+
+	nodeSize := node.endOffset - node.startOffset + 1
+	expectedSize := uint64(NodeChildrenIdx + populationCount(node.bitmap) * NodeChildPtrSize)
+	if nodeSize != expectedSize && nodeSize != expectedSize + NodeCountSize {
+		return 0, fmt.Errorf("node at offset %d has a bitmap population count of %d, which does not match its serialized size %d: %w", offset, populationCount(node.bitmap), nodeSize, ErrCorruptNode)
+	}
+
+	leaf := node.leaf
+	if leaf.startOffset != node.endOffset + 1 { return 0, fmt.Errorf("leaf of node at offset %d does not directly follow the node: %w", offset, ErrCorruptNode) }
+	if leaf.endOffset >= meta.nextStartOffset { return 0, fmt.Errorf("leaf at offset %d ends at %d, outside of the serialized data: %w", leaf.startOffset, leaf.endOffset, ErrCorruptNode) }
+	if isOverflow(leaf) && (leaf.overflowOffset < uint64(InitRootOffset) || leaf.overflowOffset + leaf.overflowLength > meta.nextStartOffset || (leaf.overflowOffset <= leaf.endOffset && leaf.overflowOffset + leaf.overflowLength > leaf.startOffset)) {
+		return 0, fmt.Errorf("leaf at offset %d has an overflow value at %d of length %d, outside of the serialized data: %w", leaf.startOffset, leaf.overflowOffset, leaf.overflowLength, ErrCorruptNode)
+	}
+
+	if leaf.version > meta.version { return 0, fmt.Errorf("leaf at offset %d has version %d, which is newer than the current version %d: %w", leaf.startOffset, leaf.version, meta.version, ErrCorruptNode) }
+	if int(leaf.keyLength) != len(leaf.key) { return 0, fmt.Errorf("leaf at offset %d has key length %d, but only %d key bytes: %w", leaf.startOffset, leaf.keyLength, len(leaf.key), ErrCorruptNode) }
+	if hasKey(leaf) && ! bytes.HasPrefix(leaf.key, path) { return 0, fmt.Errorf("leaf at offset %d has a key that does not match the path to its node: %w", leaf.startOffset, ErrCorruptNode) }
+
+	count := uint64(leafCount(leaf))
+	childPath := append(append([]byte{}, path...), 0)
+
+	var pos int
+	for index := 0; index <= MaxIndexForLevel; index++ {
+		if ! isBitSet(node.bitmap, byte(index)) { continue }
+
+		childPath[len(path)] = byte(index)
+		childCount, verifyErr := mariInst.verifyRecursive(node.children[pos].startOffset, childPath, meta)
+		if verifyErr != nil { return 0, verifyErr }
+
+		count += childCount
+		pos++
+	}
+
+	if node.hasCount && node.count != count {
+		return 0, fmt.Errorf("node at offset %d has a subtree count of %d, but %d keys were found: %w", offset, node.count, count, ErrCorruptNode)
+	}
+
+	return count, nil
+}
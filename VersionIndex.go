@@ -0,0 +1,275 @@
+package mari
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "os"
+import "runtime"
+import "sync/atomic"
+import "unsafe"
+
+
+//============================================= Mari Version Index
+
+
+// ListVersions
+//	Scan the version index and return every version that can still be resolved, in ascending order.
+//	A version is resolvable if its stored root offset is non-zero, so versions discarded by compaction are omitted.
+func (mariInst *Mari) ListVersions() ([]uint64, error) {
+	for atomic.LoadUint32(&mariInst.isResizing) == 1 { runtime.Gosched() }
+
+	mariInst.rwResizeLock.RLock()
+	defer mariInst.rwResizeLock.RUnlock()
+
+	if ! mariInst.opened { return nil, fmt.Errorf("attempting to list versions: %w", ErrClosed) }
+
+	_, currVersion, loadVErr := mariInst.loadMetaVersion()
+	if loadVErr != nil { return nil, loadVErr }
+
+	var versions []uint64
+	for version := uint64(0); version <= currVersion; version++ {
+		offset, loadOffErr := mariInst.loadStartOffset(version)
+		if loadOffErr != nil { return nil, loadOffErr }
+		if offset != 0 { versions = append(versions, version) }
+	}
+
+	return versions, nil
+}
+
+// checkVersionIndex
+//	Verify that the version index belongs to the memory mapped file, by matching the identity and epoch in both headers, and the offset indexed for the current version against the root offset in the metadata.
+//	If only one of the two files is restored from a backup, the version index would otherwise resolve versions to offsets pointing into unrelated data.
+func (mariInst *Mari) checkVersionIndex(version, rootOffset uint64) error {
+	identity, epoch, loadIdErr := mariInst.loadMetaIdentity()
+	if loadIdErr != nil { return loadIdErr }
+
+	vIdxIdentity, vIdxEpoch, loadVIdxIdErr := mariInst.loadVersionIndexIdentity()
+	if loadVIdxIdErr != nil { return loadVIdxIdErr }
+
+	if identity != vIdxIdentity || epoch != vIdxEpoch {
+		return fmt.Errorf("version index has identity %x at epoch %d, but the file has identity %x at epoch %d, open with RebuildVersionIndex to rebuild it: %w", vIdxIdentity, vIdxEpoch, identity, epoch, ErrVersionIndexMismatch)
+	}
+
+	offset, loadOffErr := mariInst.loadStartOffset(version)
+	if loadOffErr != nil { return loadOffErr }
+	if offset != 0 && offset != rootOffset { return fmt.Errorf("version index resolves version %d to offset %d, but the root of the file is at offset %d, open with RebuildVersionIndex to rebuild it: %w", version, offset, rootOffset, ErrVersionIndexMismatch) }
+
+	return nil
+}
+
+// loadVersionIndexIdentity
+//	Get the identity and epoch of the file the version index belongs to from its header.
+func (mariInst *Mari) loadVersionIndexIdentity() (identity uint64, epoch uint64, err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			identity = 0
+			epoch = 0
+			err = errors.New("error getting identity from version index")
+		}
+	}()
+
+	mariInst.vIdxLock.RLock()
+	defer mariInst.vIdxLock.RUnlock()
+
+	vIdx := mariInst.vIdx.Load().(MMap)
+	identity = binary.LittleEndian.Uint64(vIdx[VersionIndexIdentityIdx:VersionIndexEpochIdx])
+	epoch = binary.LittleEndian.Uint64(vIdx[VersionIndexEpochIdx:VersionIndexHeaderSize])
+
+	return identity, epoch, nil
+}
+
+// openVersionIndex
+//	Open the version index file associated with the instance and map it into memory, or map anonymous memory for in memory instances.
+//	If the file is new, it is truncated to the initial version index size and true is returned.
+func (mariInst *Mari) openVersionIndex(fileWithFilePath string) (bool, error) {
+	if mariInst.inMemory {
+		vIdx, mmapErr := MapAnon(InitVersionIndexSize)
+		if mmapErr != nil { return false, mmapErr }
+
+		mariInst.vIdx.Store(vIdx)
+		return true, nil
+	}
+
+	flag := os.O_RDWR | os.O_CREATE
+	if mariInst.readOnly { flag = os.O_RDONLY }
+
+	var openVIdxErr error
+	mariInst.versionIndex, openVIdxErr = os.OpenFile(fileWithFilePath + VersionIndexFileName, flag, mariInst.fileMode)
+	if openVIdxErr != nil { return false, openVIdxErr }
+
+	mariInst.vIdx.Store(MMap{})
+	if mariInst.readOnly { return false, mariInst.mMapVersionIndex() }
+
+	lockErr := lockFile(mariInst.versionIndex)
+	if lockErr != nil {
+		mariInst.versionIndex.Close()
+		return false, lockErr
+	}
+
+	stat, statErr := mariInst.versionIndex.Stat()
+	if statErr != nil { return false, statErr }
+
+	isNew := stat.Size() == 0
+	if isNew {
+		truncateErr := mariInst.versionIndex.Truncate(int64(InitVersionIndexSize))
+		if truncateErr != nil { return false, truncateErr }
+	}
+
+	return isNew, mariInst.mMapVersionIndex()
+}
+
+// closeVersionIndex
+//	Flush and unmap the version index and close the file.
+func (mariInst *Mari) closeVersionIndex() error {
+	mariInst.vIdxLock.Lock()
+	defer mariInst.vIdxLock.Unlock()
+
+	if mariInst.inMemory { return mariInst.munmapVersionIndex() }
+	if mariInst.readOnly {
+		unmapErr := mariInst.munmapVersionIndex()
+		if unmapErr != nil { return unmapErr }
+
+		return mariInst.versionIndex.Close()
+	}
+
+	flushErr := mariInst.versionIndex.Sync()
+	if flushErr != nil { return flushErr }
+
+	unmapErr := mariInst.munmapVersionIndex()
+	if unmapErr != nil { return unmapErr }
+
+	return mariInst.versionIndex.Close()
+}
+
+// loadStartOffset
+//	Load the offset of the root for a particular version from the version index.
+//	A zero offset indicates that the version has not been written or is no longer resolvable.
+func (mariInst *Mari) loadStartOffset(version uint64) (offset uint64, err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			offset = 0
+			err = errors.New("error loading start offset from version index")
+		}
+	}()
+
+	mariInst.vIdxLock.RLock()
+	defer mariInst.vIdxLock.RUnlock()
+
+	vIdx := mariInst.vIdx.Load().(MMap)
+	if VersionIndexHeaderSize + (version + 1) * OffsetSize > uint64(len(vIdx)) { return 0, nil }
+
+	offsetPtr := (*uint64)(unsafe.Pointer(&vIdx[VersionIndexHeaderSize + version * OffsetSize]))
+	return atomic.LoadUint64(offsetPtr), nil
+}
+
+// storeStartOffset
+//	Store the offset of the root for a particular version in the version index.
+//	If the version is beyond the capacity of the version index, the index is resized first.
+func (mariInst *Mari) storeStartOffset(version, offset uint64) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil { err = errors.New("error storing start offset in version index") }
+	}()
+
+	mariInst.vIdxLock.Lock()
+	defer mariInst.vIdxLock.Unlock()
+
+	endOfVersion := VersionIndexHeaderSize + (version + 1) * OffsetSize
+	for endOfVersion > uint64(len(mariInst.vIdx.Load().(MMap))) {
+		resizeErr := mariInst.resizeVersionIndex()
+		if resizeErr != nil { return resizeErr }
+	}
+
+	vIdx := mariInst.vIdx.Load().(MMap)
+	offsetPtr := (*uint64)(unsafe.Pointer(&vIdx[VersionIndexHeaderSize + version * OffsetSize]))
+	atomic.StoreUint64(offsetPtr, offset)
+
+	return nil
+}
+
+// resetVersionIndex
+//	Clear every offset in the version index, which is performed on compaction since all previous versions are discarded.
+//	The header is set to the identity and epoch of the memory mapped file, so the version index matches the file on the next open.
+func (mariInst *Mari) resetVersionIndex() error {
+	identity, epoch, loadIdErr := mariInst.loadMetaIdentity()
+	if loadIdErr != nil { return loadIdErr }
+
+	mariInst.vIdxLock.Lock()
+	defer mariInst.vIdxLock.Unlock()
+
+	vIdx := mariInst.vIdx.Load().(MMap)
+	for idx := range vIdx { vIdx[idx] = 0 }
+
+	binary.LittleEndian.PutUint64(vIdx[VersionIndexIdentityIdx:VersionIndexEpochIdx], identity)
+	binary.LittleEndian.PutUint64(vIdx[VersionIndexEpochIdx:VersionIndexHeaderSize], epoch)
+
+	if mariInst.inMemory { return nil }
+	return vIdx.Flush()
+}
+
+// resizeVersionIndex
+//	Dynamically resizes the version index as versions accumulate.
+//	Follows the same strategy as resizeMmap, doubling the index on each resize until 1GB.
+//	The caller must hold the version index write lock.
+func (mariInst *Mari) resizeVersionIndex() error {
+	vIdx := mariInst.vIdx.Load().(MMap)
+
+	allocateSize := func() int64 {
+		switch {
+			case len(vIdx) == 0:
+				return int64(InitVersionIndexSize)
+			case len(vIdx) >= MaxResize:
+				return int64(len(vIdx) + MaxResize)
+			default:
+				return int64(len(vIdx) * 2)
+		}
+	}()
+
+	if mariInst.inMemory {
+		remapped, remapErr := remapAnon(vIdx, int(allocateSize))
+		if remapErr != nil { return remapErr }
+
+		mariInst.vIdx.Store(remapped)
+		return nil
+	}
+
+	if len(vIdx) > 0 {
+		flushErr := mariInst.versionIndex.Sync()
+		if flushErr != nil { return flushErr }
+
+		unmapErr := mariInst.munmapVersionIndex()
+		if unmapErr != nil { return unmapErr }
+	}
+
+	truncateErr := mariInst.versionIndex.Truncate(allocateSize)
+	if truncateErr != nil { return truncateErr }
+
+	return mariInst.mMapVersionIndex()
+}
+
+// mMapVersionIndex
+//	Helper to memory map the version index file in to buffer.
+func (mariInst *Mari) mMapVersionIndex() error {
+	prot := RDWR
+	if mariInst.readOnly { prot = RDONLY }
+
+	vIdx, mmapErr := Map(mariInst.versionIndex, prot, 0)
+	if mmapErr != nil { return mmapErr }
+
+	mariInst.vIdx.Store(vIdx)
+
+	return nil
+}
+
+// munmapVersionIndex
+//	Unmaps the version index from RAM.
+func (mariInst *Mari) munmapVersionIndex() error {
+	vIdx := mariInst.vIdx.Load().(MMap)
+	unmapErr := vIdx.Unmap()
+	if unmapErr != nil { return unmapErr }
+
+	mariInst.vIdx.Store(MMap{})
+	return nil
+}
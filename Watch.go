@@ -0,0 +1,74 @@
+package mari
+
+import "bytes"
+
+
+//============================================= Mari Watch
+
+
+// Watch
+//	Subscribe to every committed put and delete of a key beginning with the prefix, where a deleted key is delivered with a nil value.
+//	Sends never block the writer, so if the buffer is full the channel is closed, and the consumer should re-read the keys it watches.
+//	The returned cancel function stops the subscription and closes the channel, and the channel is also closed when Mari is closed.
+func (mariInst *Mari) Watch(prefix []byte) (<-chan KeyValuePair, func()) {
+	watcher := &MariWatcher{
+		prefix: append([]byte{}, prefix...),
+		events: make(chan KeyValuePair, WatchBufferSize),
+	}
+
+	mariInst.watchLock.Lock()
+	mariInst.watchers[watcher] = true
+	mariInst.watchLock.Unlock()
+
+	cancel := func() {
+		mariInst.watchLock.Lock()
+		defer mariInst.watchLock.Unlock()
+
+		mariInst.removeWatcher(watcher)
+	}
+
+	return watcher.events, cancel
+}
+
+// notifyWatchers
+//	Deliver the changes of a committed transaction to every watcher with a matching prefix.
+//	Watchers whose buffer is full are removed and their channel is closed.
+func (mariInst *Mari) notifyWatchers(changes []KeyValuePair) {
+	if len(changes) == 0 { return }
+
+	mariInst.watchLock.Lock()
+	defer mariInst.watchLock.Unlock()
+
+	for watcher := range mariInst.watchers {
+		for _, change := range changes {
+			if ! bytes.HasPrefix(change.Key, watcher.prefix) { continue }
+
+			select {
+				case watcher.events <- change:
+				default:
+					mariInst.removeWatcher(watcher)
+			}
+
+			if ! mariInst.watchers[watcher] { break }
+		}
+	}
+}
+
+// closeWatchers
+//	Remove every watcher and close its channel when Mari is closed.
+func (mariInst *Mari) closeWatchers() {
+	mariInst.watchLock.Lock()
+	defer mariInst.watchLock.Unlock()
+
+	for watcher := range mariInst.watchers { mariInst.removeWatcher(watcher) }
+}
+
+// removeWatcher
+//	Unregister a watcher and close its channel if it is still registered, so cancelling after an overflow or close is a no-op.
+//	The watch lock must be held by the caller.
+func (mariInst *Mari) removeWatcher(watcher *MariWatcher) {
+	if ! mariInst.watchers[watcher] { return }
+
+	delete(mariInst.watchers, watcher)
+	close(watcher.events)
+}
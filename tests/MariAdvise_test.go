@@ -0,0 +1,70 @@
+package maritests
+
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariAdvise(t *testing.T) {
+	t.Run("Test Mari Advise", func(t *testing.T) {
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, Advise: mari.AdviseWillNeed + 1 })
+		if invalidErr == nil { t.Errorf("expected error opening with an unknown advise pattern") }
+
+		adviseInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, Advise: mari.AdviseSequential, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening advise instance: %s", openErr.Error()) }
+		defer adviseInst.Close()
+
+		updateErr := adviseInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("large"), make([]byte, RESIZE_VALUE_SIZE))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+		if adviseInst.Metrics().Resizes < 2 { t.Errorf("expected the large value to resize the memory map") }
+
+		for _, pattern := range []mari.AdvisePattern{ mari.AdviseRandom, mari.AdviseWillNeed, mari.AdviseNormal } {
+			adviseErr := adviseInst.Advise(pattern)
+			if adviseErr != nil { t.Errorf("error advising pattern %d: %s", pattern, adviseErr.Error()) }
+		}
+
+		if adviseInst.Advise(mari.AdviseWillNeed + 1) == nil { t.Errorf("expected error advising an unknown pattern") }
+	})
+
+	t.Run("Test Mari Lock Memory", func(t *testing.T) {
+		os.Remove(filepath.Join(os.TempDir(), "testmarilockmemory"))
+		os.Remove(filepath.Join(os.TempDir(), "testmarilockmemory.vidx"))
+
+		lockMemInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmarilockmemory", LockMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening lock memory instance: %s", openErr.Error()) }
+		defer lockMemInst.Remove()
+
+		updateErr := lockMemInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("large"), make([]byte, RESIZE_VALUE_SIZE))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+		if lockMemInst.Metrics().Resizes < 2 { t.Errorf("expected the large value to resize the memory map") }
+
+		readErr := lockMemInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("large"), nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || len(kvPair.Value) != RESIZE_VALUE_SIZE { t.Errorf("large value does not match after resize") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		for {
+			select {
+				case reportErr := <-lockMemInst.Errors():
+					if ! strings.Contains(reportErr.Error(), "unable to lock") { t.Errorf("unexpected error reported: %s", reportErr.Error()) }
+					t.Logf("memory lock degraded: %s", reportErr.Error())
+				default:
+					return
+			}
+		}
+	})
+}
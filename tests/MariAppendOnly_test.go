@@ -0,0 +1,103 @@
+package maritests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var appendOnlyMariInst *mari.Mari
+var appendOnlyInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testappendonly"))
+	os.Remove(filepath.Join(os.TempDir(), "testappendonlytemp"))
+
+	appendOnly := true
+	compactTrigger := func(metaData *mari.MariMetaData) bool { return true }
+
+	opts := mari.MariOpts{ 
+		Filepath: os.TempDir(), 
+		FileName: "testappendonly", 
+		AppendOnly: &appendOnly,
+		CompactTrigger: &compactTrigger,
+		NodePoolSize: &smallNodePoolSize,
+	}
+
+	appendOnlyMariInst, appendOnlyInitMariErr = mari.Open(opts)
+	if appendOnlyInitMariErr != nil {
+		appendOnlyMariInst.Remove()
+		panic(appendOnlyInitMariErr.Error())
+	}
+
+	fmt.Println("append only test mari initialized")
+}
+
+
+func TestMariAppendOnly(t *testing.T) {
+	defer appendOnlyMariInst.Remove()
+
+	t.Run("Test Writes Past Compaction Trigger", func(t *testing.T) {
+		prevSize, sizeErr := appendOnlyMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting mari file size: %s", sizeErr.Error()) }
+
+		for idx := range make([]int, APPEND_ONLY_VERSIONS) {
+			putErr := appendOnlyMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			size, sizeErr := appendOnlyMariInst.FileSize()
+			if sizeErr != nil { t.Fatalf("error getting mari file size: %s", sizeErr.Error()) }
+			if size < prevSize { t.Fatalf("file shrank after write %d, compaction may have occurred: actual(%d), previous(%d)", idx, size, prevSize) }
+
+			prevSize = size
+		}
+	})
+
+	t.Run("Test Old Versions Still Readable", func(t *testing.T) {
+		versions := make([]uint64, APPEND_ONLY_VERSIONS)
+
+		readErr := appendOnlyMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for idx := range make([]int, APPEND_ONLY_VERSIONS) {
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", idx)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil { return fmt.Errorf("key missing: key%d", idx) }
+
+				if kvPair.Version < uint64(idx + 1) {
+					t.Errorf("version is less than the version it was written at, compaction may have occurred: actual(%d), expected at least(%d)", kvPair.Version, idx + 1)
+				}
+
+				versions[idx] = kvPair.Version
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari get: %s", readErr.Error()) }
+
+		for _, idx := range []int{ 0, APPEND_ONLY_VERSIONS / 2, APPEND_ONLY_VERSIONS - 2 } {
+			viewErr := appendOnlyMariInst.ViewTxAtVersion(versions[idx], func(tx *mari.MariTx) error {
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", idx)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil || string(kvPair.Value) != fmt.Sprintf("value%d", idx) { t.Errorf("key%d does not match at version %d: %v", idx, versions[idx], kvPair) }
+
+				nextKvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", idx + 1)), nil)
+				if getTxErr != nil { return getTxErr }
+				if nextKvPair != nil { t.Errorf("key%d written after version %d is visible at it", idx + 1, versions[idx]) }
+
+				count, countErr := tx.Count()
+				if countErr != nil { return countErr }
+				if count != idx + 1 { t.Errorf("count at version %d does not match: actual(%d), expected(%d)", versions[idx], count, idx + 1) }
+
+				return nil
+			})
+
+			if viewErr != nil { t.Errorf("error viewing version %d: %s", versions[idx], viewErr.Error()) }
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package maritests
+
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariBatchIf(t *testing.T) {
+	t.Run("Test Mari Batch If", func(t *testing.T) {
+		batchInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening batch instance: %s", openErr.Error()) }
+		defer batchInst.Close()
+
+		runBatch := func(checks []mari.KeyValuePair) bool {
+			var applied bool
+			updateErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+				var batchErr error
+				applied, batchErr = tx.BatchIf(
+					checks,
+					[]mari.KeyValuePair{ { Key: []byte("from"), Value: []byte("0") }, { Key: []byte("to"), Value: []byte("10") } },
+					[][]byte{ []byte("pending") },
+				)
+
+				return batchErr
+			})
+
+			if updateErr != nil { t.Fatalf("error on mari batch if: %s", updateErr.Error()) }
+			return applied
+		}
+
+		updateErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("from"), []byte("10"))
+			if putErr != nil { return putErr }
+
+			return tx.Put([]byte("pending"), []byte("transfer"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		if runBatch([]mari.KeyValuePair{ { Key: []byte("from"), Value: []byte("10") }, { Key: []byte("to"), Value: []byte("5") } }) {
+			t.Errorf("expected batch with a mismatched value to not be applied")
+		}
+
+		if runBatch([]mari.KeyValuePair{ { Key: []byte("from"), Value: []byte("10") }, { Key: []byte("pending"), Value: nil } }) {
+			t.Errorf("expected batch requiring an existing key to be absent to not be applied")
+		}
+
+		readPairs := func() []*mari.KeyValuePair {
+			var pairs []*mari.KeyValuePair
+			readErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+				var rangeErr error
+				pairs, rangeErr = tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+				return rangeErr
+			})
+
+			if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+			return pairs
+		}
+
+		if pairs := readPairs(); len(pairs) != 2 || string(pairs[0].Value) != "10" { t.Errorf("expected no writes from rejected batches: %v", pairs) }
+
+		if ! runBatch([]mari.KeyValuePair{ { Key: []byte("from"), Value: []byte("10") }, { Key: []byte("to"), Value: nil } }) {
+			t.Errorf("expected batch with matching checks to be applied")
+		}
+
+		pairs := readPairs()
+		if len(pairs) != 2 || string(pairs[0].Key) != "from" || string(pairs[0].Value) != "0" || string(pairs[1].Key) != "to" || string(pairs[1].Value) != "10" {
+			t.Errorf("pairs after batch do not match: %v", pairs)
+		}
+	})
+
+	t.Run("Test Mari Put With Version", func(t *testing.T) {
+		versionInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening put with version instance: %s", openErr.Error()) }
+		defer versionInst.Close()
+
+		for range make([]int, 3) {
+			updateErr := versionInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("other"), []byte("value"))
+			})
+
+			if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+		}
+
+		updateErr := versionInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.PutWithVersion([]byte("imported"), []byte("value"), 1)
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari put with version: %s", updateErr.Error()) }
+
+		exceedErr := versionInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.PutWithVersion([]byte("future"), []byte("value"), 100)
+		})
+
+		if exceedErr == nil { t.Errorf("expected error putting a version greater than the version of the transaction") }
+
+		updateErr = versionInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("importer"), []byte("value"))
+			if putErr != nil { return putErr }
+
+			return tx.Put([]byte("imports"), []byte("value"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		readErr := versionInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("imported"), nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || kvPair.Version != 1 { t.Errorf("supplied version was not preserved: %v", kvPair) }
+
+			kvPair, getErr = tx.Get([]byte("importer"), nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || kvPair.Version != 5 { t.Errorf("committed version does not match: actual(%v), expected(5)", kvPair) }
+
+			minVersion := uint64(2)
+			pairs, rangeErr := tx.Range([]byte("i"), []byte("j"), &mari.MariRangeOpts{ MinVersion: &minVersion })
+			if rangeErr != nil { return rangeErr }
+			if len(pairs) != 2 { t.Errorf("pairs newer than the supplied version do not match: actual(%d), expected(2)", len(pairs)) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+}
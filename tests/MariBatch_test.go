@@ -0,0 +1,251 @@
+package maritests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariBatch(t *testing.T) {
+	batchInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer batchInst.Close()
+
+	t.Run("Test Mari Delete Range", func(t *testing.T) {
+		putErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			for idx := range make([]int, 10) {
+				putTxErr := tx.Put([]byte(fmt.Sprintf("drange%d", idx)), []byte(fmt.Sprintf("drange%d", idx)))
+				if putTxErr != nil { return putTxErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		var totalDeleted int
+		delErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			var delTxErr error
+			totalDeleted, delTxErr = tx.DeleteRange([]byte("drange2"), []byte("drange5"))
+			return delTxErr
+		})
+
+		if delErr != nil { t.Fatalf("error on delete range: %s", delErr.Error()) }
+		if totalDeleted != 4 { t.Errorf("total deleted does not match: actual(%d), expected(4)", totalDeleted) }
+
+		rangeErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, txRangeErr := tx.Range([]byte("drange0"), []byte("drange9"), nil)
+			if txRangeErr != nil { return txRangeErr }
+
+			var keys []string
+			for _, kvPair := range kvPairs { keys = append(keys, string(kvPair.Key)) }
+
+			expected := []string{ "drange0", "drange1", "drange6", "drange7", "drange8", "drange9" }
+			if fmt.Sprint(keys) != fmt.Sprint(expected) { t.Errorf("remaining keys do not match: actual(%v), expected(%v)", keys, expected) }
+
+			return nil
+		})
+
+		if rangeErr != nil { t.Errorf("error on mari range: %s", rangeErr.Error()) }
+	})
+
+	t.Run("Test Mari Delete Prefix", func(t *testing.T) {
+		keys := []string{ "user:", "user:1", "user:1:name", "user:1:email", "user:12:name", "user:2:name", "users" }
+
+		putErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range keys {
+				putTxErr := tx.Put([]byte(key), []byte(key))
+				if putTxErr != nil { return putTxErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		var totalDeleted int
+		delErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			var delTxErr error
+			totalDeleted, delTxErr = tx.DeletePrefix([]byte("user:1"))
+			return delTxErr
+		})
+
+		if delErr != nil { t.Fatalf("error on delete prefix: %s", delErr.Error()) }
+		if totalDeleted != 4 { t.Errorf("total deleted does not match: actual(%d), expected(4)", totalDeleted) }
+
+		getErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, key := range keys {
+				exists, hasTxErr := tx.Has([]byte(key))
+				if hasTxErr != nil { return hasTxErr }
+
+				shouldExist := ! strings.HasPrefix(key, "user:1")
+				if exists != shouldExist { t.Errorf("existence of key %s does not match: actual(%t), expected(%t)", key, exists, shouldExist) }
+			}
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+	})
+
+	t.Run("Test Mari Iterate Prefix", func(t *testing.T) {
+		keys := [][]byte{ 
+			[]byte("pfx:"), []byte("pfx:a"), []byte("pfx:b"), []byte("pfx:b:c"), []byte("pfx;"), []byte("pf"),
+			{ 0xFF, 0xFE }, { 0xFF, 0xFF }, { 0xFF, 0xFF, 0x01 }, { 0xFF, 0xFF, 0xFF },
+		}
+
+		putErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range keys {
+				putTxErr := tx.Put(key, key)
+				if putTxErr != nil { return putTxErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		cases := []struct{ prefix []byte; totalResults int; expected [][]byte }{
+			{ []byte("pfx:"), 10, [][]byte{ []byte("pfx:"), []byte("pfx:a"), []byte("pfx:b"), []byte("pfx:b:c") } },
+			{ []byte("pfx:"), 2, [][]byte{ []byte("pfx:"), []byte("pfx:a") } },
+			{ []byte("pfx:b"), 10, [][]byte{ []byte("pfx:b"), []byte("pfx:b:c") } },
+			{ []byte{ 0xFF, 0xFF }, 10, [][]byte{ { 0xFF, 0xFF }, { 0xFF, 0xFF, 0x01 }, { 0xFF, 0xFF, 0xFF } } },
+			{ []byte("pfx:z"), 10, nil },
+		}
+
+		readErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, c := range cases {
+				kvPairs, iterTxErr := tx.IteratePrefix(c.prefix, c.totalResults, nil)
+				if iterTxErr != nil { return iterTxErr }
+
+				if len(kvPairs) != len(c.expected) { 
+					t.Errorf("total results for prefix %v do not match: actual(%d), expected(%d)", c.prefix, len(kvPairs), len(c.expected)) 
+					continue
+				}
+
+				for idx, kvPair := range kvPairs {
+					if ! bytes.Equal(kvPair.Key, c.expected[idx]) { t.Errorf("key for prefix %v does not match: actual(%v), expected(%v)", c.prefix, kvPair.Key, c.expected[idx]) }
+				}
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on iterate prefix: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Put Batch", func(t *testing.T) {
+		pairs := []mari.KeyValuePair{
+			{ Key: []byte("batch:b"), Value: []byte("b") },
+			{ Key: []byte("batch:a"), Value: []byte("a") },
+			{ Key: []byte("batch:a:1"), Value: []byte("a1") },
+			{ Key: []byte("batch:c"), Value: []byte("c") },
+			{ Key: []byte("batch:a"), Value: []byte("a overwritten") },
+			{ Key: []byte("batch"), Value: []byte("root") },
+		}
+
+		putErr := batchInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.PutBatch(pairs)
+		})
+
+		if putErr != nil { t.Fatalf("error on put batch: %s", putErr.Error()) }
+
+		expected := map[string]string{ "batch": "root", "batch:a": "a overwritten", "batch:a:1": "a1", "batch:b": "b", "batch:c": "c" }
+
+		readErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			for key, value := range expected {
+				kvPair, getTxErr := tx.Get([]byte(key), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil || string(kvPair.Value) != value { t.Errorf("value for key %s does not match: actual(%v), expected(%s)", key, kvPair, value) }
+			}
+
+			kvPairs, iterTxErr := tx.IteratePrefix([]byte("batch"), len(pairs), nil)
+			if iterTxErr != nil { return iterTxErr }
+			if len(kvPairs) != len(expected) { t.Errorf("total keys after batch does not match: actual(%d), expected(%d)", len(kvPairs), len(expected)) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Get Many", func(t *testing.T) {
+		keys := [][]byte{ []byte("batch:c"), []byte("missing"), []byte("batch"), []byte("batch:a:1"), []byte("batch:c"), []byte("batch:z") }
+		expected := []string{ "c", "", "root", "a1", "c", "" }
+
+		readErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, getTxErr := tx.GetMany(keys, nil)
+			if getTxErr != nil { return getTxErr }
+			if len(kvPairs) != len(keys) { t.Fatalf("total results does not match: actual(%d), expected(%d)", len(kvPairs), len(keys)) }
+
+			for idx, kvPair := range kvPairs {
+				switch {
+					case expected[idx] == "" && kvPair != nil:
+						t.Errorf("expected nil for missing key %s: %v", keys[idx], kvPair)
+					case expected[idx] != "" && (kvPair == nil || string(kvPair.Value) != expected[idx]):
+						t.Errorf("value for key %s does not match: actual(%v), expected(%s)", keys[idx], kvPair, expected[idx])
+				}
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get many: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Has Many", func(t *testing.T) {
+		keys := [][]byte{ []byte("batch:c"), []byte("missing"), []byte("batch"), []byte("batch:a:1"), []byte("batch:c"), []byte("batch:z"), []byte("bat"), []byte("batch:a:1:2") }
+
+		readErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			flags, hasTxErr := tx.HasMany(keys)
+			if hasTxErr != nil { return hasTxErr }
+			if len(flags) != len(keys) { t.Fatalf("total results does not match: actual(%d), expected(%d)", len(flags), len(keys)) }
+
+			for idx, key := range keys {
+				exists, hasErr := tx.Has(key)
+				if hasErr != nil { return hasErr }
+				if flags[idx] != exists { t.Errorf("presence for key %s does not match has: actual(%t), expected(%t)", key, flags[idx], exists) }
+			}
+
+			expected := []bool{ true, false, true, true, true, false, false, false }
+			for idx, flag := range flags {
+				if flag != expected[idx] { t.Errorf("presence for key %s does not match: actual(%t), expected(%t)", keys[idx], flag, expected[idx]) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari has many: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Get Or And Must Get", func(t *testing.T) {
+		readErr := batchInst.ReadTx(func(tx *mari.MariTx) error {
+			expected, getTxErr := tx.Get([]byte("batch:c"), nil)
+			if getTxErr != nil { return getTxErr }
+			if expected == nil { t.Fatalf("expected key to exist: batch:c") }
+
+			value, getOrErr := tx.GetOr([]byte("batch:c"), []byte("default"))
+			if getOrErr != nil { return getOrErr }
+			if ! bytes.Equal(value, expected.Value) { t.Errorf("value does not match: actual(%s), expected(%s)", value, expected.Value) }
+
+			value, getOrErr = tx.GetOr([]byte("missing"), []byte("default"))
+			if getOrErr != nil { return getOrErr }
+			if string(value) != "default" { t.Errorf("expected default value for missing key: actual(%s)", value) }
+
+			kvPair, mustGetErr := tx.MustGet([]byte("batch:c"))
+			if mustGetErr != nil { return mustGetErr }
+			if ! bytes.Equal(kvPair.Value, expected.Value) { t.Errorf("value does not match: actual(%s), expected(%s)", kvPair.Value, expected.Value) }
+
+			kvPair, mustGetErr = tx.MustGet([]byte("missing"))
+			if ! errors.Is(mustGetErr, mari.ErrKeyNotFound) { t.Errorf("expected key not found error for missing key: actual(%v)", mustGetErr) }
+			if kvPair != nil { t.Errorf("expected nil key-value pair for missing key: %v", kvPair) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get or: %s", readErr.Error()) }
+	})
+}
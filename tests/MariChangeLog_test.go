@@ -0,0 +1,255 @@
+package maritests
+
+import "errors"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariChangeLog(t *testing.T) {
+	clogPath := filepath.Join(os.TempDir(), "testchangelog")
+	os.Remove(clogPath)
+	os.Remove(clogPath + mari.VersionIndexFileName)
+	os.Remove(clogPath + mari.ChangeLogFileName)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testchangelog", ChangeLog: true, NodePoolSize: &smallNodePoolSize }
+
+	clogMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening change log instance: %s", openErr.Error()) }
+
+	t.Run("Test Change Log Requires File", func(t *testing.T) {
+		_, inMemErr := mari.Open(mari.MariOpts{ InMemory: true, ChangeLog: true })
+		if inMemErr == nil { t.Errorf("expected error enabling the change log on an in memory instance") }
+
+		disabledInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening instance without change log: %s", openErr.Error()) }
+		defer disabledInst.Close()
+
+		_, disabledErr := disabledInst.ReadChangeLog(0)
+		if disabledErr == nil { t.Errorf("expected error reading the change log when it is not enabled") }
+	})
+
+	t.Run("Test Change Log Open Failure Releases Lock", func(t *testing.T) {
+		failedPath := filepath.Join(os.TempDir(), "testchangelogfailed")
+		os.Remove(failedPath)
+		os.Remove(failedPath + mari.VersionIndexFileName)
+		os.RemoveAll(failedPath + mari.ChangeLogFileName)
+		defer os.RemoveAll(failedPath + mari.ChangeLogFileName)
+
+		mkdirErr := os.Mkdir(failedPath + mari.ChangeLogFileName, 0700)
+		if mkdirErr != nil { t.Fatalf("error creating directory: %s", mkdirErr.Error()) }
+
+		_, failedErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testchangelogfailed", ChangeLog: true })
+		if failedErr == nil { t.Fatal("expected error opening a change log that is a directory") }
+
+		reopenedInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testchangelogfailed", NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening mari after a failed open: %s", openErr.Error()) }
+
+		removeErr := reopenedInst.Remove()
+		if removeErr != nil { t.Errorf("error removing mari: %s", removeErr.Error()) }
+	})
+
+	t.Run("Test Change Log Append Failure Aborts Commit", func(t *testing.T) {
+		abortedPath := filepath.Join(os.TempDir(), "testchangelogaborted")
+		os.Remove(abortedPath)
+		os.Remove(abortedPath + mari.VersionIndexFileName)
+		os.Remove(abortedPath + mari.ChangeLogFileName)
+
+		codec := &failingLogCodec{ key: "poison" }
+		abortedInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testchangelogaborted", ChangeLog: true, ValueCodec: codec, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening change log instance: %s", openErr.Error()) }
+		defer abortedInst.Remove()
+
+		var committed bool
+		failedErr := abortedInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("poison"), []byte("value"))
+		})
+
+		if failedErr == nil { t.Fatal("expected error when the change log entry can not be written") }
+
+		readErr := abortedInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("poison"), nil)
+			if getErr != nil { return getErr }
+
+			committed = kvPair != nil
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error reading mari: %s", readErr.Error()) }
+		if committed { t.Errorf("transaction was committed even though its change log entry was not written") }
+
+		updateErr := abortedInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("after"), []byte("value"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		entries, readLogErr := abortedInst.ReadChangeLog(0)
+		if readLogErr != nil { t.Fatalf("error reading change log: %s", readLogErr.Error()) }
+		if len(entries) != 1 { t.Fatalf("entries length does not match: actual(%d), expected(1)", len(entries)) }
+		if entries[0].Sequence != 1 || entries[0].Version != 1 || string(entries[0].Puts[0].Key) != "after" { t.Errorf("entry after the failed append does not match: %v", entries[0]) }
+	})
+
+	t.Run("Test Change Log Records Commits", func(t *testing.T) {
+		for range make([]int, CHANGE_LOG_TXS) {
+			updateErr := clogMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				putErr := tx.Put([]byte("kept"), []byte("value"))
+				if putErr != nil { return putErr }
+
+				putErr = tx.Put([]byte("removed"), []byte("value"))
+				if putErr != nil { return putErr }
+
+				return tx.Delete([]byte("removed"))
+			})
+
+			if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+		}
+
+		entries, readErr := clogMariInst.ReadChangeLog(0)
+		if readErr != nil { t.Fatalf("error reading change log: %s", readErr.Error()) }
+		if len(entries) != CHANGE_LOG_TXS { t.Fatalf("entries length does not match: actual(%d), expected(%d)", len(entries), CHANGE_LOG_TXS) }
+
+		for idx, entry := range entries {
+			if entry.Sequence != uint64(idx + 1) || entry.Version != uint64(idx + 1) { t.Errorf("entry numbering does not match: sequence(%d), version(%d), expected(%d)", entry.Sequence, entry.Version, idx + 1) }
+			if len(entry.Puts) != 1 || string(entry.Puts[0].Key) != "kept" || string(entry.Puts[0].Value) != "value" { t.Errorf("entry puts do not match: %v", entry.Puts) }
+			if len(entry.Deletes) != 1 || string(entry.Deletes[0]) != "removed" { t.Errorf("entry deletes do not match: %v", entry.Deletes) }
+		}
+
+		fromEntries, readErr := clogMariInst.ReadChangeLog(CHANGE_LOG_TXS)
+		if readErr != nil { t.Fatalf("error reading change log: %s", readErr.Error()) }
+		if len(fromEntries) != 1 || fromEntries[0].Sequence != CHANGE_LOG_TXS { t.Errorf("expected only the last entry: %v", fromEntries) }
+	})
+
+	t.Run("Test Change Log Recovers After Torn Append", func(t *testing.T) {
+		closeErr := clogMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing change log instance: %s", closeErr.Error()) }
+
+		clogFile, openFileErr := os.OpenFile(clogPath + mari.ChangeLogFileName, os.O_WRONLY | os.O_APPEND, 0600)
+		if openFileErr != nil { t.Fatalf("error opening change log file: %s", openFileErr.Error()) }
+
+		_, writeErr := clogFile.Write([]byte{ 0xFF, 0x00, 0x00, 0x00, 0x01 })
+		if writeErr != nil { t.Fatalf("error writing torn entry: %s", writeErr.Error()) }
+		clogFile.Close()
+
+		clogMariInst, openErr = mari.Open(opts)
+		if openErr != nil { t.Fatalf("error reopening change log instance: %s", openErr.Error()) }
+
+		updateErr := clogMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("after"), []byte("reopen"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		readOnlyInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testchangelog", ChangeLog: true, ReadOnly: true })
+		if openErr != nil { t.Fatalf("error opening read only instance: %s", openErr.Error()) }
+		defer readOnlyInst.Close()
+
+		entries, readErr := readOnlyInst.ReadChangeLog(0)
+		if readErr != nil { t.Fatalf("error reading change log: %s", readErr.Error()) }
+		if len(entries) != CHANGE_LOG_TXS + 1 { t.Fatalf("entries length does not match: actual(%d), expected(%d)", len(entries), CHANGE_LOG_TXS + 1) }
+
+		last := entries[len(entries) - 1]
+		if last.Sequence != CHANGE_LOG_TXS + 1 || string(last.Puts[0].Key) != "after" { t.Errorf("entry after reopen does not match: %v", last) }
+	})
+
+	t.Run("Test Apply Change Log To Follower", func(t *testing.T) {
+		followerPath := filepath.Join(os.TempDir(), "testchangelogfollower")
+		os.Remove(followerPath)
+		os.Remove(followerPath + mari.VersionIndexFileName)
+		os.Remove(followerPath + mari.ChangeLogFileName)
+
+		noLogInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening instance without change log: %s", openErr.Error()) }
+		defer noLogInst.Close()
+
+		noLogErr := noLogInst.Apply(mari.ChangeEntry{ Sequence: 1, Version: 1 })
+		if noLogErr == nil { t.Errorf("expected error applying without a change log on the follower") }
+
+		followerInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testchangelogfollower", ChangeLog: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening follower instance: %s", openErr.Error()) }
+		defer followerInst.Remove()
+
+		entries, readErr := clogMariInst.ReadChangeLog(0)
+		if readErr != nil { t.Fatalf("error reading change log: %s", readErr.Error()) }
+
+		for range make([]int, 2) {
+			for _, entry := range entries {
+				applyErr := followerInst.Apply(entry)
+				if applyErr != nil { t.Fatalf("error applying entry %d: %s", entry.Sequence, applyErr.Error()) }
+			}
+		}
+
+		var leaderPairs, followerPairs []*mari.KeyValuePair
+		leaderErr := clogMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var iterErr error
+			leaderPairs, iterErr = tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+			return iterErr
+		})
+
+		if leaderErr != nil { t.Fatalf("error reading leader: %s", leaderErr.Error()) }
+
+		followerErr := followerInst.ReadTx(func(tx *mari.MariTx) error {
+			var iterErr error
+			followerPairs, iterErr = tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+			return iterErr
+		})
+
+		if followerErr != nil { t.Fatalf("error reading follower: %s", followerErr.Error()) }
+		if len(leaderPairs) != len(followerPairs) { t.Fatalf("follower length does not match: actual(%d), expected(%d)", len(followerPairs), len(leaderPairs)) }
+
+		for idx, leaderPair := range leaderPairs {
+			followerPair := followerPairs[idx]
+			if string(leaderPair.Key) != string(followerPair.Key) || string(leaderPair.Value) != string(followerPair.Value) || leaderPair.Version != followerPair.Version {
+				t.Errorf("follower pair does not match: actual(%v), expected(%v)", followerPair, leaderPair)
+			}
+		}
+
+		followerEntries, readErr := followerInst.ReadChangeLog(0)
+		if readErr != nil { t.Fatalf("error reading follower change log: %s", readErr.Error()) }
+		if len(followerEntries) != len(entries) { t.Fatalf("re-applied entries were not a no-op: actual(%d), expected(%d)", len(followerEntries), len(entries)) }
+
+		for idx, entry := range followerEntries {
+			if entry.Sequence != entries[idx].Sequence || entry.Version != entries[idx].Version { t.Errorf("follower entry does not match: sequence(%d), version(%d)", entry.Sequence, entry.Version) }
+		}
+
+		gapEntry := mari.ChangeEntry{ Sequence: entries[len(entries) - 1].Sequence + 1, Version: entries[len(entries) - 1].Version + 5 }
+		gapEntry.Puts = []mari.KeyValuePair{ { Key: []byte("gap"), Value: []byte("value") } }
+
+		applyErr := followerInst.Apply(gapEntry)
+		if applyErr != nil { t.Fatalf("error applying entry with a version gap: %s", applyErr.Error()) }
+
+		gapErr := followerInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("gap"), nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || kvPair.Version != gapEntry.Version { t.Errorf("gap version does not match: actual(%v), expected(%d)", kvPair, gapEntry.Version) }
+
+			return nil
+		})
+
+		if gapErr != nil { t.Fatalf("error reading follower: %s", gapErr.Error()) }
+	})
+
+	removeErr := clogMariInst.Remove()
+	if removeErr != nil { t.Fatalf("error removing change log instance: %s", removeErr.Error()) }
+}
+
+// failingLogCodec encodes values unchanged, but fails the second time a value is encoded under key, which is when the change log entry of the put is serialized
+type failingLogCodec struct {
+	key string
+	encodes int
+}
+
+func (codec *failingLogCodec) Encode(key, value []byte) ([]byte, error) {
+	if string(key) == codec.key {
+		codec.encodes++
+		if codec.encodes > 1 { return nil, errors.New("failed to encode value") }
+	}
+
+	return value, nil
+}
+
+func (codec *failingLogCodec) Decode(key, stored []byte) ([]byte, error) {
+	return stored, nil
+}
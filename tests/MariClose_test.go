@@ -0,0 +1,167 @@
+package maritests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "runtime"
+import "sync"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariClose(t *testing.T) {
+	closeFileName := "testclose"
+
+	os.Remove(filepath.Join(os.TempDir(), closeFileName))
+	os.Remove(filepath.Join(os.TempDir(), closeFileName + "temp"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: closeFileName, NodePoolSize: &smallNodePoolSize }
+
+	t.Run("Test Repeated Open Close Does Not Leak Go Routines", func(t *testing.T) {
+		runtime.GC()
+		startGoRoutines := runtime.NumGoroutine()
+
+		for idx := range make([]int, CLOSE_TEST_CYCLES) {
+			closeMariInst, openErr := mari.Open(opts)
+			if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+			putErr := closeMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("key"), []byte{ byte(idx) })
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			syncErr := closeMariInst.Sync()
+			if syncErr != nil { t.Fatalf("error syncing mari: %s", syncErr.Error()) }
+
+			closeErr := closeMariInst.Close()
+			if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+			syncErr = closeMariInst.Sync()
+			if syncErr == nil { t.Fatalf("expected error syncing a closed mari") }
+
+			closeErr = closeMariInst.Close()
+			if closeErr != nil { t.Fatalf("error closing mari a second time: %s", closeErr.Error()) }
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		endGoRoutines := runtime.NumGoroutine()
+
+		t.Logf("go routines before: %d, go routines after: %d", startGoRoutines, endGoRoutines)
+		if endGoRoutines > startGoRoutines { t.Errorf("go routines leaked on close: before(%d), after(%d)", startGoRoutines, endGoRoutines) }
+	})
+
+	t.Run("Test Operations On A Closed Instance", func(t *testing.T) {
+		closeMariInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		closeErr := closeMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		readErr := closeMariInst.ReadTx(func(tx *mari.MariTx) error {
+			t.Errorf("expected read transaction not to run on a closed mari")
+			return nil
+		})
+
+		if ! errors.Is(readErr, mari.ErrClosed) { t.Errorf("expected closed error on read: actual(%v)", readErr) }
+
+		updateErr := closeMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			t.Errorf("expected update transaction not to run on a closed mari")
+			return nil
+		})
+
+		if ! errors.Is(updateErr, mari.ErrClosed) { t.Errorf("expected closed error on update: actual(%v)", updateErr) }
+
+		viewErr := closeMariInst.ViewTxAtVersion(0, func(tx *mari.MariTx) error { return nil })
+		if ! errors.Is(viewErr, mari.ErrClosed) { t.Errorf("expected closed error on view at version: actual(%v)", viewErr) }
+
+		_, snapshotErr := closeMariInst.Snapshot()
+		if ! errors.Is(snapshotErr, mari.ErrClosed) { t.Errorf("expected closed error on snapshot: actual(%v)", snapshotErr) }
+
+		_, versionErr := closeMariInst.CurrentVersion()
+		if ! errors.Is(versionErr, mari.ErrClosed) { t.Errorf("expected closed error on current version: actual(%v)", versionErr) }
+
+		closeErr = closeMariInst.Close()
+		if closeErr != nil { t.Errorf("error closing mari a second time: %s", closeErr.Error()) }
+	})
+
+	t.Run("Test Close With In Flight Transactions", func(t *testing.T) {
+		closeMariInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		var closeWG sync.WaitGroup
+
+		for writer := range make([]int, NUM_WRITER_GO_ROUTINES) {
+			closeWG.Add(1)
+			go func(writer int) {
+				defer closeWG.Done()
+
+				for idx := 0; ; idx++ {
+					putErr := closeMariInst.UpdateTx(func(tx *mari.MariTx) error {
+						return tx.Put([]byte(fmt.Sprintf("inflight%d-%d", writer, idx)), []byte("value"))
+					})
+
+					if putErr == nil { continue }
+					if ! errors.Is(putErr, mari.ErrClosed) { t.Errorf("unexpected error on put while closing: %s", putErr.Error()) }
+					return
+				}
+			}(writer)
+		}
+
+		for reader := range make([]int, NUM_READER_GO_ROUTINES) {
+			closeWG.Add(1)
+			go func(reader int) {
+				defer closeWG.Done()
+
+				for {
+					readErr := closeMariInst.ReadTx(func(tx *mari.MariTx) error {
+						_, getErr := tx.Get([]byte(fmt.Sprintf("inflight%d-0", reader % NUM_WRITER_GO_ROUTINES)), nil)
+						return getErr
+					})
+
+					if readErr == nil { continue }
+					if ! errors.Is(readErr, mari.ErrClosed) { t.Errorf("unexpected error on get while closing: %s", readErr.Error()) }
+					return
+				}
+			}(reader)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		closeErr := closeMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		closeWG.Wait()
+	})
+
+	t.Run("Test Errors Channel Closed On Close", func(t *testing.T) {
+		closeMariInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		closeErr := closeMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		for bgErr := range closeMariInst.Errors() {
+			t.Errorf("unexpected background error: %s", bgErr.Error())
+		}
+	})
+
+	t.Run("Test Data Persists Across Cycles", func(t *testing.T) {
+		closeMariInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+		defer closeMariInst.Remove()
+
+		readErr := closeMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("key"), nil)
+			if getTxErr != nil { return getTxErr }
+
+			if kvPair == nil || kvPair.Value[0] != byte(CLOSE_TEST_CYCLES - 1) { t.Errorf("unexpected value after reopen: %v", kvPair) }
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+	})
+}
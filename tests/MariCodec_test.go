@@ -0,0 +1,113 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariCodec(t *testing.T) {
+	t.Run("Test Mari Encryption Codec", func(t *testing.T) {
+		encPath := filepath.Join(os.TempDir(), "testmariencryption")
+		os.Remove(encPath)
+		os.Remove(encPath + ".vidx")
+		defer os.Remove(encPath)
+		defer os.Remove(encPath + ".vidx")
+
+		_, invalidErr := mari.NewEncryptionCodec([]byte("short"))
+		if invalidErr == nil { t.Errorf("expected error creating codec with an invalid key size") }
+
+		codec, codecErr := mari.NewEncryptionCodec(bytes.Repeat([]byte("k"), 32))
+		if codecErr != nil { t.Fatalf("error creating codec: %s", codecErr.Error()) }
+
+		openEncrypted := func(codec mari.MariValueCodec) *mari.Mari {
+			encInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmariencryption", ValueCodec: codec, NodePoolSize: &smallNodePoolSize })
+			if openErr != nil { t.Fatalf("error opening encrypted instance: %s", openErr.Error()) }
+			return encInst
+		}
+
+		encInst := openEncrypted(codec)
+
+		putErr := encInst.UpdateTx(func(tx *mari.MariTx) error {
+			for idx := range make([]int, ENCRYPTION_INPUT_SIZE) {
+				putErr := tx.Put([]byte(fmt.Sprintf("enc%02d", idx)), []byte(fmt.Sprintf("plaintext%02d", idx)))
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := encInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("enc03"), nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || string(kvPair.Value) != "plaintext03" { t.Errorf("decrypted value does not match: actual(%v)", kvPair) }
+
+			kvPairs, rangeErr := tx.Range([]byte("enc02"), []byte("enc05"), nil)
+			if rangeErr != nil { return rangeErr }
+			if len(kvPairs) != 4 { t.Errorf("range length does not match: actual(%d), expected(4)", len(kvPairs)) }
+
+			for idx, kvPair := range kvPairs {
+				if string(kvPair.Key) != fmt.Sprintf("enc%02d", idx + 2) || string(kvPair.Value) != fmt.Sprintf("plaintext%02d", idx + 2) {
+					t.Errorf("range pair out of order or not decrypted: key(%s), value(%s)", kvPair.Key, kvPair.Value)
+				}
+			}
+
+			iterated, iterErr := tx.Iterate([]byte("enc00"), ENCRYPTION_INPUT_SIZE, nil)
+			if iterErr != nil { return iterErr }
+			if len(iterated) != ENCRYPTION_INPUT_SIZE { t.Errorf("iterate length does not match: actual(%d), expected(%d)", len(iterated), ENCRYPTION_INPUT_SIZE) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		closeErr := encInst.Close()
+		if closeErr != nil { t.Fatalf("error closing encrypted instance: %s", closeErr.Error()) }
+
+		data, readFileErr := os.ReadFile(encPath)
+		if readFileErr != nil { t.Fatalf("error reading encrypted file: %s", readFileErr.Error()) }
+		if bytes.Contains(data, []byte("plaintext")) { t.Errorf("plaintext value found in the memory mapped file") }
+
+		wrongCodec, codecErr := mari.NewEncryptionCodec(bytes.Repeat([]byte("w"), 32))
+		if codecErr != nil { t.Fatalf("error creating codec: %s", codecErr.Error()) }
+
+		wrongInst := openEncrypted(wrongCodec)
+		wrongErr := wrongInst.ReadTx(func(tx *mari.MariTx) error {
+			_, getErr := tx.Get([]byte("enc03"), nil)
+			return getErr
+		})
+
+		if wrongErr == nil { t.Errorf("expected error decrypting with the wrong key") }
+		wrongInst.Close()
+
+		tamperKey := []byte("enc03")
+		for idx := bytes.Index(data, tamperKey); idx != -1; {
+			data[idx + len(tamperKey)] ^= 0xFF
+
+			next := bytes.Index(data[idx + 1:], tamperKey)
+			if next == -1 { break }
+			idx += next + 1
+		}
+
+		writeErr := os.WriteFile(encPath, data, 0600)
+		if writeErr != nil { t.Fatalf("error tampering with encrypted file: %s", writeErr.Error()) }
+
+		tamperedInst := openEncrypted(codec)
+		defer tamperedInst.Close()
+
+		tamperErr := tamperedInst.ReadTx(func(tx *mari.MariTx) error {
+			_, getErr := tx.Get([]byte("enc04"), nil)
+			if getErr != nil { t.Errorf("untampered value failed to decrypt: %s", getErr.Error()) }
+
+			_, getErr = tx.Get([]byte("enc03"), nil)
+			return getErr
+		})
+
+		if tamperErr == nil { t.Errorf("expected error decrypting a tampered value") }
+	})
+}
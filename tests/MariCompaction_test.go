@@ -0,0 +1,654 @@
+package maritests
+
+import "bytes"
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "sync/atomic"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mari"
+
+
+var compactionMariInst *mari.Mari
+var compactionKeyValPairs []KeyVal
+var compactionInitMariErr error
+var compactNow uint32
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testcompaction"))
+	os.Remove(filepath.Join(os.TempDir(), "testcompactiontemp"))
+
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&compactNow, 1, 0)
+	}
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testcompaction", CompactTrigger: &compactTrigger, NodePoolSize: &smallNodePoolSize }
+
+	compactionMariInst, compactionInitMariErr = mari.Open(opts)
+	if compactionInitMariErr != nil {
+		compactionMariInst.Remove()
+		panic(compactionInitMariErr.Error())
+	}
+
+	fmt.Println("compaction test mari initialized")
+}
+
+
+func TestMariCompaction(t *testing.T) {
+	defer compactionMariInst.Remove()
+
+	compactionKeyValPairs = GenerateKeyValPairs(InputSize(COMPACTION_INPUT_SIZE))
+	inputSize := len(compactionKeyValPairs)
+
+	remaining := compactionKeyValPairs[:inputSize / 10]
+
+	t.Run("Test Insert Then Delete Most", func(t *testing.T) {
+		for _, val := range compactionKeyValPairs {
+			putErr := compactionMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put(val.Key, val.Value)
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		for _, val := range compactionKeyValPairs[inputSize / 10:] {
+			delErr := compactionMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Delete(val.Key)
+			})
+
+			if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+		}
+	})
+
+	t.Run("Test File Size Drops After Compaction", func(t *testing.T) {
+		sizeBefore, sizeErr := compactionMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		atomic.StoreUint32(&compactNow, 1)
+
+		putErr := compactionMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(remaining[0].Key, remaining[0].Value)
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		sizeAfter := sizeBefore
+		for start := time.Now(); time.Since(start) < 10 * time.Second; time.Sleep(10 * time.Millisecond) {
+			sizeAfter, sizeErr = compactionMariInst.FileSize()
+			if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+			if sizeAfter * 4 < sizeBefore { break }
+		}
+
+		t.Logf("file size before compaction: %d, file size after compaction: %d", sizeBefore, sizeAfter)
+		if sizeAfter * 4 >= sizeBefore { t.Errorf("file size did not drop substantially: before(%d), after(%d)", sizeBefore, sizeAfter) }
+	})
+
+	t.Run("Test Remaining Keys After Compaction", func(t *testing.T) {
+		for _, val := range remaining {
+			readErr := compactionMariInst.ReadTx(func(tx *mari.MariTx) error {
+				kvPair, getTxErr := tx.Get(val.Key, nil)
+				if getTxErr != nil { return getTxErr }
+
+				if kvPair == nil || ! bytes.Equal(kvPair.Value, val.Value) {
+					t.Errorf("actual value not equal to expected: actual(%v), expected(%v)", kvPair, val)
+				}
+
+				return nil
+			})
+
+			if readErr != nil { t.Fatalf("error on mari get: %s", readErr.Error()) }
+		}
+	})
+	t.Run("Test Writes After Compaction", func(t *testing.T) {
+		chunks, chunkErr := Chunk(compactionKeyValPairs, TRANSACTION_CHUNK_SIZE)
+		if chunkErr != nil { t.Fatalf("error chunking kvPairs: %s", chunkErr.Error()) }
+
+		for _, chunk := range chunks {
+			putErr := compactionMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for _, val := range chunk {
+					putTxErr := tx.Put(val.Key, val.Value)
+					if putTxErr != nil { return putTxErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		totalCount, countErr := compactionMariInst.Count()
+		if countErr != nil { t.Fatalf("error counting keys: %s", countErr.Error()) }
+		if totalCount != inputSize { t.Errorf("count does not match expected: actual(%d), expected(%d)", totalCount, inputSize) }
+	})
+}
+
+func TestMariCompactionRetain(t *testing.T) {
+	retainFileName := "testcompactionretain"
+	
+	os.Remove(filepath.Join(os.TempDir(), retainFileName))
+	os.Remove(filepath.Join(os.TempDir(), retainFileName + "temp"))
+
+	var compactRetainNow uint32
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&compactRetainNow, 1, 0)
+	}
+
+	retain := COMPACTION_RETAIN
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: retainFileName, CompactTrigger: &compactTrigger, CompactRetain: &retain, NodePoolSize: &smallNodePoolSize }
+
+	retainMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer retainMariInst.Remove()
+
+	t.Run("Test Retained Versions After Compaction", func(t *testing.T) {
+		for idx := range make([]int, COMPACTION_RETAIN * 10) {
+			putErr := retainMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		for start := time.Now(); time.Since(start) < 10 * time.Second; time.Sleep(10 * time.Millisecond) {
+			atomic.StoreUint32(&compactRetainNow, 1)
+
+			putErr := retainMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("final"), []byte("final"))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			currVersion, versionErr := retainMariInst.CurrentVersion()
+			if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+			if currVersion <= COMPACTION_RETAIN { break }
+		}
+
+		var baseCount int
+		for idx := range make([]int, COMPACTION_RETAIN) {
+			viewErr := retainMariInst.ViewTxAtVersion(uint64(idx), func(tx *mari.MariTx) error {
+				totalCount, countTxErr := tx.Count()
+				if countTxErr != nil { return countTxErr }
+
+				if idx == 0 { baseCount = totalCount }
+				if totalCount != baseCount + idx { t.Errorf("count at retained version %d does not match: actual(%d), expected(%d)", idx, totalCount, baseCount + idx) }
+
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", COMPACTION_RETAIN * 10 - 1)), nil)
+				if getTxErr != nil { return getTxErr }
+				if idx == COMPACTION_RETAIN - 1 && kvPair == nil { t.Errorf("last written key missing at retained version %d", idx) }
+
+				return nil
+			})
+
+			if viewErr != nil { t.Errorf("error viewing retained version %d: %s", idx, viewErr.Error()) }
+		}
+
+		if baseCount < COMPACTION_RETAIN * 9 { t.Errorf("oldest retained version is older than expected: count(%d)", baseCount) }
+
+		versions, listErr := retainMariInst.ListVersions()
+		if listErr != nil { t.Fatalf("error listing versions: %s", listErr.Error()) }
+		if len(versions) < COMPACTION_RETAIN || len(versions) > COMPACTION_RETAIN + 1 { 
+			t.Errorf("total versions after compaction does not match: actual(%v), expected(%d)", versions, COMPACTION_RETAIN + 1) 
+		}
+	})
+}
+
+func TestMariCompactionFragmentation(t *testing.T) {
+	fragmentation := COMPACTION_FRAGMENTATION
+	opts := mari.MariOpts{ InMemory: true, CompactFragmentation: &fragmentation, NodePoolSize: &smallNodePoolSize }
+
+	fragmentationMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer fragmentationMariInst.Close()
+
+	t.Run("Test Invalid Fragmentation Options", func(t *testing.T) {
+		invalid := 1.5
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, CompactFragmentation: &invalid })
+		if invalidErr == nil { t.Errorf("expected error opening mari with fragmentation outside of 0 and 1") }
+
+		compactTrigger := func(metaData *mari.MariMetaData) bool { return false }
+		_, combinedErr := mari.Open(mari.MariOpts{ InMemory: true, CompactFragmentation: &fragmentation, CompactTrigger: &compactTrigger })
+		if combinedErr == nil { t.Errorf("expected error opening mari with both a fragmentation threshold and a compact trigger") }
+	})
+
+	t.Run("Test Overwrites Trigger Compaction", func(t *testing.T) {
+		var compacted bool
+		var prevVersion uint64
+		var lastRound int
+
+		sizeBefore, sizeErr := fragmentationMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		for round := 0; round < 500 && ! compacted; round++ {
+			putErr := fragmentationMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for idx := range make([]int, FRAGMENTATION_INPUT_SIZE) {
+					putErr := tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d-%d", idx, round)))
+					if putErr != nil { return putErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			currVersion, versionErr := fragmentationMariInst.CurrentVersion()
+			if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+
+			compacted = currVersion < prevVersion
+			prevVersion = currVersion
+			lastRound = round
+		}
+
+		if ! compacted { t.Fatalf("expected overwrites to trigger compaction") }
+
+		stats, statsErr := fragmentationMariInst.Stats()
+		if statsErr != nil { t.Fatalf("error on mari stats: %s", statsErr.Error()) }
+		if stats.KeyCount != FRAGMENTATION_INPUT_SIZE { t.Errorf("key count does not match: actual(%d), expected(%d)", stats.KeyCount, FRAGMENTATION_INPUT_SIZE) }
+		if stats.FileSize >= uint64(sizeBefore) { t.Errorf("expected file size to drop after compaction: before(%d), after(%d)", sizeBefore, stats.FileSize) }
+
+		getErr := fragmentationMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("key0"), nil)
+			if getTxErr != nil { return getTxErr }
+
+			expected := fmt.Sprintf("value0-%d", lastRound)
+			if kvPair == nil || string(kvPair.Value) != expected { t.Errorf("value after compaction does not match: actual(%v), expected(%s)", kvPair, expected) }
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+	})
+}
+
+func TestMariCompactionRetainSharedRoot(t *testing.T) {
+	sharedFileName := "testcompactionsharedroot"
+	sharedPath := filepath.Join(os.TempDir(), sharedFileName)
+
+	for _, path := range []string{ sharedPath, sharedPath + "temp", sharedPath + mari.VersionIndexFileName } { os.Remove(path) }
+
+	var compactSharedNow uint32
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&compactSharedNow, 1, 0)
+	}
+
+	retain := COMPACTION_RETAIN
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: sharedFileName, CompactTrigger: &compactTrigger, CompactRetain: &retain, NodePoolSize: &smallNodePoolSize }
+
+	sharedMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+	for idx := range make([]int, COMPACTION_RETAIN * 2) {
+		putErr := sharedMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+	}
+
+	currVersion, versionErr := sharedMariInst.CurrentVersion()
+	if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+
+	closeErr := sharedMariInst.Close()
+	if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+	vIdx, readErr := os.ReadFile(sharedPath + mari.VersionIndexFileName)
+	if readErr != nil { t.Fatalf("error reading version index: %s", readErr.Error()) }
+
+	currEntry := mari.VersionIndexHeaderSize + currVersion * mari.OffsetSize
+	sharedEntry := currEntry - mari.OffsetSize
+	binary.LittleEndian.PutUint64(vIdx[sharedEntry:currEntry], binary.LittleEndian.Uint64(vIdx[currEntry:currEntry + mari.OffsetSize]))
+
+	writeErr := os.WriteFile(sharedPath + mari.VersionIndexFileName, vIdx, 0600)
+	if writeErr != nil { t.Fatalf("error writing version index: %s", writeErr.Error()) }
+
+	sharedMariInst, openErr = mari.Open(opts)
+	if openErr != nil { t.Fatalf("error reopening mari: %s", openErr.Error()) }
+	defer sharedMariInst.Remove()
+
+	t.Run("Test Retained Version Sharing Current Root After Compaction", func(t *testing.T) {
+		abortErr := errors.New("abort after compaction is signalled")
+
+		for start := time.Now(); time.Since(start) < 10 * time.Second; time.Sleep(10 * time.Millisecond) {
+			atomic.StoreUint32(&compactSharedNow, 1)
+
+			putErr := sharedMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				if atomic.LoadUint32(&compactSharedNow) == 0 { return abortErr }
+				return tx.Put([]byte("final"), []byte("final"))
+			})
+
+			if putErr != abortErr { t.Fatalf("expected the put to be aborted once compaction is signalled: %v", putErr) }
+
+			compactedVersion, versionErr := sharedMariInst.CurrentVersion()
+			if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+			if compactedVersion < currVersion { break }
+		}
+
+		compactedVersion, versionErr := sharedMariInst.CurrentVersion()
+		if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+		if compactedVersion != COMPACTION_RETAIN - 1 { t.Fatalf("compacted version does not match: actual(%d), expected(%d)", compactedVersion, COMPACTION_RETAIN - 1) }
+
+		viewErr := sharedMariInst.ViewTxAtVersion(uint64(COMPACTION_RETAIN - 2), func(tx *mari.MariTx) error {
+			totalCount, countTxErr := tx.Count()
+			if countTxErr != nil { return countTxErr }
+			if totalCount != COMPACTION_RETAIN * 2 { t.Errorf("count at shared version does not match: actual(%d), expected(%d)", totalCount, COMPACTION_RETAIN * 2) }
+
+			kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", COMPACTION_RETAIN * 2 - 1)), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil { t.Error("last written key missing at shared version") }
+
+			return nil
+		})
+
+		if viewErr != nil { t.Errorf("error viewing shared version: %s", viewErr.Error()) }
+	})
+}
+
+func TestMariCompactionRecovery(t *testing.T) {
+	recoveryFileName := "testcompactionrecovery"
+	recoveryPath := filepath.Join(os.TempDir(), recoveryFileName)
+	compactedPath := recoveryPath + "compacted"
+
+	for _, path := range []string{ recoveryPath, recoveryPath + "temp", recoveryPath + "swap", compactedPath } { os.Remove(path) }
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: recoveryFileName, NodePoolSize: &smallNodePoolSize }
+
+	recoveryMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+	for idx := range make([]int, COMPACTION_RETAIN * 10) {
+		putErr := recoveryMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+	}
+
+	compactedInst, cloneErr := recoveryMariInst.Clone(compactedPath)
+	if cloneErr != nil { t.Fatalf("error cloning mari: %s", cloneErr.Error()) }
+
+	closeErr := compactedInst.Close()
+	if closeErr != nil { t.Fatalf("error closing clone: %s", closeErr.Error()) }
+	defer os.Remove(compactedPath + mari.VersionIndexFileName)
+	defer os.Remove(compactedPath)
+
+	putErr := recoveryMariInst.UpdateTx(func(tx *mari.MariTx) error {
+		return tx.Put([]byte("original"), []byte("original"))
+	})
+
+	if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+	closeErr = recoveryMariInst.Close()
+	if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+	copyCompacted := func(t *testing.T, destPath string) {
+		compacted, readErr := os.ReadFile(compactedPath)
+		if readErr != nil { t.Fatalf("error reading compacted copy: %s", readErr.Error()) }
+
+		writeErr := os.WriteFile(destPath, compacted, 0600)
+		if writeErr != nil { t.Fatalf("error writing compacted copy: %s", writeErr.Error()) }
+	}
+
+	renameOriginal := func(t *testing.T) {
+		renameErr := os.Rename(recoveryPath, recoveryPath + "swap")
+		if renameErr != nil { t.Fatalf("error renaming original: %s", renameErr.Error()) }
+	}
+
+	checkRecovered := func(t *testing.T) {
+		recoveredInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari after interrupted compaction: %s", openErr.Error()) }
+		defer recoveredInst.Close()
+
+		for _, path := range []string{ recoveryPath + "temp", recoveryPath + "swap" } {
+			_, statErr := os.Stat(path)
+			if ! os.IsNotExist(statErr) { t.Errorf("expected leftover compaction file to be removed: %s", path) }
+		}
+
+		readErr := recoveredInst.ReadTx(func(tx *mari.MariTx) error {
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != COMPACTION_RETAIN * 10 + 1 { t.Errorf("count does not match: actual(%d), expected(%d)", count, COMPACTION_RETAIN * 10 + 1) }
+
+			kvPair, getErr := tx.Get([]byte("original"), nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil { t.Error("expected original file to be restored") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := recoveredInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying mari: %s", verifyErr.Error()) }
+	}
+
+	t.Run("Test Recover Before Original Renamed", func(t *testing.T) {
+		copyCompacted(t, recoveryPath + "temp")
+		checkRecovered(t)
+	})
+
+	t.Run("Test Recover After Original Renamed", func(t *testing.T) {
+		renameOriginal(t)
+		copyCompacted(t, recoveryPath + "temp")
+		checkRecovered(t)
+	})
+
+	t.Run("Test Recover Before Swap File Removed", func(t *testing.T) {
+		renameOriginal(t)
+		copyCompacted(t, recoveryPath)
+		checkRecovered(t)
+	})
+
+	t.Run("Test Recover After Version Index Reset", func(t *testing.T) {
+		renameOriginal(t)
+		copyCompacted(t, recoveryPath)
+
+		compactedVIdx, readErr := os.ReadFile(compactedPath + mari.VersionIndexFileName)
+		if readErr != nil { t.Fatalf("error reading compacted version index: %s", readErr.Error()) }
+
+		writeErr := os.WriteFile(recoveryPath + mari.VersionIndexFileName, compactedVIdx, 0600)
+		if writeErr != nil { t.Fatalf("error writing compacted version index: %s", writeErr.Error()) }
+
+		recoveredInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari after compaction was interrupted before the swap file was removed: %s", openErr.Error()) }
+		defer recoveredInst.Close()
+
+		_, statErr := os.Stat(recoveryPath + "swap")
+		if ! os.IsNotExist(statErr) { t.Errorf("expected original of completed compaction to be removed") }
+
+		readErr = recoveredInst.ReadTx(func(tx *mari.MariTx) error {
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != COMPACTION_RETAIN * 10 { t.Errorf("count does not match: actual(%d), expected(%d)", count, COMPACTION_RETAIN * 10) }
+
+			kvPair, getErr := tx.Get([]byte("original"), nil)
+			if getErr != nil { return getErr }
+			if kvPair != nil { t.Error("expected compacted copy to be kept") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := recoveredInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying mari: %s", verifyErr.Error()) }
+	})
+
+	removeErr := os.Remove(recoveryPath)
+	if removeErr != nil { t.Errorf("error removing mari: %s", removeErr.Error()) }
+	os.Remove(recoveryPath + mari.VersionIndexFileName)
+}
+
+func TestMariCompactionFileMode(t *testing.T) {
+	fileModeFileName := "testcompactionfilemode"
+	fileModePath := filepath.Join(os.TempDir(), fileModeFileName)
+
+	os.Remove(fileModePath)
+	os.Remove(fileModePath + "temp")
+	os.Remove(fileModePath + mari.VersionIndexFileName)
+
+	_, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: fileModeFileName, FileMode: os.ModeDir | 0640 })
+	if openErr == nil { t.Fatal("expected error opening mari with a file mode that is not only permission bits") }
+
+	var compactFileModeNow uint32
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&compactFileModeNow, 1, 0)
+	}
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: fileModeFileName, FileMode: 0640, CompactTrigger: &compactTrigger, NodePoolSize: &smallNodePoolSize }
+
+	fileModeMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer fileModeMariInst.Remove()
+
+	checkFileModes := func(t *testing.T) {
+		for _, path := range []string{ fileModePath, fileModePath + mari.VersionIndexFileName } {
+			stat, statErr := os.Stat(path)
+			if statErr != nil { t.Fatalf("error getting file mode: %s", statErr.Error()) }
+			if stat.Mode().Perm() != 0640 { t.Errorf("file mode does not match for %s: actual(%o), expected(%o)", path, stat.Mode().Perm(), 0640) }
+		}
+	}
+
+	t.Run("Test File Mode On Open", func(t *testing.T) {
+		checkFileModes(t)
+	})
+
+	t.Run("Test File Mode Survives Compaction", func(t *testing.T) {
+		var compacted bool
+		for start := time.Now(); time.Since(start) < 10 * time.Second && ! compacted; time.Sleep(10 * time.Millisecond) {
+			putErr := fileModeMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("key"), []byte("value"))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			atomic.StoreUint32(&compactFileModeNow, 1)
+
+			putErr = fileModeMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("final"), []byte("final"))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			currVersion, versionErr := fileModeMariInst.CurrentVersion()
+			if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+			compacted = currVersion <= 1
+		}
+
+		if ! compacted { t.Fatal("expected compaction to occur") }
+		checkFileModes(t)
+	})
+
+	t.Run("Test File Mode Applies To Segments", func(t *testing.T) {
+		segmentPath := fileModePath + "segment"
+		os.Remove(segmentPath)
+		defer os.Remove(segmentPath)
+
+		writeErr := fileModeMariInst.WriteSegment(segmentPath, 4096)
+		if writeErr != nil { t.Fatalf("error writing segment: %s", writeErr.Error()) }
+
+		stat, statErr := os.Stat(segmentPath)
+		if statErr != nil { t.Fatalf("error getting file mode: %s", statErr.Error()) }
+		if stat.Mode().Perm() != 0640 { t.Errorf("file mode does not match for segment: actual(%o), expected(%o)", stat.Mode().Perm(), 0640) }
+	})
+}
+
+func TestMariCompactionProgress(t *testing.T) {
+	progressFileName := "testcompactionprogress"
+	progressPath := filepath.Join(os.TempDir(), progressFileName)
+
+	os.Remove(progressPath)
+	os.Remove(progressPath + "temp")
+	os.Remove(progressPath + mari.VersionIndexFileName)
+
+	var compactProgressNow uint32
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&compactProgressNow, 1, 0)
+	}
+
+	var progressLock sync.Mutex
+	var progress [][2]uint64
+	completed := make(chan [2]int64, 1)
+
+	opts := mari.MariOpts{
+		Filepath: os.TempDir(),
+		FileName: progressFileName,
+		CompactTrigger: &compactTrigger,
+		OnCompactionProgress: func(written, total uint64) {
+			progressLock.Lock()
+			defer progressLock.Unlock()
+
+			progress = append(progress, [2]uint64{ written, total })
+		},
+		OnCompactionComplete: func(oldSize, newSize int64) { completed <- [2]int64{ oldSize, newSize } },
+		NodePoolSize: &smallNodePoolSize,
+	}
+
+	progressMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer progressMariInst.Remove()
+
+	t.Run("Test Compaction Progress Inserts", func(t *testing.T) {
+		for round := range make([]int, 3) {
+			for idx := range make([]int, 16) {
+				value, _ := GenerateRandomBytes(1024 * 1024)
+
+				putErr := progressMariInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Put([]byte(fmt.Sprintf("key%d", idx)), value)
+				})
+
+				if putErr != nil { t.Fatalf("error on mari put in round %d: %s", round, putErr.Error()) }
+			}
+		}
+	})
+
+	t.Run("Test Compaction Reports Progress And Completion", func(t *testing.T) {
+		atomic.StoreUint32(&compactProgressNow, 1)
+
+		putErr := progressMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("final"), []byte("final"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		var sizes [2]int64
+		select {
+			case sizes = <-completed:
+			case <-time.After(10 * time.Second):
+				t.Fatal("expected compaction to complete")
+		}
+
+		t.Logf("compaction old size: %d, new size: %d", sizes[0], sizes[1])
+		if sizes[1] <= 0 || sizes[1] >= sizes[0] { t.Errorf("expected compaction to shrink the memory map: old(%d), new(%d)", sizes[0], sizes[1]) }
+
+		progressLock.Lock()
+		defer progressLock.Unlock()
+
+		if len(progress) < 2 { t.Fatalf("expected progress to be reported periodically: %v", progress) }
+
+		var prevWritten uint64
+		for _, report := range progress {
+			if report[0] < prevWritten { t.Errorf("written bytes decreased: %v", progress) }
+			if report[0] > report[1] { t.Errorf("written bytes exceed total: %v", report) }
+			prevWritten = report[0]
+		}
+
+		final := progress[len(progress) - 1]
+		if final[0] != final[1] { t.Errorf("expected final progress to report the total as written: %v", final) }
+		if int64(final[0]) > sizes[1] { t.Errorf("final written bytes exceed the compacted size: written(%d), size(%d)", final[0], sizes[1]) }
+
+		readErr := progressMariInst.ReadTx(func(tx *mari.MariTx) error {
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != 17 { t.Errorf("count does not match after compaction: actual(%d), expected(17)", count) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+}
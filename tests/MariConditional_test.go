@@ -0,0 +1,161 @@
+package maritests
+
+import "fmt"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariConditionalWrites(t *testing.T) {
+	condInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer condInst.Close()
+
+	t.Run("Test Mari Put If Absent", func(t *testing.T) {
+		var firstWrite, secondWrite bool
+
+		putErr := condInst.UpdateTx(func(tx *mari.MariTx) error {
+			var putTxErr error
+			firstWrite, putTxErr = tx.PutIfAbsent([]byte("absent"), []byte("first"))
+			if putTxErr != nil { return putTxErr }
+
+			secondWrite, putTxErr = tx.PutIfAbsent([]byte("absent"), []byte("second"))
+			if putTxErr != nil { return putTxErr }
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on put if absent: %s", putErr.Error()) }
+		if ! firstWrite { t.Errorf("expected first put if absent to write") }
+		if secondWrite { t.Errorf("expected second put if absent to be skipped") }
+
+		getErr := condInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("absent"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || string(kvPair.Value) != "first" { t.Errorf("value was overwritten by put if absent: %v", kvPair) }
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+	})
+
+	t.Run("Test Mari Compare And Swap Value", func(t *testing.T) {
+		var staleSwap, swap, missingSwap bool
+
+		swapErr := condInst.UpdateTx(func(tx *mari.MariTx) error {
+			var swapTxErr error
+			staleSwap, swapTxErr = tx.CompareAndSwapValue([]byte("absent"), []byte("stale"), []byte("swapped"))
+			if swapTxErr != nil { return swapTxErr }
+
+			swap, swapTxErr = tx.CompareAndSwapValue([]byte("absent"), []byte("first"), []byte("swapped"))
+			if swapTxErr != nil { return swapTxErr }
+
+			missingSwap, swapTxErr = tx.CompareAndSwapValue([]byte("missing"), nil, []byte("swapped"))
+			if swapTxErr != nil { return swapTxErr }
+
+			return nil
+		})
+
+		if swapErr != nil { t.Fatalf("error on compare and swap value: %s", swapErr.Error()) }
+		if staleSwap { t.Errorf("expected swap with stale expected value to fail") }
+		if ! swap { t.Errorf("expected swap with current expected value to succeed") }
+		if missingSwap { t.Errorf("expected swap on missing key to fail") }
+
+		getErr := condInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("absent"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || string(kvPair.Value) != "swapped" { t.Errorf("value was not swapped: %v", kvPair) }
+
+			kvPair, getTxErr = tx.Get([]byte("missing"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair != nil { t.Errorf("missing key was created by compare and swap") }
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+	})
+
+	t.Run("Test Mari Update", func(t *testing.T) {
+		increment := func(old []byte) ([]byte, error) {
+			if old == nil { return []byte{1}, nil }
+			return []byte{old[0] + 1}, nil
+		}
+
+		for range make([]int, 3) {
+			updateErr := condInst.UpdateTx(func(tx *mari.MariTx) error { return tx.Update([]byte("counter"), increment) })
+			if updateErr != nil { t.Fatalf("error on update: %s", updateErr.Error()) }
+		}
+
+		fnErr := fmt.Errorf("fail")
+		updateErr := condInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Update([]byte("counter"), func(old []byte) ([]byte, error) { return nil, fnErr })
+		})
+
+		if updateErr != fnErr { t.Errorf("expected error from update function to be returned: %v", updateErr) }
+
+		getErr := condInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("counter"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || kvPair.Value[0] != 3 { t.Errorf("counter does not match: actual(%v), expected(3)", kvPair) }
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+	})
+
+	t.Run("Test Mari Delete Existing", func(t *testing.T) {
+		var deleted, deletedAgain bool
+
+		delErr := condInst.UpdateTx(func(tx *mari.MariTx) error {
+			var delTxErr error
+			deleted, delTxErr = tx.DeleteExisting([]byte("counter"))
+			if delTxErr != nil { return delTxErr }
+
+			deletedAgain, delTxErr = tx.DeleteExisting([]byte("counter"))
+			if delTxErr != nil { return delTxErr }
+
+			return nil
+		})
+
+		if delErr != nil { t.Fatalf("error on delete existing: %s", delErr.Error()) }
+		if ! deleted { t.Errorf("expected delete of existing key to report true") }
+		if deletedAgain { t.Errorf("expected delete of missing key to report false") }
+	})
+
+	t.Run("Test Mari Put Returning", func(t *testing.T) {
+		var firstPrev, secondPrev *mari.KeyValuePair
+		var firstVersion uint64
+
+		putErr := condInst.UpdateTx(func(tx *mari.MariTx) error {
+			var putTxErr error
+			firstPrev, putTxErr = tx.PutReturning([]byte("returning"), []byte("first"))
+			return putTxErr
+		})
+
+		if putErr != nil { t.Fatalf("error on put returning: %s", putErr.Error()) }
+		if firstPrev != nil { t.Errorf("expected no previous pair for a new key: %v", firstPrev) }
+
+		getErr := condInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("returning"), nil)
+			if getTxErr != nil { return getTxErr }
+
+			firstVersion = kvPair.Version
+			return nil
+		})
+
+		if getErr != nil { t.Fatalf("error on mari get: %s", getErr.Error()) }
+
+		putErr = condInst.UpdateTx(func(tx *mari.MariTx) error {
+			var putTxErr error
+			secondPrev, putTxErr = tx.PutReturning([]byte("returning"), []byte("second"))
+			return putTxErr
+		})
+
+		if putErr != nil { t.Fatalf("error on put returning: %s", putErr.Error()) }
+		if secondPrev == nil || string(secondPrev.Value) != "first" { t.Fatalf("previous value does not match: actual(%v), expected(first)", secondPrev) }
+		if secondPrev.Version != firstVersion { t.Errorf("previous version does not match: actual(%d), expected(%d)", secondPrev.Version, firstVersion) }
+	})
+}
@@ -0,0 +1,199 @@
+package maritests
+
+import "bytes"
+import "encoding/binary"
+import "encoding/json"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariCopy(t *testing.T) {
+	os.Remove(filepath.Join(os.TempDir(), "testmaricopy"))
+
+	copyInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmaricopy", NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer copyInst.Remove()
+
+	putErr := copyInst.UpdateTx(func(tx *mari.MariTx) error {
+		for idx := range make([]int, 100) {
+			putTxErr := tx.Put([]byte(fmt.Sprintf("copy%d", idx)), []byte(fmt.Sprintf("copy%d", idx)))
+			if putTxErr != nil { return putTxErr }
+		}
+
+		return tx.PutBatch([]mari.KeyValuePair{ { Key: []byte("batch"), Value: []byte("root") }, { Key: []byte("batch:a:1"), Value: []byte("a1") } })
+	})
+
+	if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+	t.Run("Test Mari Clone", func(t *testing.T) {
+		clonePath := filepath.Join(os.TempDir(), "testmariclone")
+		os.Remove(clonePath)
+		os.Remove(clonePath + ".vidx")
+
+		cloneInst, cloneErr := copyInst.Clone(clonePath)
+		if cloneErr != nil { t.Fatalf("error on mari clone: %s", cloneErr.Error()) }
+		defer cloneInst.Remove()
+
+		_, existsErr := copyInst.Clone(clonePath)
+		if existsErr == nil { t.Errorf("expected error cloning to an existing file") }
+
+		expectedCount, countErr := copyInst.Count()
+		if countErr != nil { t.Fatalf("error counting keys: %s", countErr.Error()) }
+
+		cloneCount, countErr := cloneInst.Count()
+		if countErr != nil { t.Fatalf("error counting clone keys: %s", countErr.Error()) }
+		if cloneCount != expectedCount { t.Errorf("clone count does not match: actual(%d), expected(%d)", cloneCount, expectedCount) }
+
+		putErr = cloneInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("clone:only"), []byte("clone"))
+		})
+
+		if putErr != nil { t.Fatalf("error on clone put: %s", putErr.Error()) }
+
+		readErr := copyInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("clone:only"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair != nil { t.Errorf("write to clone is visible in the source instance") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+
+		readErr = cloneInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("batch"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || string(kvPair.Value) != "root" { t.Errorf("clone value does not match source: %v", kvPair) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on clone get: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Backup", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		written, backupErr := copyInst.Backup(&buf)
+		if backupErr != nil { t.Fatalf("error on mari backup: %s", backupErr.Error()) }
+		if written != int64(buf.Len()) { t.Errorf("bytes written does not match buffer: actual(%d), expected(%d)", written, buf.Len()) }
+
+		backup := buf.Bytes()
+		if ! bytes.Equal(backup[:8], []byte(mari.BackupMagic)) { t.Fatalf("backup does not begin with the backup magic") }
+
+		trailer := backup[len(backup) - mari.BackupTrailerSize:]
+		rootOffset := binary.LittleEndian.Uint64(trailer[:8])
+		endOffset := binary.LittleEndian.Uint64(trailer[8:])
+
+		nodes := backup[mari.BackupHeaderSize:len(backup) - mari.BackupTrailerSize]
+		if endOffset != uint64(mari.InitRootOffset + len(nodes)) { t.Fatalf("backup end offset does not match serialized nodes: actual(%d), expected(%d)", endOffset, mari.InitRootOffset + len(nodes)) }
+
+		restorePath := filepath.Join(os.TempDir(), "testmaribackup")
+		os.Remove(restorePath)
+		os.Remove(restorePath + ".vidx")
+
+		restored := make([]byte, mari.InitRootOffset)
+		binary.LittleEndian.PutUint64(restored[8:16], rootOffset)
+		binary.LittleEndian.PutUint64(restored[16:24], endOffset)
+		copy(restored[mari.MetaFormatIdx:], mari.FileFormatMagic)
+		restored = append(restored, nodes...)
+
+		writeErr := os.WriteFile(restorePath, restored, 0600)
+		if writeErr != nil { t.Fatalf("error writing restored file: %s", writeErr.Error()) }
+
+		restoreInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmaribackup", NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening restored file: %s", openErr.Error()) }
+		defer restoreInst.Remove()
+
+		expectedCount, countErr := copyInst.Count()
+		if countErr != nil { t.Fatalf("error counting keys: %s", countErr.Error()) }
+
+		restoredCount, countErr := restoreInst.Count()
+		if countErr != nil { t.Fatalf("error counting restored keys: %s", countErr.Error()) }
+		if restoredCount != expectedCount { t.Errorf("restored count does not match: actual(%d), expected(%d)", restoredCount, expectedCount) }
+
+		readErr := restoreInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("batch:a:1"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || string(kvPair.Value) != "a1" { t.Errorf("restored value does not match source: %v", kvPair) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on restored get: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Export JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exportErr := copyInst.ExportJSON(&buf)
+		if exportErr != nil { t.Fatalf("error on mari export: %s", exportErr.Error()) }
+
+		var exported []mari.MariJSONPair
+		unmarshalErr := json.Unmarshal(buf.Bytes(), &exported)
+		if unmarshalErr != nil { t.Fatalf("export is not valid json: %s", unmarshalErr.Error()) }
+
+		readErr := copyInst.ReadTx(func(tx *mari.MariTx) error {
+			var idx int
+
+			forEachErr := tx.ForEach(nil, func(kvPair *mari.KeyValuePair) (bool, error) {
+				if idx >= len(exported) { return false, fmt.Errorf("export is missing pairs from index %d", idx) }
+
+				pair := exported[idx]
+				if ! bytes.Equal(pair.Key, kvPair.Key) || ! bytes.Equal(pair.Value, kvPair.Value) || pair.Version != kvPair.Version {
+					t.Errorf("exported pair does not match at %d: actual(%v), expected(%v)", idx, pair, kvPair)
+				}
+
+				idx++
+				return true, nil
+			})
+
+			if forEachErr != nil { return forEachErr }
+			if idx != len(exported) { t.Errorf("total exported does not match: actual(%d), expected(%d)", len(exported), idx) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Import JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exportErr := copyInst.ExportJSON(&buf)
+		if exportErr != nil { t.Fatalf("error on mari export: %s", exportErr.Error()) }
+
+		importInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening import instance: %s", openErr.Error()) }
+		defer importInst.Close()
+
+		imported, importErr := importInst.ImportJSON(&buf)
+		if importErr != nil { t.Fatalf("error on mari import: %s", importErr.Error()) }
+
+		expectedCount, countErr := copyInst.Count()
+		if countErr != nil { t.Fatalf("error counting keys: %s", countErr.Error()) }
+		if imported != expectedCount { t.Errorf("total imported does not match: actual(%d), expected(%d)", imported, expectedCount) }
+
+		importedCount, countErr := importInst.Count()
+		if countErr != nil { t.Fatalf("error counting imported keys: %s", countErr.Error()) }
+		if importedCount != expectedCount { t.Errorf("imported count does not match: actual(%d), expected(%d)", importedCount, expectedCount) }
+
+		readErr := importInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("batch:a:1"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || string(kvPair.Value) != "a1" { t.Errorf("imported value does not match source: %v", kvPair) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on imported get: %s", readErr.Error()) }
+
+		_, invalidErr := importInst.ImportJSON(strings.NewReader(`{"key": "aGVsbG8="}`))
+		if invalidErr == nil { t.Errorf("expected error importing json that is not an array") }
+	})
+}
@@ -0,0 +1,217 @@
+package maritests
+
+import "bytes"
+import "context"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var cursorMariInst *mari.Mari
+var cursorKeyValPairs []KeyVal
+var cursorInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testcursor"))
+	os.Remove(filepath.Join(os.TempDir(), "testcursortemp"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testcursor", NodePoolSize: &smallNodePoolSize }
+
+	cursorMariInst, cursorInitMariErr = mari.Open(opts)
+	if cursorInitMariErr != nil {
+		cursorMariInst.Remove()
+		panic(cursorInitMariErr.Error())
+	}
+
+	fmt.Println("cursor test mari initialized")
+}
+
+
+func TestMariCursor(t *testing.T) {
+	defer cursorMariInst.Remove()
+
+	cursorKeyValPairs = GenerateKeyValPairs(InputSize(CURSOR_INPUT_SIZE))
+	inputSize := len(cursorKeyValPairs)
+
+	t.Run("Test Cursor Scan", func(t *testing.T) {
+		chunks, chunkErr := Chunk(cursorKeyValPairs, TRANSACTION_CHUNK_SIZE)
+		if chunkErr != nil { t.Fatalf("error chunking input: %s", chunkErr.Error()) }
+
+		for _, chunk := range chunks {
+			putErr := cursorMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for _, val := range chunk {
+					putTxErr := tx.Put(val.Key, val.Value)
+					if putTxErr != nil { return putTxErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		readErr := cursorMariInst.ReadTx(func(tx *mari.MariTx) error {
+			cursor := tx.Cursor(nil)
+			defer cursor.Close()
+
+			var prev *mari.KeyValuePair
+			var totalScanned int
+
+			for {
+				kvPair, ok, nextErr := cursor.Next()
+				if nextErr != nil { return nextErr }
+				if ! ok { break }
+
+				if prev != nil && bytes.Compare(prev.Key, kvPair.Key) != -1 { t.Fatalf("cursor is not in ascending order at %d", totalScanned) }
+				if ! bytes.Equal(kvPair.Key, kvPair.Value) { t.Fatalf("value does not match key at %d", totalScanned) }
+
+				prev = kvPair
+				totalScanned++
+			}
+
+			if totalScanned != inputSize { t.Errorf("total scanned does not match: actual(%d), expected(%d)", totalScanned, inputSize) }
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari cursor: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Cursor Start Key And Seek", func(t *testing.T) {
+		readErr := cursorMariInst.ReadTx(func(tx *mari.MariTx) error {
+			startKey := cursorKeyValPairs[0].Key
+
+			cursor := tx.Cursor(startKey)
+			defer cursor.Close()
+
+			kvPair, ok, nextErr := cursor.Next()
+			if nextErr != nil { return nextErr }
+			if ! ok || ! bytes.Equal(kvPair.Key, startKey) { t.Errorf("cursor did not begin at the start key") }
+
+			for _, val := range cursorKeyValPairs[1:CURSOR_SEEKS] {
+				seekErr := cursor.Seek(val.Key)
+				if seekErr != nil { return seekErr }
+
+				kvPair, ok, nextErr = cursor.Next()
+				if nextErr != nil { return nextErr }
+				if ! ok || ! bytes.Equal(kvPair.Key, val.Key) { t.Errorf("cursor did not seek to key: %v", val.Key) }
+
+				next, ok, nextErr := cursor.Next()
+				if nextErr != nil { return nextErr }
+				if ok && bytes.Compare(kvPair.Key, next.Key) != -1 { t.Errorf("cursor is not in ascending order after seek") }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari cursor: %s", readErr.Error()) }
+	})
+
+	t.Run("Test ForEach", func(t *testing.T) {
+		readErr := cursorMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var prev *mari.KeyValuePair
+			var totalVisited int
+
+			forEachErr := tx.ForEach(nil, func(kvPair *mari.KeyValuePair) (bool, error) {
+				if prev != nil && bytes.Compare(prev.Key, kvPair.Key) != -1 { return false, fmt.Errorf("for each is not in ascending order at %d", totalVisited) }
+
+				prev = kvPair
+				totalVisited++
+				return true, nil
+			})
+
+			if forEachErr != nil { return forEachErr }
+			if totalVisited != inputSize { t.Errorf("total visited does not match: actual(%d), expected(%d)", totalVisited, inputSize) }
+
+			totalVisited = 0
+			forEachErr = tx.ForEach(cursorKeyValPairs[0].Key, func(kvPair *mari.KeyValuePair) (bool, error) {
+				if totalVisited == 0 && ! bytes.Equal(kvPair.Key, cursorKeyValPairs[0].Key) { t.Errorf("for each did not begin at the start key") }
+
+				totalVisited++
+				return totalVisited < CURSOR_SEEKS, nil
+			})
+
+			if forEachErr != nil { return forEachErr }
+			if totalVisited != CURSOR_SEEKS { t.Errorf("for each did not stop early: visited(%d), expected(%d)", totalVisited, CURSOR_SEEKS) }
+
+			abortErr := fmt.Errorf("abort")
+			forEachErr = tx.ForEach(nil, func(kvPair *mari.KeyValuePair) (bool, error) { return true, abortErr })
+			if forEachErr != abortErr { t.Errorf("expected callback error to be returned: %v", forEachErr) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari for each: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Range And Iterate With Context", func(t *testing.T) {
+		readErr := cursorMariInst.ReadTx(func(tx *mari.MariTx) error {
+			first, minErr := tx.MinKey()
+			if minErr != nil { return minErr }
+
+			last, maxErr := tx.MaxKey()
+			if maxErr != nil { return maxErr }
+
+			kvPairs, rangeErr := tx.RangeCtx(context.Background(), first.Key, last.Key, nil)
+			if rangeErr != nil { return rangeErr }
+			if len(kvPairs) != inputSize { t.Errorf("range with context count does not match: actual(%d), expected(%d)", len(kvPairs), inputSize) }
+
+			cancelledCtx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, rangeErr = tx.RangeCtx(cancelledCtx, first.Key, last.Key, nil)
+			if ! errors.Is(rangeErr, context.Canceled) { t.Errorf("expected range on cancelled context to return context canceled: %v", rangeErr) }
+
+			_, iterErr := tx.IterateCtx(cancelledCtx, first.Key, inputSize, nil)
+			if ! errors.Is(iterErr, context.Canceled) { t.Errorf("expected iterate on cancelled context to return context canceled: %v", iterErr) }
+
+			for _, scan := range []string{ "range", "iterate" } {
+				scanCtx, cancelScan := context.WithCancel(context.Background())
+
+				var totalSeen int
+				transform := func(kvPair *mari.KeyValuePair) *mari.KeyValuePair {
+					totalSeen++
+					if totalSeen == CURSOR_SEEKS { cancelScan() }
+					return kvPair
+				}
+
+				var scanErr error
+				switch scan {
+					case "range":
+						_, scanErr = tx.RangeCtx(scanCtx, first.Key, last.Key, &mari.MariRangeOpts{ Transform: &transform })
+					default:
+						_, scanErr = tx.IterateCtx(scanCtx, first.Key, inputSize, &mari.MariRangeOpts{ Transform: &transform })
+				}
+
+				cancelScan()
+
+				if ! errors.Is(scanErr, context.Canceled) { t.Errorf("expected %s cancelled mid scan to return context canceled: %v", scan, scanErr) }
+				if totalSeen >= inputSize { t.Errorf("%s did not stop after cancellation: seen(%d)", scan, totalSeen) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari scan with context: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Closed Cursor", func(t *testing.T) {
+		readErr := cursorMariInst.ReadTx(func(tx *mari.MariTx) error {
+			cursor := tx.Cursor(nil)
+
+			closeErr := cursor.Close()
+			if closeErr != nil { return closeErr }
+
+			_, _, nextErr := cursor.Next()
+			if nextErr == nil { t.Errorf("expected error advancing a closed cursor") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari cursor: %s", readErr.Error()) }
+	})
+}
@@ -0,0 +1,110 @@
+package maritests
+
+import "bytes"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariDebug(t *testing.T) {
+	t.Run("Test Mari Dump Tree", func(t *testing.T) {
+		dumpInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening dump instance: %s", openErr.Error()) }
+		defer dumpInst.Close()
+
+		updateErr := dumpInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("dump1"), []byte("value1"))
+			if putErr != nil { return putErr }
+
+			return tx.Put([]byte("dump2"), []byte("value2"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		var dump bytes.Buffer
+		dumpErr := dumpInst.DumpTree(&dump)
+		if dumpErr != nil { t.Fatalf("error on mari dump tree: %s", dumpErr.Error()) }
+
+		output := dump.String()
+		if ! strings.Contains(output, "Key: dump1, Value: value1, Version:1") || ! strings.Contains(output, "Key: dump2, Value: value2, Version:1") {
+			t.Errorf("dump does not contain the inserted pairs: %s", output)
+		}
+
+		if ! strings.HasSuffix(output, "total count of elements: 2\n") { t.Errorf("dump does not end with the total count: %s", output) }
+	})
+
+	t.Run("Test Mari Export DOT", func(t *testing.T) {
+		dotInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening dot instance: %s", openErr.Error()) }
+		defer dotInst.Close()
+
+		updateErr := dotInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range []string{ "a", "ab", "b" } {
+				putErr := tx.Put([]byte(key), []byte("value"))
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		var full bytes.Buffer
+		exportErr := dotInst.ExportDOT(&full, -1)
+		if exportErr != nil { t.Fatalf("error on mari export dot: %s", exportErr.Error()) }
+
+		output := full.String()
+		if ! strings.HasPrefix(output, "digraph mari {") || ! strings.HasSuffix(output, "}\n") { t.Errorf("dot output is not a digraph: %s", output) }
+		if ! strings.Contains(output, `key: \"ab\"`) { t.Errorf("dot output does not contain the nested key: %s", output) }
+		if strings.Contains(output, "omitted") { t.Errorf("expected no omitted children without a max depth: %s", output) }
+
+		var bounded bytes.Buffer
+		exportErr = dotInst.ExportDOT(&bounded, 0)
+		if exportErr != nil { t.Fatalf("error on mari export dot: %s", exportErr.Error()) }
+
+		boundedOutput := bounded.String()
+		if ! strings.Contains(boundedOutput, "2 children omitted") || strings.Contains(boundedOutput, "key:") {
+			t.Errorf("expected children of the root to be omitted at max depth 0: %s", boundedOutput)
+		}
+	})
+
+	t.Run("Test Mari Depth", func(t *testing.T) {
+		depthInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening depth instance: %s", openErr.Error()) }
+		defer depthInst.Close()
+
+		readDepth := func() int {
+			var depth int
+			readErr := depthInst.ReadTx(func(tx *mari.MariTx) error {
+				var depthErr error
+				depth, depthErr = tx.Depth()
+				return depthErr
+			})
+
+			if readErr != nil { t.Fatalf("error on mari depth: %s", readErr.Error()) }
+			return depth
+		}
+
+		if depth := readDepth(); depth != 0 { t.Errorf("expected depth of empty trie to be 0: actual(%d)", depth) }
+
+		prefix := bytes.Repeat([]byte("p"), 64)
+		putErr := depthInst.UpdateTx(func(tx *mari.MariTx) error {
+			for idx := 0; idx < 10; idx++ {
+				putErr := tx.Put(append(append([]byte{}, prefix...), byte('0' + idx)), []byte("value"))
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		depth := readDepth()
+		if depth < len(prefix) { t.Errorf("expected depth to reach the shared prefix: actual(%d), expected at least(%d)", depth, len(prefix)) }
+
+		stats, statsErr := depthInst.Stats()
+		if statsErr != nil { t.Fatalf("error on mari stats: %s", statsErr.Error()) }
+		if stats.Depth != depth { t.Errorf("depth does not match stats: actual(%d), expected(%d)", depth, stats.Depth) }
+	})
+}
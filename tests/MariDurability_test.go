@@ -0,0 +1,93 @@
+package maritests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariDurability(t *testing.T) {
+	t.Run("Test Mari Flush Interval", func(t *testing.T) {
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, FlushInterval: -time.Second })
+		if invalidErr == nil { t.Errorf("expected a negative flush interval to fail") }
+
+		os.Remove(filepath.Join(os.TempDir(), "testmariflush"))
+		os.Remove(filepath.Join(os.TempDir(), "testmariflush.vidx"))
+
+		flushInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmariflush", FlushInterval: 50 * time.Millisecond, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening flush interval instance: %s", openErr.Error()) }
+		defer flushInst.Remove()
+
+		for idx := 0; idx < 100; idx++ {
+			putErr := flushInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("flush%d", idx)), []byte("value"))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		for start := time.Now(); time.Since(start) < 10 * time.Second; time.Sleep(10 * time.Millisecond) {
+			if flushInst.Metrics().Flushes > 0 { break }
+		}
+
+		flushes := flushInst.Metrics().Flushes
+		t.Logf("writes: %d, timed flushes: %d", 100, flushes)
+		if flushes == 0 { t.Errorf("expected the pending writes to be flushed on the timer") }
+		if flushes >= 100 { t.Errorf("expected writes to be coalesced into fewer flushes: actual(%d)", flushes) }
+
+		time.Sleep(200 * time.Millisecond)
+		if flushInst.Metrics().Flushes != flushes { t.Errorf("expected no timed flushes without pending writes") }
+	})
+
+	t.Run("Test Mari Durability", func(t *testing.T) {
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, Durability: mari.DurabilitySync + 1 })
+		if invalidErr == nil { t.Errorf("expected an unknown durability level to fail") }
+
+		_, invalidErr = mari.Open(mari.MariOpts{ InMemory: true, Durability: mari.DurabilitySync, FlushInterval: time.Second })
+		if invalidErr == nil { t.Errorf("expected a flush interval without async durability to fail") }
+
+		for _, durability := range []mari.DurabilityLevel{ mari.DurabilityNoSync, mari.DurabilitySync } {
+			os.Remove(filepath.Join(os.TempDir(), "testmaridurability"))
+			os.Remove(filepath.Join(os.TempDir(), "testmaridurability.vidx"))
+
+			opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmaridurability", Durability: durability, NodePoolSize: &smallNodePoolSize }
+			durabilityInst, openErr := mari.Open(opts)
+			if openErr != nil { t.Fatalf("error opening durability instance: %s", openErr.Error()) }
+
+			for idx := 0; idx < 10; idx++ {
+				putErr := durabilityInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Put([]byte(fmt.Sprintf("durable%d", idx)), []byte("value"))
+				})
+
+				if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+			}
+
+			flushes := durabilityInst.Metrics().Flushes
+			switch durability {
+				case mari.DurabilitySync:
+					if flushes != 10 { t.Errorf("expected a flush per write transaction: actual(%d), expected(10)", flushes) }
+				default:
+					if flushes != 0 { t.Errorf("expected no flushes without sync: actual(%d)", flushes) }
+			}
+
+			closeErr := durabilityInst.Close()
+			if closeErr != nil { t.Fatalf("error closing durability instance: %s", closeErr.Error()) }
+
+			durabilityInst, openErr = mari.Open(opts)
+			if openErr != nil { t.Fatalf("error reopening durability instance: %s", openErr.Error()) }
+
+			readErr := durabilityInst.ReadTx(func(tx *mari.MariTx) error {
+				count, countErr := tx.Count()
+				if countErr != nil { return countErr }
+				if count != 10 { t.Errorf("keys do not match after reopen: actual(%d), expected(10)", count) }
+				return nil
+			})
+
+			if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+			durabilityInst.Remove()
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var emptyKeyMariInst *mari.Mari
+var emptyKeyOpts mari.MariOpts
+var emptyKeyInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testemptykey"))
+	os.Remove(filepath.Join(os.TempDir(), "testemptykeytemp"))
+
+	emptyKeyOpts = mari.MariOpts{ Filepath: os.TempDir(), FileName: "testemptykey", NodePoolSize: &smallNodePoolSize }
+
+	emptyKeyMariInst, emptyKeyInitMariErr = mari.Open(emptyKeyOpts)
+	if emptyKeyInitMariErr != nil {
+		emptyKeyMariInst.Remove()
+		panic(emptyKeyInitMariErr.Error())
+	}
+
+	fmt.Println("empty key test mari initialized")
+}
+
+
+func TestMariEmptyKey(t *testing.T) {
+	defer func() { emptyKeyMariInst.Remove() }()
+
+	keys := [][]byte{ []byte("a"), []byte("ab"), []byte("b") }
+
+	t.Run("Test Delete Missing Empty Key", func(t *testing.T) {
+		putErr := emptyKeyMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range keys {
+				putTxErr := tx.Put(key, key)
+				if putTxErr != nil { return putTxErr }
+			}
+
+			return tx.Delete([]byte{})
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := emptyKeyMariInst.ReadTx(func(tx *mari.MariTx) error {
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != len(keys) { t.Errorf("count does not match: actual(%d), expected(%d)", count, len(keys)) }
+
+			kvPair, getErr := tx.Get([]byte{}, nil)
+			if getErr != nil { return getErr }
+			if kvPair != nil { t.Errorf("expected empty key to not exist: %v", kvPair) }
+
+			exists, hasErr := tx.Has([]byte{})
+			if hasErr != nil { return hasErr }
+			if exists { t.Error("expected has to be false for the empty key") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Put And Get Empty Key", func(t *testing.T) {
+		putErr := emptyKeyMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte{}, []byte("empty"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := emptyKeyMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, key := range [][]byte{ []byte{}, nil } {
+				kvPair, getErr := tx.Get(key, nil)
+				if getErr != nil { return getErr }
+				if kvPair == nil { t.Fatal("expected empty key to exist") }
+				if len(kvPair.Key) != 0 || string(kvPair.Value) != "empty" { t.Errorf("empty key does not match: key(%v), value(%s)", kvPair.Key, kvPair.Value) }
+			}
+
+			exists, hasErr := tx.Has([]byte{})
+			if hasErr != nil { return hasErr }
+			if ! exists { t.Error("expected has to be true for the empty key") }
+
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != len(keys) + 1 { t.Errorf("count does not match: actual(%d), expected(%d)", count, len(keys) + 1) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Empty Key Sorts First", func(t *testing.T) {
+		readErr := emptyKeyMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, rangeErr := tx.Range([]byte{}, []byte("z"), nil)
+			if rangeErr != nil { return rangeErr }
+			if len(kvPairs) != len(keys) + 1 { t.Fatalf("range length does not match: actual(%d), expected(%d)", len(kvPairs), len(keys) + 1) }
+			if len(kvPairs[0].Key) != 0 { t.Errorf("expected empty key first: actual(%s)", kvPairs[0].Key) }
+
+			for idx, key := range keys {
+				if ! bytes.Equal(kvPairs[idx + 1].Key, key) { t.Errorf("range key does not match: actual(%s), expected(%s)", kvPairs[idx + 1].Key, key) }
+			}
+
+			for _, startKey := range [][]byte{ []byte{}, nil } {
+				iterPairs, iterErr := tx.Iterate(startKey, len(keys) + 1, nil)
+				if iterErr != nil { return iterErr }
+				if len(iterPairs) != len(keys) + 1 { t.Fatalf("iterate length does not match: actual(%d), expected(%d)", len(iterPairs), len(keys) + 1) }
+				if len(iterPairs[0].Key) != 0 { t.Errorf("expected empty key first in iterate: actual(%s)", iterPairs[0].Key) }
+
+				for idx, key := range keys {
+					if ! bytes.Equal(iterPairs[idx + 1].Key, key) { t.Errorf("iterate key does not match: actual(%s), expected(%s)", iterPairs[idx + 1].Key, key) }
+				}
+			}
+
+			rank, rankErr := tx.Rank([]byte("a"))
+			if rankErr != nil { return rankErr }
+			if rank != 1 { t.Errorf("rank does not match: actual(%d), expected(1)", rank) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Empty Key Survives Reopen", func(t *testing.T) {
+		closeErr := emptyKeyMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		var openErr error
+		emptyKeyMariInst, openErr = mari.Open(emptyKeyOpts)
+		if openErr != nil { t.Fatalf("error reopening mari: %s", openErr.Error()) }
+
+		readErr := emptyKeyMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte{}, nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || string(kvPair.Value) != "empty" { t.Fatalf("expected empty key to exist after reopen: %v", kvPair) }
+
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != len(keys) + 1 { t.Errorf("count does not match: actual(%d), expected(%d)", count, len(keys) + 1) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := emptyKeyMariInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying mari: %s", verifyErr.Error()) }
+	})
+
+	t.Run("Test Delete Empty Key", func(t *testing.T) {
+		delErr := emptyKeyMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Delete([]byte{})
+		})
+
+		if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+
+		readErr := emptyKeyMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte{}, nil)
+			if getErr != nil { return getErr }
+			if kvPair != nil { t.Errorf("expected empty key to be deleted: %v", kvPair) }
+
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != len(keys) { t.Errorf("count does not match: actual(%d), expected(%d)", count, len(keys)) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+	})
+}
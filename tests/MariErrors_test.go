@@ -0,0 +1,82 @@
+package maritests
+
+import "bytes"
+import "errors"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariErrors(t *testing.T) {
+	errInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer errInst.Close()
+
+	t.Run("Test Mari Typed Errors", func(t *testing.T) {
+		readErr := errInst.ReadTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("readonly"), []byte("value"))
+			if ! errors.Is(putErr, mari.ErrReadOnlyTx) { t.Errorf("expected read only transaction error on put: actual(%v)", putErr) }
+
+			deleteErr := tx.Delete([]byte("readonly"))
+			if ! errors.Is(deleteErr, mari.ErrReadOnlyTx) { t.Errorf("expected read only transaction error on delete: actual(%v)", deleteErr) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+
+		closedInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening typed errors instance: %s", openErr.Error()) }
+
+		closeErr := closedInst.Close()
+		if closeErr != nil { t.Fatalf("error closing typed errors instance: %s", closeErr.Error()) }
+
+		syncErr := closedInst.Sync()
+		if ! errors.Is(syncErr, mari.ErrClosed) { t.Errorf("expected closed error on sync: actual(%v)", syncErr) }
+
+		_, statsErr := closedInst.Stats()
+		if ! errors.Is(statsErr, mari.ErrClosed) { t.Errorf("expected closed error on stats: actual(%v)", statsErr) }
+	})
+
+	t.Run("Test Mari Key Too Long", func(t *testing.T) {
+		longKey := bytes.Repeat([]byte("k"), TOO_LONG_KEY_SIZE)
+		maxKey := bytes.Repeat([]byte("m"), mari.MaxKeyLength)
+
+		putErr := errInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(longKey, []byte("value"))
+		})
+
+		if ! errors.Is(putErr, mari.ErrKeyTooLong) { t.Errorf("expected key too long error: actual(%v)", putErr) }
+
+		putErr = errInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(maxKey, []byte("value"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put of key with the maximum length: %s", putErr.Error()) }
+
+		getErr := errInst.ReadTx(func(tx *mari.MariTx) error {
+			exists, hasErr := tx.Has(longKey)
+			if hasErr != nil { return hasErr }
+			if exists { t.Error("expected rejected key to not exist") }
+
+			truncatedLength := uint16(len(longKey))
+			exists, hasErr = tx.Has(longKey[:truncatedLength])
+			if hasErr != nil { return hasErr }
+			if exists { t.Error("expected truncated key to not exist") }
+
+			kvPair, getTxErr := tx.Get(maxKey, nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || ! bytes.Equal(kvPair.Key, maxKey) { t.Error("key with the maximum length does not match after put") }
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+
+		delErr := errInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Delete(maxKey)
+		})
+
+		if delErr != nil { t.Errorf("error on mari delete: %s", delErr.Error()) }
+	})
+}
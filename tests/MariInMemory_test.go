@@ -0,0 +1,163 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync/atomic"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mari"
+
+
+var inMemoryMariInst *mari.Mari
+var inMemoryKeyValPairs []KeyVal
+var inMemoryInitMariErr error
+var inMemoryCompactNow uint32
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testinmemory"))
+
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&inMemoryCompactNow, 1, 0)
+	}
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testinmemory", CompactTrigger: &compactTrigger, InMemory: true, NodePoolSize: &smallNodePoolSize }
+
+	inMemoryMariInst, inMemoryInitMariErr = mari.Open(opts)
+	if inMemoryInitMariErr != nil {
+		inMemoryMariInst.Remove()
+		panic(inMemoryInitMariErr.Error())
+	}
+
+	fmt.Println("in memory test mari initialized")
+}
+
+
+func TestMariInMemory(t *testing.T) {
+	defer inMemoryMariInst.Remove()
+
+	inMemoryKeyValPairs = GenerateKeyValPairs(InputSize(IN_MEMORY_INPUT_SIZE))
+	inputSize := len(inMemoryKeyValPairs)
+
+	t.Run("Test Write Operations", func(t *testing.T) {
+		for _, val := range inMemoryKeyValPairs {
+			putErr := inMemoryMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put(val.Key, val.Value)
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		fileSize, sizeErr := inMemoryMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		t.Logf("in memory size after writes: %d", fileSize)
+	})
+
+	t.Run("Test No Files Created", func(t *testing.T) {
+		for _, fileName := range []string{ "testinmemory", "testinmemory.vidx" } {
+			_, statErr := os.Stat(filepath.Join(os.TempDir(), fileName))
+			if ! os.IsNotExist(statErr) { t.Errorf("expected no file on disk for in memory instance: %s", fileName) }
+		}
+	})
+
+	t.Run("Test Read Operations", func(t *testing.T) {
+		for _, val := range inMemoryKeyValPairs {
+			readErr := inMemoryMariInst.ReadTx(func(tx *mari.MariTx) error {
+				kvPair, getTxErr := tx.Get(val.Key, nil)
+				if getTxErr != nil { return getTxErr }
+
+				if kvPair == nil || ! bytes.Equal(kvPair.Value, val.Value) {
+					t.Errorf("actual value not equal to expected: actual(%v), expected(%v)", kvPair, val)
+				}
+
+				return nil
+			})
+
+			if readErr != nil { t.Fatalf("error on mari get: %s", readErr.Error()) }
+		}
+	})
+
+	t.Run("Test Iterate And Range Operations", func(t *testing.T) {
+		readErr := inMemoryMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var first, last *mari.KeyValuePair
+			var totalVisited int
+
+			forEachErr := tx.ForEach(nil, func(kvPair *mari.KeyValuePair) (bool, error) {
+				if first == nil { first = kvPair }
+				last = kvPair
+				totalVisited++
+				return true, nil
+			})
+
+			if forEachErr != nil { return forEachErr }
+			if totalVisited != inputSize { t.Fatalf("for each count does not match: actual(%d), expected(%d)", totalVisited, inputSize) }
+
+			kvPairs, iterErr := tx.Iterate(first.Key, inputSize, nil)
+			if iterErr != nil { return iterErr }
+			if len(kvPairs) != inputSize { t.Errorf("iterate count does not match: actual(%d), expected(%d)", len(kvPairs), inputSize) }
+
+			rangePairs, rangeErr := tx.Range(first.Key, last.Key, nil)
+			if rangeErr != nil { return rangeErr }
+			if len(rangePairs) != inputSize { t.Errorf("range count does not match: actual(%d), expected(%d)", len(rangePairs), inputSize) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+
+	remaining := inMemoryKeyValPairs[:inputSize / 10]
+
+	t.Run("Test Delete Then Compact", func(t *testing.T) {
+		for _, val := range inMemoryKeyValPairs[inputSize / 10:] {
+			delErr := inMemoryMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Delete(val.Key)
+			})
+
+			if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+		}
+
+		sizeBefore, sizeErr := inMemoryMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		atomic.StoreUint32(&inMemoryCompactNow, 1)
+
+		putErr := inMemoryMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(remaining[0].Key, remaining[0].Value)
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		sizeAfter := sizeBefore
+		for start := time.Now(); time.Since(start) < 10 * time.Second; time.Sleep(10 * time.Millisecond) {
+			sizeAfter, sizeErr = inMemoryMariInst.FileSize()
+			if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+			if sizeAfter < sizeBefore { break }
+		}
+
+		t.Logf("in memory size before compaction: %d, in memory size after compaction: %d", sizeBefore, sizeAfter)
+		if sizeAfter >= sizeBefore { t.Errorf("in memory size did not drop after compaction: before(%d), after(%d)", sizeBefore, sizeAfter) }
+
+		for _, val := range remaining {
+			readErr := inMemoryMariInst.ReadTx(func(tx *mari.MariTx) error {
+				kvPair, getTxErr := tx.Get(val.Key, nil)
+				if getTxErr != nil { return getTxErr }
+
+				if kvPair == nil || ! bytes.Equal(kvPair.Value, val.Value) {
+					t.Errorf("actual value not equal to expected: actual(%v), expected(%v)", kvPair, val)
+				}
+
+				return nil
+			})
+
+			if readErr != nil { t.Fatalf("error on mari get: %s", readErr.Error()) }
+		}
+
+		totalCount, countErr := inMemoryMariInst.Count()
+		if countErr != nil { t.Fatalf("error counting keys: %s", countErr.Error()) }
+		if totalCount != len(remaining) { t.Errorf("count does not match expected: actual(%d), expected(%d)", totalCount, len(remaining)) }
+	})
+}
@@ -0,0 +1,91 @@
+package maritests
+
+import "bytes"
+import "math/rand"
+import "sort"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariKeyEncoding(t *testing.T) {
+	t.Run("Test Uint64 Keys Round Trip And Sort", func(t *testing.T) {
+		values := make([]uint64, KEY_ENCODING_INPUT_SIZE)
+		encoded := make([][]byte, KEY_ENCODING_INPUT_SIZE)
+
+		for idx := range values {
+			values[idx] = rand.Uint64()
+			encoded[idx] = mari.EncodeUint64(values[idx])
+
+			decoded, decodeErr := mari.DecodeUint64(encoded[idx])
+			if decodeErr != nil { t.Fatalf("error decoding uint64 key: %s", decodeErr.Error()) }
+			if decoded != values[idx] { t.Errorf("decoded uint64 does not match: actual(%d), expected(%d)", decoded, values[idx]) }
+		}
+
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) == -1 })
+
+		for idx := range values {
+			decoded, _ := mari.DecodeUint64(encoded[idx])
+			if decoded != values[idx] { t.Fatalf("byte order does not match numeric order at %d: actual(%d), expected(%d)", idx, decoded, values[idx]) }
+		}
+	})
+
+	t.Run("Test Int64 Keys Round Trip And Sort", func(t *testing.T) {
+		values := make([]int64, KEY_ENCODING_INPUT_SIZE)
+		encoded := make([][]byte, KEY_ENCODING_INPUT_SIZE)
+
+		for idx := range values {
+			values[idx] = int64(rand.Uint64())
+			encoded[idx] = mari.EncodeInt64(values[idx])
+
+			decoded, decodeErr := mari.DecodeInt64(encoded[idx])
+			if decodeErr != nil { t.Fatalf("error decoding int64 key: %s", decodeErr.Error()) }
+			if decoded != values[idx] { t.Errorf("decoded int64 does not match: actual(%d), expected(%d)", decoded, values[idx]) }
+		}
+
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) == -1 })
+
+		for idx := range values {
+			decoded, _ := mari.DecodeInt64(encoded[idx])
+			if decoded != values[idx] { t.Fatalf("byte order does not match numeric order at %d: actual(%d), expected(%d)", idx, decoded, values[idx]) }
+		}
+
+		_, invalidErr := mari.DecodeInt64([]byte{ 0x01 })
+		if invalidErr == nil { t.Errorf("expected error decoding a key of the wrong length") }
+	})
+
+	t.Run("Test Int64 Keys Range In Numeric Order", func(t *testing.T) {
+		encodingInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening key encoding instance: %s", openErr.Error()) }
+		defer encodingInst.Close()
+
+		putErr := encodingInst.UpdateTx(func(tx *mari.MariTx) error {
+			for v := int64(-50); v < 50; v++ {
+				putErr := tx.Put(mari.EncodeInt64(v), nil)
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := encodingInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, rangeErr := tx.Range(mari.EncodeInt64(-10), mari.EncodeInt64(10), nil)
+			if rangeErr != nil { return rangeErr }
+			if len(kvPairs) != 21 { t.Errorf("range length does not match: actual(%d), expected(21)", len(kvPairs)) }
+
+			for idx, kvPair := range kvPairs {
+				decoded, decodeErr := mari.DecodeInt64(kvPair.Key)
+				if decodeErr != nil { return decodeErr }
+				if decoded != int64(idx - 10) { t.Errorf("range key out of order: actual(%d), expected(%d)", decoded, idx - 10) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+}
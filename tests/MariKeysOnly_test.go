@@ -0,0 +1,186 @@
+package maritests
+
+import "bytes"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariKeysOnly(t *testing.T) {
+	t.Run("Test Mari Keys Only", func(t *testing.T) {
+		os.Remove(filepath.Join(os.TempDir(), "testmarikeysonly"))
+		os.Remove(filepath.Join(os.TempDir(), "testmarikeysonly.vidx"))
+
+		codec, codecErr := mari.NewEncryptionCodec(bytes.Repeat([]byte("k"), 32))
+		if codecErr != nil { t.Fatalf("error creating encryption codec: %s", codecErr.Error()) }
+
+		keysOnlyInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmarikeysonly", ValueCodec: codec, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening keys only instance: %s", openErr.Error()) }
+
+		keys := []string{ "a", "ab", "abc", "b", "ba", "c" }
+		updateErr := keysOnlyInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range keys {
+				putErr := tx.Put([]byte(key), []byte("value"))
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		closeErr := keysOnlyInst.Close()
+		if closeErr != nil { t.Fatalf("error closing keys only instance: %s", closeErr.Error()) }
+
+		wrongCodec, codecErr := mari.NewEncryptionCodec(bytes.Repeat([]byte("w"), 32))
+		if codecErr != nil { t.Fatalf("error creating encryption codec: %s", codecErr.Error()) }
+
+		keysOnlyInst, openErr = mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmarikeysonly", ValueCodec: wrongCodec, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error reopening keys only instance: %s", openErr.Error()) }
+		defer keysOnlyInst.Remove()
+
+		checkKeys := func(name string, kvPairs []*mari.KeyValuePair, expected []string) {
+			if len(kvPairs) != len(expected) { t.Fatalf("%s length does not match: actual(%d), expected(%d)", name, len(kvPairs), len(expected)) }
+
+			for idx, kvPair := range kvPairs {
+				if string(kvPair.Key) != expected[idx] { t.Errorf("%s key does not match: actual(%s), expected(%s)", name, kvPair.Key, expected[idx]) }
+				if kvPair.Value != nil { t.Errorf("%s expected nil value for key %s: %v", name, kvPair.Key, kvPair.Value) }
+				if kvPair.Version == 0 { t.Errorf("%s expected version for key %s", name, kvPair.Key) }
+			}
+		}
+
+		readErr := keysOnlyInst.ReadTx(func(tx *mari.MariTx) error {
+			_, decodeErr := tx.Range([]byte("a"), []byte("c"), nil)
+			if decodeErr == nil { t.Errorf("expected range with values to fail decoding with the wrong codec") }
+
+			opts := &mari.MariRangeOpts{ KeysOnly: true }
+
+			kvPairs, rangeErr := tx.Range([]byte("a"), []byte("c"), opts)
+			if rangeErr != nil { return rangeErr }
+			checkKeys("range", kvPairs, keys)
+
+			kvPairs, rangeErr = tx.Range([]byte("a"), []byte("c"), &mari.MariRangeOpts{ KeysOnly: true, Reverse: true })
+			if rangeErr != nil { return rangeErr }
+			checkKeys("reverse range", kvPairs, []string{ "c", "ba", "b", "abc", "ab", "a" })
+
+			kvPairs, rangeErr = tx.Iterate([]byte("ab"), 3, opts)
+			if rangeErr != nil { return rangeErr }
+			checkKeys("iterate", kvPairs, []string{ "ab", "abc", "b" })
+
+			kvPairs, rangeErr = tx.IterateReverse([]byte("b"), 2, opts)
+			if rangeErr != nil { return rangeErr }
+			checkKeys("iterate reverse", kvPairs, []string{ "b", "abc" })
+
+			kvPairs, rangeErr = tx.IteratePrefix([]byte("ab"), 10, opts)
+			if rangeErr != nil { return rangeErr }
+			checkKeys("iterate prefix", kvPairs, []string{ "ab", "abc" })
+
+			var streamed []*mari.KeyValuePair
+			kvPairsChan, errChan := tx.RangeChan([]byte("b"), []byte("c"), opts)
+			for kvPair := range kvPairsChan { streamed = append(streamed, kvPair) }
+
+			chanErr := <-errChan
+			if chanErr != nil { return chanErr }
+			checkKeys("range chan", streamed, []string{ "b", "ba", "c" })
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari keys only read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Prefix Keys", func(t *testing.T) {
+		prefixKeys := []string{ "a", "ab", "abc", "abcd", "abd", "b", "ba", "xyz", "xyzw" }
+		missingKeys := []string{ "abcde", "abce", "aa", "bab", "c" }
+
+		orders := [][]string{ prefixKeys, make([]string, len(prefixKeys)) }
+		for idx, key := range prefixKeys { orders[1][len(prefixKeys) - 1 - idx] = key }
+
+		for _, order := range orders {
+			prefixInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+			if openErr != nil { t.Fatalf("error opening prefix instance: %s", openErr.Error()) }
+
+			for _, key := range order {
+				updateErr := prefixInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Put([]byte(key), []byte("value:" + key))
+				})
+
+				if updateErr != nil { t.Fatalf("error on mari put: %s", updateErr.Error()) }
+			}
+
+			checkKeys := func(present []string, valuePrefix string) {
+				readErr := prefixInst.ReadTx(func(tx *mari.MariTx) error {
+					isPresent := make(map[string]bool)
+					for _, key := range present { isPresent[key] = true }
+
+					lookups := append(append([]string{}, prefixKeys...), missingKeys...)
+					lookupKeys := make([][]byte, len(lookups))
+					for idx, key := range lookups { lookupKeys[idx] = []byte(key) }
+
+					pairs, rangeErr := tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+					if rangeErr != nil { return rangeErr }
+					if len(pairs) != len(present) { t.Errorf("range length does not match: actual(%d), expected(%d)", len(pairs), len(present)) }
+
+					count, countErr := tx.Count()
+					if countErr != nil { return countErr }
+					if count != len(present) { t.Errorf("count does not match: actual(%d), expected(%d)", count, len(present)) }
+
+					for idx, kvPair := range pairs {
+						if idx < len(present) && string(kvPair.Key) != present[idx] { t.Errorf("range is out of order at %d: actual(%s), expected(%s)", idx, kvPair.Key, present[idx]) }
+					}
+
+					results, getManyErr := tx.GetMany(lookupKeys, nil)
+					if getManyErr != nil { return getManyErr }
+
+					for idx, key := range lookups {
+						kvPair, getErr := tx.Get([]byte(key), nil)
+						if getErr != nil { return getErr }
+
+						has, hasErr := tx.Has([]byte(key))
+						if hasErr != nil { return hasErr }
+
+						if isPresent[key] {
+							if kvPair == nil || string(kvPair.Key) != key || string(kvPair.Value) != valuePrefix + key { t.Errorf("get for %q returned the wrong pair: %v", key, kvPair) }
+							if results[idx] == nil || string(results[idx].Value) != valuePrefix + key { t.Errorf("get many for %q returned the wrong pair: %v", key, results[idx]) }
+							if ! has { t.Errorf("expected has for %q to be true", key) }
+						} else {
+							if kvPair != nil { t.Errorf("expected get for %q to be nil: %v", key, kvPair) }
+							if results[idx] != nil { t.Errorf("expected get many for %q to be nil: %v", key, results[idx]) }
+							if has { t.Errorf("expected has for %q to be false", key) }
+						}
+					}
+
+					return nil
+				})
+
+				if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+			}
+
+			checkKeys(prefixKeys, "value:")
+
+			for _, key := range order {
+				updateErr := prefixInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Put([]byte(key), []byte("updated:" + key))
+				})
+
+				if updateErr != nil { t.Fatalf("error on mari put: %s", updateErr.Error()) }
+			}
+
+			checkKeys(prefixKeys, "updated:")
+
+			deleteErr := prefixInst.UpdateTx(func(tx *mari.MariTx) error {
+				delErr := tx.Delete([]byte("ab"))
+				if delErr != nil { return delErr }
+
+				return tx.Delete([]byte("abcde"))
+			})
+
+			if deleteErr != nil { t.Fatalf("error on mari delete: %s", deleteErr.Error()) }
+
+			checkKeys([]string{ "a", "abc", "abcd", "abd", "b", "ba", "xyz", "xyzw" }, "updated:")
+			prefixInst.Close()
+		}
+	})
+}
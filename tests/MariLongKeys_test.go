@@ -0,0 +1,102 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "runtime/debug"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariLongKeys(t *testing.T) {
+	t.Run("Test Mari Long Keys Concurrently", func(t *testing.T) {
+		defer debug.SetMaxStack(debug.SetMaxStack(LONG_KEY_MAX_STACK))
+
+		longKeyInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening long key instance: %s", openErr.Error()) }
+		defer longKeyInst.Close()
+
+		prefix := bytes.Repeat([]byte("k"), LONG_KEY_SIZE / 2)
+		longKey := func(writer, idx int) []byte {
+			suffix := bytes.Repeat([]byte(fmt.Sprintf("%d-%d", writer, idx)), LONG_KEY_SIZE / 8)
+			return append(append([]byte{}, prefix...), suffix...)[:LONG_KEY_SIZE]
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, LONG_KEY_WRITERS)
+
+		for writer := 0; writer < LONG_KEY_WRITERS; writer++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+
+				for idx := 0; idx < LONG_KEYS_PER_WRITER; idx++ {
+					key := longKey(writer, idx)
+					putErr := longKeyInst.UpdateTx(func(tx *mari.MariTx) error {
+						return tx.Put(key, key[len(key) - 16:])
+					})
+
+					if putErr != nil {
+						errs <- putErr
+						return
+					}
+				}
+			}(writer)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for putErr := range errs { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := longKeyInst.ReadTx(func(tx *mari.MariTx) error {
+			for writer := 0; writer < LONG_KEY_WRITERS; writer++ {
+				for idx := 0; idx < LONG_KEYS_PER_WRITER; idx++ {
+					key := longKey(writer, idx)
+					kvPair, getErr := tx.Get(key, nil)
+					if getErr != nil { return getErr }
+
+					if kvPair == nil || ! bytes.Equal(kvPair.Value, key[len(key) - 16:]) { t.Errorf("long key missing or value mismatch for writer %d at %d", writer, idx) }
+				}
+			}
+
+			totalCount, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if totalCount != LONG_KEY_WRITERS * LONG_KEYS_PER_WRITER { t.Errorf("total count does not match: actual(%d), expected(%d)", totalCount, LONG_KEY_WRITERS * LONG_KEYS_PER_WRITER) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Large Value", func(t *testing.T) {
+		os.Remove(filepath.Join(os.TempDir(), "testmarilargevalue"))
+		os.Remove(filepath.Join(os.TempDir(), "testmarilargevalue.vidx"))
+
+		largeInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmarilargevalue", NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening large value instance: %s", openErr.Error()) }
+		defer largeInst.Remove()
+
+		largeValue := bytes.Repeat([]byte("v"), LARGE_VALUE_SIZE)
+		putErr := largeInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("large"), largeValue)
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		if largeInst.TxRetries() > 1 { t.Errorf("expected a single resize to fit the value: retries(%d)", largeInst.TxRetries()) }
+
+		getErr := largeInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("large"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair == nil || ! bytes.Equal(kvPair.Value, largeValue) { t.Errorf("large value does not match after put") }
+
+			return nil
+		})
+
+		if getErr != nil { t.Errorf("error on mari get: %s", getErr.Error()) }
+	})
+}
@@ -0,0 +1,136 @@
+package maritests
+
+import "encoding/binary"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariMerge(t *testing.T) {
+	t.Run("Test Mari Merge", func(t *testing.T) {
+		mergeFunc := func(existing, operand []byte) []byte {
+			var total uint64
+			if existing != nil { total = binary.BigEndian.Uint64(existing) }
+
+			merged := make([]byte, 8)
+			binary.BigEndian.PutUint64(merged, total + binary.BigEndian.Uint64(operand))
+			return merged
+		}
+
+		noMergeInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening instance without merge func: %s", openErr.Error()) }
+		defer noMergeInst.Close()
+
+		noMergeErr := noMergeInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Merge([]byte("counter"), mari.EncodeUint64(1))
+		})
+
+		if noMergeErr == nil { t.Errorf("expected error merging without a merge func") }
+
+		mergeInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, MergeFunc: mergeFunc, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening merge instance: %s", openErr.Error()) }
+		defer mergeInst.Close()
+
+		mergeErr := mergeInst.UpdateTx(func(tx *mari.MariTx) error {
+			for range make([]int, 3) {
+				mergeErr := tx.Merge([]byte("counter"), mari.EncodeUint64(2))
+				if mergeErr != nil { return mergeErr }
+			}
+
+			return nil
+		})
+
+		if mergeErr != nil { t.Fatalf("error on mari merge: %s", mergeErr.Error()) }
+
+		var wg sync.WaitGroup
+		for range make([]int, MERGE_WRITERS) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				mergeErr := mergeInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Merge([]byte("counter"), mari.EncodeUint64(1))
+				})
+
+				if mergeErr != nil { t.Errorf("error on concurrent mari merge: %s", mergeErr.Error()) }
+			}()
+		}
+
+		wg.Wait()
+
+		readErr := mergeInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("counter"), nil)
+			if getErr != nil { return getErr }
+
+			total, decodeErr := mari.DecodeUint64(kvPair.Value)
+			if decodeErr != nil { return decodeErr }
+
+			expected := uint64(6 + MERGE_WRITERS)
+			if total != expected { t.Errorf("merged counter does not match: actual(%d), expected(%d)", total, expected) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Increment", func(t *testing.T) {
+		incrementInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening increment instance: %s", openErr.Error()) }
+		defer incrementInst.Close()
+
+		var total int64
+		incrementErr := incrementInst.UpdateTx(func(tx *mari.MariTx) error {
+			var incErr error
+			total, incErr = tx.Increment([]byte("counter"), -5)
+			if incErr != nil { return incErr }
+
+			total, incErr = tx.Increment([]byte("counter"), 2)
+			return incErr
+		})
+
+		if incrementErr != nil { t.Fatalf("error on mari increment: %s", incrementErr.Error()) }
+		if total != -3 { t.Errorf("incremented total does not match: actual(%d), expected(-3)", total) }
+
+		var wg sync.WaitGroup
+		for range make([]int, MERGE_WRITERS) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				incrementErr := incrementInst.UpdateTx(func(tx *mari.MariTx) error {
+					_, incErr := tx.Increment([]byte("counter"), 1)
+					return incErr
+				})
+
+				if incrementErr != nil { t.Errorf("error on concurrent mari increment: %s", incrementErr.Error()) }
+			}()
+		}
+
+		wg.Wait()
+
+		readErr := incrementInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("counter"), nil)
+			if getErr != nil { return getErr }
+
+			expected := int64(MERGE_WRITERS - 3)
+			actual := int64(binary.LittleEndian.Uint64(kvPair.Value))
+			if actual != expected { t.Errorf("concurrent increments do not match: actual(%d), expected(%d)", actual, expected) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		invalidErr := incrementInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("invalid"), []byte("abc"))
+			if putErr != nil { return putErr }
+
+			_, incErr := tx.Increment([]byte("invalid"), 1)
+			return incErr
+		})
+
+		if invalidErr == nil { t.Errorf("expected error incrementing a value that is not 8 bytes") }
+	})
+}
@@ -0,0 +1,79 @@
+package maritests
+
+import "fmt"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariMetrics(t *testing.T) {
+	t.Run("Test Mari Stats", func(t *testing.T) {
+		statsInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening stats instance: %s", openErr.Error()) }
+		defer statsInst.Close()
+
+		for round := 0; round < 2; round++ {
+			putErr := statsInst.UpdateTx(func(tx *mari.MariTx) error {
+				for idx := 0; idx < 1000; idx++ {
+					key := []byte(fmt.Sprintf("stats%d", idx))
+					putErr := tx.Put(key, key)
+					if putErr != nil { return putErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		stats, statsErr := statsInst.Stats()
+		if statsErr != nil { t.Fatalf("error on mari stats: %s", statsErr.Error()) }
+
+		if stats.KeyCount != 1000 { t.Errorf("key count does not match: actual(%d), expected(1000)", stats.KeyCount) }
+		if stats.Version != 2 { t.Errorf("version does not match: actual(%d), expected(2)", stats.Version) }
+		if stats.Depth < 1 { t.Errorf("expected trie to be deeper than the root: depth(%d)", stats.Depth) }
+		if stats.LiveBytes == 0 || stats.DeadBytes == 0 { t.Errorf("expected both live and dead bytes: live(%d), dead(%d)", stats.LiveBytes, stats.DeadBytes) }
+		if stats.LiveBytes + stats.DeadBytes != stats.FileSize {
+			t.Errorf("live and dead bytes do not sum to the file size: live(%d), dead(%d), file size(%d)", stats.LiveBytes, stats.DeadBytes, stats.FileSize)
+		}
+
+		if stats.VersionIndexSize == 0 { t.Errorf("expected non-empty version index") }
+	})
+
+	t.Run("Test Mari Metrics", func(t *testing.T) {
+		metricsInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening metrics instance: %s", openErr.Error()) }
+		defer metricsInst.Close()
+
+		initial := metricsInst.Metrics()
+		if initial.Resizes != 1 { t.Errorf("expected the initial allocation to be counted as a resize: actual(%d)", initial.Resizes) }
+
+		updateErr := metricsInst.UpdateTx(func(tx *mari.MariTx) error {
+			for idx := 0; idx < 10; idx++ {
+				putErr := tx.Put([]byte(fmt.Sprintf("metrics%d", idx)), []byte("value"))
+				if putErr != nil { return putErr }
+			}
+
+			return tx.Delete([]byte("metrics0"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		readErr := metricsInst.ReadTx(func(tx *mari.MariTx) error {
+			_, getErr := tx.Get([]byte("metrics1"), nil)
+			if getErr != nil { return getErr }
+
+			_, getErr = tx.GetMany([][]byte{ []byte("metrics2"), []byte("metrics3") }, nil)
+			return getErr
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		metrics := metricsInst.Metrics()
+		if metrics.Puts != 10 { t.Errorf("puts do not match: actual(%d), expected(10)", metrics.Puts) }
+		if metrics.Deletes != 1 { t.Errorf("deletes do not match: actual(%d), expected(1)", metrics.Deletes) }
+		if metrics.Gets != 3 { t.Errorf("gets do not match: actual(%d), expected(3)", metrics.Gets) }
+		if metrics.BytesWritten == 0 { t.Errorf("expected bytes written to be counted") }
+		if metrics.CASRetries != metricsInst.TxRetries() { t.Errorf("retries do not match: actual(%d), expected(%d)", metrics.CASRetries, metricsInst.TxRetries()) }
+	})
+}
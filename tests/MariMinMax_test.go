@@ -0,0 +1,86 @@
+package maritests
+
+import "fmt"
+import mrand "math/rand"
+import "sort"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariMinMaxAfterDeletes(t *testing.T) {
+	checkMinMax := func(t *testing.T, inst *mari.Mari, live map[string]bool) {
+		var expected []string
+		for key := range live { expected = append(expected, key) }
+		sort.Strings(expected)
+
+		readErr := inst.ReadTx(func(tx *mari.MariTx) error {
+			minKvPair, minErr := tx.MinKey()
+			if minErr != nil { return minErr }
+
+			maxKvPair, maxErr := tx.MaxKey()
+			if maxErr != nil { return maxErr }
+
+			if len(expected) == 0 {
+				if minKvPair != nil || maxKvPair != nil { t.Errorf("expected no min or max key on an empty trie: min(%v), max(%v)", minKvPair, maxKvPair) }
+				return nil
+			}
+
+			if minKvPair == nil || string(minKvPair.Key) != expected[0] { t.Errorf("min key does not match: actual(%v), expected(%s)", minKvPair, expected[0]) }
+			if maxKvPair == nil || string(maxKvPair.Key) != expected[len(expected) - 1] { t.Errorf("max key does not match: actual(%v), expected(%s)", maxKvPair, expected[len(expected) - 1]) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari min/max key: %s", readErr.Error()) }
+	}
+
+	t.Run("Test Min And Max Skip Emptied Subtrees", func(t *testing.T) {
+		inst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+		defer inst.Close()
+
+		for _, key := range []string{ "a", "c", "cb" } {
+			putErr := inst.UpdateTx(func(tx *mari.MariTx) error { return tx.Put([]byte(key), []byte(key)) })
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		for _, key := range []string{ "c", "cb" } {
+			delErr := inst.UpdateTx(func(tx *mari.MariTx) error { return tx.Delete([]byte(key)) })
+			if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+		}
+
+		checkMinMax(t, inst, map[string]bool{ "a": true })
+	})
+
+	t.Run("Test Min And Max After Random Deletes", func(t *testing.T) {
+		for seed := range make([]int, 300) {
+			inst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+			if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+			rnd := mrand.New(mrand.NewSource(int64(seed)))
+			live := make(map[string]bool)
+
+			for range make([]int, 60) {
+				keyBytes := make([]byte, 1 + rnd.Intn(4))
+				for idx := range keyBytes { keyBytes[idx] = "abc"[rnd.Intn(3)] }
+				key := string(keyBytes)
+
+				updateErr := inst.UpdateTx(func(tx *mari.MariTx) error {
+					if rnd.Intn(2) == 0 {
+						delete(live, key)
+						return tx.Delete([]byte(key))
+					}
+
+					live[key] = true
+					return tx.Put([]byte(key), []byte(key))
+				})
+
+				if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+			}
+
+			t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) { checkMinMax(t, inst, live) })
+			inst.Close()
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package maritests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func BenchmarkMariNodeCache(b *testing.B) {
+	keyValPairs := GenerateKeyValPairs(NODE_CACHE_INPUT_SIZE)
+
+	for _, cacheSize := range []int{ 0, 4 * NODE_CACHE_INPUT_SIZE } {
+		b.Run(fmt.Sprintf("Cache Size %d", cacheSize), func(b *testing.B) {
+			benchPath := filepath.Join(os.TempDir(), "benchnodecache")
+			os.Remove(benchPath)
+			os.Remove(benchPath + mari.VersionIndexFileName)
+
+			opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "benchnodecache", NodeCacheSize: cacheSize, NodePoolSize: &smallNodePoolSize }
+
+			benchInst, openErr := mari.Open(opts)
+			if openErr != nil { b.Fatalf("error opening mari: %s", openErr.Error()) }
+			defer benchInst.Remove()
+
+			InsertKeyValPairs(b, benchInst, keyValPairs)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for idx := 0; idx < b.N; idx++ {
+				readErr := benchInst.ReadTx(func(tx *mari.MariTx) error {
+					for _, val := range keyValPairs {
+						kvPair, getErr := tx.Get(val.Key, nil)
+						if getErr != nil { return getErr }
+						if kvPair == nil { b.Fatalf("key missing on mari get: %v", val.Key) }
+					}
+
+					return nil
+				})
+
+				if readErr != nil { b.Fatalf("error on mari get: %s", readErr.Error()) }
+			}
+		})
+	}
+}
+
+
+func TestMariNodeCache(t *testing.T) {
+	t.Run("Test Mari Node Cache", func(t *testing.T) {
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, NodeCacheSize: -1 })
+		if invalidErr == nil { t.Errorf("expected a negative node cache size to fail") }
+
+		cacheInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodeCacheSize: 64, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening node cache instance: %s", openErr.Error()) }
+		defer cacheInst.Close()
+
+		putAll := func(suffix string) {
+			updateErr := cacheInst.UpdateTx(func(tx *mari.MariTx) error {
+				for idx := 0; idx < 1000; idx++ {
+					putErr := tx.Put([]byte(fmt.Sprintf("cache%d", idx)), []byte(fmt.Sprintf("value%d%s", idx, suffix)))
+					if putErr != nil { return putErr }
+				}
+
+				return nil
+			})
+
+			if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+		}
+
+		checkAll := func(suffix string) {
+			for pass := 0; pass < 2; pass++ {
+				readErr := cacheInst.ReadTx(func(tx *mari.MariTx) error {
+					for idx := 0; idx < 1000; idx++ {
+						kvPair, getErr := tx.Get([]byte(fmt.Sprintf("cache%d", idx)), nil)
+						if getErr != nil { return getErr }
+
+						expected := fmt.Sprintf("value%d%s", idx, suffix)
+						if kvPair == nil || string(kvPair.Value) != expected { t.Errorf("value mismatch for cache%d: expected(%s)", idx, expected) }
+					}
+
+					return nil
+				})
+
+				if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+			}
+		}
+
+		putAll("a")
+		checkAll("a")
+
+		metrics := cacheInst.Metrics()
+		if metrics.NodeCacheHits == 0 { t.Errorf("expected node cache hits to be counted") }
+		if metrics.NodeCacheMisses == 0 { t.Errorf("expected node cache misses to be counted") }
+
+		putAll("b")
+		checkAll("b")
+
+		delErr := cacheInst.UpdateTx(func(tx *mari.MariTx) error { return tx.Delete([]byte("cache0")) })
+		if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+
+		readErr := cacheInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("cache0"), nil)
+			if getErr != nil { return getErr }
+			if kvPair != nil { t.Errorf("expected deleted key to be missing from cached reads") }
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		truncateErr := cacheInst.Truncate()
+		if truncateErr != nil { t.Fatalf("error truncating mari: %s", truncateErr.Error()) }
+
+		putAll("c")
+		checkAll("c")
+	})
+}
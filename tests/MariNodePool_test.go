@@ -0,0 +1,171 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var nodePoolMariInst *mari.Mari
+var nodePoolInitMariErr error
+var nodePoolWG sync.WaitGroup
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testnodepool"))
+	os.Remove(filepath.Join(os.TempDir(), "testnodepooltemp"))
+
+	nodePoolSize := int64(NODE_POOL_TEST_SIZE)
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testnodepool", NodePoolSize: &nodePoolSize }
+
+	nodePoolMariInst, nodePoolInitMariErr = mari.Open(opts)
+	if nodePoolInitMariErr != nil {
+		nodePoolMariInst.Remove()
+		panic(nodePoolInitMariErr.Error())
+	}
+
+	fmt.Println("node pool test mari initialized")
+}
+
+
+func TestMariNodePool(t *testing.T) {
+	defer nodePoolMariInst.Remove()
+
+	hotKeys := make([][]byte, NODE_POOL_HOT_KEYS)
+	for idx := range hotKeys {
+		hotKeys[idx], _ = GenerateRandomBytes(8)
+	}
+
+	t.Run("Test Contended Writes On Hot Keys", func(t *testing.T) {
+		for i := range make([]int, NUM_READER_GO_ROUTINES) {
+			writer := i
+
+			nodePoolWG.Add(1)
+			go func() {
+				defer nodePoolWG.Done()
+				for iter := range make([]int, NODE_POOL_ITERATIONS) {
+					putErr := nodePoolMariInst.UpdateTx(func(tx *mari.MariTx) error {
+						for _, key := range hotKeys {
+							putTxErr := tx.Put(key, []byte(fmt.Sprintf("%d-%d", writer, iter)))
+							if putTxErr != nil { return putTxErr }
+						}
+
+						return nil
+					})
+
+					if putErr != nil { t.Errorf("error on mari put: %s", putErr.Error()) }
+				}
+			}()
+		}
+
+		nodePoolWG.Wait()
+	})
+
+	t.Run("Test Hot Keys Are Consistent", func(t *testing.T) {
+		readErr := nodePoolMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var expected []byte
+			for _, key := range hotKeys {
+				kvPair, getTxErr := tx.Get(key, nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil { return fmt.Errorf("hot key missing: %s", key) }
+
+				if expected == nil { expected = kvPair.Value }
+				if ! bytes.Equal(expected, kvPair.Value) {
+					t.Errorf("hot keys written in a single transaction do not match: actual(%s), expected(%s)", kvPair.Value, expected)
+				}
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Recycled Nodes After Contention", func(t *testing.T) {
+		for idx := range make([]int, NODE_POOL_HOT_KEYS) {
+			putErr := nodePoolMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("recycled%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		readErr := nodePoolMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for idx := range make([]int, NODE_POOL_HOT_KEYS) {
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("recycled%d", idx)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil || string(kvPair.Value) != fmt.Sprintf("value%d", idx) { t.Errorf("value written after contention does not match: actual(%v), expected(value%d)", kvPair, idx) }
+			}
+
+			for _, key := range hotKeys {
+				kvPair, getTxErr := tx.Get(key, nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil { t.Errorf("hot key missing after contention: %s", key) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Node Pool Stats", func(t *testing.T) {
+		size, maxSize, gets, puts, misses := nodePoolMariInst.NodePoolStats()
+		t.Logf("size: %d, max size: %d, gets: %d, puts: %d, misses: %d", size, maxSize, gets, puts, misses)
+
+		if maxSize != NODE_POOL_TEST_SIZE { t.Errorf("max size does not match: actual(%d), expected(%d)", maxSize, NODE_POOL_TEST_SIZE) }
+		if size < 0 || size > maxSize { t.Errorf("size is out of bounds: actual(%d), max size(%d)", size, maxSize) }
+		if gets == 0 { t.Errorf("expected gets to be counted") }
+		if puts == 0 { t.Errorf("expected puts to be counted") }
+		if misses == 0 { t.Errorf("expected the undersized pool to be starved") }
+		if misses > gets { t.Errorf("misses exceed gets: misses(%d), gets(%d)", misses, gets) }
+	})
+
+	t.Run("Test Auto Tuning Node Pool", func(t *testing.T) {
+		nodePoolSize := int64(NODE_POOL_TEST_SIZE)
+		invalidCeiling := nodePoolSize - 1
+
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &nodePoolSize, NodePoolCeiling: &invalidCeiling })
+		if invalidErr == nil { t.Errorf("expected a ceiling below the node pool size to fail") }
+
+		ceiling := int64(NODE_POOL_TEST_CEILING)
+		tuningInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &nodePoolSize, NodePoolCeiling: &ceiling })
+		if openErr != nil { t.Fatalf("error opening auto tuning instance: %s", openErr.Error()) }
+		defer tuningInst.Close()
+
+		var tuningWG sync.WaitGroup
+
+		for i := range make([]int, NUM_READER_GO_ROUTINES) {
+			writer := i
+
+			tuningWG.Add(1)
+			go func() {
+				defer tuningWG.Done()
+				for iter := range make([]int, NODE_POOL_ITERATIONS) {
+					putErr := tuningInst.UpdateTx(func(tx *mari.MariTx) error {
+						for _, key := range hotKeys {
+							putTxErr := tx.Put(key, []byte(fmt.Sprintf("%d-%d", writer, iter)))
+							if putTxErr != nil { return putTxErr }
+						}
+
+						return nil
+					})
+
+					if putErr != nil { t.Errorf("error on mari put: %s", putErr.Error()) }
+				}
+			}()
+		}
+
+		tuningWG.Wait()
+
+		size, maxSize, gets, puts, misses := tuningInst.NodePoolStats()
+		t.Logf("size: %d, max size: %d, gets: %d, puts: %d, misses: %d", size, maxSize, gets, puts, misses)
+
+		if maxSize <= nodePoolSize { t.Errorf("expected the starved pool to grow: actual(%d), initial(%d)", maxSize, nodePoolSize) }
+		if maxSize > ceiling { t.Errorf("max size exceeds the ceiling: actual(%d), ceiling(%d)", maxSize, ceiling) }
+	})
+}
@@ -0,0 +1,121 @@
+package maritests
+
+import "fmt"
+import "sync"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariOnCommit(t *testing.T) {
+	t.Run("Test Mari On Commit", func(t *testing.T) {
+		var commitLock sync.Mutex
+		var commitVersions []uint64
+		var commitChanges [][]mari.KeyValuePair
+
+		onCommit := func(version uint64, changed []mari.KeyValuePair) {
+			commitLock.Lock()
+			defer commitLock.Unlock()
+
+			commitVersions = append(commitVersions, version)
+			commitChanges = append(commitChanges, changed)
+		}
+
+		commitInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, OnCommit: onCommit, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening commit instance: %s", openErr.Error()) }
+		defer commitInst.Close()
+
+		abortErr := commitInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("aborted"), []byte("aborted"))
+			if putErr != nil { return putErr }
+
+			return fmt.Errorf("abort")
+		})
+
+		if abortErr == nil { t.Fatalf("expected aborted transaction to return an error") }
+
+		updateErr := commitInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("hook1"), []byte("value1"))
+			if putErr != nil { return putErr }
+
+			putErr = tx.Put([]byte("hook2"), []byte("value2"))
+			if putErr != nil { return putErr }
+
+			return tx.Delete([]byte("hook1"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		var version uint64
+		readErr := commitInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("hook2"), nil)
+			if getErr != nil { return getErr }
+
+			version = kvPair.Version
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		commitLock.Lock()
+		defer commitLock.Unlock()
+
+		if len(commitVersions) != 1 || commitVersions[0] != version { t.Fatalf("commit versions do not match: actual(%v), expected([%d])", commitVersions, version) }
+
+		changed := commitChanges[0]
+		if len(changed) != 3 { t.Fatalf("changed length does not match: actual(%d), expected(3)", len(changed)) }
+		if string(changed[0].Key) != "hook1" || string(changed[0].Value) != "value1" { t.Errorf("first change does not match: %v", changed[0]) }
+		if string(changed[1].Key) != "hook2" || string(changed[1].Value) != "value2" { t.Errorf("second change does not match: %v", changed[1]) }
+		if string(changed[2].Key) != "hook1" || changed[2].Value != nil { t.Errorf("delete change does not match: %v", changed[2]) }
+
+		for _, change := range changed {
+			if change.Version != version { t.Errorf("change version does not match: actual(%d), expected(%d)", change.Version, version) }
+		}
+	})
+
+	t.Run("Test Mari Max Tx Retries", func(t *testing.T) {
+		maxTxRetries := 1
+		retryInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, MaxTxRetries: &maxTxRetries, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening retry instance: %s", openErr.Error()) }
+		defer retryInst.Close()
+
+		conflictingWrite := func() error {
+			done := make(chan error)
+			go func() {
+				done <- retryInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Put([]byte("conflict"), []byte("conflict"))
+				})
+			}()
+
+			return <-done
+		}
+
+		var attempts int
+		updateErr := retryInst.UpdateTx(func(tx *mari.MariTx) error {
+			attempts++
+			if attempts == 1 {
+				conflictErr := conflictingWrite()
+				if conflictErr != nil { return conflictErr }
+			}
+
+			return tx.Put([]byte("retried"), []byte("retried"))
+		})
+
+		if updateErr != nil { t.Errorf("expected transaction to succeed on retry: %s", updateErr.Error()) }
+		if attempts != 2 { t.Errorf("expected exactly one retry: attempts(%d)", attempts) }
+		if retryInst.TxRetries() != 1 { t.Errorf("retry count does not match: actual(%d), expected(1)", retryInst.TxRetries()) }
+
+		attempts = 0
+		updateErr = retryInst.UpdateTx(func(tx *mari.MariTx) error {
+			attempts++
+
+			conflictErr := conflictingWrite()
+			if conflictErr != nil { return conflictErr }
+
+			return tx.Put([]byte("exhausted"), []byte("exhausted"))
+		})
+
+		if updateErr == nil { t.Errorf("expected transaction to fail once retries are exhausted") }
+		if attempts != maxTxRetries + 1 { t.Errorf("total attempts does not match: actual(%d), expected(%d)", attempts, maxTxRetries + 1) }
+	})
+}
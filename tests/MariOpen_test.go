@@ -0,0 +1,142 @@
+package maritests
+
+import "bytes"
+import "errors"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariOpen(t *testing.T) {
+	t.Run("Test Mari Growth Factor", func(t *testing.T) {
+		invalidFactor := 1.0
+		_, invalidErr := mari.Open(mari.MariOpts{ InMemory: true, GrowthFactor: &invalidFactor })
+		if invalidErr == nil { t.Errorf("expected error opening mari with a growth factor of 1") }
+
+		growthFactor := GROWTH_FACTOR
+		growthInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, GrowthFactor: &growthFactor, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening growth instance: %s", openErr.Error()) }
+		defer growthInst.Close()
+
+		initialSize, sizeErr := growthInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		putErr := growthInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("grow"), bytes.Repeat([]byte("v"), initialSize))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		grownSize, sizeErr := growthInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		expectedSize := int(float64(initialSize) * GROWTH_FACTOR)
+		if grownSize != expectedSize { t.Errorf("grown size does not match: actual(%d), expected(%d)", grownSize, expectedSize) }
+	})
+
+	t.Run("Test Mari Default File Name", func(t *testing.T) {
+		dir, dirErr := os.MkdirTemp(os.TempDir(), "testmaridefault")
+		if dirErr != nil { t.Fatalf("error creating directory: %s", dirErr.Error()) }
+		defer os.RemoveAll(dir)
+
+		defaultInst, openErr := mari.Open(mari.MariOpts{ Filepath: dir, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening mari without a file name: %s", openErr.Error()) }
+
+		putErr := defaultInst.UpdateTx(func(tx *mari.MariTx) error { return tx.Put([]byte("key"), []byte("value")) })
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		for _, path := range []string{ filepath.Join(dir, mari.DefaultFileName), filepath.Join(dir, mari.DefaultFileName + mari.VersionIndexFileName) } {
+			_, statErr := os.Stat(path)
+			if statErr != nil { t.Errorf("expected file at default path %s: %s", path, statErr.Error()) }
+		}
+
+		closeErr := defaultInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+	})
+
+	t.Run("Test Mari Filepath Must Be A Directory", func(t *testing.T) {
+		dir, dirErr := os.MkdirTemp(os.TempDir(), "testmarifilepath")
+		if dirErr != nil { t.Fatalf("error creating directory: %s", dirErr.Error()) }
+		defer os.RemoveAll(dir)
+
+		fullPath := filepath.Join(dir, "testmarifullpath")
+		writeErr := os.WriteFile(fullPath, []byte{}, 0600)
+		if writeErr != nil { t.Fatalf("error writing file: %s", writeErr.Error()) }
+
+		_, openErr := mari.Open(mari.MariOpts{ Filepath: fullPath })
+		if openErr == nil || ! strings.Contains(openErr.Error(), "is not a directory") { t.Errorf("expected error opening mari with the full path of a file as the filepath: %v", openErr) }
+
+		_, openErr = mari.Open(mari.MariOpts{ Filepath: filepath.Join(dir, "missing"), FileName: "testmarifullpath" })
+		if openErr == nil { t.Error("expected error opening mari in a directory that does not exist") }
+
+		_, statErr := os.Stat(fullPath + mari.VersionIndexFileName)
+		if ! os.IsNotExist(statErr) { t.Errorf("expected no version index to be created for an invalid filepath: %v", statErr) }
+	})
+
+	t.Run("Test Mari File Lock", func(t *testing.T) {
+		os.Remove(filepath.Join(os.TempDir(), "testmarilock"))
+		os.Remove(filepath.Join(os.TempDir(), "testmarilock.vidx"))
+
+		opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmarilock", NodePoolSize: &smallNodePoolSize }
+
+		lockInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		_, secondOpenErr := mari.Open(opts)
+		if secondOpenErr == nil || ! strings.Contains(secondOpenErr.Error(), "already opened by another process") {
+			t.Errorf("expected error opening a file that is already open: %v", secondOpenErr)
+		}
+
+		closeErr := lockInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		lockInst, openErr = mari.Open(opts)
+		if openErr != nil { t.Fatalf("error reopening mari after close: %s", openErr.Error()) }
+
+		removeErr := lockInst.Remove()
+		if removeErr != nil { t.Errorf("error removing mari: %s", removeErr.Error()) }
+	})
+
+	t.Run("Test Mari Rejects Older File Format", func(t *testing.T) {
+		dir, dirErr := os.MkdirTemp(os.TempDir(), "testmariformat")
+		if dirErr != nil { t.Fatalf("error creating directory: %s", dirErr.Error()) }
+		defer os.RemoveAll(dir)
+
+		formatInst, openErr := mari.Open(mari.MariOpts{ Filepath: dir, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		putErr := formatInst.UpdateTx(func(tx *mari.MariTx) error { return tx.Put([]byte("key"), []byte("value")) })
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		closeErr := formatInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		writeHeader := func(t *testing.T, format []byte) {
+			file, openFileErr := os.OpenFile(filepath.Join(dir, mari.DefaultFileName), os.O_WRONLY, 0600)
+			if openFileErr != nil { t.Fatalf("error opening file: %s", openFileErr.Error()) }
+			defer file.Close()
+
+			_, writeErr := file.WriteAt(format, mari.MetaFormatIdx)
+			if writeErr != nil { t.Fatalf("error writing header: %s", writeErr.Error()) }
+		}
+
+		writeHeader(t, make([]byte, 8))
+
+		_, openErr = mari.Open(mari.MariOpts{ Filepath: dir })
+		if ! errors.Is(openErr, mari.ErrUnsupportedFormat) { t.Errorf("expected unsupported format opening a file without the format marker: %v", openErr) }
+
+		_, openErr = mari.Open(mari.MariOpts{ Filepath: dir, ReadOnly: true })
+		if ! errors.Is(openErr, mari.ErrUnsupportedFormat) { t.Errorf("expected unsupported format opening a file without the format marker read only: %v", openErr) }
+
+		writeHeader(t, []byte(mari.FileFormatMagic))
+
+		formatInst, openErr = mari.Open(mari.MariOpts{ Filepath: dir, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error reopening mari with the format marker restored: %s", openErr.Error()) }
+
+		closeErr = formatInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+	})
+}
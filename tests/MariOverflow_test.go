@@ -0,0 +1,210 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var overflowMariInst *mari.Mari
+var overflowOpts mari.MariOpts
+var overflowKeyValPairs []KeyVal
+var overflowInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testoverflow"))
+	os.Remove(filepath.Join(os.TempDir(), "testoverflowtemp"))
+
+	overflowOpts = mari.MariOpts{ Filepath: os.TempDir(), FileName: "testoverflow", OverflowThreshold: OVERFLOW_THRESHOLD, NodePoolSize: &smallNodePoolSize }
+
+	overflowMariInst, overflowInitMariErr = mari.Open(overflowOpts)
+	if overflowInitMariErr != nil {
+		overflowMariInst.Remove()
+		panic(overflowInitMariErr.Error())
+	}
+
+	fmt.Println("overflow test mari initialized")
+
+	overflowKeyValPairs = make([]KeyVal, OVERFLOW_INPUT_SIZE)
+
+	for idx := range overflowKeyValPairs {
+		key, _ := GenerateRandomBytes(32)
+
+		valueSize := 32
+		if idx % 2 == 0 { valueSize = OVERFLOW_VALUE_SIZE }
+
+		value, _ := GenerateRandomBytes(valueSize)
+		overflowKeyValPairs[idx] = KeyVal{ Key: key, Value: value }
+	}
+}
+
+
+func TestMariOverflow(t *testing.T) {
+	defer func() { overflowMariInst.Remove() }()
+
+	checkValues := func(t *testing.T, inst *mari.Mari) {
+		readErr := inst.ReadTx(func(tx *mari.MariTx) error {
+			for _, val := range overflowKeyValPairs {
+				kvPair, getErr := tx.Get(val.Key, nil)
+				if getErr != nil { return getErr }
+				if kvPair == nil || ! bytes.Equal(kvPair.Value, val.Value) { t.Fatalf("value does not match for key: %v", val.Key) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := inst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying mari: %s", verifyErr.Error()) }
+	}
+
+	t.Run("Test Overflow Put And Get", func(t *testing.T) {
+		for _, val := range overflowKeyValPairs {
+			putErr := overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put(val.Key, val.Value)
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		checkValues(t, overflowMariInst)
+	})
+
+	t.Run("Test Overflow Values Are Not Rewritten On Path Copies", func(t *testing.T) {
+		var maxWritten uint64
+
+		for idx := range make([]int, OVERFLOW_INPUT_SIZE) {
+			before := overflowMariInst.Metrics().BytesWritten
+
+			putErr := overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("small%d", idx)), []byte("value"))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+			written := overflowMariInst.Metrics().BytesWritten - before
+			if written > maxWritten { maxWritten = written }
+		}
+
+		t.Logf("most bytes written for a small put: %d", maxWritten)
+		if maxWritten >= OVERFLOW_VALUE_SIZE { t.Errorf("expected small puts to not rewrite overflow values: written(%d)", maxWritten) }
+
+		checkValues(t, overflowMariInst)
+	})
+
+	t.Run("Test Overflow Keys Only Range", func(t *testing.T) {
+		readErr := overflowMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, rangeErr := tx.Range([]byte{}, bytes.Repeat([]byte{ 0xFF }, 33), &mari.MariRangeOpts{ KeysOnly: true })
+			if rangeErr != nil { return rangeErr }
+			if len(kvPairs) != 2 * OVERFLOW_INPUT_SIZE { t.Errorf("range length does not match: actual(%d), expected(%d)", len(kvPairs), 2 * OVERFLOW_INPUT_SIZE) }
+
+			for _, kvPair := range kvPairs {
+				if kvPair.Value != nil { t.Fatalf("expected keys only range to not return values: %v", kvPair.Key) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari range: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Overflow Values Move Inline On Update", func(t *testing.T) {
+		first := overflowKeyValPairs[0]
+
+		putErr := overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(first.Key, []byte("small"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := overflowMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get(first.Key, nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || string(kvPair.Value) != "small" { t.Errorf("expected updated value to be inline: %v", kvPair) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		putErr = overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(first.Key, first.Value)
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		checkValues(t, overflowMariInst)
+	})
+
+	t.Run("Test Overflow Leaf Relocation", func(t *testing.T) {
+		longKey := []byte("relocate/long")
+		shortKey := []byte("relocate")
+		largeValue := bytes.Repeat([]byte("r"), OVERFLOW_VALUE_SIZE)
+
+		putErr := overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(longKey, largeValue)
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		putErr = overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(shortKey, []byte("short"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := overflowMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get(longKey, nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || ! bytes.Equal(kvPair.Value, largeValue) { t.Errorf("relocated overflow value does not match") }
+
+			kvPair, getErr = tx.Get(shortKey, nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || string(kvPair.Value) != "short" { t.Errorf("short key value does not match: %v", kvPair) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		delErr := overflowMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			delTxErr := tx.Delete(longKey)
+			if delTxErr != nil { return delTxErr }
+
+			return tx.Delete(shortKey)
+		})
+
+		if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+	})
+
+	t.Run("Test Overflow Values Survive Reopen", func(t *testing.T) {
+		closeErr := overflowMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		var openErr error
+		overflowMariInst, openErr = mari.Open(overflowOpts)
+		if openErr != nil { t.Fatalf("error reopening mari: %s", openErr.Error()) }
+
+		checkValues(t, overflowMariInst)
+	})
+
+	t.Run("Test Overflow Values Survive Clone", func(t *testing.T) {
+		clonePath := filepath.Join(os.TempDir(), "testoverflowclone")
+		os.Remove(clonePath)
+
+		cloneInst, cloneErr := overflowMariInst.Clone(clonePath)
+		if cloneErr != nil { t.Fatalf("error cloning mari: %s", cloneErr.Error()) }
+		defer cloneInst.Remove()
+
+		checkValues(t, cloneInst)
+
+		cloneStats, statsErr := cloneInst.Stats()
+		if statsErr != nil { t.Fatalf("error getting stats: %s", statsErr.Error()) }
+		if cloneStats.LiveBytes < OVERFLOW_INPUT_SIZE / 2 * OVERFLOW_VALUE_SIZE { t.Errorf("expected live bytes to include overflow values: %d", cloneStats.LiveBytes) }
+	})
+}
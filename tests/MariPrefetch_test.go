@@ -0,0 +1,162 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var prefetchMariInst *mari.Mari
+var prefetchKeyValPairs []KeyVal
+var prefetchInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testprefetch"))
+	os.Remove(filepath.Join(os.TempDir(), "testprefetchtemp"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testprefetch", NodePoolSize: &smallNodePoolSize }
+
+	prefetchMariInst, prefetchInitMariErr = mari.Open(opts)
+	if prefetchInitMariErr != nil {
+		prefetchMariInst.Remove()
+		panic(prefetchInitMariErr.Error())
+	}
+
+	fmt.Println("prefetch test mari initialized")
+}
+
+
+func TestMariPrefetch(t *testing.T) {
+	defer prefetchMariInst.Remove()
+
+	prefetchKeyValPairs = GenerateKeyValPairs(InputSize(PREFETCH_INPUT_SIZE))
+	inputSize := len(prefetchKeyValPairs)
+
+	compareResults := func(t *testing.T, actual, expected []*mari.KeyValuePair) {
+		if len(actual) != len(expected) { t.Fatalf("prefetched results length does not match: actual(%d), expected(%d)", len(actual), len(expected)) }
+
+		for idx := range expected {
+			if ! bytes.Equal(actual[idx].Key, expected[idx].Key) || ! bytes.Equal(actual[idx].Value, expected[idx].Value) {
+				t.Fatalf("prefetched pair does not match at %d: actual(%v), expected(%v)", idx, actual[idx].Key, expected[idx].Key)
+			}
+		}
+	}
+
+	t.Run("Test Prefetch Inserts", func(t *testing.T) {
+		InsertKeyValPairs(t, prefetchMariInst, prefetchKeyValPairs)
+	})
+
+	t.Run("Test Prefetch Matches Scans", func(t *testing.T) {
+		first, second, randomErr := TwoRandomDistinctValues(0, inputSize)
+		if randomErr != nil { t.Fatalf("error generating random values: %s", randomErr.Error()) }
+
+		startKey := prefetchKeyValPairs[first].Key
+		endKey := prefetchKeyValPairs[second].Key
+		if bytes.Compare(startKey, endKey) == 1 { startKey, endKey = endKey, startKey }
+
+		prefetch := &mari.MariRangeOpts{ Prefetch: true }
+
+		readErr := prefetchMariInst.ReadTx(func(tx *mari.MariTx) error {
+			expected, rangeTxErr := tx.Range(startKey, endKey, nil)
+			if rangeTxErr != nil { return rangeTxErr }
+
+			actual, rangeTxErr := tx.Range(startKey, endKey, prefetch)
+			if rangeTxErr != nil { return rangeTxErr }
+			compareResults(t, actual, expected)
+
+			expected, rangeTxErr = tx.Range(nil, nil, nil)
+			if rangeTxErr != nil { return rangeTxErr }
+
+			actual, rangeTxErr = tx.Range(nil, nil, prefetch)
+			if rangeTxErr != nil { return rangeTxErr }
+			if len(actual) != inputSize { t.Errorf("range length does not match input: actual(%d), expected(%d)", len(actual), inputSize) }
+			compareResults(t, actual, expected)
+
+			expected, iterTxErr := tx.Iterate(startKey, 1000, nil)
+			if iterTxErr != nil { return iterTxErr }
+
+			actual, iterTxErr = tx.Iterate(startKey, 1000, prefetch)
+			if iterTxErr != nil { return iterTxErr }
+			compareResults(t, actual, expected)
+
+			expected, iterTxErr = tx.IterateReverse(endKey, 1000, nil)
+			if iterTxErr != nil { return iterTxErr }
+
+			actual, iterTxErr = tx.IterateReverse(endKey, 1000, prefetch)
+			if iterTxErr != nil { return iterTxErr }
+			compareResults(t, actual, expected)
+
+			expected, iterTxErr = tx.IteratePrefix(startKey[:1], 1000, nil)
+			if iterTxErr != nil { return iterTxErr }
+
+			actual, iterTxErr = tx.IteratePrefix(startKey[:1], 1000, prefetch)
+			if iterTxErr != nil { return iterTxErr }
+			compareResults(t, actual, expected)
+
+			expected, _, pageErr := tx.Page(startKey, 100, nil)
+			if pageErr != nil { return pageErr }
+
+			actual, _, pageErr = tx.Page(startKey, 100, prefetch)
+			if pageErr != nil { return pageErr }
+			compareResults(t, actual, expected)
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari prefetched scan: %s", readErr.Error()) }
+	})
+}
+
+func BenchmarkMariColdRange(b *testing.B) {
+	benchPath := filepath.Join(os.TempDir(), "benchprefetch")
+	os.Remove(benchPath)
+	os.Remove(benchPath + mari.VersionIndexFileName)
+
+	defer os.Remove(benchPath)
+	defer os.Remove(benchPath + mari.VersionIndexFileName)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "benchprefetch" }
+
+	benchInst, openErr := mari.Open(opts)
+	if openErr != nil { b.Fatalf("error opening mari: %s", openErr.Error()) }
+
+	InsertKeyValPairs(b, benchInst, GenerateKeyValPairs(PREFETCH_INPUT_SIZE))
+
+	closeErr := benchInst.Close()
+	if closeErr != nil { b.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+	for _, prefetch := range []bool{ false, true } {
+		b.Run(fmt.Sprintf("Prefetch %t", prefetch), func(b *testing.B) {
+			for idx := 0; idx < b.N; idx++ {
+				b.StopTimer()
+
+				dropErr := os.WriteFile("/proc/sys/vm/drop_caches", []byte("3"), 0200)
+				if dropErr != nil { b.Skipf("unable to drop the page cache: %s", dropErr.Error()) }
+
+				benchInst, openErr = mari.Open(opts)
+				if openErr != nil { b.Fatalf("error opening mari: %s", openErr.Error()) }
+
+				b.StartTimer()
+
+				readErr := benchInst.ReadTx(func(tx *mari.MariTx) error {
+					kvPairs, rangeTxErr := tx.Range(nil, nil, &mari.MariRangeOpts{ Prefetch: prefetch })
+					if rangeTxErr != nil { return rangeTxErr }
+					if len(kvPairs) != PREFETCH_INPUT_SIZE { b.Errorf("range length does not match input: actual(%d), expected(%d)", len(kvPairs), PREFETCH_INPUT_SIZE) }
+
+					return nil
+				})
+
+				b.StopTimer()
+
+				if readErr != nil { b.Fatalf("error on mari range: %s", readErr.Error()) }
+
+				closeErr := benchInst.Close()
+				if closeErr != nil { b.Fatalf("error closing mari: %s", closeErr.Error()) }
+			}
+		})
+	}
+}
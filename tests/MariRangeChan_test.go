@@ -0,0 +1,53 @@
+package maritests
+
+import "context"
+import "errors"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariRangeChan(t *testing.T) {
+	t.Run("Test Range Chan Stops Early", func(t *testing.T) {
+		chanInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+		defer chanInst.Close()
+
+		putErr := chanInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range []string{ "a", "b", "c", "d" } {
+				putTxErr := tx.Put([]byte(key), []byte(key))
+				if putTxErr != nil { return putTxErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := chanInst.ReadTx(func(tx *mari.MariTx) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			kvPairsChan, errChan := tx.RangeChanCtx(ctx, []byte("a"), []byte("d"), nil)
+
+			<-kvPairsChan
+			cancel()
+			for range kvPairsChan {}
+
+			if chanErr := <-errChan; ! errors.Is(chanErr, context.Canceled) { t.Errorf("expected the range chan to be cancelled, got: %v", chanErr) }
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari range chan: %s", readErr.Error()) }
+
+		var errChan <-chan error
+		readErr = chanInst.ReadTx(func(tx *mari.MariTx) error {
+			var kvPairsChan <-chan *mari.KeyValuePair
+			kvPairsChan, errChan = tx.RangeChan([]byte("a"), []byte("d"), nil)
+
+			<-kvPairsChan
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari range chan: %s", readErr.Error()) }
+		if <-errChan == nil { t.Errorf("expected an error from a range chan left undrained when the transaction returned") }
+	})
+}
@@ -0,0 +1,165 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var rangeParallelMariInst *mari.Mari
+var rangeParallelKeyValPairs []KeyVal
+var rangeParallelInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testrangeparallel"))
+	os.Remove(filepath.Join(os.TempDir(), "testrangeparalleltemp"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testrangeparallel", NodePoolSize: &smallNodePoolSize }
+
+	rangeParallelMariInst, rangeParallelInitMariErr = mari.Open(opts)
+	if rangeParallelInitMariErr != nil {
+		rangeParallelMariInst.Remove()
+		panic(rangeParallelInitMariErr.Error())
+	}
+
+	fmt.Println("range parallel test mari initialized")
+}
+
+
+func TestMariRangeParallel(t *testing.T) {
+	defer rangeParallelMariInst.Remove()
+
+	rangeParallelKeyValPairs = GenerateKeyValPairs(InputSize(RANGE_PARALLEL_INPUT_SIZE))
+	inputSize := len(rangeParallelKeyValPairs)
+
+	compareResults := func(t *testing.T, actual, expected []*mari.KeyValuePair) {
+		if len(actual) != len(expected) { t.Fatalf("parallel range length does not match range length: actual(%d), expected(%d)", len(actual), len(expected)) }
+
+		for idx := range expected {
+			if ! bytes.Equal(actual[idx].Key, expected[idx].Key) || ! bytes.Equal(actual[idx].Value, expected[idx].Value) {
+				t.Fatalf("parallel range pair does not match at %d: actual(%v), expected(%v)", idx, actual[idx].Key, expected[idx].Key)
+			}
+		}
+	}
+
+	t.Run("Test Range Parallel Inserts", func(t *testing.T) {
+		chunks, chunkErr := Chunk(rangeParallelKeyValPairs, TRANSACTION_CHUNK_SIZE)
+		if chunkErr != nil { t.Fatalf("error chunking input: %s", chunkErr.Error()) }
+
+		for _, chunk := range chunks {
+			putErr := rangeParallelMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for _, val := range chunk {
+					putTxErr := tx.Put(val.Key, val.Value)
+					if putTxErr != nil { return putTxErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+	})
+
+	t.Run("Test Range Parallel Against Range", func(t *testing.T) {
+		readErr := rangeParallelMariInst.ReadTx(func(tx *mari.MariTx) error {
+			expected, rangeTxErr := tx.Range(nil, nil, nil)
+			if rangeTxErr != nil { return rangeTxErr }
+
+			actual, parallelTxErr := tx.RangeParallel(nil, nil, RANGE_PARALLEL_WORKERS, nil)
+			if parallelTxErr != nil { return parallelTxErr }
+
+			if len(expected) != inputSize { t.Errorf("range length does not match input: actual(%d), expected(%d)", len(expected), inputSize) }
+			compareResults(t, actual, expected)
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari parallel range: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Range Parallel Bounds", func(t *testing.T) {
+		first, second, randomErr := TwoRandomDistinctValues(0, inputSize)
+		if randomErr != nil { t.Fatalf("error generating random values: %s", randomErr.Error()) }
+
+		startKey := rangeParallelKeyValPairs[first].Key
+		endKey := rangeParallelKeyValPairs[second].Key
+		if bytes.Compare(startKey, endKey) == 1 { startKey, endKey = endKey, startKey }
+
+		exclusive := false
+		optsList := []*mari.MariRangeOpts{
+			nil,
+			{ Reverse: true },
+			{ StartInclusive: &exclusive, EndInclusive: &exclusive },
+			{ Reverse: true, StartInclusive: &exclusive },
+			{ KeysOnly: true },
+			{ Prefetch: true },
+			{ Reverse: true, Prefetch: true },
+		}
+
+		readErr := rangeParallelMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, opts := range optsList {
+				expected, rangeTxErr := tx.Range(startKey, endKey, opts)
+				if rangeTxErr != nil { return rangeTxErr }
+
+				for _, workers := range []int{ 1, RANGE_PARALLEL_WORKERS, 300 } {
+					actual, parallelTxErr := tx.RangeParallel(startKey, endKey, workers, opts)
+					if parallelTxErr != nil { return parallelTxErr }
+
+					compareResults(t, actual, expected)
+				}
+			}
+
+			_, invalidErr := tx.RangeParallel(endKey, startKey, RANGE_PARALLEL_WORKERS, nil)
+			if invalidErr == nil { t.Errorf("expected error when the start key is larger than the end key") }
+
+			_, invalidErr = tx.RangeParallel(startKey, endKey, 0, nil)
+			if invalidErr == nil { t.Errorf("expected error when no workers are provided") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari parallel range: %s", readErr.Error()) }
+	})
+}
+
+func BenchmarkRangeParallel(b *testing.B) {
+	benchPath := filepath.Join(os.TempDir(), "benchrangeparallel")
+	os.Remove(benchPath)
+	os.Remove(benchPath + mari.VersionIndexFileName)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "benchrangeparallel" }
+
+	benchInst, openErr := mari.Open(opts)
+	if openErr != nil { b.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer benchInst.Remove()
+
+	InsertKeyValPairs(b, benchInst, GenerateKeyValPairs(RANGE_PARALLEL_INPUT_SIZE))
+
+	for _, workers := range []int{ 1, RANGE_PARALLEL_WORKERS } {
+		b.Run(fmt.Sprintf("Workers %d", workers), func(b *testing.B) {
+			b.ResetTimer()
+
+			for idx := 0; idx < b.N; idx++ {
+				readErr := benchInst.ReadTx(func(tx *mari.MariTx) error {
+					var kvPairs []*mari.KeyValuePair
+					var rangeTxErr error
+
+					if workers == 1 {
+						kvPairs, rangeTxErr = tx.Range(nil, nil, nil)
+					} else { kvPairs, rangeTxErr = tx.RangeParallel(nil, nil, workers, nil) }
+
+					if rangeTxErr != nil { return rangeTxErr }
+					if len(kvPairs) != RANGE_PARALLEL_INPUT_SIZE { b.Errorf("range length does not match input: actual(%d), expected(%d)", len(kvPairs), RANGE_PARALLEL_INPUT_SIZE) }
+
+					return nil
+				})
+
+				if readErr != nil { b.Fatalf("error on mari range: %s", readErr.Error()) }
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "sort"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var rankMariInst *mari.Mari
+var rankKeyValPairs []KeyVal
+var rankInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testrank"))
+	os.Remove(filepath.Join(os.TempDir(), "testranktemp"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testrank", NodePoolSize: &smallNodePoolSize }
+
+	rankMariInst, rankInitMariErr = mari.Open(opts)
+	if rankInitMariErr != nil {
+		rankMariInst.Remove()
+		panic(rankInitMariErr.Error())
+	}
+
+	fmt.Println("rank test mari initialized")
+}
+
+
+func TestMariRank(t *testing.T) {
+	defer rankMariInst.Remove()
+
+	rankKeyValPairs = GenerateKeyValPairs(InputSize(RANK_INPUT_SIZE))
+	inputSize := len(rankKeyValPairs)
+
+	sortedKeys := make([][]byte, 0, inputSize)
+
+	t.Run("Test Rank And Select After Inserts", func(t *testing.T) {
+		chunks, chunkErr := Chunk(rankKeyValPairs, TRANSACTION_CHUNK_SIZE)
+		if chunkErr != nil { t.Fatalf("error chunking input: %s", chunkErr.Error()) }
+
+		for _, chunk := range chunks {
+			putErr := rankMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for _, val := range chunk {
+					putTxErr := tx.Put(val.Key, val.Value)
+					if putTxErr != nil { return putTxErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		for _, val := range rankKeyValPairs { sortedKeys = append(sortedKeys, val.Key) }
+		sort.Slice(sortedKeys, func(i, j int) bool { return bytes.Compare(sortedKeys[i], sortedKeys[j]) == -1 })
+
+		readErr := rankMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for idx := 0; idx < inputSize; idx += inputSize / RANK_SAMPLES {
+				rank, rankTxErr := tx.Rank(sortedKeys[idx])
+				if rankTxErr != nil { return rankTxErr }
+				if rank != idx { t.Errorf("rank does not match: actual(%d), expected(%d)", rank, idx) }
+
+				kvPair, selectTxErr := tx.Select(idx)
+				if selectTxErr != nil { return selectTxErr }
+				if kvPair == nil || ! bytes.Equal(kvPair.Key, sortedKeys[idx]) { t.Errorf("selected key does not match at %d: actual(%v), expected(%v)", idx, kvPair, sortedKeys[idx]) }
+			}
+
+			kvPair, selectTxErr := tx.Select(inputSize)
+			if selectTxErr != nil { return selectTxErr }
+			if kvPair != nil { t.Errorf("expected nil when selecting past the total number of keys: %v", kvPair) }
+
+			rank, rankTxErr := tx.Rank(append(append([]byte{}, sortedKeys[inputSize - 1]...), 0))
+			if rankTxErr != nil { return rankTxErr }
+			if rank != inputSize { t.Errorf("rank past the largest key does not match: actual(%d), expected(%d)", rank, inputSize) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari rank: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Range Size Estimate", func(t *testing.T) {
+		readErr := rankMariInst.ReadTx(func(tx *mari.MariTx) error {
+			totalKeys, totalBytes, estimateTxErr := tx.RangeSizeEstimate(sortedKeys[0], sortedKeys[inputSize - 1])
+			if estimateTxErr != nil { return estimateTxErr }
+			if totalKeys != inputSize { t.Errorf("estimated keys for the full range do not match: actual(%d), expected(%d)", totalKeys, inputSize) }
+			if totalBytes <= 0 { t.Errorf("expected estimated bytes for the full range: %d", totalBytes) }
+
+			for idx := 0; idx < inputSize / 2; idx += inputSize / RANK_SAMPLES {
+				endIdx := idx + inputSize / 2
+
+				rangeKeys, rangeBytes, estimateTxErr := tx.RangeSizeEstimate(sortedKeys[idx], sortedKeys[endIdx])
+				if estimateTxErr != nil { return estimateTxErr }
+				if rangeKeys != endIdx - idx + 1 { t.Errorf("estimated keys do not match: actual(%d), expected(%d)", rangeKeys, endIdx - idx + 1) }
+				if rangeBytes <= 0 || rangeBytes > totalBytes { t.Errorf("estimated bytes are out of bounds: actual(%d), total(%d)", rangeBytes, totalBytes) }
+			}
+
+			missingKey := append(append([]byte{}, sortedKeys[0]...), 0)
+			emptyKeys, emptyBytes, estimateTxErr := tx.RangeSizeEstimate(missingKey, missingKey)
+			if estimateTxErr != nil { return estimateTxErr }
+			if emptyKeys != 0 || emptyBytes != 0 { t.Errorf("expected an empty estimate for a missing key: keys(%d), bytes(%d)", emptyKeys, emptyBytes) }
+
+			_, _, invalidErr := tx.RangeSizeEstimate(sortedKeys[1], sortedKeys[0])
+			if invalidErr == nil { t.Errorf("expected error when the start key is larger than the end key") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari range size estimate: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Rank And Select After Deletes", func(t *testing.T) {
+		delErr := rankMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			for idx := 0; idx < inputSize; idx += 2 {
+				delTxErr := tx.Delete(sortedKeys[idx])
+				if delTxErr != nil { return delTxErr }
+			}
+
+			return nil
+		})
+
+		if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+
+		sampleStep := 2 * (inputSize / (2 * RANK_SAMPLES))
+
+		readErr := rankMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for idx := 1; idx < inputSize; idx += sampleStep {
+				rank, rankTxErr := tx.Rank(sortedKeys[idx])
+				if rankTxErr != nil { return rankTxErr }
+				if rank != idx / 2 { t.Errorf("rank after deletes does not match: actual(%d), expected(%d)", rank, idx / 2) }
+
+				kvPair, selectTxErr := tx.Select(idx / 2)
+				if selectTxErr != nil { return selectTxErr }
+				if kvPair == nil || ! bytes.Equal(kvPair.Key, sortedKeys[idx]) { t.Errorf("selected key after deletes does not match at %d", idx / 2) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari rank: %s", readErr.Error()) }
+	})
+}
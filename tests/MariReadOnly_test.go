@@ -0,0 +1,124 @@
+package maritests
+
+import "bytes"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var readOnlyWriterInst *mari.Mari
+var readOnlyKeyValPairs []KeyVal
+var readOnlyInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testreadonly"))
+	os.Remove(filepath.Join(os.TempDir(), "testreadonly.vidx"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testreadonly", NodePoolSize: &smallNodePoolSize }
+
+	readOnlyWriterInst, readOnlyInitMariErr = mari.Open(opts)
+	if readOnlyInitMariErr != nil {
+		readOnlyWriterInst.Remove()
+		panic(readOnlyInitMariErr.Error())
+	}
+
+	fmt.Println("read only test mari initialized")
+}
+
+
+func TestMariReadOnly(t *testing.T) {
+	defer readOnlyWriterInst.Remove()
+
+	readOnlyKeyValPairs = GenerateKeyValPairs(READ_ONLY_INPUT_SIZE)
+	inputSize := len(readOnlyKeyValPairs)
+
+	firstHalf := readOnlyKeyValPairs[:inputSize / 2]
+	secondHalf := readOnlyKeyValPairs[inputSize / 2:]
+
+	putAll := func(t *testing.T, pairs []KeyVal) {
+		for _, val := range pairs {
+			putErr := readOnlyWriterInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put(val.Key, val.Value)
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+	}
+
+	verifyAll := func(t *testing.T, readOnlyInst *mari.Mari, pairs []KeyVal) {
+		readErr := readOnlyInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, val := range pairs {
+				kvPair, getTxErr := tx.Get(val.Key, nil)
+				if getTxErr != nil { return getTxErr }
+
+				if kvPair == nil || ! bytes.Equal(kvPair.Value, val.Value) {
+					t.Errorf("actual value not equal to expected: actual(%v), expected(%v)", kvPair, val)
+				}
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on read only get: %s", readErr.Error()) }
+	}
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testreadonly", ReadOnly: true }
+
+	t.Run("Test Read Only Attaches To Writer", func(t *testing.T) {
+		putAll(t, firstHalf)
+
+		readOnlyInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening read only mari: %s", openErr.Error()) }
+		defer readOnlyInst.Close()
+
+		verifyAll(t, readOnlyInst, firstHalf)
+	})
+
+	t.Run("Test Read Only Rejects Writes", func(t *testing.T) {
+		readOnlyInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening read only mari: %s", openErr.Error()) }
+		defer readOnlyInst.Close()
+
+		updateErr := readOnlyInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("readonly"), []byte("readonly"))
+		})
+
+		if updateErr == nil { t.Errorf("expected error on update in read only mari") }
+
+		removeErr := readOnlyInst.Remove()
+		if removeErr == nil { t.Errorf("expected error removing read only mari") }
+
+		_, statErr := os.Stat(filepath.Join(os.TempDir(), "testreadonly"))
+		if statErr != nil { t.Errorf("read only remove deleted the file: %s", statErr.Error()) }
+	})
+
+	t.Run("Test Read Only Follows Writer Growth", func(t *testing.T) {
+		readOnlyInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening read only mari: %s", openErr.Error()) }
+		defer readOnlyInst.Close()
+
+		sizeBefore, sizeErr := readOnlyWriterInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		putAll(t, secondHalf)
+
+		sizeAfter, sizeErr := readOnlyWriterInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if sizeAfter <= sizeBefore { t.Fatalf("writer did not resize: before(%d), after(%d)", sizeBefore, sizeAfter) }
+
+		verifyAll(t, readOnlyInst, readOnlyKeyValPairs)
+
+		totalCount, countErr := readOnlyInst.Count()
+		if countErr != nil { t.Fatalf("error counting keys: %s", countErr.Error()) }
+		if totalCount != inputSize { t.Errorf("count does not match expected: actual(%d), expected(%d)", totalCount, inputSize) }
+	})
+
+	t.Run("Test Read Only Empty File", func(t *testing.T) {
+		_, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testreadonlymissing", ReadOnly: true })
+		if openErr == nil { t.Errorf("expected error opening a missing file read only") }
+	})
+}
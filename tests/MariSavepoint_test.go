@@ -0,0 +1,81 @@
+package maritests
+
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariSavepoint(t *testing.T) {
+	t.Run("Test Mari Savepoints", func(t *testing.T) {
+		savepointInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening savepoint instance: %s", openErr.Error()) }
+		defer savepointInst.Close()
+
+		var committed []mari.KeyValuePair
+		events, cancel := savepointInst.Watch(nil)
+		defer cancel()
+
+		updateErr := savepointInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("a"), []byte("1"))
+			if putErr != nil { return putErr }
+
+			first := tx.Savepoint()
+
+			putErr = tx.Put([]byte("b"), []byte("1"))
+			if putErr != nil { return putErr }
+
+			delErr := tx.Delete([]byte("a"))
+			if delErr != nil { return delErr }
+
+			second := tx.Savepoint()
+
+			putErr = tx.Put([]byte("a"), []byte("2"))
+			if putErr != nil { return putErr }
+
+			rollbackErr := tx.RollbackTo(second)
+			if rollbackErr != nil { return rollbackErr }
+
+			kvPair, getErr := tx.Get([]byte("a"), nil)
+			if getErr != nil { return getErr }
+			if kvPair != nil { t.Errorf("expected a to be deleted at the second savepoint: %v", kvPair) }
+
+			rollbackErr = tx.RollbackTo(first)
+			if rollbackErr != nil { return rollbackErr }
+
+			if tx.RollbackTo(second) == nil { t.Errorf("expected error rolling back to a discarded savepoint") }
+
+			putErr = tx.Put([]byte("c"), []byte("1"))
+			if putErr != nil { return putErr }
+
+			rollbackErr = tx.RollbackTo(first)
+			if rollbackErr != nil { return rollbackErr }
+
+			return tx.Put([]byte("d"), []byte("1"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		for range make([]int, 2) { committed = append(committed, <-events) }
+
+		if string(committed[0].Key) != "a" || string(committed[1].Key) != "d" { t.Errorf("committed changes do not match: %v", committed) }
+
+		readErr := savepointInst.ReadTx(func(tx *mari.MariTx) error {
+			if tx.RollbackTo(0) == nil { t.Errorf("expected error rolling back a read only transaction") }
+
+			pairs, rangeErr := tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+			if rangeErr != nil { return rangeErr }
+
+			if len(pairs) != 2 || string(pairs[0].Key) != "a" || string(pairs[0].Value) != "1" || string(pairs[1].Key) != "d" {
+				t.Errorf("pairs after rollback do not match: %v", pairs)
+			}
+
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != 2 { t.Errorf("count after rollback does not match: actual(%d), expected(2)", count) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+}
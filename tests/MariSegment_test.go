@@ -0,0 +1,294 @@
+package maritests
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var segmentMariInst *mari.Mari
+var segmentKeyValPairs []KeyVal
+var segmentInitMariErr error
+var segmentPath = filepath.Join(os.TempDir(), "testsegment.seg")
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testsegment"))
+	os.Remove(filepath.Join(os.TempDir(), "testsegmenttemp"))
+	os.Remove(segmentPath)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testsegment", NodePoolSize: &smallNodePoolSize }
+
+	segmentMariInst, segmentInitMariErr = mari.Open(opts)
+	if segmentInitMariErr != nil {
+		segmentMariInst.Remove()
+		panic(segmentInitMariErr.Error())
+	}
+
+	fmt.Println("segment test mari initialized")
+}
+
+
+func TestMariSegment(t *testing.T) {
+	defer segmentMariInst.Remove()
+
+	segmentKeyValPairs = GenerateKeyValPairs(InputSize(SEGMENT_INPUT_SIZE))
+	inputSize := len(segmentKeyValPairs)
+
+	defer os.Remove(segmentPath)
+
+	var segment *mari.MariSegment
+
+	t.Run("Test Write Segment", func(t *testing.T) {
+		chunks, chunkErr := Chunk(segmentKeyValPairs, TRANSACTION_CHUNK_SIZE)
+		if chunkErr != nil { t.Fatalf("error chunking kvPairs: %s", chunkErr.Error()) }
+
+		for _, chunk := range chunks {
+			putErr := segmentMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for _, kvPair := range chunk {
+					putTxErr := tx.Put(kvPair.Key, kvPair.Value)
+					if putTxErr != nil { return putTxErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		writeErr := segmentMariInst.WriteSegment(segmentPath, 4096)
+		if writeErr != nil { t.Fatalf("error writing segment: %s", writeErr.Error()) }
+
+		mariSize, mariSizeErr := segmentMariInst.FileSize()
+		if mariSizeErr != nil { t.Fatalf("error getting mari file size: %s", mariSizeErr.Error()) }
+
+		stat, statErr := os.Stat(segmentPath)
+		if statErr != nil { t.Fatalf("error getting segment file size: %s", statErr.Error()) }
+
+		t.Logf("mari file size: %d, segment file size: %d", mariSize, stat.Size())
+		if stat.Size() * 4 > int64(mariSize) { t.Errorf("segment is not substantially smaller than mari file: segment(%d), mari(%d)", stat.Size(), mariSize) }
+
+		var openErr error
+		segment, openErr = mari.OpenSegment(segmentPath)
+		if openErr != nil { t.Fatalf("error opening segment: %s", openErr.Error()) }
+	})
+
+	t.Run("Test Segment Get", func(t *testing.T) {
+		for _, val := range segmentKeyValPairs {
+			kvPair, getErr := segment.Get(val.Key)
+			if getErr != nil { t.Fatalf("error on segment get: %s", getErr.Error()) }
+
+			if kvPair == nil || ! bytes.Equal(kvPair.Key, val.Key) || ! bytes.Equal(kvPair.Value, val.Value) {
+				t.Fatalf("actual value not equal to expected: actual(%v), expected(%v)", kvPair, val)
+			}
+		}
+
+		kvPair, getErr := segment.Get([]byte("missing"))
+		if getErr != nil { t.Errorf("error on segment get: %s", getErr.Error()) }
+		if kvPair != nil { t.Errorf("expected nil for missing key, got: %v", kvPair) }
+	})
+
+	t.Run("Test Count Range", func(t *testing.T) {
+		first, second, randomErr := TwoRandomDistinctValues(0, inputSize)
+		if randomErr != nil { t.Fatalf("error generating random values: %s", randomErr.Error()) }
+
+		startKey := segmentKeyValPairs[first].Key
+		endKey := segmentKeyValPairs[second].Key
+		if bytes.Compare(startKey, endKey) == 1 { startKey, endKey = endKey, startKey }
+
+		readErr := segmentMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, rangeTxErr := tx.Range(startKey, endKey, nil)
+			if rangeTxErr != nil { return rangeTxErr }
+
+			totalCount, countTxErr := tx.CountRange(startKey, endKey)
+			if countTxErr != nil { return countTxErr }
+
+			t.Logf("range length: %d, count range: %d", len(kvPairs), totalCount)
+			if totalCount != len(kvPairs) { t.Errorf("count range does not match range length: actual(%d), expected(%d)", totalCount, len(kvPairs)) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari count range: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Range Keys", func(t *testing.T) {
+		first, second, randomErr := TwoRandomDistinctValues(0, inputSize)
+		if randomErr != nil { t.Fatalf("error generating random values: %s", randomErr.Error()) }
+
+		startKey := segmentKeyValPairs[first].Key
+		endKey := segmentKeyValPairs[second].Key
+		if bytes.Compare(startKey, endKey) == 1 { startKey, endKey = endKey, startKey }
+
+		readErr := segmentMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairs, rangeTxErr := tx.Range(startKey, endKey, nil)
+			if rangeTxErr != nil { return rangeTxErr }
+
+			keys, keysTxErr := tx.RangeKeys(startKey, endKey)
+			if keysTxErr != nil { return keysTxErr }
+
+			t.Logf("range length: %d, range keys length: %d", len(kvPairs), len(keys))
+			if len(keys) != len(kvPairs) { t.Fatalf("range keys length does not match range length: actual(%d), expected(%d)", len(keys), len(kvPairs)) }
+
+			for idx, key := range keys {
+				if ! bytes.Equal(key, kvPairs[idx].Key) { t.Errorf("range key does not match: actual(%v), expected(%v)", key, kvPairs[idx].Key) }
+			}
+
+			_, invalidErr := tx.RangeKeys(endKey, startKey)
+			if invalidErr == nil { t.Errorf("expected error when the start key is larger than the end key") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari range keys: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Segment Range", func(t *testing.T) {
+		defer segment.Close()
+
+		first, second, randomErr := TwoRandomDistinctValues(0, inputSize)
+		if randomErr != nil { t.Fatalf("error generating random values: %s", randomErr.Error()) }
+
+		startKey := segmentKeyValPairs[first].Key
+		endKey := segmentKeyValPairs[second].Key
+		if bytes.Compare(startKey, endKey) == 1 { startKey, endKey = endKey, startKey }
+
+		var expected []*mari.KeyValuePair
+		readErr := segmentMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var rangeTxErr error
+			expected, rangeTxErr = tx.Range(startKey, endKey, nil)
+			return rangeTxErr
+		})
+
+		if readErr != nil { t.Fatalf("error on mari range: %s", readErr.Error()) }
+
+		actual, rangeErr := segment.Range(startKey, endKey)
+		if rangeErr != nil { t.Fatalf("error on segment range: %s", rangeErr.Error()) }
+
+		if ! IsSorted(actual) { t.Errorf("segment range is not in sorted order") }
+
+		actualKeys := make(map[string]bool)
+		for _, kvPair := range actual {
+			if bytes.Compare(kvPair.Key, startKey) == -1 || bytes.Compare(kvPair.Key, endKey) == 1 {
+				t.Fatalf("segment range returned key outside of bounds: %s", kvPair.Key)
+			}
+
+			actualKeys[string(kvPair.Key)] = true
+		}
+
+		t.Logf("mari range length: %d, segment range length: %d", len(expected), len(actual))
+		for _, kvPair := range expected {
+			if ! actualKeys[string(kvPair.Key)] { t.Fatalf("segment range missing key from mari range: %s", kvPair.Key) }
+		}
+	})
+}
+
+func TestMariSegmentPrefixKeys(t *testing.T) {
+	prefixSegmentPath := filepath.Join(os.TempDir(), "testsegmentprefix.seg")
+	os.Remove(prefixSegmentPath)
+	defer os.Remove(prefixSegmentPath)
+
+	prefixKeys := []string{ "abc", "abcd", "abd", "az", "ba", "bazzz", "bb" }
+
+	prefixInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer prefixInst.Close()
+
+	for _, key := range []string{ "az", "abc", "bazzz", "abd", "bb", "abcd", "ba" } {
+		putErr := prefixInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte(key), []byte("value:" + key))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+	}
+
+	for _, blockSize := range []int{ 1, 32, 4096 } {
+		writeErr := prefixInst.WriteSegment(prefixSegmentPath, blockSize)
+		if writeErr != nil { t.Fatalf("error writing segment: %s", writeErr.Error()) }
+
+		segment, openSegmentErr := mari.OpenSegment(prefixSegmentPath)
+		if openSegmentErr != nil { t.Fatalf("error opening segment: %s", openSegmentErr.Error()) }
+
+		for _, key := range prefixKeys {
+			kvPair, getErr := segment.Get([]byte(key))
+			if getErr != nil { t.Fatalf("error on segment get: %s", getErr.Error()) }
+			if kvPair == nil || string(kvPair.Value) != "value:" + key { t.Errorf("segment get for %q with block size %d returned the wrong pair: %v", key, blockSize, kvPair) }
+		}
+
+		kvPairs, rangeErr := segment.Range([]byte("a"), []byte("bb"))
+		if rangeErr != nil { t.Fatalf("error on segment range: %s", rangeErr.Error()) }
+		if len(kvPairs) != len(prefixKeys) { t.Fatalf("segment range length with block size %d does not match: actual(%d), expected(%d)", blockSize, len(kvPairs), len(prefixKeys)) }
+
+		for idx, kvPair := range kvPairs {
+			if string(kvPair.Key) != prefixKeys[idx] { t.Errorf("segment range with block size %d is out of order at %d: actual(%s), expected(%s)", blockSize, idx, kvPair.Key, prefixKeys[idx]) }
+		}
+
+		segment.Close()
+	}
+}
+
+func TestMariSegmentCorrupt(t *testing.T) {
+	corruptSegmentPath := filepath.Join(os.TempDir(), "testsegmentcorrupt.seg")
+	os.Remove(corruptSegmentPath)
+	defer os.Remove(corruptSegmentPath)
+
+	corruptInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+	defer corruptInst.Close()
+
+	putErr := corruptInst.UpdateTx(func(tx *mari.MariTx) error {
+		for _, key := range []string{ "a", "b", "c" } {
+			putTxErr := tx.Put([]byte(key), []byte("value:" + key))
+			if putTxErr != nil { return putTxErr }
+		}
+
+		return nil
+	})
+
+	if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+	writeErr := corruptInst.WriteSegment(corruptSegmentPath, 4096)
+	if writeErr != nil { t.Fatalf("error writing segment: %s", writeErr.Error()) }
+
+	original, readErr := os.ReadFile(corruptSegmentPath)
+	if readErr != nil { t.Fatalf("error reading segment: %s", readErr.Error()) }
+
+	footerStart := len(original) - 16
+	indexOffset := binary.LittleEndian.Uint64(original[footerStart:footerStart + 8])
+
+	writeCorrupt := func(t *testing.T, corrupt func(data []byte)) {
+		data := append([]byte{}, original...)
+		corrupt(data)
+
+		writeCorruptErr := os.WriteFile(corruptSegmentPath, data, 0644)
+		if writeCorruptErr != nil { t.Fatalf("error writing corrupt segment: %s", writeCorruptErr.Error()) }
+	}
+
+	t.Run("Test Block Count Larger Than Index", func(t *testing.T) {
+		writeCorrupt(t, func(data []byte) { binary.LittleEndian.PutUint64(data[footerStart + 8:], 1 << 40) })
+
+		segment, openSegmentErr := mari.OpenSegment(corruptSegmentPath)
+		if openSegmentErr == nil {
+			segment.Close()
+			t.Fatalf("expected an error opening a segment with a block count larger than the index")
+		}
+	})
+
+	t.Run("Test Block Past End Of File", func(t *testing.T) {
+		writeCorrupt(t, func(data []byte) { binary.LittleEndian.PutUint64(data[indexOffset + 8:], 1 << 40) })
+
+		segment, openSegmentErr := mari.OpenSegment(corruptSegmentPath)
+		if openSegmentErr != nil { t.Fatalf("error opening segment: %s", openSegmentErr.Error()) }
+		defer segment.Close()
+
+		_, getErr := segment.Get([]byte("a"))
+		if getErr == nil { t.Errorf("expected an error reading a block past the end of the segment") }
+
+		_, rangeErr := segment.Range([]byte("a"), []byte("c"))
+		if rangeErr == nil { t.Errorf("expected an error reading a range past the end of the segment") }
+	})
+}
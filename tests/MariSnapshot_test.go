@@ -0,0 +1,128 @@
+package maritests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync/atomic"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mari"
+
+
+var snapshotMariInst *mari.Mari
+var snapshotInitMariErr error
+var snapshotCompactNow uint32
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testsnapshot"))
+	os.Remove(filepath.Join(os.TempDir(), "testsnapshottemp"))
+
+	compactTrigger := func(metaData *mari.MariMetaData) bool {
+		return atomic.CompareAndSwapUint32(&snapshotCompactNow, 1, 0)
+	}
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testsnapshot", CompactTrigger: &compactTrigger, NodePoolSize: &smallNodePoolSize }
+
+	snapshotMariInst, snapshotInitMariErr = mari.Open(opts)
+	if snapshotInitMariErr != nil {
+		snapshotMariInst.Remove()
+		panic(snapshotInitMariErr.Error())
+	}
+
+	fmt.Println("snapshot test mari initialized")
+}
+
+
+func TestMariSnapshot(t *testing.T) {
+	defer snapshotMariInst.Remove()
+
+	compactUntil := func(done func() bool) {
+		for start := time.Now(); time.Since(start) < 10 * time.Second; time.Sleep(10 * time.Millisecond) {
+			atomic.StoreUint32(&snapshotCompactNow, 1)
+
+			putErr := snapshotMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("trigger"), []byte("trigger"))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+			if done() { return }
+		}
+
+		t.Fatalf("compaction did not complete")
+	}
+
+	var snapshot *mari.MariSnapshot
+
+	t.Run("Test Snapshot Pins Version", func(t *testing.T) {
+		for idx := range make([]int, SNAPSHOT_INPUT_SIZE) {
+			putErr := snapshotMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		var snapshotErr error
+		snapshot, snapshotErr = snapshotMariInst.Snapshot()
+		if snapshotErr != nil { t.Fatalf("error taking snapshot: %s", snapshotErr.Error()) }
+		if snapshot.Version() != uint64(SNAPSHOT_INPUT_SIZE) { t.Errorf("snapshot version does not match: actual(%d), expected(%d)", snapshot.Version(), SNAPSHOT_INPUT_SIZE) }
+
+		for idx := range make([]int, SNAPSHOT_INPUT_SIZE) {
+			delErr := snapshotMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Delete([]byte(fmt.Sprintf("key%d", idx)))
+			})
+
+			if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+		}
+	})
+
+	t.Run("Test Snapshot Reads Across Compaction", func(t *testing.T) {
+		compactUntil(func() bool { return snapshot.Version() == 0 })
+
+		viewErr := snapshot.ViewTx(func(tx *mari.MariTx) error {
+			totalCount, countTxErr := tx.Count()
+			if countTxErr != nil { return countTxErr }
+			if totalCount != SNAPSHOT_INPUT_SIZE { t.Errorf("count in snapshot does not match: actual(%d), expected(%d)", totalCount, SNAPSHOT_INPUT_SIZE) }
+
+			for idx := range make([]int, SNAPSHOT_INPUT_SIZE) {
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", idx)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil || string(kvPair.Value) != fmt.Sprintf("value%d", idx) { return fmt.Errorf("unexpected value for key%d: %v", idx, kvPair) }
+			}
+
+			return nil
+		})
+
+		if viewErr != nil { t.Errorf("error on snapshot view tx: %s", viewErr.Error()) }
+
+		readErr := snapshotMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getTxErr := tx.Get([]byte("key0"), nil)
+			if getTxErr != nil { return getTxErr }
+			if kvPair != nil { t.Errorf("deleted key visible in current version") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Closed Snapshot Is Reclaimed", func(t *testing.T) {
+		closeErr := snapshot.Close()
+		if closeErr != nil { t.Fatalf("error closing snapshot: %s", closeErr.Error()) }
+
+		viewErr := snapshot.ViewTx(func(tx *mari.MariTx) error { return nil })
+		if viewErr == nil { t.Errorf("expected error viewing a closed snapshot") }
+
+		compactUntil(func() bool {
+			currVersion, versionErr := snapshotMariInst.CurrentVersion()
+			if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+			return currVersion <= 1
+		})
+
+		versions, listErr := snapshotMariInst.ListVersions()
+		if listErr != nil { t.Fatalf("error listing versions: %s", listErr.Error()) }
+		if len(versions) > 2 { t.Errorf("versions were not reclaimed after closing snapshot: %v", versions) }
+	})
+}
@@ -0,0 +1,195 @@
+package maritests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sort"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariSplit(t *testing.T) {
+	t.Run("Test Mari Split", func(t *testing.T) {
+		splitInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening split instance: %s", openErr.Error()) }
+		defer splitInst.Close()
+
+		splitKeys := []string{ "a", "ab", "abc", "abcd", "abd", "abde", "b", "ba", "bb", "c" }
+		for idx := range make([]int, 500) { splitKeys = append(splitKeys, fmt.Sprintf("key:%04d", idx)) }
+
+		updateErr := splitInst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, key := range splitKeys {
+				putErr := tx.Put([]byte(key), []byte("value:" + key))
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		sort.Strings(splitKeys)
+
+		for _, at := range []string{ "abd", "abcz", "key:0250", "", "zzz" } {
+			leftName := "testmarisplitleft"
+			rightName := "testmarisplitright"
+			for _, name := range []string{ leftName, rightName } {
+				os.Remove(filepath.Join(os.TempDir(), name))
+				os.Remove(filepath.Join(os.TempDir(), name + ".vidx"))
+			}
+
+			splitErr := splitInst.Split([]byte(at), filepath.Join(os.TempDir(), leftName), filepath.Join(os.TempDir(), rightName))
+			if splitErr != nil { t.Fatalf("error on mari split at %q: %s", at, splitErr.Error()) }
+
+			var expectedLeft, expectedRight []string
+			for _, key := range splitKeys {
+				if key < at {
+					expectedLeft = append(expectedLeft, key)
+				} else { expectedRight = append(expectedRight, key) }
+			}
+
+			for _, side := range []struct{ name string; expected []string }{ { leftName, expectedLeft }, { rightName, expectedRight } } {
+				sideInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: side.name, NodePoolSize: &smallNodePoolSize })
+				if openErr != nil { t.Fatalf("error opening split file %s: %s", side.name, openErr.Error()) }
+
+				verifyErr := sideInst.VerifyIntegrity()
+				if verifyErr != nil { t.Errorf("split file %s at %q failed verification: %s", side.name, at, verifyErr.Error()) }
+
+				count, countErr := sideInst.Count()
+				if countErr != nil { t.Fatalf("error counting split keys: %s", countErr.Error()) }
+				if count != len(side.expected) { t.Errorf("split count for %s at %q does not match: actual(%d), expected(%d)", side.name, at, count, len(side.expected)) }
+
+				readErr := sideInst.ReadTx(func(tx *mari.MariTx) error {
+					kvPairs, rangeErr := tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+					if rangeErr != nil { return rangeErr }
+					if len(kvPairs) != len(side.expected) { t.Fatalf("split range for %s at %q does not match: actual(%d), expected(%d)", side.name, at, len(kvPairs), len(side.expected)) }
+
+					for idx, kvPair := range kvPairs {
+						if string(kvPair.Key) != side.expected[idx] || string(kvPair.Value) != "value:" + side.expected[idx] {
+							t.Errorf("split pair for %s at %q does not match: actual(%s), expected(%s)", side.name, at, kvPair.Key, side.expected[idx])
+						}
+					}
+
+					return nil
+				})
+
+				if readErr != nil { t.Errorf("error reading split file: %s", readErr.Error()) }
+
+				putErr := sideInst.UpdateTx(func(tx *mari.MariTx) error {
+					return tx.Put([]byte("after:split"), []byte("value"))
+				})
+
+				if putErr != nil { t.Errorf("error writing to split file: %s", putErr.Error()) }
+
+				removeErr := sideInst.Remove()
+				if removeErr != nil { t.Errorf("error removing split file: %s", removeErr.Error()) }
+			}
+		}
+
+		existingPath := filepath.Join(os.TempDir(), "testmarisplitexisting")
+		os.WriteFile(existingPath, []byte{}, 0600)
+		defer os.Remove(existingPath)
+
+		existsErr := splitInst.Split([]byte("b"), filepath.Join(os.TempDir(), "testmarisplitother"), existingPath)
+		if existsErr == nil { t.Errorf("expected error splitting to an existing file") }
+
+		samePathErr := splitInst.Split([]byte("b"), existingPath + "same", existingPath + "same")
+		if samePathErr == nil { t.Errorf("expected error splitting to the same file") }
+	})
+
+	t.Run("Test Mari Merge Files", func(t *testing.T) {
+		names := []string{ "testmarimergefirst", "testmarimergesecond", "testmarimergedest" }
+		for _, name := range names {
+			os.Remove(filepath.Join(os.TempDir(), name))
+			os.Remove(filepath.Join(os.TempDir(), name + ".vidx"))
+		}
+
+		sources := []map[string]string{
+			{ "a": "first", "b": "first", "shared": "first" },
+			{ "c": "second", "d": "second", "shared": "second" },
+		}
+
+		for idx, pairs := range sources {
+			sourceInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: names[idx], NodePoolSize: &smallNodePoolSize })
+			if openErr != nil { t.Fatalf("error opening merge source: %s", openErr.Error()) }
+
+			updateErr := sourceInst.UpdateTx(func(tx *mari.MariTx) error {
+				for key, value := range pairs {
+					putErr := tx.Put([]byte(key), []byte(value))
+					if putErr != nil { return putErr }
+				}
+
+				return nil
+			})
+
+			if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+			closeErr := sourceInst.Close()
+			if closeErr != nil { t.Fatalf("error closing merge source: %s", closeErr.Error()) }
+		}
+
+		firstPath := filepath.Join(os.TempDir(), names[0])
+		secondPath := filepath.Join(os.TempDir(), names[1])
+		destPath := filepath.Join(os.TempDir(), names[2])
+
+		firstOpts := mari.MariOpts{ Filepath: os.TempDir(), FileName: names[0] }
+		secondOpts := mari.MariOpts{ Filepath: os.TempDir(), FileName: names[1] }
+		destOpts := mari.MariOpts{ Filepath: os.TempDir(), FileName: names[2], NodePoolSize: &smallNodePoolSize }
+
+		defer func() {
+			for _, path := range []string{ firstPath, secondPath } {
+				os.Remove(path)
+				os.Remove(path + ".vidx")
+			}
+		}()
+
+		checkMerged := func(destInst *mari.Mari, expected map[string]string) {
+			count, countErr := destInst.Count()
+			if countErr != nil { t.Fatalf("error counting merged keys: %s", countErr.Error()) }
+			if count != len(expected) { t.Errorf("merged count does not match: actual(%d), expected(%d)", count, len(expected)) }
+
+			readErr := destInst.ReadTx(func(tx *mari.MariTx) error {
+				for key, value := range expected {
+					kvPair, getErr := tx.Get([]byte(key), nil)
+					if getErr != nil { return getErr }
+					if kvPair == nil || string(kvPair.Value) != value { t.Errorf("merged value for %s does not match: actual(%v), expected(%s)", key, kvPair, value) }
+				}
+
+				return nil
+			})
+
+			if readErr != nil { t.Errorf("error reading merged file: %s", readErr.Error()) }
+
+			removeErr := destInst.Remove()
+			if removeErr != nil { t.Errorf("error removing merged file: %s", removeErr.Error()) }
+		}
+
+		destInst, mergeErr := mari.Merge(destOpts, nil, firstOpts, secondOpts)
+		if mergeErr != nil { t.Fatalf("error on mari merge: %s", mergeErr.Error()) }
+		checkMerged(destInst, map[string]string{ "a": "first", "b": "first", "c": "second", "d": "second", "shared": "second" })
+
+		destInst, mergeErr = mari.Merge(destOpts, func(key, existing, incoming []byte) []byte {
+			return append(append(append([]byte{}, existing...), '+'), incoming...)
+		}, firstOpts, secondOpts)
+
+		if mergeErr != nil { t.Fatalf("error on mari merge with resolver: %s", mergeErr.Error()) }
+		checkMerged(destInst, map[string]string{ "a": "first", "b": "first", "c": "second", "d": "second", "shared": "first+second" })
+
+		destInst, mergeErr = mari.Merge(destOpts, func(key, existing, incoming []byte) []byte { return nil }, firstOpts, secondOpts)
+		if mergeErr != nil { t.Fatalf("error on mari merge with removing resolver: %s", mergeErr.Error()) }
+		checkMerged(destInst, map[string]string{ "a": "first", "b": "first", "c": "second", "d": "second" })
+
+		_, existsErr := mari.Merge(firstOpts, nil, secondOpts)
+		if existsErr == nil { t.Errorf("expected error merging into an existing file") }
+
+		_, noSourcesErr := mari.Merge(destOpts, nil)
+		if noSourcesErr == nil { t.Errorf("expected error merging without sources") }
+
+		_, missingErr := mari.Merge(destOpts, nil, mari.MariOpts{ Filepath: os.TempDir(), FileName: "testmarimergemissing" })
+		if missingErr == nil { t.Errorf("expected error merging a missing source") }
+
+		_, statErr := os.Stat(destPath)
+		if ! os.IsNotExist(statErr) { t.Errorf("expected failed merge to remove the destination: %v", statErr) }
+	})
+}
@@ -0,0 +1,317 @@
+package maritests
+
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+import "github.com/sirgallo/mari"
+
+
+var streamMariInst *mari.Mari
+var streamOpts mari.MariOpts
+var streamCommitted []mari.KeyValuePair
+var streamInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "teststream"))
+	os.Remove(filepath.Join(os.TempDir(), "teststreamtemp"))
+
+	streamOpts = mari.MariOpts{
+		Filepath: os.TempDir(),
+		FileName: "teststream",
+		NodePoolSize: &smallNodePoolSize,
+		OnCommit: func(version uint64, changes []mari.KeyValuePair) { streamCommitted = changes },
+	}
+
+	streamMariInst, streamInitMariErr = mari.Open(streamOpts)
+	if streamInitMariErr != nil {
+		streamMariInst.Remove()
+		panic(streamInitMariErr.Error())
+	}
+
+	fmt.Println("stream test mari initialized")
+}
+
+
+func TestMariStream(t *testing.T) {
+	defer func() { streamMariInst.Remove() }()
+
+	streamKey := []byte("stream/blob")
+	streamValue, _ := GenerateRandomBytes(OVERFLOW_VALUE_SIZE)
+
+	checkStream := func(t *testing.T, inst *mari.Mari) {
+		readErr := inst.ReadTx(func(tx *mari.MariTx) error {
+			reader, getErr := tx.GetReader(streamKey)
+			if getErr != nil { return getErr }
+			defer reader.Close()
+
+			value, readAllErr := io.ReadAll(reader)
+			if readAllErr != nil { return readAllErr }
+			if ! bytes.Equal(value, streamValue) { t.Errorf("streamed value does not match: actual length(%d), expected length(%d)", len(value), len(streamValue)) }
+
+			kvPair, getErr := tx.Get(streamKey, nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || ! bytes.Equal(kvPair.Value, streamValue) { t.Errorf("value from get does not match streamed value") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := inst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying mari: %s", verifyErr.Error()) }
+	}
+
+	t.Run("Test Put Reader And Get Reader", func(t *testing.T) {
+		before := streamMariInst.Metrics().BytesWritten
+
+		putErr := streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			putTxErr := tx.Put([]byte("stream/small"), []byte("small"))
+			if putTxErr != nil { return putTxErr }
+
+			return tx.PutReader(streamKey, bytes.NewReader(streamValue), int64(len(streamValue)))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put reader: %s", putErr.Error()) }
+
+		written := streamMariInst.Metrics().BytesWritten - before
+		if written < OVERFLOW_VALUE_SIZE { t.Errorf("expected streamed value to be written: written(%d)", written) }
+
+		checkStream(t, streamMariInst)
+	})
+
+	t.Run("Test Put Reader Records Committed Value", func(t *testing.T) {
+		if len(streamCommitted) != 2 { t.Fatalf("committed changes length does not match: actual(%d), expected(2)", len(streamCommitted)) }
+		if ! bytes.Equal(streamCommitted[1].Key, streamKey) || ! bytes.Equal(streamCommitted[1].Value, streamValue) { t.Errorf("committed change does not match streamed value") }
+	})
+
+	t.Run("Test Put Reader Value Not Readable Before Commit", func(t *testing.T) {
+		pendingKey := []byte("stream/pending")
+
+		putErr := streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			putTxErr := tx.PutReader(pendingKey, bytes.NewReader(streamValue), int64(len(streamValue)))
+			if putTxErr != nil { return putTxErr }
+
+			_, getErr := tx.Get(pendingKey, nil)
+			return getErr
+		})
+
+		if putErr == nil { t.Error("expected error reading streamed value before commit") }
+	})
+
+	t.Run("Test Put Reader Short Reader", func(t *testing.T) {
+		shortKey := []byte("stream/short")
+
+		putErr := streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.PutReader(shortKey, bytes.NewReader(streamValue[:1024]), int64(len(streamValue)))
+		})
+
+		if ! errors.Is(putErr, io.ErrUnexpectedEOF) { t.Fatalf("expected unexpected eof for short reader: %v", putErr) }
+
+		readErr := streamMariInst.ReadTx(func(tx *mari.MariTx) error {
+			_, getErr := tx.GetReader(shortKey)
+			if ! errors.Is(getErr, mari.ErrKeyNotFound) { t.Errorf("expected short streamed value to not be committed: %v", getErr) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		putErr = streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put(shortKey, []byte("short"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put after failed stream: %s", putErr.Error()) }
+
+		checkStream(t, streamMariInst)
+	})
+
+	t.Run("Test Put Reader Overwritten In Transaction", func(t *testing.T) {
+		overwriteKey := []byte("stream/overwrite")
+
+		putErr := streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			putTxErr := tx.PutReader(overwriteKey, bytes.NewReader(streamValue[:1024]), int64(len(streamValue)))
+			if putTxErr != nil { return putTxErr }
+
+			return tx.Put(overwriteKey, []byte("overwrite"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+
+		readErr := streamMariInst.ReadTx(func(tx *mari.MariTx) error {
+			value, getErr := tx.GetOr(overwriteKey, nil)
+			if getErr != nil { return getErr }
+			if string(value) != "overwrite" { t.Errorf("overwritten value does not match: %s", value) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Blocked Reader Does Not Stall Writers", func(t *testing.T) {
+		blockedKey := []byte("stream/blocked")
+		started := make(chan struct{}, 1)
+		unblock := make(chan struct{})
+		reader := &blockingReader{ reader: bytes.NewReader(streamValue), started: started, unblock: unblock }
+
+		streamDone := make(chan error, 1)
+		go func() {
+			streamDone <- streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.PutReader(blockedKey, reader, int64(len(streamValue)))
+			})
+		}()
+
+		<-started
+
+		writerDone := make(chan error, 1)
+		go func() {
+			writerDone <- streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte("stream/unblocked"), []byte("value"))
+			})
+		}()
+
+		select {
+			case writeErr := <-writerDone:
+				if writeErr != nil { t.Errorf("error on mari put while a reader is blocked: %s", writeErr.Error()) }
+			case <-time.After(10 * time.Second):
+				t.Errorf("write did not commit while a streamed value was blocked on its reader")
+		}
+
+		close(unblock)
+
+		streamErr := <-streamDone
+		if streamErr != nil { t.Fatalf("error on mari put reader: %s", streamErr.Error()) }
+
+		readErr := streamMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get(blockedKey, nil)
+			if getErr != nil { return getErr }
+			if kvPair == nil || ! bytes.Equal(kvPair.Value, streamValue) { t.Errorf("value from blocked reader does not match") }
+
+			unblockedPair, getErr := tx.Get([]byte("stream/unblocked"), nil)
+			if getErr != nil { return getErr }
+			if unblockedPair == nil { t.Errorf("expected write made while the reader was blocked to be committed") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := streamMariInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying mari: %s", verifyErr.Error()) }
+	})
+
+	t.Run("Test Get Reader Outlives Transaction", func(t *testing.T) {
+		var reader io.ReadCloser
+		readErr := streamMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var getErr error
+			reader, getErr = tx.GetReader(streamKey)
+			return getErr
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		chunk := make([]byte, 1024)
+		var value []byte
+		for {
+			n, chunkErr := reader.Read(chunk)
+			value = append(value, chunk[:n]...)
+			if chunkErr == io.EOF { break }
+			if chunkErr != nil { t.Fatalf("error reading value: %s", chunkErr.Error()) }
+		}
+
+		if ! bytes.Equal(value, streamValue) { t.Errorf("value read after the transaction does not match: actual length(%d), expected length(%d)", len(value), len(streamValue)) }
+
+		closeErr := reader.Close()
+		if closeErr != nil { t.Fatalf("error closing reader: %s", closeErr.Error()) }
+
+		closeErr = reader.Close()
+		if closeErr != nil { t.Errorf("expected closing a reader twice to have no effect: %s", closeErr.Error()) }
+
+		_, readClosedErr := reader.Read(chunk)
+		if readClosedErr == nil { t.Errorf("expected error reading from a closed reader") }
+	})
+
+	t.Run("Test Open Get Reader Does Not Block Resize", func(t *testing.T) {
+		var reader io.ReadCloser
+		readErr := streamMariInst.ReadTx(func(tx *mari.MariTx) error {
+			var getErr error
+			reader, getErr = tx.GetReader(streamKey)
+			return getErr
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+		defer reader.Close()
+
+		prevSize, sizeErr := streamMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+
+		growValue := make([]byte, prevSize)
+		putDone := make(chan error, 1)
+		go func() {
+			putDone <- streamMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.PutReader([]byte("stream/grow"), bytes.NewReader(growValue), int64(len(growValue)))
+			})
+		}()
+
+		select {
+			case putErr := <-putDone:
+				if putErr != nil { t.Fatalf("error on mari put reader: %s", putErr.Error()) }
+			case <-time.After(10 * time.Second):
+				t.Fatalf("write that grows the file stalled while a value reader was open")
+		}
+
+		size, sizeErr := streamMariInst.FileSize()
+		if sizeErr != nil { t.Fatalf("error getting file size: %s", sizeErr.Error()) }
+		if size <= prevSize { t.Errorf("expected the file to grow: actual(%d), previous(%d)", size, prevSize) }
+
+		value, readAllErr := io.ReadAll(reader)
+		if readAllErr != nil { t.Fatalf("error reading value after resize: %s", readAllErr.Error()) }
+		if ! bytes.Equal(value, streamValue) { t.Errorf("value read across a resize does not match: actual length(%d), expected length(%d)", len(value), len(streamValue)) }
+	})
+
+	t.Run("Test Streamed Value Survives Reopen", func(t *testing.T) {
+		closeErr := streamMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		var openErr error
+		streamMariInst, openErr = mari.Open(streamOpts)
+		if openErr != nil { t.Fatalf("error reopening mari: %s", openErr.Error()) }
+
+		checkStream(t, streamMariInst)
+	})
+
+	t.Run("Test Streamed Value Survives Clone", func(t *testing.T) {
+		clonePath := filepath.Join(os.TempDir(), "teststreamclone")
+		os.Remove(clonePath)
+
+		cloneInst, cloneErr := streamMariInst.Clone(clonePath)
+		if cloneErr != nil { t.Fatalf("error cloning mari: %s", cloneErr.Error()) }
+		defer cloneInst.Remove()
+
+		checkStream(t, cloneInst)
+	})
+}
+
+// blockingReader signals started and blocks its reads until unblock is closed, like a reader waiting on a slow network
+type blockingReader struct {
+	reader io.Reader
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (reader *blockingReader) Read(p []byte) (int, error) {
+	select {
+		case reader.started <- struct{}{}:
+		default:
+	}
+
+	<-reader.unblock
+	return reader.reader.Read(p)
+}
@@ -139,6 +139,61 @@ func TestMariTransactionOperations(t *testing.T) {
 		txRetrieveWG.Wait()
 	})
 
+	t.Run("Test Read Transactions Are Consistent Snapshots", func(t *testing.T) {
+		snapshotInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening snapshot instance: %s", openErr.Error()) }
+		defer snapshotInst.Close()
+
+		writeBoth := func(value []byte) error {
+			return snapshotInst.UpdateTx(func(tx *mari.MariTx) error {
+				putErr := tx.Put([]byte("first"), value)
+				if putErr != nil { return putErr }
+
+				return tx.Put([]byte("second"), value)
+			})
+		}
+
+		initErr := writeBoth([]byte{ 0 })
+		if initErr != nil { t.Fatalf("error on initial write: %s", initErr.Error()) }
+
+		var stopped uint32
+		var writerWG sync.WaitGroup
+
+		writerWG.Add(1)
+		go func() {
+			defer writerWG.Done()
+			for counter := byte(1); atomic.LoadUint32(&stopped) == 0; counter++ {
+				writeErr := writeBoth([]byte{ counter })
+				if writeErr != nil { t.Errorf("error on concurrent write: %s", writeErr.Error()) }
+			}
+		}()
+
+		readBoth := func(tx *mari.MariTx) error {
+			first, getErr := tx.Get([]byte("first"), nil)
+			if getErr != nil { return getErr }
+
+			second, getErr := tx.Get([]byte("second"), nil)
+			if getErr != nil { return getErr }
+
+			if ! bytes.Equal(first.Value, second.Value) || first.Version != second.Version {
+				t.Errorf("reads within one transaction do not match: first(%v), second(%v)", first, second)
+			}
+
+			return nil
+		}
+
+		for range make([]int, 1000) {
+			readErr := snapshotInst.ReadTx(readBoth)
+			if readErr != nil { t.Fatalf("error on read tx: %s", readErr.Error()) }
+
+			viewErr := snapshotInst.ViewTx(readBoth)
+			if viewErr != nil { t.Fatalf("error on view tx: %s", viewErr.Error()) }
+		}
+
+		atomic.StoreUint32(&stopped, 1)
+		writerWG.Wait()
+	})
+
 	t.Run("Test Batched Read Operations", func(t *testing.T) {
 		for i := range make([]int, NUM_READER_GO_ROUTINES) {
 			kvPairsForReader := txKeyValPairs[i * READ_CHUNK_SIZE:(i + 1) * READ_CHUNK_SIZE]
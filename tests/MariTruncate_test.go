@@ -0,0 +1,66 @@
+package maritests
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariTruncate(t *testing.T) {
+	t.Run("Test Mari Truncate", func(t *testing.T) {
+		truncatePath := filepath.Join(os.TempDir(), "testtruncate")
+		os.Remove(truncatePath)
+		os.Remove(truncatePath + mari.VersionIndexFileName)
+
+		truncateOpts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testtruncate", NodePoolSize: &smallNodePoolSize }
+		truncateInst, openErr := mari.Open(truncateOpts)
+		if openErr != nil { t.Fatalf("error opening truncate instance: %s", openErr.Error()) }
+
+		for idx := range make([]int, 10) {
+			updateErr := truncateInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("truncate%d", idx)), []byte("value"))
+			})
+
+			if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+		}
+
+		snapshot, snapshotErr := truncateInst.Snapshot()
+		if snapshotErr != nil { t.Fatalf("error taking snapshot: %s", snapshotErr.Error()) }
+		if truncateInst.Truncate() == nil { t.Errorf("expected error truncating with an open snapshot") }
+		snapshot.Close()
+
+		truncateErr := truncateInst.Truncate()
+		if truncateErr != nil { t.Fatalf("error on mari truncate: %s", truncateErr.Error()) }
+
+		viewErr := truncateInst.ViewTxAtVersion(5, func(tx *mari.MariTx) error { return nil })
+		if viewErr == nil { t.Errorf("expected previous versions to be unreachable after truncate") }
+
+		updateErr := truncateInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("after"), []byte("truncate"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update after truncate: %s", updateErr.Error()) }
+
+		closeErr := truncateInst.Close()
+		if closeErr != nil { t.Fatalf("error closing truncate instance: %s", closeErr.Error()) }
+
+		truncateInst, openErr = mari.Open(truncateOpts)
+		if openErr != nil { t.Fatalf("error reopening truncate instance: %s", openErr.Error()) }
+		defer truncateInst.Remove()
+
+		readErr := truncateInst.ReadTx(func(tx *mari.MariTx) error {
+			pairs, rangeErr := tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+			if rangeErr != nil { return rangeErr }
+			if len(pairs) != 1 || string(pairs[0].Key) != "after" || pairs[0].Version != 1 { t.Errorf("pairs after truncate do not match: %v", pairs) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		verifyErr := truncateInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying after truncate: %s", verifyErr.Error()) }
+	})
+}
@@ -0,0 +1,60 @@
+package maritests
+
+import "fmt"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+type TypedUser struct {
+	Name string `json:"name"`
+	Age int `json:"age"`
+}
+
+
+func TestMariTypedStore(t *testing.T) {
+	typedMariInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening typed instance: %s", openErr.Error()) }
+	defer typedMariInst.Close()
+
+	users := mari.NewTypedStore(typedMariInst, mari.Int64KeyCodec(), mari.JSONValueCodec[TypedUser]())
+
+	t.Run("Test Typed Put And Get", func(t *testing.T) {
+		for id := int64(-TYPED_INPUT_SIZE / 2); id < TYPED_INPUT_SIZE / 2; id++ {
+			putErr := users.Put(id, TypedUser{ Name: fmt.Sprintf("user%d", id), Age: int(id) })
+			if putErr != nil { t.Fatalf("error on typed put: %s", putErr.Error()) }
+		}
+
+		user, ok, getErr := users.Get(-3)
+		if getErr != nil { t.Fatalf("error on typed get: %s", getErr.Error()) }
+		if ! ok || user.Name != "user-3" || user.Age != -3 { t.Errorf("typed value does not match: actual(%v), found(%t)", user, ok) }
+
+		_, ok, getErr = users.Get(TYPED_INPUT_SIZE)
+		if getErr != nil { t.Fatalf("error on typed get: %s", getErr.Error()) }
+		if ok { t.Errorf("expected missing key to not be found") }
+	})
+
+	t.Run("Test Typed Range And Iterate", func(t *testing.T) {
+		entries, rangeErr := users.Range(-5, 5)
+		if rangeErr != nil { t.Fatalf("error on typed range: %s", rangeErr.Error()) }
+		if len(entries) != 11 { t.Fatalf("range length does not match: actual(%d), expected(11)", len(entries)) }
+
+		for idx, entry := range entries {
+			expected := int64(idx - 5)
+			if entry.Key != expected || entry.Value.Age != int(expected) { t.Errorf("range entry does not match: actual(%d), expected(%d)", entry.Key, expected) }
+		}
+
+		entries, iterErr := users.Iterate(0, 3)
+		if iterErr != nil { t.Fatalf("error on typed iterate: %s", iterErr.Error()) }
+		if len(entries) != 3 || entries[0].Key != 0 || entries[2].Key != 2 { t.Errorf("iterate entries do not match: %v", entries) }
+	})
+
+	t.Run("Test Typed Delete", func(t *testing.T) {
+		deleteErr := users.Delete(0)
+		if deleteErr != nil { t.Fatalf("error on typed delete: %s", deleteErr.Error()) }
+
+		_, ok, getErr := users.Get(0)
+		if getErr != nil { t.Fatalf("error on typed get: %s", getErr.Error()) }
+		if ok { t.Errorf("expected deleted key to not be found") }
+	})
+}
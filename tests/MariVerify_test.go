@@ -0,0 +1,193 @@
+package maritests
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var verifyMariInst *mari.Mari
+var verifyKeyValPairs []KeyVal
+var verifyInitMariErr error
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testverify"))
+	os.Remove(filepath.Join(os.TempDir(), "testverify.vidx"))
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testverify", NodePoolSize: &smallNodePoolSize }
+
+	verifyMariInst, verifyInitMariErr = mari.Open(opts)
+	if verifyInitMariErr != nil {
+		verifyMariInst.Remove()
+		panic(verifyInitMariErr.Error())
+	}
+
+	fmt.Println("verify test mari initialized")
+}
+
+
+func TestMariVerify(t *testing.T) {
+	defer verifyMariInst.Remove()
+
+	verifyKeyValPairs = GenerateKeyValPairs(InputSize(VERIFY_INPUT_SIZE))
+	inputSize := len(verifyKeyValPairs)
+
+	t.Run("Test Verify Intact Instance", func(t *testing.T) {
+		chunks, chunkErr := Chunk(verifyKeyValPairs, TRANSACTION_CHUNK_SIZE)
+		if chunkErr != nil { t.Fatalf("error chunking input: %s", chunkErr.Error()) }
+
+		for _, chunk := range chunks {
+			putErr := verifyMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				for _, val := range chunk {
+					putTxErr := tx.Put(val.Key, val.Value)
+					if putTxErr != nil { return putTxErr }
+				}
+
+				return nil
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		for _, val := range verifyKeyValPairs[:inputSize / 2] {
+			delErr := verifyMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Delete(val.Key)
+			})
+
+			if delErr != nil { t.Fatalf("error on mari delete: %s", delErr.Error()) }
+		}
+
+		verifyErr := verifyMariInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("expected intact instance to verify: %s", verifyErr.Error()) }
+	})
+
+	t.Run("Test Verify Corrupted Bitmap", func(t *testing.T) {
+		closeErr := verifyMariInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		filePath := filepath.Join(os.TempDir(), "testverify")
+		data, readErr := os.ReadFile(filePath)
+		if readErr != nil { t.Fatalf("error reading mari file: %s", readErr.Error()) }
+
+		rootOffset := binary.LittleEndian.Uint64(data[8:16])
+		bitmapIdx := rootOffset + 24
+
+		var setIndex int
+		for setIndex = 0; setIndex < 256; setIndex++ {
+			subBitmap := binary.LittleEndian.Uint32(data[bitmapIdx + uint64(setIndex / 32) * 4:])
+			if subBitmap & (1 << (setIndex % 32)) != 0 { break }
+		}
+
+		if setIndex == 256 { t.Fatalf("root has no set bit to corrupt") }
+
+		subBitmapIdx := bitmapIdx + uint64(setIndex / 32) * 4
+		subBitmap := binary.LittleEndian.Uint32(data[subBitmapIdx:])
+		binary.LittleEndian.PutUint32(data[subBitmapIdx:], subBitmap &^ (1 << (setIndex % 32)))
+
+		writeErr := os.WriteFile(filePath, data, 0600)
+		if writeErr != nil { t.Fatalf("error writing corrupted file: %s", writeErr.Error()) }
+
+		var openErr error
+		verifyMariInst, openErr = mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: "testverify", NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error reopening mari: %s", openErr.Error()) }
+
+		verifyErr := verifyMariInst.VerifyIntegrity()
+		if verifyErr == nil { t.Fatalf("expected corrupted bitmap to fail verification") }
+		if ! strings.Contains(verifyErr.Error(), fmt.Sprintf("offset %d", rootOffset)) { t.Errorf("expected error to contain the corrupted offset %d: %s", rootOffset, verifyErr.Error()) }
+		if ! errors.Is(verifyErr, mari.ErrCorruptNode) { t.Errorf("expected error to wrap the corrupt node error: %s", verifyErr.Error()) }
+	})
+}
+
+func TestMariRecoverCorruptRoot(t *testing.T) {
+	recoverFileName := "testrecoverroot"
+	recoverPath := filepath.Join(os.TempDir(), recoverFileName)
+	os.Remove(recoverPath)
+	os.Remove(recoverPath + mari.VersionIndexFileName)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: recoverFileName, NodePoolSize: &smallNodePoolSize }
+
+	recoverMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+	for idx := range make([]int, 10) {
+		putErr := recoverMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+	}
+
+	closeErr := recoverMariInst.Close()
+	if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+	data, readErr := os.ReadFile(recoverPath)
+	if readErr != nil { t.Fatalf("error reading mari file: %s", readErr.Error()) }
+
+	rootOffset := binary.LittleEndian.Uint64(data[8:16])
+	binary.LittleEndian.PutUint64(data[rootOffset + 16:], ^uint64(0))
+
+	writeErr := os.WriteFile(recoverPath, data, 0600)
+	if writeErr != nil { t.Fatalf("error writing corrupted file: %s", writeErr.Error()) }
+
+	t.Run("Test Corrupt Root Fails Without Recovery", func(t *testing.T) {
+		corruptInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+		defer corruptInst.Close()
+
+		readErr := corruptInst.ReadTx(func(tx *mari.MariTx) error {
+			_, getErr := tx.Get([]byte("key0"), nil)
+			return getErr
+		})
+
+		if ! errors.Is(readErr, mari.ErrCorruptNode) { t.Errorf("expected corrupt root to fail reads: %v", readErr) }
+	})
+
+	t.Run("Test Recover Corrupt Root", func(t *testing.T) {
+		recoverOpts := opts
+		recoverOpts.RecoverCorruptRoot = true
+
+		recoveredInst, openErr := mari.Open(recoverOpts)
+		if openErr != nil { t.Fatalf("error recovering mari: %s", openErr.Error()) }
+		defer recoveredInst.Remove()
+
+		select {
+			case reportErr := <-recoveredInst.Errors():
+				if ! errors.Is(reportErr, mari.ErrCorruptNode) || ! strings.Contains(reportErr.Error(), "discarded 1 versions") { t.Errorf("unexpected recovery report: %v", reportErr) }
+			default:
+				t.Error("expected recovery to be reported on the errors channel")
+		}
+
+		currVersion, versionErr := recoveredInst.CurrentVersion()
+		if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+		if currVersion != 9 { t.Errorf("recovered version does not match: actual(%d), expected(9)", currVersion) }
+
+		readErr := recoveredInst.ReadTx(func(tx *mari.MariTx) error {
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != 9 { t.Errorf("count does not match recovered version: actual(%d), expected(9)", count) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		putErr := recoveredInst.UpdateTx(func(tx *mari.MariTx) error {
+			return tx.Put([]byte("key9"), []byte("value9"))
+		})
+
+		if putErr != nil { t.Fatalf("error on mari put after recovery: %s", putErr.Error()) }
+
+		verifyErr := recoveredInst.VerifyIntegrity()
+		if verifyErr != nil { t.Errorf("error verifying recovered mari: %s", verifyErr.Error()) }
+
+		versions, listErr := recoveredInst.ListVersions()
+		if listErr != nil { t.Fatalf("error listing versions: %s", listErr.Error()) }
+		if len(versions) != 11 { t.Errorf("total versions does not match: actual(%d), expected(11)", len(versions)) }
+	})
+}
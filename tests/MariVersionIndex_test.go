@@ -0,0 +1,224 @@
+package maritests
+
+import "errors"
+import "fmt"
+import "os"
+import "path/filepath"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+var vIdxMariInst *mari.Mari
+var vIdxInitMariErr error
+var vIdxPath = filepath.Join(os.TempDir(), "testversionindex" + mari.VersionIndexFileName)
+
+
+func init() {
+	os.Remove(filepath.Join(os.TempDir(), "testversionindex"))
+	os.Remove(filepath.Join(os.TempDir(), "testversionindextemp"))
+	os.Remove(vIdxPath)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: "testversionindex", NodePoolSize: &smallNodePoolSize }
+
+	vIdxMariInst, vIdxInitMariErr = mari.Open(opts)
+	if vIdxInitMariErr != nil {
+		vIdxMariInst.Remove()
+		panic(vIdxInitMariErr.Error())
+	}
+
+	fmt.Println("version index test mari initialized")
+}
+
+
+func TestMariVersionIndex(t *testing.T) {
+	defer vIdxMariInst.Remove()
+
+	t.Run("Test Version Index Resizes", func(t *testing.T) {
+		for idx := range make([]int, VERSION_INDEX_VERSIONS) {
+			putErr := vIdxMariInst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("key%d", idx)), []byte(fmt.Sprintf("value%d", idx)))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+
+		currVersion, versionErr := vIdxMariInst.CurrentVersion()
+		if versionErr != nil { t.Fatalf("error getting current version: %s", versionErr.Error()) }
+		if currVersion != uint64(VERSION_INDEX_VERSIONS) { t.Errorf("current version does not match: actual(%d), expected(%d)", currVersion, VERSION_INDEX_VERSIONS) }
+
+		stat, statErr := os.Stat(vIdxPath)
+		if statErr != nil { t.Fatalf("error getting version index size: %s", statErr.Error()) }
+
+		t.Logf("initial version index size: %d, version index size: %d", mari.InitVersionIndexSize, stat.Size())
+		if stat.Size() <= int64(mari.InitVersionIndexSize) { t.Errorf("version index was not resized: size(%d)", stat.Size()) }
+	})
+
+	t.Run("Test Reads After Version Index Resize", func(t *testing.T) {
+		readErr := vIdxMariInst.ReadTx(func(tx *mari.MariTx) error {
+			for idx := range make([]int, VERSION_INDEX_VERSIONS) {
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", idx)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil || string(kvPair.Value) != fmt.Sprintf("value%d", idx) { return fmt.Errorf("unexpected value for key%d: %v", idx, kvPair) }
+			}
+
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari get: %s", readErr.Error()) }
+	})
+	t.Run("Test View Tx At Version", func(t *testing.T) {
+		for _, version := range []int{ 1, VERSION_INDEX_VERSIONS / 2, VERSION_INDEX_VERSIONS } {
+			viewErr := vIdxMariInst.ViewTxAtVersion(uint64(version), func(tx *mari.MariTx) error {
+				totalCount, countTxErr := tx.Count()
+				if countTxErr != nil { return countTxErr }
+				if totalCount != version { t.Errorf("count at version does not match: actual(%d), expected(%d)", totalCount, version) }
+
+				kvPair, getTxErr := tx.Get([]byte(fmt.Sprintf("key%d", version - 1)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair == nil { t.Errorf("key written at version %d missing", version) }
+
+				kvPair, getTxErr = tx.Get([]byte(fmt.Sprintf("key%d", version)), nil)
+				if getTxErr != nil { return getTxErr }
+				if kvPair != nil { t.Errorf("key written after version %d is visible", version) }
+
+				putTxErr := tx.Put([]byte("key"), []byte("value"))
+				if putTxErr == nil { t.Errorf("expected put to fail in a historical transaction") }
+
+				return nil
+			})
+
+			if viewErr != nil { t.Errorf("error on view tx at version %d: %s", version, viewErr.Error()) }
+		}
+
+		viewErr := vIdxMariInst.ViewTxAtVersion(uint64(VERSION_INDEX_VERSIONS + 1), func(tx *mari.MariTx) error { return nil })
+		if viewErr == nil { t.Errorf("expected error viewing a version that has not been written") }
+	})
+	t.Run("Test List Versions", func(t *testing.T) {
+		versions, listErr := vIdxMariInst.ListVersions()
+		if listErr != nil { t.Fatalf("error listing versions: %s", listErr.Error()) }
+
+		if len(versions) != VERSION_INDEX_VERSIONS + 1 { t.Fatalf("total versions does not match: actual(%d), expected(%d)", len(versions), VERSION_INDEX_VERSIONS + 1) }
+		for idx, version := range versions {
+			if version != uint64(idx) { t.Fatalf("version does not match: actual(%d), expected(%d)", version, idx) }
+		}
+	})
+}
+
+func TestMariVersionIndexMismatch(t *testing.T) {
+	mismatchFileName := "testversionindexmismatch"
+	mismatchPath := filepath.Join(os.TempDir(), mismatchFileName)
+	backupPath := mismatchPath + "backup"
+
+	for _, path := range []string{ mismatchPath, mismatchPath + mari.VersionIndexFileName, backupPath } { os.Remove(path) }
+	defer os.Remove(backupPath)
+
+	opts := mari.MariOpts{ Filepath: os.TempDir(), FileName: mismatchFileName, NodePoolSize: &smallNodePoolSize }
+
+	putKeys := func(t *testing.T, inst *mari.Mari, prefix string) {
+		for idx := range make([]int, 10) {
+			putErr := inst.UpdateTx(func(tx *mari.MariTx) error {
+				return tx.Put([]byte(fmt.Sprintf("%s%d", prefix, idx)), []byte(fmt.Sprintf("value%d", idx)))
+			})
+
+			if putErr != nil { t.Fatalf("error on mari put: %s", putErr.Error()) }
+		}
+	}
+
+	copyFile := func(t *testing.T, srcPath, destPath string) {
+		data, readErr := os.ReadFile(srcPath)
+		if readErr != nil { t.Fatalf("error reading file: %s", readErr.Error()) }
+
+		writeErr := os.WriteFile(destPath, data, 0600)
+		if writeErr != nil { t.Fatalf("error writing file: %s", writeErr.Error()) }
+	}
+
+	mismatchMariInst, openErr := mari.Open(opts)
+	if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+	putKeys(t, mismatchMariInst, "backup")
+
+	closeErr := mismatchMariInst.Close()
+	if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+	copyFile(t, mismatchPath, backupPath)
+
+	t.Run("Test Older File With Newer Version Index", func(t *testing.T) {
+		truncatedInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		truncateErr := truncatedInst.Truncate()
+		if truncateErr != nil { t.Fatalf("error truncating mari: %s", truncateErr.Error()) }
+
+		putKeys(t, truncatedInst, "truncated")
+
+		closeErr := truncatedInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		copyFile(t, backupPath, mismatchPath)
+
+		_, openErr = mari.Open(opts)
+		if ! errors.Is(openErr, mari.ErrVersionIndexMismatch) { t.Fatalf("expected version index mismatch: %v", openErr) }
+	})
+
+	t.Run("Test Version Index Of Another File", func(t *testing.T) {
+		otherFileName := mismatchFileName + "other"
+		otherPath := filepath.Join(os.TempDir(), otherFileName)
+		os.Remove(otherPath)
+		os.Remove(otherPath + mari.VersionIndexFileName)
+
+		otherInst, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: otherFileName, NodePoolSize: &smallNodePoolSize })
+		if openErr != nil { t.Fatalf("error opening mari: %s", openErr.Error()) }
+
+		putKeys(t, otherInst, "other")
+
+		closeErr := otherInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		copyFile(t, otherPath + mari.VersionIndexFileName, mismatchPath + mari.VersionIndexFileName)
+		os.Remove(otherPath)
+		os.Remove(otherPath + mari.VersionIndexFileName)
+
+		_, openErr = mari.Open(opts)
+		if ! errors.Is(openErr, mari.ErrVersionIndexMismatch) { t.Fatalf("expected version index mismatch: %v", openErr) }
+	})
+
+	t.Run("Test Read Only Cannot Rebuild Version Index", func(t *testing.T) {
+		_, openErr := mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: mismatchFileName, ReadOnly: true, RebuildVersionIndex: true })
+		if openErr == nil { t.Fatal("expected error rebuilding the version index of a read only mari") }
+
+		_, openErr = mari.Open(mari.MariOpts{ Filepath: os.TempDir(), FileName: mismatchFileName, ReadOnly: true })
+		if ! errors.Is(openErr, mari.ErrVersionIndexMismatch) { t.Fatalf("expected version index mismatch on read only open: %v", openErr) }
+	})
+
+	t.Run("Test Rebuild Version Index", func(t *testing.T) {
+		rebuildOpts := opts
+		rebuildOpts.RebuildVersionIndex = true
+
+		rebuiltInst, openErr := mari.Open(rebuildOpts)
+		if openErr != nil { t.Fatalf("error rebuilding version index: %s", openErr.Error()) }
+
+		readErr := rebuiltInst.ReadTx(func(tx *mari.MariTx) error {
+			count, countErr := tx.Count()
+			if countErr != nil { return countErr }
+			if count != 10 { t.Errorf("count does not match restored file: actual(%d), expected(10)", count) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		versions, listErr := rebuiltInst.ListVersions()
+		if listErr != nil { t.Fatalf("error listing versions: %s", listErr.Error()) }
+		if len(versions) != 1 || versions[0] != 10 { t.Errorf("expected only the current version after rebuild: %v", versions) }
+
+		closeErr := rebuiltInst.Close()
+		if closeErr != nil { t.Fatalf("error closing mari: %s", closeErr.Error()) }
+
+		reopenedInst, openErr := mari.Open(opts)
+		if openErr != nil { t.Fatalf("error reopening mari after rebuild: %s", openErr.Error()) }
+
+		removeErr := reopenedInst.Remove()
+		if removeErr != nil { t.Errorf("error removing mari: %s", removeErr.Error()) }
+	})
+}
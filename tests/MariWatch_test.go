@@ -0,0 +1,93 @@
+package maritests
+
+import "errors"
+import "fmt"
+import "testing"
+
+import "github.com/sirgallo/mari"
+
+
+func TestMariWatch(t *testing.T) {
+	watchMariInst, openErr := mari.Open(mari.MariOpts{ InMemory: true, NodePoolSize: &smallNodePoolSize })
+	if openErr != nil { t.Fatalf("error opening watch instance: %s", openErr.Error()) }
+	defer watchMariInst.Close()
+
+	t.Run("Test Watch Prefix Puts And Deletes", func(t *testing.T) {
+		events, cancel := watchMariInst.Watch([]byte("user:"))
+		defer cancel()
+
+		failedErr := watchMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("user:discarded"), []byte("discarded"))
+			if putErr != nil { return putErr }
+
+			return errors.New("abort")
+		})
+
+		if failedErr == nil { t.Fatalf("expected aborted transaction to return an error") }
+
+		updateErr := watchMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			putErr := tx.Put([]byte("user:1"), []byte("one"))
+			if putErr != nil { return putErr }
+
+			putErr = tx.Put([]byte("order:1"), []byte("ignored"))
+			if putErr != nil { return putErr }
+
+			return tx.Delete([]byte("user:1"))
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		var version uint64
+		readErr := watchMariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPair, getErr := tx.Get([]byte("order:1"), nil)
+			if getErr != nil { return getErr }
+
+			version = kvPair.Version
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari read: %s", readErr.Error()) }
+
+		put := <-events
+		if string(put.Key) != "user:1" || string(put.Value) != "one" || put.Version != version { t.Errorf("put event does not match: %v", put) }
+
+		deleted := <-events
+		if string(deleted.Key) != "user:1" || deleted.Value != nil || deleted.Version != version { t.Errorf("delete event does not match: %v", deleted) }
+
+		select {
+			case event := <-events:
+				t.Errorf("unexpected event: %v", event)
+			default:
+		}
+	})
+
+	t.Run("Test Watch Cancel", func(t *testing.T) {
+		events, cancel := watchMariInst.Watch(nil)
+		cancel()
+		cancel()
+
+		_, ok := <-events
+		if ok { t.Errorf("expected channel to be closed after cancel") }
+	})
+
+	t.Run("Test Watch Overflow", func(t *testing.T) {
+		events, cancel := watchMariInst.Watch([]byte("overflow:"))
+		defer cancel()
+
+		updateErr := watchMariInst.UpdateTx(func(tx *mari.MariTx) error {
+			for idx := range make([]int, mari.WatchBufferSize + 1) {
+				putErr := tx.Put([]byte(fmt.Sprintf("overflow:%d", idx)), []byte("value"))
+				if putErr != nil { return putErr }
+			}
+
+			return nil
+		})
+
+		if updateErr != nil { t.Fatalf("error on mari update: %s", updateErr.Error()) }
+
+		var received int
+		for range events { received++ }
+
+		if received != mari.WatchBufferSize { t.Errorf("received events do not match: actual(%d), expected(%d)", received, mari.WatchBufferSize) }
+	})
+}
@@ -1,5 +1,6 @@
 package maritests
 
+import "bytes"
 import "os"
 import "fmt"
 import "path/filepath"
@@ -97,6 +98,24 @@ func TestMari(t *testing.T) {
 		mariInst.PrintChildren()
 	})
 
+	t.Run("Test Mari Count", func(t *testing.T) {
+		expectedCount := 16
+
+		totalCount, countErr := mariInst.Count()
+		if countErr != nil { t.Errorf("error counting keys: %s", countErr.Error()) }
+		if totalCount != expectedCount { t.Errorf("count does not match expected: actual(%d), expected(%d)", totalCount, expectedCount) }
+
+		var txCount int
+		countErr = mariInst.ReadTx(func(tx *mari.MariTx) error {
+			var countTxErr error
+			txCount, countTxErr = tx.Count()
+			return countTxErr
+		})
+
+		if countErr != nil { t.Errorf("error counting keys in tx: %s", countErr.Error()) }
+		if txCount != expectedCount { t.Errorf("tx count does not match expected: actual(%d), expected(%d)", txCount, expectedCount) }
+	})
+
 	t.Run("Test Mari Get", func(t *testing.T) {
 		getErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
 			expVal1 := "world"
@@ -145,6 +164,131 @@ func TestMari(t *testing.T) {
 		if getErr != nil { t.Errorf("error getting val: %s", getErr.Error()) }
 	})
 
+	t.Run("Test Mari Has", func(t *testing.T) {
+		hasErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, key := range []string{ "hello", "asdf", "asdfasdf", "fasdfasdfasdfasdf", "Woah" } {
+				exists, hasTxErr := tx.Has([]byte(key))
+				if hasTxErr != nil { return hasTxErr }
+				if ! exists { t.Errorf("expected key to exist: %s", key) }
+			}
+
+			for _, key := range []string{ "missing", "asdfa", "hel", "helloo" } {
+				exists, hasTxErr := tx.Has([]byte(key))
+				if hasTxErr != nil { return hasTxErr }
+				if exists { t.Errorf("expected key to not exist: %s", key) }
+			}
+
+			return nil
+		})
+
+		if hasErr != nil { t.Errorf("error checking keys: %s", hasErr.Error()) }
+	})
+
+	t.Run("Test Mari Min And Max Key", func(t *testing.T) {
+		var minKvPair, maxKvPair *mari.KeyValuePair
+
+		readErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			var txErr error
+			minKvPair, txErr = tx.MinKey()
+			if txErr != nil { return txErr }
+
+			maxKvPair, txErr = tx.MaxKey()
+			if txErr != nil { return txErr }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari min/max key: %s", readErr.Error()) }
+
+		if minKvPair == nil || string(minKvPair.Key) != "Woah" { t.Errorf("min key does not match: actual(%v), expected(Woah)", minKvPair) }
+		if maxKvPair == nil || string(maxKvPair.Key) != "yup" { t.Errorf("max key does not match: actual(%v), expected(yup)", maxKvPair) }
+	})
+
+	t.Run("Test Mari First And Last", func(t *testing.T) {
+		readErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			allPairs, rangeErr := tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+			if rangeErr != nil { return rangeErr }
+			if len(allPairs) < 3 { t.Fatalf("expected at least 3 keys: %d", len(allPairs)) }
+
+			firstPairs, firstErr := tx.First(3)
+			if firstErr != nil { return firstErr }
+			if len(firstPairs) != 3 { t.Fatalf("first length does not match: actual(%d), expected(3)", len(firstPairs)) }
+
+			lastPairs, lastErr := tx.Last(3)
+			if lastErr != nil { return lastErr }
+			if len(lastPairs) != 3 { t.Fatalf("last length does not match: actual(%d), expected(3)", len(lastPairs)) }
+
+			for idx := range make([]int, 3) {
+				if ! bytes.Equal(firstPairs[idx].Key, allPairs[idx].Key) { t.Errorf("first key does not match: actual(%s), expected(%s)", firstPairs[idx].Key, allPairs[idx].Key) }
+
+				expectedLast := allPairs[len(allPairs) - 1 - idx]
+				if ! bytes.Equal(lastPairs[idx].Key, expectedLast.Key) { t.Errorf("last key does not match: actual(%s), expected(%s)", lastPairs[idx].Key, expectedLast.Key) }
+			}
+
+			allFirst, firstErr := tx.First(len(allPairs) + 5)
+			if firstErr != nil { return firstErr }
+			if len(allFirst) != len(allPairs) { t.Errorf("expected first to return every key: actual(%d), expected(%d)", len(allFirst), len(allPairs)) }
+
+			noPairs, firstErr := tx.First(0)
+			if firstErr != nil { return firstErr }
+			if len(noPairs) != 0 { t.Errorf("expected no pairs for n of 0: %d", len(noPairs)) }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari first/last: %s", readErr.Error()) }
+	})
+
+	t.Run("Test Mari Page", func(t *testing.T) {
+		readErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			allPairs, rangeErr := tx.Range([]byte{ 0x00 }, []byte{ 0xFF }, nil)
+			if rangeErr != nil { return rangeErr }
+
+			upperTransform := mari.MariOpTransform(func(kvPair *mari.KeyValuePair) *mari.KeyValuePair {
+				return &mari.KeyValuePair{ Version: kvPair.Version, Key: bytes.ToUpper(kvPair.Key), Value: kvPair.Value }
+			})
+
+			for _, opts := range []*mari.MariRangeOpts{ nil, { Reverse: true }, { Transform: &upperTransform } } {
+				var paged []*mari.KeyValuePair
+				var afterKey []byte
+				var totalPages int
+
+				for {
+					kvPairs, nextAfter, pageErr := tx.Page(afterKey, 2, opts)
+					if pageErr != nil { return pageErr }
+					if len(kvPairs) > 2 { t.Fatalf("page exceeds limit: %d", len(kvPairs)) }
+
+					paged = append(paged, kvPairs...)
+					totalPages++
+
+					if nextAfter == nil { break }
+					if totalPages > len(allPairs) { t.Fatalf("pagination did not terminate") }
+
+					afterKey = nextAfter
+				}
+
+				if len(paged) != len(allPairs) { t.Fatalf("paged length does not match: actual(%d), expected(%d)", len(paged), len(allPairs)) }
+				if totalPages != (len(allPairs) + 1) / 2 { t.Errorf("total pages does not match: actual(%d), expected(%d)", totalPages, (len(allPairs) + 1) / 2) }
+
+				for idx, kvPair := range paged {
+					expected := allPairs[idx]
+					if opts != nil && opts.Reverse { expected = allPairs[len(allPairs) - 1 - idx] }
+
+					expectedKey := expected.Key
+					if opts != nil && opts.Transform != nil { expectedKey = bytes.ToUpper(expectedKey) }
+					if ! bytes.Equal(kvPair.Key, expectedKey) { t.Errorf("paged key does not match: actual(%s), expected(%s)", kvPair.Key, expectedKey) }
+				}
+			}
+
+			_, _, limitErr := tx.Page(nil, 0, nil)
+			if limitErr == nil { t.Errorf("expected error for a page limit of 0") }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on mari page: %s", readErr.Error()) }
+	})
+
 	t.Run("Test Iterate Operation", func(t *testing.T) {
 		var kvPairs []*mari.KeyValuePair
 
@@ -175,6 +319,47 @@ func TestMari(t *testing.T) {
 		}
 	})
 
+	t.Run("Test Iterate Reverse Operation", func(t *testing.T) {
+		var kvPairs, allKvPairs, allReverseKvPairs []*mari.KeyValuePair
+
+		iterErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			var txIterErr error
+			kvPairs, txIterErr = tx.IterateReverse([]byte("hello"), 3, nil)
+			if txIterErr != nil { return txIterErr }
+
+			allKvPairs, txIterErr = tx.Iterate([]byte{0}, 100, nil)
+			if txIterErr != nil { return txIterErr }
+
+			allReverseKvPairs, txIterErr = tx.IterateReverse(nil, 100, nil)
+			if txIterErr != nil { return txIterErr }
+
+			return nil
+		})
+
+		if iterErr != nil { t.Errorf("error on mari iterate reverse: %s", iterErr.Error()) }
+
+		t.Log("keys in kv pairs", func() []string{
+			var keys []string
+			for _, kv := range kvPairs { 
+				keys = append(keys, string(kv.Key))
+			}
+
+			return keys
+		}())
+
+		if len(kvPairs) != 3 || string(kvPairs[0].Key) != "hello" { t.Errorf("iterate reverse did not begin at the start key") }
+		if ! IsSortedDescending(kvPairs) { t.Errorf("key value pairs are not in descending order") }
+
+		if len(allReverseKvPairs) != len(allKvPairs) {
+			t.Fatalf("total results do not match: actual(%d), expected(%d)", len(allReverseKvPairs), len(allKvPairs))
+		}
+
+		for idx, kvPair := range allReverseKvPairs {
+			expected := allKvPairs[len(allKvPairs) - 1 - idx]
+			if ! bytes.Equal(kvPair.Key, expected.Key) { t.Errorf("key at position %d does not match: actual(%s), expected(%s)", idx, kvPair.Key, expected.Key) }
+		}
+	})
+
 	t.Run("Test Range Operation", func(t *testing.T) {
 		var kvPairs []*mari.KeyValuePair
 
@@ -205,6 +390,102 @@ func TestMari(t *testing.T) {
 		}
 	})
 
+	t.Run("Test Reverse Range Operation", func(t *testing.T) {
+		var kvPairs, reverseKvPairs []*mari.KeyValuePair
+
+		rangeErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			var txRangeErr error
+			kvPairs, txRangeErr = tx.Range([]byte("hello"), []byte("yup"), nil)
+			if txRangeErr != nil { return txRangeErr }
+
+			reverseKvPairs, txRangeErr = tx.Range([]byte("hello"), []byte("yup"), &mari.MariRangeOpts{ Reverse: true })
+			if txRangeErr != nil { return txRangeErr }
+
+			return nil
+		})
+
+		if rangeErr != nil { t.Errorf("error on mari range: %s", rangeErr.Error()) }
+
+		t.Log("keys in kv pairs", func() []string{
+			var keys []string
+			for _, kv := range reverseKvPairs { 
+				keys = append(keys, string(kv.Key))
+			}
+
+			return keys
+		}())
+
+		if ! IsSortedDescending(reverseKvPairs) { t.Errorf("key value pairs are not in descending order") }
+		if len(reverseKvPairs) != len(kvPairs) { t.Fatalf("total results do not match: actual(%d), expected(%d)", len(reverseKvPairs), len(kvPairs)) }
+
+		for idx, kvPair := range reverseKvPairs {
+			expected := kvPairs[len(kvPairs) - 1 - idx]
+			if ! bytes.Equal(kvPair.Key, expected.Key) { t.Errorf("key at position %d does not match: actual(%s), expected(%s)", idx, kvPair.Key, expected.Key) }
+		}
+	})
+
+	t.Run("Test Range Bounds", func(t *testing.T) {
+		exclusive := false
+		startKey, endKey := []byte("hello"), []byte("yup")
+
+		rangeErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			for _, opts := range []*mari.MariRangeOpts{
+				nil,
+				{ StartInclusive: &exclusive },
+				{ EndInclusive: &exclusive },
+				{ StartInclusive: &exclusive, EndInclusive: &exclusive, Reverse: true },
+			} {
+				kvPairs, txRangeErr := tx.Range(startKey, endKey, opts)
+				if txRangeErr != nil { return txRangeErr }
+				if len(kvPairs) == 0 { t.Fatalf("range returned no results for opts: %v", opts) }
+
+				startIncluded, endIncluded := opts == nil || opts.StartInclusive == nil, opts == nil || opts.EndInclusive == nil
+
+				first, last := kvPairs[0], kvPairs[len(kvPairs) - 1]
+				if opts != nil && opts.Reverse { first, last = last, first }
+
+				if bytes.Equal(first.Key, startKey) != startIncluded { t.Errorf("start key inclusion does not match: actual(%s), expected included(%t)", first.Key, startIncluded) }
+				if bytes.Equal(last.Key, endKey) != endIncluded { t.Errorf("end key inclusion does not match: actual(%s), expected included(%t)", last.Key, endIncluded) }
+			}
+
+			return nil
+		})
+
+		if rangeErr != nil { t.Errorf("error on mari range: %s", rangeErr.Error()) }
+	})
+
+	t.Run("Test Range Chan Operation", func(t *testing.T) {
+		var kvPairs, streamedKvPairs []*mari.KeyValuePair
+
+		rangeErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			var txRangeErr error
+			kvPairs, txRangeErr = tx.Range([]byte("hello"), []byte("yup"), nil)
+			if txRangeErr != nil { return txRangeErr }
+
+			kvPairsChan, errChan := tx.RangeChan([]byte("hello"), []byte("yup"), nil)
+			for kvPair := range kvPairsChan { streamedKvPairs = append(streamedKvPairs, kvPair) }
+
+			return <-errChan
+		})
+
+		if rangeErr != nil { t.Errorf("error on mari range chan: %s", rangeErr.Error()) }
+
+		if len(streamedKvPairs) != len(kvPairs) { t.Fatalf("total results do not match: actual(%d), expected(%d)", len(streamedKvPairs), len(kvPairs)) }
+		for idx, kvPair := range streamedKvPairs {
+			if ! bytes.Equal(kvPair.Key, kvPairs[idx].Key) { t.Errorf("key at position %d does not match: actual(%s), expected(%s)", idx, kvPair.Key, kvPairs[idx].Key) }
+		}
+
+		readErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			kvPairsChan, errChan := tx.RangeChan([]byte("yup"), []byte("hello"), nil)
+			for range kvPairsChan {}
+
+			if <-errChan == nil { t.Errorf("expected error when start key is larger than end key") }
+			return nil
+		})
+
+		if readErr != nil { t.Errorf("error on mari range chan: %s", readErr.Error()) }
+	})
+
 	t.Run("Test Transform on Iterate Operation", func(t *testing.T) {
 		var kvPairs []*mari.KeyValuePair
 
@@ -251,6 +532,46 @@ func TestMari(t *testing.T) {
 		}
 	})
 
+	t.Run("Test Filtering Transform", func(t *testing.T) {
+		filter := func(kvPair *mari.KeyValuePair) *mari.KeyValuePair {
+			if ! bytes.HasPrefix(kvPair.Key, []byte("a")) { return nil }
+			return kvPair
+		}
+
+		opts := &mari.MariRangeOpts{ Transform: &filter }
+
+		var getKvPair *mari.KeyValuePair
+		var iterKvPairs, rangeKvPairs, reverseKvPairs []*mari.KeyValuePair
+
+		readErr := mariInst.ReadTx(func(tx *mari.MariTx) error {
+			var txErr error
+			getKvPair, txErr = tx.Get([]byte("hello"), &filter)
+			if txErr != nil { return txErr }
+
+			iterKvPairs, txErr = tx.Iterate([]byte{0}, 3, opts)
+			if txErr != nil { return txErr }
+
+			rangeKvPairs, txErr = tx.Range([]byte("Woah"), []byte("yup"), opts)
+			if txErr != nil { return txErr }
+
+			reverseKvPairs, txErr = tx.IterateReverse(nil, 100, opts)
+			if txErr != nil { return txErr }
+
+			return nil
+		})
+
+		if readErr != nil { t.Fatalf("error on filtered read: %s", readErr.Error()) }
+
+		if getKvPair != nil { t.Errorf("expected filtered get to return nil") }
+		if len(iterKvPairs) != 3 { t.Errorf("filtered results should not count towards total results: actual(%d), expected(3)", len(iterKvPairs)) }
+
+		for _, kvPairs := range [][]*mari.KeyValuePair{ iterKvPairs, rangeKvPairs, reverseKvPairs } {
+			for _, kvPair := range kvPairs {
+				if kvPair == nil || ! bytes.HasPrefix(kvPair.Key, []byte("a")) { t.Errorf("unfiltered key value pair in results: %v", kvPair) }
+			}
+		}
+	})
+
 	t.Run("Test Mari Delete", func(t *testing.T) {
 		delErr = mariInst.UpdateTx(func(tx *mari.MariTx) error {
 			delTxErr := tx.Delete([]byte("hello"))
@@ -4,6 +4,7 @@ import "bytes"
 import "crypto/rand"
 import "errors"
 import mrand "math/rand"
+import "testing"
 
 import "github.com/sirgallo/mari"
 
@@ -14,6 +15,48 @@ const NUM_RANGE_GO_ROUTINES = 10
 const INPUT_SIZE = 3000000
 const ITERATE_SIZE = 500000
 const TRANSACTION_CHUNK_SIZE = 10000
+const SEGMENT_INPUT_SIZE = 50000
+const NODE_POOL_TEST_SIZE = 10
+const NODE_POOL_HOT_KEYS = 50
+const NODE_POOL_ITERATIONS = 200
+const NODE_POOL_TEST_CEILING = 10000
+const SMALL_NODE_POOL_SIZE = 10000
+const APPEND_ONLY_VERSIONS = 1000
+const CLOSE_TEST_CYCLES = 20
+const COMPACTION_INPUT_SIZE = 20000
+const COMPACTION_RETAIN = 5
+const COMPACTION_FRAGMENTATION = 0.6
+const FRAGMENTATION_INPUT_SIZE = 1000
+const LONG_KEY_SIZE = 32 * 1024
+const LONG_KEY_MAX_STACK = 2 << 20
+const LONG_KEY_WRITERS = 4
+const LONG_KEYS_PER_WRITER = 5
+const LARGE_VALUE_SIZE = 100 * 1024 * 1024
+const RESIZE_VALUE_SIZE = 80 * 1024 * 1024
+const TOO_LONG_KEY_SIZE = 70000
+const OVERFLOW_INPUT_SIZE = 100
+const OVERFLOW_THRESHOLD = 1024
+const OVERFLOW_VALUE_SIZE = 256 * 1024
+const GROWTH_FACTOR = 1.5
+const ENCRYPTION_INPUT_SIZE = 10
+const KEY_ENCODING_INPUT_SIZE = 1000
+const TYPED_INPUT_SIZE = 100
+const MERGE_WRITERS = 16
+const CHANGE_LOG_TXS = 5
+const VERSION_INDEX_VERSIONS = 10000
+const SNAPSHOT_INPUT_SIZE = 1000
+const CURSOR_INPUT_SIZE = 50000
+const CURSOR_SEEKS = 1000
+const RANK_INPUT_SIZE = 50000
+const RANK_SAMPLES = 1000
+const IN_MEMORY_INPUT_SIZE = 50000
+const VERIFY_INPUT_SIZE = 20000
+const RANGE_PARALLEL_INPUT_SIZE = 200000
+const RANGE_PARALLEL_WORKERS = 8
+const PREFETCH_INPUT_SIZE = 100000
+const NODE_CACHE_INPUT_SIZE = 20000
+const READ_ONLY_INPUT_SIZE = 80000
+const SHORT_INPUT_DIVISOR = 10
 const PWRITE_INPUT_SIZE = INPUT_SIZE / 5
 const WRITE_CHUNK_SIZE = INPUT_SIZE / NUM_WRITER_GO_ROUTINES
 const READ_CHUNK_SIZE = INPUT_SIZE / NUM_READER_GO_ROUTINES
@@ -21,6 +64,10 @@ const PCHUNK_SIZE_READ = (INPUT_SIZE - PWRITE_INPUT_SIZE) / NUM_READER_GO_ROUTIN
 const PCHUNK_SIZE_WRITE = PWRITE_INPUT_SIZE / NUM_WRITER_GO_ROUTINES
 
 
+// smallNodePoolSize is for instances that are not under load, so each one does not pre-allocate the default node pool
+var smallNodePoolSize = int64(SMALL_NODE_POOL_SIZE)
+
+
 type KeyVal struct {
 	Key   []byte
 	Value []byte
@@ -46,6 +93,40 @@ func GenerateRandomBytes(length int) ([]byte, error) {
 	return randomBytes, nil
 }
 
+func InputSize(size int) int {
+	if testing.Short() { return size / SHORT_INPUT_DIVISOR }
+	return size
+}
+
+func GenerateKeyValPairs(size int) []KeyVal {
+	keyValPairs := make([]KeyVal, size)
+
+	for idx := range keyValPairs {
+		randomBytes, _ := GenerateRandomBytes(32)
+		keyValPairs[idx] = KeyVal{ Key: randomBytes, Value: randomBytes }
+	}
+
+	return keyValPairs
+}
+
+func InsertKeyValPairs(tb testing.TB, inst *mari.Mari, pairs []KeyVal) {
+	chunks, chunkErr := Chunk(pairs, TRANSACTION_CHUNK_SIZE)
+	if chunkErr != nil { tb.Fatalf("error chunking input: %s", chunkErr.Error()) }
+
+	for _, chunk := range chunks {
+		putErr := inst.UpdateTx(func(tx *mari.MariTx) error {
+			for _, val := range chunk {
+				putTxErr := tx.Put(val.Key, val.Value)
+				if putTxErr != nil { return putTxErr }
+			}
+
+			return nil
+		})
+
+		if putErr != nil { tb.Fatalf("error on mari put: %s", putErr.Error()) }
+	}
+}
+
 func TwoRandomDistinctValues(min, max int) (int, int, error) {
 	if min >= max { return 0, 0, errors.New("min cannot be greater than max") }
 
@@ -67,6 +148,14 @@ func IsSorted(s []*mari.KeyValuePair) bool {
 	return true
 }
 
+func IsSortedDescending(s []*mari.KeyValuePair) bool {
+	for i := 1; i < len(s); i++ {
+		if bytes.Compare(s[i - 1].Key, s[i].Key) < 0 { return false }
+	}
+
+	return true
+}
+
 func Chunk (array[]KeyVal, chunkSize int) ([][]KeyVal, error) {
 	if chunkSize <= 0 { return nil, errors.New("chunk size needs to be greater than 0") }
 	